@@ -45,6 +45,11 @@ var flags = []cli.Flag{
 		Name:    "server-host",
 		Usage:   "server fully qualified url (<scheme>://<host>)",
 	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_PUBLIC_URL"},
+		Name:    "server-public-url",
+		Usage:   "canonical public url (<scheme>://<host>) used for webhooks and status links, defaults to the server host",
+	},
 	&cli.StringFlag{
 		EnvVars: []string{"WOODPECKER_SERVER_ADDR"},
 		Name:    "server-addr",
@@ -97,6 +102,38 @@ var flags = []cli.Flag{
 		Name:    "authenticate-public-repos",
 		Usage:   "Always use authentication to clone repositories even if they are public. Needed if the SCM requires to always authenticate as used by many companies.",
 	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_MAX_HOOKS"},
+		Name:    "max-hooks",
+		Usage:   "Maximum number of hooks processed at once, hooks of the same repository are processed one after the other. 0 means no limit.",
+	},
+	&cli.DurationFlag{
+		EnvVars: []string{"WOODPECKER_HOOK_DELIVERY_TTL"},
+		Name:    "hook-delivery-ttl",
+		Usage:   "How long the ids of received hook deliveries are remembered to ignore deliveries sent again.",
+		Value:   10 * time.Minute,
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_INITIAL_BUILD"},
+		Name:    "initial-build",
+		Usage:   "Build the head of the default branch when a repository is activated.",
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_INCLUDE_REPOS"},
+		Name:    "include-repos",
+		Usage:   "Repositories of other owners pipeline configs may include configs of, e.g. org/templates or org/*.",
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_SKIP_TOKENS"},
+		Name:    "skip-tokens",
+		Usage:   "Words skipping a build if found in square brackets in its commit message, empty to never skip builds.",
+		Value:   cli.NewStringSlice("ci skip", "skip ci"),
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_EVENT_CONFIGS"},
+		Name:    "event-configs",
+		Usage:   "Use the config file named after the event of the build, e.g. .woodpecker/push.yml, as the only config of repositories without custom config path.",
+	},
 	&cli.StringFlag{
 		EnvVars: []string{"WOODPECKER_DEFAULT_CLONE_IMAGE"},
 		Name:    "default-clone-image",
@@ -332,11 +369,193 @@ var flags = []cli.Flag{
 		Usage:    "gitea oauth2 client secret",
 		FilePath: os.Getenv("WOODPECKER_GITEA_SECRET_FILE"),
 	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_INSTANCES"},
+		Name:    "gitea-instances",
+		Usage:   "names of additional gitea instances, configured by WOODPECKER_GITEA_<NAME>_URL, _CLIENT and _SECRET",
+	},
 	&cli.BoolFlag{
 		EnvVars: []string{"WOODPECKER_GITEA_SKIP_VERIFY"},
 		Name:    "gitea-skip-verify",
 		Usage:   "gitea skip ssl verification",
 	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_PROXY"},
+		Name:    "gitea-proxy",
+		Usage:   "gitea url of the HTTP or SOCKS5 proxy used for API calls",
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_CA_CERT"},
+		Name:    "gitea-ca-cert",
+		Usage:   "gitea PEM bundle or path to one of additionally trusted CA certificates",
+	},
+	&cli.DurationFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_TIMEOUT"},
+		Name:    "gitea-timeout",
+		Usage:   "gitea api call timeout",
+		Value:   10 * time.Second,
+	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_RETRIES"},
+		Name:    "gitea-retries",
+		Usage:   "gitea api call retries on server errors and rate limits",
+		Value:   3,
+	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_REPO_PAGE_SIZE"},
+		Name:    "gitea-repo-page-size",
+		Usage:   "gitea number of repositories listed per api call when syncing",
+		Value:   50,
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_AVATAR_BASE_URL"},
+		Name:    "gitea-avatar-base-url",
+		Usage:   "gitea base url relative avatar urls are resolved against",
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_AVATAR_FALLBACK"},
+		Name:    "gitea-avatar-fallback",
+		Usage:   "gitea avatar url used if none is provided",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_AVATAR_GRAVATAR"},
+		Name:    "gitea-avatar-gravatar",
+		Usage:   "gitea derive missing avatars from the email via gravatar",
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_PULL_REQUEST_ACTIONS"},
+		Name:    "gitea-pull-request-actions",
+		Usage:   "gitea pull request actions triggering builds",
+		Value:   cli.NewStringSlice("opened", "synchronized", "reopened"),
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_SKIP_DRAFT_PULL_REQUESTS"},
+		Name:    "gitea-skip-draft-pull-requests",
+		Usage:   "gitea do not build draft pull requests",
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_EVENT_MAPPING"},
+		Name:    "gitea-event-mapping",
+		Usage:   "gitea events builds are created with instead of the hook event, e.g. pull_request=push",
+	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_MAX_CHANGED_FILES"},
+		Name:    "gitea-max-changed-files",
+		Usage:   "gitea maximum number of changed files stored per build",
+		Value:   500,
+	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_MAX_MESSAGE_LENGTH"},
+		Name:    "gitea-max-message-length",
+		Usage:   "gitea maximum number of characters of build titles and messages",
+		Value:   2000,
+	},
+	&cli.Int64Flag{
+		EnvVars: []string{"WOODPECKER_GITEA_MAX_FILE_SIZE"},
+		Name:    "gitea-max-file-size",
+		Usage:   "gitea maximum size in bytes of fetched pipeline files",
+		Value:   5 << 20,
+	},
+	&cli.Int64Flag{
+		EnvVars: []string{"WOODPECKER_GITEA_MAX_DIFF_SIZE"},
+		Name:    "gitea-max-diff-size",
+		Usage:   "gitea maximum size in bytes of build diffs",
+		Value:   10 << 20,
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_REBUILD_COMMAND"},
+		Name:    "gitea-rebuild-command",
+		Usage:   "gitea pull request comment command triggering a rebuild",
+		Value:   "/rebuild",
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_STATUS_CONTEXT_FORMAT"},
+		Name:    "gitea-status-context-format",
+		Usage:   "gitea commit status context template, e.g. {{ .context }}/{{ .pipeline }}",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_STEP_STATUSES"},
+		Name:    "gitea-step-statuses",
+		Usage:   "gitea report the status of every pipeline step",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_SUMMARY"},
+		Name:    "gitea-summary",
+		Usage:   "gitea post a summary of finished builds as comment to their pull request",
+	},
+	&cli.StringFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_SUMMARY_TEMPLATE"},
+		Name:    "gitea-summary-template",
+		Usage:   "gitea template of the build summary comment",
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_IGNORE_BRANCHES"},
+		Name:    "gitea-ignore-branches",
+		Usage:   "gitea glob patterns of branches whose pushes are ignored",
+	},
+	&cli.StringFlag{
+		EnvVars:  []string{"WOODPECKER_GITEA_DEPLOY_KEY"},
+		Name:     "gitea-deploy-key",
+		Usage:    "gitea public ssh key registered as read-only deploy key of activated repositories",
+		FilePath: os.Getenv("WOODPECKER_GITEA_DEPLOY_KEY_FILE"),
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_CLONE_SSH"},
+		Name:    "gitea-clone-ssh",
+		Usage:   "gitea clone repositories via ssh instead of http",
+	},
+	&cli.IntFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_SSH_PORT"},
+		Name:    "gitea-ssh-port",
+		Usage:   "gitea port of the ssh server used in derived ssh clone urls",
+		Value:   22,
+	},
+	&cli.StringSliceFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_NETRC_HOSTS"},
+		Name:    "gitea-netrc-hosts",
+		Usage:   "gitea additional hosts the netrc authenticates against with the gitea credentials",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_TAG_CHANGED_FILES"},
+		Name:    "gitea-tag-changed-files",
+		Usage:   "gitea compare tags against the previous tag to get the changed files",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_TAG_MESSAGES"},
+		Name:    "gitea-tag-messages",
+		Usage:   "gitea use the message of annotated tags as message of tag builds",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_PUSH_COMPARE_FILES"},
+		Name:    "gitea-push-compare-files",
+		Usage:   "gitea compare before and after of forced or truncated pushes to get the changed files",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_FETCH_TOPICS"},
+		Name:    "gitea-fetch-topics",
+		Usage:   "gitea fetch the topics of repositories when activating or repairing them",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_PACKAGE_DELETIONS"},
+		Name:    "gitea-package-deletions",
+		Usage:   "gitea also build package events of deleted package versions",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_TAG_DELETIONS"},
+		Name:    "gitea-tag-deletions",
+		Usage:   "gitea also build delete events of deleted tags",
+	},
+	&cli.BoolFlag{
+		EnvVars: []string{"WOODPECKER_GITEA_SENDER_TEAMS"},
+		Name:    "gitea-sender-teams",
+		Usage:   "gitea look up the organization teams of the sender of push and pull request builds",
+	},
+	&cli.StringFlag{
+		EnvVars:  []string{"WOODPECKER_GITEA_ORG_HOOK_SECRET"},
+		Name:     "gitea-org-hook-secret",
+		Usage:    "gitea secret of the organization hooks notifying about deleted repositories",
+		FilePath: os.Getenv("WOODPECKER_GITEA_ORG_HOOK_SECRET_FILE"),
+	},
 	//
 	// Bitbucket
 	//
@@ -100,6 +100,19 @@ func run(c *cli.Context) error {
 		)
 	}
 
+	if publicURL := c.String("server-public-url"); publicURL != "" {
+		if !strings.Contains(publicURL, "://") {
+			log.Fatal().Msg(
+				"WOODPECKER_PUBLIC_URL must be <scheme>://<hostname> format",
+			)
+		}
+		if strings.HasSuffix(publicURL, "/") {
+			log.Fatal().Msg(
+				"WOODPECKER_PUBLIC_URL must not have trailing slash",
+			)
+		}
+	}
+
 	_remote, err := setupRemote(c)
 	if err != nil {
 		log.Fatal().Err(err).Msg("")
@@ -267,6 +280,7 @@ func setupEvilGlobals(c *cli.Context, v store.Store, r remote.Remote) {
 	server.Config.Services.Secrets = setupSecretService(c, v)
 	server.Config.Services.Senders = sender.New(v, v)
 	server.Config.Services.Environ = setupEnvironService(c, v)
+	server.Config.Services.Deliveries = setupDeliveryService(c)
 
 	if endpoint := c.String("gating-service"); endpoint != "" {
 		server.Config.Services.Senders = sender.NewRemote(endpoint)
@@ -287,6 +301,14 @@ func setupEvilGlobals(c *cli.Context, v store.Store, r remote.Remote) {
 	// Cloning
 	server.Config.Pipeline.DefaultCloneImage = c.String("default-clone-image")
 
+	// activation
+	server.Config.Pipeline.InitialBuild = c.Bool("initial-build")
+
+	// includes
+	server.Config.Pipeline.IncludeRepos = c.StringSlice("include-repos")
+	server.Config.Pipeline.EventConfigs = c.Bool("event-configs")
+	server.Config.Pipeline.SkipTokens = c.StringSlice("skip-tokens")
+
 	// limits
 	server.Config.Pipeline.Limits.MemSwapLimit = c.Int64("limit-mem-swap")
 	server.Config.Pipeline.Limits.MemLimit = c.Int64("limit-mem")
@@ -305,10 +327,16 @@ func setupEvilGlobals(c *cli.Context, v store.Store, r remote.Remote) {
 	} else {
 		server.Config.Server.OAuthHost = c.String("server-host")
 	}
+	if c.IsSet("server-public-url") {
+		server.Config.Server.PublicHost = c.String("server-public-url")
+	} else {
+		server.Config.Server.PublicHost = c.String("server-host")
+	}
 	server.Config.Server.Port = c.String("server-addr")
 	server.Config.Server.Docs = c.String("docs")
 	server.Config.Server.StatusContext = c.String("status-context")
 	server.Config.Server.SessionExpires = c.Duration("session-expires")
+	server.Config.Server.MaxHooks = c.Int("max-hooks")
 	server.Config.Pipeline.Networks = c.StringSlice("network")
 	server.Config.Pipeline.Volumes = c.StringSlice("volume")
 	server.Config.Pipeline.Privileged = c.StringSlice("escalate")
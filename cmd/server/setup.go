@@ -30,6 +30,7 @@ import (
 
 	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/plugins/deliveries"
 	"github.com/woodpecker-ci/woodpecker/server/plugins/environments"
 	"github.com/woodpecker-ci/woodpecker/server/plugins/registry"
 	"github.com/woodpecker-ci/woodpecker/server/plugins/secrets"
@@ -176,6 +177,10 @@ func setupEnvironService(c *cli.Context, s store.Store) model.EnvironService {
 	return environments.Parse(c.StringSlice("environment"))
 }
 
+func setupDeliveryService(c *cli.Context) model.DeliveryService {
+	return deliveries.New(c.Duration("hook-delivery-ttl"))
+}
+
 // setupRemote helper function to setup the remote from the CLI arguments.
 func setupRemote(c *cli.Context) (remote.Remote, error) {
 	switch {
@@ -232,12 +237,73 @@ func setupGitea(c *cli.Context) (remote.Remote, error) {
 		Client:     c.String("gitea-client"),
 		Secret:     c.String("gitea-secret"),
 		SkipVerify: c.Bool("gitea-skip-verify"),
+		Proxy:      c.String("gitea-proxy"),
+		CACert:     c.String("gitea-ca-cert"),
+		Timeout:    c.Duration("gitea-timeout"),
+		Retries:    c.Int("gitea-retries"),
+
+		RepoPageSize: c.Int("gitea-repo-page-size"),
+
+		AvatarBaseURL:  c.String("gitea-avatar-base-url"),
+		AvatarFallback: c.String("gitea-avatar-fallback"),
+		AvatarGravatar: c.Bool("gitea-avatar-gravatar"),
+
+		PullRequestActions:    c.StringSlice("gitea-pull-request-actions"),
+		SkipDraftPullRequests: c.Bool("gitea-skip-draft-pull-requests"),
+		EventMapping:          c.StringSlice("gitea-event-mapping"),
+		MaxChangedFiles:       c.Int("gitea-max-changed-files"),
+		MaxFileSize:           c.Int64("gitea-max-file-size"),
+		MaxDiffSize:           c.Int64("gitea-max-diff-size"),
+		MaxMessageLen:         c.Int("gitea-max-message-length"),
+		RebuildCommand:        c.String("gitea-rebuild-command"),
+		StatusContextFormat:   c.String("gitea-status-context-format"),
+		StepStatuses:          c.Bool("gitea-step-statuses"),
+		Summary:               c.Bool("gitea-summary"),
+		SummaryTemplate:       c.String("gitea-summary-template"),
+		IgnoreBranches:        c.StringSlice("gitea-ignore-branches"),
+		DeployKey:             c.String("gitea-deploy-key"),
+		CloneSSH:              c.Bool("gitea-clone-ssh"),
+		SSHPort:               c.Int("gitea-ssh-port"),
+		NetrcHosts:            c.StringSlice("gitea-netrc-hosts"),
+		TagChangedFiles:       c.Bool("gitea-tag-changed-files"),
+		TagMessages:           c.Bool("gitea-tag-messages"),
+		PushCompareFiles:      c.Bool("gitea-push-compare-files"),
+		FetchTopics:           c.Bool("gitea-fetch-topics"),
+		PackageDeletions:      c.Bool("gitea-package-deletions"),
+		TagDeletions:          c.Bool("gitea-tag-deletions"),
+		SenderTeams:           c.Bool("gitea-sender-teams"),
+		OrgHookSecret:         c.String("gitea-org-hook-secret"),
 	}
 	if len(opts.URL) == 0 {
 		log.Fatal().Msg("WOODPECKER_GITEA_URL must be set")
 	}
 	log.Trace().Msgf("Remote (gitea) opts: %#v", opts)
-	return gitea.New(opts)
+
+	names := c.StringSlice("gitea-instances")
+	if len(names) == 0 {
+		return gitea.New(opts)
+	}
+	instances := []gitea.Opts{opts}
+	for _, name := range names {
+		instances = append(instances, giteaInstanceOpts(opts, name))
+	}
+	return gitea.NewInstances(instances...)
+}
+
+// helper function to setup an additional Gitea instance from the environment,
+// all options but url and OAuth2 application are taken from the default one.
+func giteaInstanceOpts(opts gitea.Opts, name string) gitea.Opts {
+	prefix := "WOODPECKER_GITEA_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+	opts.Name = name
+	opts.URL = strings.TrimRight(os.Getenv(prefix+"URL"), "/")
+	opts.Client = os.Getenv(prefix + "CLIENT")
+	opts.Secret = os.Getenv(prefix + "SECRET")
+	if len(opts.URL) == 0 {
+		log.Fatal().Msgf("%sURL must be set", prefix)
+	}
+	log.Trace().Msgf("Remote (gitea instance %s) url: %s", name, opts.URL)
+	return opts
 }
 
 // helper function to setup the Stash remote from the CLI arguments.
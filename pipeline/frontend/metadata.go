@@ -10,10 +10,15 @@ import (
 
 // Event types corresponding to scm hooks.
 const (
-	EventPush   = "push"
-	EventPull   = "pull_request"
-	EventTag    = "tag"
-	EventDeploy = "deployment"
+	EventPush    = "push"
+	EventPull    = "pull_request"
+	EventTag     = "tag"
+	EventDeploy  = "deployment"
+	EventRelease = "release"
+	EventCron    = "cron"
+	EventPackage = "package"
+	EventDelete  = "delete"
+	EventManual  = "manual"
 )
 
 type (
@@ -35,22 +40,45 @@ type (
 		Private bool     `json:"private,omitempty"`
 		Secrets []Secret `json:"secrets,omitempty"`
 		Branch  string   `json:"default_branch,omitempty"`
+		Topics  []string `json:"topics,omitempty"`
 	}
 
 	// Build defines runtime metadata for a build.
 	Build struct {
-		Number   int64  `json:"number,omitempty"`
-		Created  int64  `json:"created,omitempty"`
-		Started  int64  `json:"started,omitempty"`
-		Finished int64  `json:"finished,omitempty"`
-		Timeout  int64  `json:"timeout,omitempty"`
-		Status   string `json:"status,omitempty"`
-		Event    string `json:"event,omitempty"`
-		Link     string `json:"link,omitempty"`
-		Target   string `json:"target,omitempty"`
-		Trusted  bool   `json:"trusted,omitempty"`
-		Commit   Commit `json:"commit,omitempty"`
-		Parent   int64  `json:"parent,omitempty"`
+		Number   int64   `json:"number,omitempty"`
+		Created  int64   `json:"created,omitempty"`
+		Started  int64   `json:"started,omitempty"`
+		Finished int64   `json:"finished,omitempty"`
+		Timeout  int64   `json:"timeout,omitempty"`
+		Status   string  `json:"status,omitempty"`
+		Event    string  `json:"event,omitempty"`
+		Link     string  `json:"link,omitempty"`
+		Target   string  `json:"target,omitempty"`
+		Trusted  bool    `json:"trusted,omitempty"`
+		Commit   Commit  `json:"commit,omitempty"`
+		Parent   int64   `json:"parent,omitempty"`
+		Cron     string  `json:"cron,omitempty"`
+		Package  Package `json:"package,omitempty"`
+		Deleted  Ref     `json:"deleted,omitempty"`
+		// Prerelease is set for release events of prereleases.
+		Prerelease bool `json:"prerelease,omitempty"`
+		// FirstTag is set for tag events of the first tag of the repository.
+		FirstTag bool `json:"first_tag,omitempty"`
+	}
+
+	// Package defines runtime metadata for the package version of a package
+	// event.
+	Package struct {
+		Type    string `json:"type,omitempty"`
+		Name    string `json:"name,omitempty"`
+		Version string `json:"version,omitempty"`
+		Action  string `json:"action,omitempty"`
+	}
+
+	// Ref defines runtime metadata for the branch or tag of a delete event.
+	Ref struct {
+		Type string `json:"type,omitempty"`
+		Name string `json:"name,omitempty"`
 	}
 
 	// Commit defines runtime metadata for a commit.
@@ -58,10 +86,34 @@ type (
 		Sha          string   `json:"sha,omitempty"`
 		Ref          string   `json:"ref,omitempty"`
 		Refspec      string   `json:"refspec,omitempty"`
+		SourceBranch string   `json:"source_branch,omitempty"`
+		PullRequest  int64    `json:"pull_request,omitempty"`
 		Branch       string   `json:"branch,omitempty"`
 		Message      string   `json:"message,omitempty"`
 		Author       Author   `json:"author,omitempty"`
+		Committer    Author   `json:"committer,omitempty"`
 		ChangedFiles []string `json:"changed_files,omitempty"`
+		Labels       []string `json:"labels,omitempty"`
+		Assignees    []string `json:"assignees,omitempty"`
+		Verified     bool     `json:"verified,omitempty"`
+		Signer       string   `json:"signer,omitempty"`
+		// BaseProtected is nil if the protection of the target branch is unknown.
+		BaseProtected *bool  `json:"base_protected,omitempty"`
+		Milestone     string `json:"milestone,omitempty"`
+		// BaseCommit, MergeBase and MergeCommit are the head of the target
+		// branch, the merge base with it and the merge commit of a pull request.
+		BaseCommit  string `json:"base_commit,omitempty"`
+		MergeBase   string `json:"merge_base,omitempty"`
+		MergeCommit string `json:"merge_commit,omitempty"`
+		// Draft is set for pull requests marked as work in progress.
+		Draft bool `json:"draft,omitempty"`
+		// Teams are the teams of the repository organization the sender is a member of.
+		Teams []string `json:"teams,omitempty"`
+		// Approver is the user whose approving review started the pull request build.
+		Approver string `json:"approver,omitempty"`
+		// ReviewState is the state of the review which started the pull request
+		// build, one of approved, rejected or comment.
+		ReviewState string `json:"review_state,omitempty"`
 	}
 
 	// Author defines runtime metadata for a commit author.
@@ -117,6 +169,9 @@ func (m *Metadata) Environ() map[string]string {
 		sourceBranch = branchParts[0]
 		targetBranch = branchParts[1]
 	}
+	if m.Curr.Commit.SourceBranch != "" {
+		sourceBranch = m.Curr.Commit.SourceBranch
+	}
 
 	params := map[string]string{
 		"CI":                     m.Sys.Name,
@@ -128,6 +183,7 @@ func (m *Metadata) Environ() map[string]string {
 		"CI_REPO_REMOTE":         m.Repo.Remote,
 		"CI_REPO_DEFAULT_BRANCH": m.Repo.Branch,
 		"CI_REPO_PRIVATE":        strconv.FormatBool(m.Repo.Private),
+		"CI_REPO_TOPICS":         strings.Join(m.Repo.Topics, ","),
 		"CI_REPO_TRUSTED":        "false", // TODO should this be added?
 
 		"CI_COMMIT_SHA":           m.Curr.Commit.Sha,
@@ -141,19 +197,36 @@ func (m *Metadata) Environ() map[string]string {
 		"CI_COMMIT_AUTHOR":        m.Curr.Commit.Author.Name,
 		"CI_COMMIT_AUTHOR_EMAIL":  m.Curr.Commit.Author.Email,
 		"CI_COMMIT_AUTHOR_AVATAR": m.Curr.Commit.Author.Avatar,
-		"CI_COMMIT_TAG":           "", // will be set if event is tag
+		"CI_COMMIT_TAG":           "", // will be set if event is tag or release
 		"CI_COMMIT_PULL_REQUEST":  "", // will be set if event is pr
+		"CI_COMMIT_ASSIGNEES":     "", // will be set if event is pr
+
+		"CI_COMMIT_TARGET_BRANCH_PROTECTED": "", // will be set if event is pr and the protection is known
+
+		"CI_COMMIT_COMMITTER":       m.Curr.Commit.Committer.Name,
+		"CI_COMMIT_COMMITTER_EMAIL": m.Curr.Commit.Committer.Email,
 
 		"CI_BUILD_NUMBER":        strconv.FormatInt(m.Curr.Number, 10),
 		"CI_BUILD_PARENT":        strconv.FormatInt(m.Curr.Parent, 10),
 		"CI_BUILD_EVENT":         m.Curr.Event,
 		"CI_BUILD_LINK":          m.Curr.Link,
 		"CI_BUILD_DEPLOY_TARGET": m.Curr.Target,
+		"CI_BUILD_CRON":          m.Curr.Cron,
 		"CI_BUILD_STATUS":        m.Curr.Status,
 		"CI_BUILD_CREATED":       strconv.FormatInt(m.Curr.Created, 10),
 		"CI_BUILD_STARTED":       strconv.FormatInt(m.Curr.Started, 10),
 		"CI_BUILD_FINISHED":      strconv.FormatInt(m.Curr.Finished, 10),
 
+		"CI_PACKAGE_TYPE":    m.Curr.Package.Type,
+		"CI_PACKAGE_NAME":    m.Curr.Package.Name,
+		"CI_PACKAGE_VERSION": m.Curr.Package.Version,
+		"CI_PACKAGE_ACTION":  m.Curr.Package.Action,
+
+		"CI_RELEASE_IS_PRERELEASE": "", // will be set if event is release
+
+		"CI_DELETED_REF_TYPE": m.Curr.Deleted.Type,
+		"CI_DELETED_REF_NAME": m.Curr.Deleted.Name,
+
 		"CI_JOB_NUMBER":   strconv.Itoa(m.Job.Number),
 		"CI_JOB_STATUS":   "", // will be set by agent
 		"CI_JOB_STARTED":  "", // will be set by agent
@@ -203,13 +276,33 @@ func (m *Metadata) Environ() map[string]string {
 		"CI_TAG":                     "",                                   // use CI_COMMIT_TAG
 		"CI_PULL_REQUEST":            "",                                   // use CI_COMMIT_PULL_REQUEST
 	}
-	if m.Curr.Event == EventTag {
+	if m.Curr.Event == EventTag || m.Curr.Event == EventRelease {
 		params["CI_COMMIT_TAG"] = strings.TrimPrefix(m.Curr.Commit.Ref, "refs/tags/")
 		params["CI_TAG"] = params["CI_COMMIT_TAG"]
 	}
+	if m.Curr.Event == EventTag {
+		params["CI_COMMIT_TAG_IS_FIRST"] = strconv.FormatBool(m.Curr.FirstTag)
+	}
+	if m.Curr.Event == EventRelease {
+		params["CI_RELEASE_IS_PRERELEASE"] = strconv.FormatBool(m.Curr.Prerelease)
+	}
 	if m.Curr.Event == EventPull {
 		params["CI_COMMIT_PULL_REQUEST"] = pullRegexp.FindString(m.Curr.Commit.Ref)
+		if m.Curr.Commit.PullRequest != 0 {
+			params["CI_COMMIT_PULL_REQUEST"] = strconv.FormatInt(m.Curr.Commit.PullRequest, 10)
+		}
 		params["CI_PULL_REQUEST"] = params["CI_COMMIT_PULL_REQUEST"]
+		params["CI_COMMIT_ASSIGNEES"] = strings.Join(m.Curr.Commit.Assignees, ",")
+		if m.Curr.Commit.BaseProtected != nil {
+			params["CI_COMMIT_TARGET_BRANCH_PROTECTED"] = strconv.FormatBool(*m.Curr.Commit.BaseProtected)
+		}
+		params["CI_COMMIT_MILESTONE"] = m.Curr.Commit.Milestone
+		params["CI_COMMIT_APPROVER"] = m.Curr.Commit.Approver
+		params["CI_COMMIT_REVIEW_STATE"] = m.Curr.Commit.ReviewState
+		params["CI_COMMIT_PULL_REQUEST_DRAFT"] = strconv.FormatBool(m.Curr.Commit.Draft)
+		params["CI_COMMIT_BASE_SHA"] = m.Curr.Commit.BaseCommit
+		params["CI_COMMIT_MERGE_BASE_SHA"] = m.Curr.Commit.MergeBase
+		params["CI_COMMIT_MERGE_SHA"] = m.Curr.Commit.MergeCommit
 	}
 
 	return params
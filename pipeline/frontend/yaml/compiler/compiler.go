@@ -110,6 +110,15 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		})
 	}
 
+	// mask the netrc password so it never shows up in the logs
+	if password := c.cloneEnv["CI_NETRC_PASSWORD"]; password != "" {
+		config.Secrets = append(config.Secrets, &backend.Secret{
+			Name:  "netrc_password",
+			Value: password,
+			Mask:  true,
+		})
+	}
+
 	// overrides the default workspace paths when specified
 	// in the YAML file.
 	if len(conf.Workspace.Base) != 0 {
@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/woodpecker-ci/woodpecker/pipeline/frontend"
@@ -65,14 +66,37 @@ func WithMetadata(metadata frontend.Metadata) Option {
 
 // WithNetrc configures the compiler with netrc authentication
 // credentials added by default to every container in the pipeline.
-func WithNetrc(username, password, machine string) Option {
+// The credentials are used for the machine and any additional hosts,
+// e.g. the hosts of submodules.
+func WithNetrc(username, password, machine string, hosts ...string) Option {
 	return func(compiler *Compiler) {
 		compiler.cloneEnv["CI_NETRC_USERNAME"] = username
 		compiler.cloneEnv["CI_NETRC_PASSWORD"] = password
 		compiler.cloneEnv["CI_NETRC_MACHINE"] = machine
+		if len(hosts) != 0 {
+			compiler.cloneEnv["CI_NETRC_HOSTS"] = strings.Join(hosts, ",")
+		}
+
+		// clone plugins only write the netrc of the machine, so the
+		// additional hosts are passed to git as credential helpers
+		for i, host := range hosts {
+			for j, scheme := range []string{"https", "http"} {
+				n := strconv.Itoa(2*i + j)
+				compiler.cloneEnv["GIT_CONFIG_KEY_"+n] = "credential." + scheme + "://" + host + ".helper"
+				compiler.cloneEnv["GIT_CONFIG_VALUE_"+n] = netrcCredentialHelper
+			}
+		}
+		if len(hosts) != 0 {
+			compiler.cloneEnv["GIT_CONFIG_COUNT"] = strconv.Itoa(2 * len(hosts))
+		}
 	}
 }
 
+// netrcCredentialHelper is a git credential helper returning the netrc
+// credentials. Steps with commands remove them from the environment after
+// writing the netrc file, then git falls back to the netrc file.
+const netrcCredentialHelper = `!f() { test "$1" = get && test -n "$CI_NETRC_PASSWORD" && printf 'username=%s\npassword=%s\n' "$CI_NETRC_USERNAME" "$CI_NETRC_PASSWORD"; }; f`
+
 // WithWorkspace configures the compiler with the workspace base
 // and path. The workspace base is a volume created at runtime and
 // mounted into all containers in the pipeline. The base and path
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/woodpecker-ci/woodpecker/pipeline/frontend"
+	"github.com/woodpecker-ci/woodpecker/pipeline/frontend/yaml"
 )
 
 func TestWithWorkspace(t *testing.T) {
@@ -150,6 +151,47 @@ func TestWithNetrc(t *testing.T) {
 	if compiler.cloneEnv["CI_NETRC_MACHINE"] != "github.com" {
 		t.Errorf("WithNetrc should set CI_NETRC_MACHINE")
 	}
+	if _, ok := compiler.cloneEnv["CI_NETRC_HOSTS"]; ok {
+		t.Errorf("WithNetrc should not set CI_NETRC_HOSTS without additional hosts")
+	}
+
+	compiler = New(
+		WithNetrc(
+			"octocat",
+			"password",
+			"github.com",
+			"gitea.com",
+			"codeberg.org",
+		),
+	)
+	if compiler.cloneEnv["CI_NETRC_HOSTS"] != "gitea.com,codeberg.org" {
+		t.Errorf("WithNetrc should set CI_NETRC_HOSTS")
+	}
+	if compiler.cloneEnv["GIT_CONFIG_COUNT"] != "4" {
+		t.Errorf("WithNetrc should configure a git credential helper per host and scheme")
+	}
+	if compiler.cloneEnv["GIT_CONFIG_KEY_2"] != "credential.https://codeberg.org.helper" {
+		t.Errorf("WithNetrc should configure a git credential helper for codeberg.org, got %q", compiler.cloneEnv["GIT_CONFIG_KEY_2"])
+	}
+	if compiler.cloneEnv["GIT_CONFIG_VALUE_2"] != netrcCredentialHelper {
+		t.Errorf("WithNetrc should use the netrc credentials helper")
+	}
+}
+
+func TestWithNetrcMasked(t *testing.T) {
+	config := New(
+		WithNetrc(
+			"octocat",
+			"password",
+			"github.com",
+		),
+	).Compile(&yaml.Config{})
+	for _, secret := range config.Secrets {
+		if secret.Value == "password" && secret.Mask {
+			return
+		}
+	}
+	t.Errorf("WithNetrc should mask the password in the logs")
 }
 
 func TestWithProxy(t *testing.T) {
@@ -36,6 +36,13 @@ machine $CI_NETRC_MACHINE
 login $CI_NETRC_USERNAME
 password $CI_NETRC_PASSWORD
 EOF
+for CI_NETRC_HOST in $(echo "$CI_NETRC_HOSTS" | tr ',' ' '); do
+cat <<EOF >> $HOME/.netrc
+machine $CI_NETRC_HOST
+login $CI_NETRC_USERNAME
+password $CI_NETRC_PASSWORD
+EOF
+done
 chmod 0600 $HOME/.netrc
 fi
 unset CI_NETRC_USERNAME
@@ -33,6 +33,13 @@ $netrc=[string]::Format("{0}\_netrc",$Env:HOME);
 "machine $Env:CI_NETRC_MACHINE" >> $netrc;
 "login $Env:CI_NETRC_USERNAME" >> $netrc;
 "password $Env:CI_NETRC_PASSWORD" >> $netrc;
+if ($Env:CI_NETRC_HOSTS) {
+foreach ($machine in $Env:CI_NETRC_HOSTS.Split(",")) {
+"machine $machine" >> $netrc;
+"login $Env:CI_NETRC_USERNAME" >> $netrc;
+"password $Env:CI_NETRC_PASSWORD" >> $netrc;
+};
+};
 };
 [Environment]::SetEnvironmentVariable("CI_NETRC_PASSWORD",$null);
 [Environment]::SetEnvironmentVariable("CI_SCRIPT",$null);
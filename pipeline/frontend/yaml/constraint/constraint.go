@@ -22,6 +22,11 @@ type (
 		Event       List
 		Branch      List
 		Status      List
+		Labels      List
+		Teams       List
+		Approver    List
+		Verified    *bool
+		Draft       *bool
 		Matrix      Map
 		Local       types.BoolTrue
 		Path        Path
@@ -57,6 +62,11 @@ func (c *Constraints) Match(metadata frontend.Metadata) bool {
 		c.Repo.Match(metadata.Repo.Name) &&
 		c.Ref.Match(metadata.Curr.Commit.Ref) &&
 		c.Instance.Match(metadata.Sys.Host) &&
+		c.Labels.MatchAny(metadata.Curr.Commit.Labels) &&
+		c.Teams.MatchAny(metadata.Curr.Commit.Teams) &&
+		c.Approver.Match(metadata.Curr.Commit.Approver) &&
+		(c.Verified == nil || *c.Verified == metadata.Curr.Commit.Verified) &&
+		(c.Draft == nil || *c.Draft == metadata.Curr.Commit.Draft) &&
 		c.Matrix.Match(metadata.Job.Matrix)
 
 	// changed files filter do only apply for pull-request and push events
@@ -82,6 +92,25 @@ func (c *List) Match(v string) bool {
 	return false
 }
 
+// MatchAny returns true if any of the strings matches the include patterns and
+// none of them matches the exclude patterns.
+func (c *List) MatchAny(vs []string) bool {
+	for _, v := range vs {
+		if c.Excludes(v) {
+			return false
+		}
+	}
+	if len(c.Include) == 0 {
+		return true
+	}
+	for _, v := range vs {
+		if c.Includes(v) {
+			return true
+		}
+	}
+	return false
+}
+
 // Includes returns true if the string matches the include patterns.
 func (c *List) Includes(v string) bool {
 	for _, pattern := range c.Include {
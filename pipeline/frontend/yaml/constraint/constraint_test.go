@@ -448,6 +448,96 @@ func TestConstraints(t *testing.T) {
 			with: frontend.Metadata{Sys: frontend.System{Arch: "windows/amd64"}},
 			want: false,
 		},
+		// labels constraint
+		{
+			conf: "{ labels: deploy }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Labels: []string{"kind/bug", "deploy"}}}},
+			want: true,
+		},
+		{
+			conf: "{ labels: deploy }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Labels: []string{}}}},
+			want: false,
+		},
+		{
+			conf: "{ labels: { exclude: wip } }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Labels: []string{"deploy", "wip"}}}},
+			want: false,
+		},
+		{
+			conf: "{ labels: { exclude: wip } }",
+			with: frontend.Metadata{},
+			want: true,
+		},
+		// draft constraint
+		{
+			conf: "{ draft: false }",
+			with: frontend.Metadata{Curr: frontend.Build{Event: frontend.EventPull, Commit: frontend.Commit{Draft: true}}},
+			want: false,
+		},
+		{
+			conf: "{ draft: false }",
+			with: frontend.Metadata{Curr: frontend.Build{Event: frontend.EventPull}},
+			want: true,
+		},
+		{
+			conf: "{ draft: true }",
+			with: frontend.Metadata{Curr: frontend.Build{Event: frontend.EventPull, Commit: frontend.Commit{Draft: true}}},
+			want: true,
+		},
+		// verified constraint
+		{
+			conf: "{ verified: true }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Verified: true, Signer: "gordon"}}},
+			want: true,
+		},
+		{
+			conf: "{ verified: true }",
+			with: frontend.Metadata{},
+			want: false,
+		},
+		{
+			conf: "{ verified: false }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Verified: true}}},
+			want: false,
+		},
+		{
+			conf: "{ branch: master }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Branch: "master"}}},
+			want: true,
+		},
+		// teams constraint
+		{
+			conf: "{ teams: maintainers }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Teams: []string{"owners", "maintainers"}}}},
+			want: true,
+		},
+		{
+			conf: "{ teams: maintainers }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Teams: []string{}}}},
+			want: false,
+		},
+		{
+			conf: "{ teams: { exclude: contractors } }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Teams: []string{"developers", "contractors"}}}},
+			want: false,
+		},
+		// approver constraint
+		{
+			conf: "{ approver: [ octocat, gordon ] }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Approver: "gordon"}}},
+			want: true,
+		},
+		{
+			conf: "{ approver: gordon }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{}}},
+			want: false,
+		},
+		{
+			conf: "{ approver: { exclude: bot } }",
+			with: frontend.Metadata{Curr: frontend.Build{Commit: frontend.Commit{Approver: "bot"}}},
+			want: false,
+		},
 		// instance constraint
 		{
 			conf: "{ instance: agent.tld }",
@@ -204,11 +204,23 @@ func GetBuildConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, configs)
 }
 
-// DeleteBuild cancels a build
-func DeleteBuild(c *gin.Context) {
+// GetBuildDiff streams the diff of the changes the build was triggered by,
+// requested from the remote with the token of the repo owner.
+func GetBuildDiff(c *gin.Context) {
 	_store := store.FromContext(c)
 	repo := session.Repo(c)
-	num, _ := strconv.ParseInt(c.Params.ByName("number"), 10, 64)
+
+	differ, ok := server.Config.Services.Remote.(remote.Differ)
+	if !ok {
+		c.String(http.StatusNotImplemented, "remote does not support build diffs")
+		return
+	}
+
+	num, err := strconv.ParseInt(c.Param("number"), 10, 64)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
 
 	build, err := _store.GetBuildNumber(repo, num)
 	if err != nil {
@@ -216,7 +228,46 @@ func DeleteBuild(c *gin.Context) {
 		return
 	}
 
-	procs, err := _store.ProcList(build)
+	user, err := _store.GetUser(repo.UserID)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	rc, err := differ.Diff(c, user, repo, build)
+	if errors.Is(err, remote.ErrDiffTooLarge) {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		c.String(remoteErrorStatus(err), err.Error())
+		return
+	}
+
+	defer rc.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	_, err = io.Copy(c.Writer, rc)
+	if errors.Is(err, remote.ErrDiffTooLarge) {
+		// the status is already sent, so the truncation is marked at the end
+		_, _ = fmt.Fprintf(c.Writer, "\n%s %s\n", diffTruncatedMarker, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("could not copy diff to http response")
+	}
+}
+
+// diffTruncatedMarker starts the last line of diffs cut at the maximum size.
+const diffTruncatedMarker = "# diff truncated:"
+
+// DeleteBuild cancels a build
+func DeleteBuild(c *gin.Context) {
+	_store := store.FromContext(c)
+	repo := session.Repo(c)
+	num, _ := strconv.ParseInt(c.Params.ByName("number"), 10, 64)
+
+	build, err := _store.GetBuildNumber(repo, num)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusNotFound, err)
 		return
@@ -227,6 +278,22 @@ func DeleteBuild(c *gin.Context) {
 		return
 	}
 
+	if err := cancelBuild(c, _store, repo, build); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(204, "")
+}
+
+// cancelBuild cancels the running and evicts the pending procs of a build and
+// marks it as killed.
+func cancelBuild(ctx context.Context, _store store.Store, repo *model.Repo, build *model.Build) error {
+	procs, err := _store.ProcList(build)
+	if err != nil {
+		return err
+	}
+
 	// First cancel/evict procs in the queue in one go
 	var (
 		procToCancel []string
@@ -245,15 +312,15 @@ func DeleteBuild(c *gin.Context) {
 	}
 
 	if len(procToEvict) != 0 {
-		if err := server.Config.Services.Queue.EvictAtOnce(c, procToEvict); err != nil {
+		if err := server.Config.Services.Queue.EvictAtOnce(ctx, procToEvict); err != nil {
 			log.Error().Err(err).Msgf("queue: evict_at_once: %v", procToEvict)
 		}
-		if err := server.Config.Services.Queue.ErrorAtOnce(c, procToEvict, queue.ErrCancel); err != nil {
+		if err := server.Config.Services.Queue.ErrorAtOnce(ctx, procToEvict, queue.ErrCancel); err != nil {
 			log.Error().Err(err).Msgf("queue: evict_at_once: %v", procToEvict)
 		}
 	}
 	if len(procToCancel) != 0 {
-		if err := server.Config.Services.Queue.ErrorAtOnce(c, procToCancel, queue.ErrCancel); err != nil {
+		if err := server.Config.Services.Queue.ErrorAtOnce(ctx, procToCancel, queue.ErrCancel); err != nil {
 			log.Error().Err(err).Msgf("queue: evict_at_once: %v", procToCancel)
 		}
 	}
@@ -277,8 +344,7 @@ func DeleteBuild(c *gin.Context) {
 	killedBuild, err := shared.UpdateToStatusKilled(_store, *build)
 	if err != nil {
 		log.Error().Err(err).Msgf("UpdateToStatusKilled: %v", build)
-		_ = c.AbortWithError(http.StatusInternalServerError, err)
-		return
+		return err
 	}
 
 	// For pending builds, we stream the UI the latest state.
@@ -286,19 +352,16 @@ func DeleteBuild(c *gin.Context) {
 	if build.Status == model.StatusPending {
 		procs, err = _store.ProcList(killedBuild)
 		if err != nil {
-			_ = c.AbortWithError(404, err)
-			return
+			return err
 		}
 		if killedBuild.Procs, err = model.Tree(procs); err != nil {
-			_ = c.AbortWithError(http.StatusInternalServerError, err)
-			return
+			return err
 		}
-		if err := publishToTopic(c, killedBuild, repo); err != nil {
+		if err := publishToTopic(ctx, killedBuild, repo); err != nil {
 			log.Error().Err(err).Msg("publishToTopic")
 		}
 	}
-
-	c.String(204, "")
+	return nil
 }
 
 func PostApproval(c *gin.Context) {
@@ -505,6 +568,13 @@ func PostBuild(c *gin.Context) {
 		}
 	}
 
+	// deployments are compared with the last successful deployment
+	if comparer, ok := _remote.(remote.Comparer); ok {
+		if _, err := shared.UpdateDeployChangedFiles(c, _store, comparer, user, repo, build); err != nil {
+			log.Warn().Err(err).Msgf("could not get the files changed since the last deployment of %s", repo.FullName)
+		}
+	}
+
 	err = _store.CreateBuild(build)
 	if err != nil {
 		msg := fmt.Sprintf("failure to save build for %s", repo.FullName)
@@ -595,6 +665,120 @@ func DeleteBuildLogs(c *gin.Context) {
 	c.String(204, "")
 }
 
+// PostManualBuild starts a build of a commit on a branch, or of the head of
+// the branch if no commit is given. The commit may be a short sha.
+func PostManualBuild(c *gin.Context) {
+	_store := store.FromContext(c)
+	repo := session.Repo(c)
+
+	user, err := _store.GetUser(repo.UserID)
+	if err != nil {
+		log.Error().Msgf("failure to find repo owner %s. %s", repo.FullName, err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	build, err := shared.CreateManualBuild(c, server.Config.Services.Remote, user, repo, c.Query("branch"), c.Query("commit"))
+	if err != nil {
+		c.String(remoteErrorStatus(err), err.Error())
+		return
+	}
+	build.Sender = session.User(c).Login
+
+	build, err = startManualBuild(c, _store, user, repo, build)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if build == nil {
+		c.String(http.StatusNoContent, "no steps to run")
+		return
+	}
+	c.JSON(http.StatusOK, build)
+}
+
+// PostCronBuild starts the build of the cron job with the name for the head
+// of a branch, or of the default branch if none is given. Woodpecker does not
+// schedule cron jobs itself, schedulers outside of it call this endpoint.
+func PostCronBuild(c *gin.Context) {
+	_store := store.FromContext(c)
+	repo := session.Repo(c)
+
+	user, err := _store.GetUser(repo.UserID)
+	if err != nil {
+		log.Error().Msgf("failure to find repo owner %s. %s", repo.FullName, err)
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	build, err := shared.CreateCronBuild(c, server.Config.Services.Remote, user, repo, c.Param("cron"), c.Query("branch"))
+	if err != nil {
+		c.String(remoteErrorStatus(err), err.Error())
+		return
+	}
+	build.Sender = session.User(c).Login
+
+	build, err = startManualBuild(c, _store, user, repo, build)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if build == nil {
+		c.String(http.StatusNoContent, "no steps to run")
+		return
+	}
+	c.JSON(http.StatusOK, build)
+}
+
+// startManualBuild creates and starts a build which was not triggered by a
+// hook. Nil is returned if there are no steps to run for the build.
+func startManualBuild(c *gin.Context, _store store.Store, user *model.User, repo *model.Repo, build *model.Build) (*model.Build, error) {
+	configFetcher := shared.NewConfigFetcher(server.Config.Services.Remote, server.Config.Services.ConfigService, user, repo, build)
+	remoteYamlConfigs, err := configFetcher.Fetch(c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find config '%s' in '%s': %w", repo.Config, build.Ref, err)
+	}
+
+	filtered, err := branchFiltered(build, remoteYamlConfigs)
+	if err != nil {
+		return nil, err
+	}
+	if filtered || zeroSteps(build, remoteYamlConfigs) {
+		log.Debug().Msgf("skip %s build of %s: no steps to run on %s", build.Event, repo.FullName, build.Branch)
+		return nil, nil
+	}
+
+	build.RepoID = repo.ID
+	build.Verified = true
+	build.Status = model.StatusPending
+	if repo.IsGated {
+		build.Status = model.StatusBlocked
+	}
+
+	if err := _store.CreateBuild(build, build.Procs...); err != nil {
+		return nil, err
+	}
+	for _, remoteYamlConfig := range remoteYamlConfigs {
+		if _, err := findOrPersistPipelineConfig(_store, build, remoteYamlConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	build, buildItems, err := createBuildItems(c, _store, build, user, repo, remoteYamlConfigs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if build.Status == model.StatusBlocked {
+		if err := publishToTopic(c, build, repo); err != nil {
+			log.Error().Err(err).Msg("publishToTopic")
+		}
+		return build, updateBuildStatus(c, build, repo, user)
+	}
+
+	return startBuild(c, _store, build, user, repo, buildItems)
+}
+
 func createBuildItems(ctx context.Context, store store.Store, build *model.Build, user *model.User, repo *model.Repo, yamls []*remote.FileMeta, envs map[string]string) (*model.Build, []*shared.BuildItem, error) {
 	netrc, err := server.Config.Services.Remote.Netrc(user, repo)
 	if err != nil {
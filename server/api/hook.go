@@ -21,11 +21,13 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -43,12 +45,46 @@ import (
 	"github.com/woodpecker-ci/woodpecker/shared/token"
 )
 
-var skipRe = regexp.MustCompile(`\[(?i:ci *skip|skip *ci)\]`)
-
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+var (
+	hookLimiter     *shared.HookLimiter
+	hookLimiterOnce sync.Once
+
+	skipPattern     *regexp.Regexp
+	skipPatternOnce sync.Once
+)
+
+// getHookLimiter returns the limiter of the hooks processed at once, which is
+// created on first use as the server config is not set up before.
+func getHookLimiter() *shared.HookLimiter {
+	hookLimiterOnce.Do(func() {
+		hookLimiter = shared.NewHookLimiter(server.Config.Server.MaxHooks)
+	})
+	return hookLimiter
+}
+
+// skipBuild returns the skip token found in the message of the build or an
+// empty string. Builds of tags and releases are never skipped.
+func skipBuild(build *model.Build) string {
+	pattern := getSkipPattern()
+	if pattern == nil || build.Event == model.EventTag || build.Event == model.EventRelease {
+		return ""
+	}
+	return pattern.FindString(build.Message)
+}
+
+// getSkipPattern returns the pattern of the tokens skipping a build if found
+// in its message, which is nil if skipping is disabled.
+func getSkipPattern() *regexp.Regexp {
+	skipPatternOnce.Do(func() {
+		skipPattern = shared.NewSkipPattern(server.Config.Pipeline.SkipTokens)
+	})
+	return skipPattern
+}
+
 func GetQueueInfo(c *gin.Context) {
 	c.IndentedJSON(200,
 		server.Config.Services.Queue.Info(c),
@@ -78,7 +114,33 @@ func BlockTilQueueHasRunningItem(c *gin.Context) {
 func PostHook(c *gin.Context) {
 	_store := store.FromContext(c)
 
+	// process the hooks of a repo in order, without blocking other repos.
+	// Parsing already queries the remote, so the repo is named beforehand
+	hookRepo := ""
+	if namer, ok := server.Config.Services.Remote.(remote.HookRepoNamer); ok {
+		if instance, name := namer.HookRepo(c.Request); name != "" {
+			hookRepo = hookRepoKey(instance, name)
+		}
+	}
+	if hookRepo != "" {
+		release, ok := acquireHook(c, hookRepo)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
 	tmpRepo, build, err := server.Config.Services.Remote.Hook(c, c.Request)
+	if errors.Is(err, remote.ErrInvalidSignature) {
+		msg := "failure to verify hook signature"
+		log.Debug().Err(err).Msg(msg)
+		c.String(http.StatusUnauthorized, msg)
+		return
+	}
+	if errors.Is(err, remote.ErrRepoDeleted) && tmpRepo != nil {
+		postRepoDeleted(c, _store, tmpRepo)
+		return
+	}
 	if err != nil {
 		msg := "failure to parse hook"
 		log.Debug().Err(err).Msg(msg)
@@ -98,17 +160,48 @@ func PostHook(c *gin.Context) {
 		return
 	}
 
-	// skip the build if any case-insensitive combination of the words "skip" and "ci"
-	// wrapped in square brackets appear in the commit message
-	skipMatch := skipRe.FindString(build.Message)
-	if len(skipMatch) > 0 {
+	// the remote sends hooks again if building them takes too long, these
+	// deliveries are ignored unless building failed
+	if delivery := hookDelivery(c); delivery != "" {
+		if !server.Config.Services.Deliveries.DeliveryAdd(delivery) {
+			msg := fmt.Sprintf("ignoring hook: delivery %s was already received", delivery)
+			log.Debug().Str("repo", tmpRepo.FullName).Msg(msg)
+			c.String(http.StatusOK, msg)
+			return
+		}
+		defer func() {
+			if c.Writer.Status() >= http.StatusInternalServerError {
+				server.Config.Services.Deliveries.DeliveryRemove(delivery)
+			}
+		}()
+	}
+
+	// remotes not naming the repo before parsing are limited after parsing
+	if hookRepo == "" {
+		release, ok := acquireHook(c, hookRepoKey(tmpRepo.Remote, tmpRepo.Owner+"/"+tmpRepo.Name))
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	// skip the build if one of the skip tokens wrapped in square brackets
+	// appears in the commit message, tags are always built
+	if skipMatch := skipBuild(build); skipMatch != "" {
 		msg := fmt.Sprintf("ignoring hook: %s found in %s", skipMatch, build.Commit)
 		log.Debug().Msg(msg)
 		c.String(http.StatusNoContent, msg)
 		return
 	}
 
-	repo, err := _store.GetRepoName(tmpRepo.Owner + "/" + tmpRepo.Name)
+	repo, err := _store.GetRepoRemoteName(tmpRepo.Remote, tmpRepo.Owner+"/"+tmpRepo.Name)
+	moved := false
+	if err != nil {
+		// hooks of renamed or transferred repos still carry the token
+		// signed for the name the repo had when it was activated
+		repo, err = hookTokenRepo(c, _store, tmpRepo.Remote)
+		moved = err == nil
+	}
 	if err != nil {
 		msg := fmt.Sprintf("failure to get repo %s from store", tmpRepo.FullName)
 		log.Error().Err(err).Msg(msg)
@@ -121,11 +214,16 @@ func PostHook(c *gin.Context) {
 		c.String(http.StatusNoContent, msg)
 		return
 	}
+	// paused repos keep their hook, whatever it delivers is dropped
+	if repo.IsPaused {
+		msg := fmt.Sprintf("ignoring hook: repo %s is paused", repo.FullName)
+		log.Debug().Msg(msg)
+		c.String(http.StatusNoContent, msg)
+		return
+	}
 
 	// get the token and verify the hook is authorized
-	parsed, err := token.ParseRequest(c.Request, func(_ *token.Token) (string, error) {
-		return repo.Hash, nil
-	})
+	parsed, err := parseHookToken(c, repo)
 	if err != nil {
 		msg := fmt.Sprintf("failure to parse token from hook for %s", repo.FullName)
 		log.Error().Err(err).Msg(msg)
@@ -146,6 +244,23 @@ func PostHook(c *gin.Context) {
 		return
 	}
 
+	renamed := false
+	if moved {
+		renamed, err = shared.UpdateRenamedRepo(_store, repo, tmpRepo)
+		if errors.Is(err, shared.ErrRepoNameConflict) {
+			msg := fmt.Sprintf("failure to rename repo %s to %s: name is already taken", repo.FullName, tmpRepo.FullName)
+			log.Warn().Err(err).Msg(msg)
+			c.String(http.StatusConflict, msg)
+			return
+		}
+		if err != nil {
+			msg := fmt.Sprintf("failure to rename repo %s to %s", repo.FullName, tmpRepo.FullName)
+			log.Error().Err(err).Msg(msg)
+			c.String(http.StatusInternalServerError, msg)
+			return
+		}
+	}
+
 	if build.Event == model.EventPull && !repo.AllowPull {
 		msg := "ignoring hook: pull requests are disabled for this repo in woodpecker"
 		log.Debug().Str("repo", repo.FullName).Msg(msg)
@@ -176,6 +291,15 @@ func PostHook(c *gin.Context) {
 		}
 	}
 
+	// the hook token of a renamed repo names the old repo, so the hook
+	// has to be registered again to keep future hooks verifiable
+	if renamed {
+		if err := reactivateRepo(c, repoUser, repo); err != nil {
+			log.Error().Err(err).Msgf("failure to update hook of renamed repo %s", repo.FullName)
+			// move forward
+		}
+	}
+
 	// fetch the build file from the remote
 	configFetcher := shared.NewConfigFetcher(server.Config.Services.Remote, server.Config.Services.ConfigService, repoUser, repo, build)
 	remoteYamlConfigs, err := configFetcher.Fetch(c)
@@ -268,6 +392,36 @@ func PostHook(c *gin.Context) {
 	c.JSON(http.StatusOK, build)
 }
 
+// hookDelivery returns the id the remote sent the hook with, or an empty id if
+// the remote or the hook has none or no delivery service is set up.
+func hookDelivery(c *gin.Context) string {
+	identifier, ok := server.Config.Services.Remote.(remote.DeliveryIdentifier)
+	if !ok || server.Config.Services.Deliveries == nil {
+		return ""
+	}
+	return identifier.DeliveryID(c.Request)
+}
+
+// hookRepoKey returns the key the hooks of a repo are limited by. Repos of
+// different remote instances may have the same full name.
+func hookRepoKey(remote, fullName string) string {
+	return remote + "/" + fullName
+}
+
+// acquireHook waits until the hook of the repo may be processed and returns
+// the function to call once it is processed. If the remote closes the request
+// before, the hook is dropped.
+func acquireHook(c *gin.Context, repo string) (func(), bool) {
+	release, err := getHookLimiter().Acquire(c.Request.Context(), repo)
+	if err != nil {
+		msg := fmt.Sprintf("failure to process hook of %s in time", repo)
+		log.Warn().Err(err).Msg(msg)
+		c.String(http.StatusServiceUnavailable, msg)
+		return nil, false
+	}
+	return release, true
+}
+
 // TODO: parse yaml once and not for each filter function
 func branchFiltered(build *model.Build, remoteYamlConfigs []*remote.FileMeta) (bool, error) {
 	log.Trace().Msgf("hook.branchFiltered(): build branch: '%s' build event: '%s' config count: %d", build.Branch, build.Event, len(remoteYamlConfigs))
@@ -369,7 +523,7 @@ func publishToTopic(c context.Context, build *model.Build, repo *model.Repo) (er
 func queueBuild(build *model.Build, repo *model.Repo, buildItems []*shared.BuildItem) error {
 	var tasks []*queue.Task
 	for _, item := range buildItems {
-		if item.Proc.State == model.StatusSkipped {
+		if item.Proc.State == model.StatusSkipped || item.Proc.State == model.StatusError {
 			continue
 		}
 		task := new(queue.Task)
@@ -413,3 +567,147 @@ func shasum(raw []byte) string {
 	sum := sha256.Sum256(raw)
 	return fmt.Sprintf("%x", sum)
 }
+
+// ParseHook returns the repo and build the remote parses from the hook
+// payload of the request, without creating the build.
+func ParseHook(c *gin.Context) {
+	parser, ok := server.Config.Services.Remote.(remote.HookParser)
+	if !ok {
+		c.String(http.StatusNotImplemented, "remote does not support parsing hooks")
+		return
+	}
+
+	parsed, err := parser.ParseHook(c, c.Request)
+	if err != nil {
+		msg := "failure to parse hook"
+		log.Debug().Err(err).Msg(msg)
+		c.String(http.StatusBadRequest, fmt.Sprintf("%s: %s", msg, err))
+		return
+	}
+	c.JSON(http.StatusOK, parsed)
+}
+
+// hookTokenRepo returns the stored repo of the remote instance named by the
+// hook token of the request. The token may be signed with the previous hash
+// of the repo shortly after rotating it.
+func hookTokenRepo(c *gin.Context, _store store.Store, remote string) (*model.Repo, error) {
+	var repo *model.Repo
+	_, err := token.ParseRequest(c.Request, func(t *token.Token) (string, error) {
+		var err error
+		repo, err = _store.GetRepoRemoteName(remote, t.Text)
+		if err != nil {
+			return "", err
+		}
+		return repo.Hash, nil
+	})
+	if err != nil && repo != nil {
+		_, err = parseHookToken(c, repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// parseHookToken parses the hook token of the request signed with the hash of
+// the repo, or with its previous hash shortly after rotating it.
+func parseHookToken(c *gin.Context, repo *model.Repo) (parsed *token.Token, err error) {
+	for _, hash := range repo.Hashes(time.Now()) {
+		parsed, err = token.ParseRequest(c.Request, func(_ *token.Token) (string, error) {
+			return hash, nil
+		})
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return nil, err
+}
+
+// postRepoDeleted deactivates the repo of a hook notifying about its deletion
+// in the remote and cancels its active builds. The hook of the repo was deleted
+// along with it, so it is not removed from the remote. The secrets of the repo
+// are kept, as the repo may reappear e.g. after a transfer.
+func postRepoDeleted(c *gin.Context, _store store.Store, tmpRepo *model.Repo) {
+	repo, err := _store.GetRepoRemoteName(tmpRepo.Remote, tmpRepo.FullName)
+	if err != nil {
+		msg := fmt.Sprintf("ignoring hook: deleted repo %s is unknown", tmpRepo.FullName)
+		log.Debug().Err(err).Msg(msg)
+		c.String(http.StatusNoContent, msg)
+		return
+	}
+
+	if !repo.IsActive || repo.UserID == 0 {
+		msg := fmt.Sprintf("ignoring hook: deleted repo %s is inactive", repo.FullName)
+		log.Debug().Msg(msg)
+		c.String(http.StatusNoContent, msg)
+		return
+	}
+
+	// Gitea only sends repository events to organization hooks, which carry
+	// no token of the repo, so the deletion is verified with the remote
+	owner, err := _store.GetUser(repo.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("failure to find repo owner via id '%d'", repo.UserID)
+		log.Error().Err(err).Str("repo", repo.FullName).Msg(msg)
+		c.String(http.StatusInternalServerError, msg)
+		return
+	}
+	if _, err := server.Config.Services.Remote.Repo(c, owner, repo.Owner, repo.Name); !errors.Is(err, remote.ErrNotFound) {
+		msg := fmt.Sprintf("ignoring hook: repo %s still exists in the remote", repo.FullName)
+		log.Debug().Err(err).Msg(msg)
+		c.String(http.StatusForbidden, msg)
+		return
+	}
+
+	feed, err := _store.GetBuildQueue()
+	if err != nil {
+		log.Error().Err(err).Msgf("failure to list the active builds of deleted repo %s", repo.FullName)
+	}
+	for _, item := range feed {
+		if item.FullName != repo.FullName {
+			continue
+		}
+		build, err := _store.GetBuildNumber(repo, item.Number)
+		if err != nil {
+			log.Error().Err(err).Msgf("failure to get build %d of deleted repo %s", item.Number, repo.FullName)
+			continue
+		}
+		if err := cancelBuild(c, _store, repo, build); err != nil {
+			log.Error().Err(err).Msgf("failure to cancel build %d of deleted repo %s", build.Number, repo.FullName)
+		}
+	}
+
+	repo.IsActive = false
+	repo.UserID = 0
+	if err := _store.UpdateRepo(repo); err != nil {
+		msg := fmt.Sprintf("failure to deactivate deleted repo %s", repo.FullName)
+		log.Error().Err(err).Msg(msg)
+		c.String(http.StatusInternalServerError, msg)
+		return
+	}
+
+	msg := fmt.Sprintf("deactivated deleted repo %s", repo.FullName)
+	log.Info().Msg(msg)
+	c.String(http.StatusOK, msg)
+}
+
+// reactivateRepo registers the hook of the repo again with a token for
+// its current name.
+func reactivateRepo(c *gin.Context, user *model.User, repo *model.Repo) error {
+	sig, err := token.New(token.HookToken, repo.FullName).Sign(repo.Hash)
+	if err != nil {
+		return err
+	}
+
+	host := server.Config.Server.PublicHost
+	link := fmt.Sprintf(
+		"%s/hook?access_token=%s",
+		host,
+		sig,
+	)
+
+	if err := server.Config.Services.Remote.Deactivate(c, user, repo, host); err != nil {
+		log.Trace().Err(err).Msgf("deactivate renamed repo '%s' failed", repo.FullName)
+	}
+	return server.Config.Services.Remote.Activate(c, user, repo, link)
+}
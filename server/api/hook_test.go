@@ -0,0 +1,223 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/woodpecker-ci/woodpecker/server"
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/plugins/deliveries"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+	"github.com/woodpecker-ci/woodpecker/server/remote/mocks"
+	"github.com/woodpecker-ci/woodpecker/server/store"
+	"github.com/woodpecker-ci/woodpecker/shared/token"
+)
+
+// hookStore is the part of the store used by the hook handler, the other
+// methods are not implemented.
+type hookStore struct {
+	store.Store
+	repos map[string]*model.Repo
+}
+
+func (s *hookStore) GetRepoRemoteName(_, name string) (*model.Repo, error) {
+	repo, ok := s.repos[name]
+	if !ok {
+		return nil, fmt.Errorf("repo %s not found", name)
+	}
+	return repo, nil
+}
+
+// postHook sends a hook to the handler and returns the recorded response.
+func postHook(_store store.Store, header http.Header, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	for key := range header {
+		c.Request.Header.Set(key, header.Get(key))
+	}
+	store.ToContext(c, _store)
+	PostHook(c)
+	return w
+}
+
+func TestPostHookPausedRepo(t *testing.T) {
+	defer func(r remote.Remote) { server.Config.Services.Remote = r }(server.Config.Services.Remote)
+
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", IsActive: true, IsPaused: true, UserID: 1}
+	build := &model.Build{Event: model.EventPush, Commit: "6b5b8f1"}
+
+	_remote := new(mocks.Remote)
+	_remote.On("Hook", mock.Anything, mock.Anything).Return(&model.Repo{Owner: repo.Owner, Name: repo.Name, FullName: repo.FullName}, build, nil)
+	server.Config.Services.Remote = _remote
+
+	w := postHook(&hookStore{repos: map[string]*model.Repo{repo.FullName: repo}}, nil, "")
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	// the hook of a paused repo is kept with the remote
+	_remote.AssertNotCalled(t, "Deactivate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	_remote.AssertNotCalled(t, "Activate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.True(t, repo.IsActive)
+	assert.True(t, repo.IsPaused)
+}
+
+func TestPostHookInvalidSignature(t *testing.T) {
+	defer func(r remote.Remote) { server.Config.Services.Remote = r }(server.Config.Services.Remote)
+
+	repo := &model.Repo{Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world", IsActive: true, UserID: 1, Hash: "secret"}
+	server.Config.Services.Remote, _ = gitea.New(gitea.Opts{URL: "http://gitea.example.com"})
+
+	w := postHook(&hookStore{repos: map[string]*model.Repo{repo.FullName: repo}}, http.Header{
+		"X-Gitea-Event":     {"push"},
+		"X-Gitea-Signature": {"0123456789abcdef"},
+	}, fixtures.HookPush)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// deliveryRemote is a remote identifying hooks by their X-Gitea-Delivery
+// header.
+type deliveryRemote struct {
+	*mocks.Remote
+}
+
+func (r *deliveryRemote) DeliveryID(req *http.Request) string {
+	return req.Header.Get("X-Gitea-Delivery")
+}
+
+// deliveryStore fails to get the owner of the repo, so hooks fail with a
+// server error once they passed the checks of the repo.
+type deliveryStore struct {
+	hookStore
+	ownerLookups int
+}
+
+func (s *deliveryStore) GetUser(int64) (*model.User, error) {
+	s.ownerLookups++
+	return nil, fmt.Errorf("database is down")
+}
+
+func TestPostHookDelivery(t *testing.T) {
+	services := server.Config.Services
+	defer func() { server.Config.Services = services }()
+
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", IsActive: true, UserID: 1, Hash: "secret"}
+	hookToken, err := token.New(token.HookToken, repo.FullName).Sign(repo.Hash)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_remote := &deliveryRemote{new(mocks.Remote)}
+	_remote.On("Hook", mock.Anything, mock.Anything).Return(&model.Repo{Owner: repo.Owner, Name: repo.Name, FullName: repo.FullName}, &model.Build{Event: model.EventPush, Commit: "6b5b8f1"}, nil)
+	server.Config.Services.Remote = _remote
+	server.Config.Services.Deliveries = deliveries.New(time.Hour)
+
+	t.Run("Ignore duplicate deliveries", func(t *testing.T) {
+		paused := *repo
+		paused.IsPaused = true
+		_store := &hookStore{repos: map[string]*model.Repo{repo.FullName: &paused}}
+		header := http.Header{"X-Gitea-Delivery": {"duplicate"}}
+
+		assert.Equal(t, http.StatusNoContent, postHook(_store, header, "").Code)
+		w := postHook(_store, header, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "delivery duplicate was already received")
+	})
+
+	t.Run("Process failed deliveries again", func(t *testing.T) {
+		_store := &deliveryStore{hookStore: hookStore{repos: map[string]*model.Repo{repo.FullName: repo}}}
+		header := http.Header{
+			"X-Gitea-Delivery": {"failed"},
+			"Authorization":    {"Bearer " + hookToken},
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, postHook(_store, header, "").Code)
+		assert.Equal(t, http.StatusInternalServerError, postHook(_store, header, "").Code)
+		assert.Equal(t, 2, _store.ownerLookups)
+	})
+}
+
+func TestHookTokenRepo(t *testing.T) {
+	repo := &model.Repo{Owner: "octocat", Name: "old-name", FullName: "octocat/old-name", Hash: "new", PrevHash: "old", HashRotated: time.Now().Unix()}
+	_store := &hookStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+	tests := []struct {
+		name  string
+		hash  string
+		found bool
+	}{
+		{name: "current hash", hash: "new", found: true},
+		{name: "previous hash", hash: "old", found: true},
+		{name: "unknown hash", hash: "other", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hookToken, err := token.New(token.HookToken, repo.FullName).Sign(tt.hash)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/hook", nil)
+			c.Request.Header.Set("Authorization", "Bearer "+hookToken)
+
+			found, err := hookTokenRepo(c, _store, "")
+			if tt.found {
+				assert.NoError(t, err)
+				assert.Equal(t, repo, found)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// deletionStore records the repos updated while handling a hook, all other
+// writes are not implemented.
+type deletionStore struct {
+	hookStore
+	updated []*model.Repo
+}
+
+func (s *deletionStore) UpdateRepo(repo *model.Repo) error {
+	s.updated = append(s.updated, repo)
+	return nil
+}
+
+func TestPostHookUnsignedRepoDeletion(t *testing.T) {
+	defer func(r remote.Remote) { server.Config.Services.Remote = r }(server.Config.Services.Remote)
+
+	repo := &model.Repo{Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world", IsActive: true, UserID: 1, Hash: "secret"}
+	server.Config.Services.Remote, _ = gitea.New(gitea.Opts{URL: "http://gitea.example.com", OrgHookSecret: "secret"})
+	_store := &deletionStore{hookStore: hookStore{repos: map[string]*model.Repo{repo.FullName: repo}}}
+
+	w := postHook(_store, http.Header{"X-Gitea-Event": {"repository"}}, fixtures.HookRepositoryDeleted)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, _store.updated)
+	assert.True(t, repo.IsActive)
+	assert.Equal(t, int64(1), repo.UserID)
+}
@@ -17,6 +17,7 @@ package api
 import (
 	"encoding/base32"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/store"
 	"github.com/woodpecker-ci/woodpecker/shared/httputil"
 	"github.com/woodpecker-ci/woodpecker/shared/token"
@@ -38,9 +40,16 @@ func HandleLogin(c *gin.Context) {
 	if err := r.FormValue("error"); err != "" {
 		http.Redirect(w, r, "/login/error?code="+err, 303)
 	} else {
-		intendedURL := r.URL.Query()["url"]
-		if len(intendedURL) > 0 {
-			http.Redirect(w, r, "/authorize?url="+intendedURL[0], 303)
+		// the url to return to and the remote instance to log in with
+		query := url.Values{}
+		if intendedURL := r.URL.Query().Get("url"); intendedURL != "" {
+			query.Set("url", intendedURL)
+		}
+		if instance := r.URL.Query().Get("remote"); instance != "" {
+			query.Set("remote", instance)
+		}
+		if len(query) > 0 {
+			http.Redirect(w, r, "/authorize?"+query.Encode(), 303)
 		} else {
 			http.Redirect(w, r, "/authorize", 303)
 		}
@@ -68,8 +77,16 @@ func HandleAuth(c *gin.Context) {
 	config := ToConfig(c)
 
 	// get the user from the database
-	u, err := _store.GetUserLogin(tmpuser.Login)
+	u, err := _store.GetUserRemoteLogin(tmpuser.Remote, tmpuser.Login)
 	if err != nil {
+		// logins are unique across remote instances, a login registered with
+		// another instance must not be taken over
+		if other, oerr := _store.GetUserLogin(tmpuser.Login); oerr == nil {
+			log.Error().Msgf("cannot register %s of remote %q. login belongs to remote %q", tmpuser.Login, tmpuser.Remote, other.Remote)
+			c.Redirect(303, "/login?error=access_denied")
+			return
+		}
+
 		// if self-registration is disabled we should return a not authorized error
 		if !config.Open && !config.IsAdmin(tmpuser) {
 			log.Error().Msgf("cannot register %s. registration closed", tmpuser.Login)
@@ -95,6 +112,7 @@ func HandleAuth(c *gin.Context) {
 			Secret: tmpuser.Secret,
 			Email:  tmpuser.Email,
 			Avatar: tmpuser.Avatar,
+			Remote: tmpuser.Remote,
 			Hash: base32.StdEncoding.EncodeToString(
 				securecookie.GenerateRandomKey(32),
 			),
@@ -165,13 +183,18 @@ func GetLoginToken(c *gin.Context) {
 		return
 	}
 
-	login, err := server.Config.Services.Remote.Auth(c, in.Access, in.Refresh)
+	var login, instance string
+	if auther, ok := server.Config.Services.Remote.(remote.InstanceAuther); ok {
+		login, instance, err = auther.AuthInstance(c, in.Access, in.Refresh)
+	} else {
+		login, err = server.Config.Services.Remote.Auth(c, in.Access, in.Refresh)
+	}
 	if err != nil {
 		_ = c.AbortWithError(http.StatusUnauthorized, err)
 		return
 	}
 
-	user, err := _store.GetUserLogin(login)
+	user, err := _store.GetUserRemoteLogin(instance, login)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusNotFound, err)
 		return
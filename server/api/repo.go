@@ -16,9 +16,12 @@ package api
 
 import (
 	"encoding/base32"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/securecookie"
@@ -26,9 +29,12 @@ import (
 
 	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/router/middleware/session"
+	"github.com/woodpecker-ci/woodpecker/server/shared"
 	"github.com/woodpecker-ci/woodpecker/server/store"
 	"github.com/woodpecker-ci/woodpecker/shared/token"
+	"github.com/woodpecker-ci/woodpecker/shared/utils"
 )
 
 // TODO: make it set system wide via environment variables
@@ -49,6 +55,7 @@ func PostRepo(c *gin.Context) {
 	}
 
 	repo.IsActive = true
+	repo.IsPaused = false
 	repo.UserID = user.ID
 	repo.AllowPull = true
 
@@ -81,13 +88,13 @@ func PostRepo(c *gin.Context) {
 
 	link := fmt.Sprintf(
 		"%s/hook?access_token=%s",
-		server.Config.Server.Host,
+		server.Config.Server.PublicHost,
 		sig,
 	)
 
 	err = remote.Activate(c, user, repo, link)
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		c.String(remoteErrorStatus(err), err.Error())
 		return
 	}
 
@@ -102,9 +109,32 @@ func PostRepo(c *gin.Context) {
 		return
 	}
 
+	if server.Config.Pipeline.InitialBuild {
+		if err := startInitialBuild(c, _store, user, repo); err != nil {
+			log.Error().Err(err).Msgf("failure to start initial build of %s", repo.FullName)
+			// the repo is active anyway
+		}
+	}
+
 	c.JSON(http.StatusOK, repo)
 }
 
+// startInitialBuild builds the head of the default branch of a newly activated
+// repo like a hook would. Empty repos are skipped.
+func startInitialBuild(c *gin.Context, _store store.Store, user *model.User, repo *model.Repo) error {
+	build, err := shared.CreateInitialBuild(c, server.Config.Services.Remote, user, repo)
+	if err != nil {
+		return err
+	}
+	if build == nil {
+		log.Debug().Msgf("skip initial build of empty repo %s", repo.FullName)
+		return nil
+	}
+
+	_, err = startManualBuild(c, _store, user, repo, build)
+	return err
+}
+
 func PatchRepo(c *gin.Context) {
 	_store := store.FromContext(c)
 	repo := session.Repo(c)
@@ -149,6 +179,28 @@ func PatchRepo(c *gin.Context) {
 			return
 		}
 	}
+	hookEventsChanged := false
+	if in.HookEvents != nil {
+		for _, event := range *in.HookEvents {
+			if !model.ValidateWebhookEvent(model.WebhookEvent(event)) {
+				c.String(http.StatusBadRequest, fmt.Sprintf("Invalid hook event %s", event))
+				return
+			}
+		}
+		hookEventsChanged = !utils.EqualStringSlice(repo.HookEvents, *in.HookEvents)
+		repo.HookEvents = *in.HookEvents
+	}
+	statusContextChanged := false
+	if in.StatusContext != nil {
+		// an empty context resets the repo to the default one
+		statusContext := strings.TrimSpace(*in.StatusContext)
+		if statusContext == "" && *in.StatusContext != "" {
+			c.String(http.StatusBadRequest, "Invalid status context, it must not be blank")
+			return
+		}
+		statusContextChanged = repo.StatusContext != statusContext
+		repo.StatusContext = statusContext
+	}
 
 	err := _store.UpdateRepo(repo)
 	if err != nil {
@@ -156,9 +208,45 @@ func PatchRepo(c *gin.Context) {
 		return
 	}
 
+	// the hook has to be registered again to subscribe to the new events
+	if hookEventsChanged && repo.IsActive {
+		if err := reactivateRepo(c, user, repo); err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if statusContextChanged && repo.StatusContext != "" {
+		checkRequiredStatusContext(c, user, repo)
+	}
+
 	c.JSON(http.StatusOK, repo)
 }
 
+// checkRequiredStatusContext warns if the branch protections of the repo
+// require status checks, but none with the status context of the repo.
+func checkRequiredStatusContext(c *gin.Context, user *model.User, repo *model.Repo) {
+	lister, ok := server.Config.Services.Remote.(remote.StatusCheckLister)
+	if !ok {
+		return
+	}
+	required, err := lister.RequiredStatusChecks(c, user, repo)
+	if err != nil {
+		log.Debug().Err(err).Msgf("could not list the required status checks of %s", repo.FullName)
+		return
+	}
+	if len(required) == 0 {
+		return
+	}
+	for _, check := range required {
+		if check == repo.StatusContext {
+			return
+		}
+	}
+	log.Warn().Msgf("status context '%s' of %s is not required by its branch protections, they require %s",
+		repo.StatusContext, repo.FullName, strings.Join(required, ", "))
+}
+
 func ChownRepo(c *gin.Context) {
 	_store := store.FromContext(c)
 	repo := session.Repo(c)
@@ -173,6 +261,31 @@ func ChownRepo(c *gin.Context) {
 	c.JSON(http.StatusOK, repo)
 }
 
+// PauseRepo pauses the repo, the hooks delivered by the remote are dropped
+// but the hook is kept, so the repo can be resumed at once.
+func PauseRepo(c *gin.Context) {
+	setRepoPaused(c, shared.PauseRepo)
+}
+
+// ResumeRepo resumes the paused repo.
+func ResumeRepo(c *gin.Context) {
+	setRepoPaused(c, shared.ResumeRepo)
+}
+
+func setRepoPaused(c *gin.Context, set func(shared.PauseRepoStore, *model.Repo) error) {
+	repo := session.Repo(c)
+	err := set(store.FromContext(c), repo)
+	if errors.Is(err, shared.ErrRepoInactive) {
+		c.String(http.StatusConflict, "Repository is not active.")
+		return
+	}
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, repo)
+}
+
 func GetRepo(c *gin.Context) {
 	c.JSON(http.StatusOK, session.Repo(c))
 }
@@ -196,6 +309,31 @@ func GetRepoBranches(c *gin.Context) {
 	c.JSON(http.StatusOK, branches)
 }
 
+func GetRepoPullRequests(c *gin.Context) {
+	repo := session.Repo(c)
+	user := session.User(c)
+
+	lister, ok := server.Config.Services.Remote.(remote.PullRequestLister)
+	if !ok {
+		c.String(http.StatusNotImplemented, "remote does not support listing pull requests")
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		c.String(http.StatusBadRequest, "invalid page")
+		return
+	}
+
+	pulls, err := lister.PullRequests(c, user, repo, page)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pulls)
+}
+
 func DeleteRepo(c *gin.Context) {
 	remove, _ := strconv.ParseBool(c.Query("remove"))
 	_store := store.FromContext(c)
@@ -204,6 +342,7 @@ func DeleteRepo(c *gin.Context) {
 	user := session.User(c)
 
 	repo.IsActive = false
+	repo.IsPaused = false
 	repo.UserID = 0
 
 	if err := _store.UpdateRepo(repo); err != nil {
@@ -218,8 +357,9 @@ func DeleteRepo(c *gin.Context) {
 		}
 	}
 
-	if err := server.Config.Services.Remote.Deactivate(c, user, repo, server.Config.Server.Host); err != nil {
-		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	// repositories deleted in the remote have no hook left to remove
+	if err := server.Config.Services.Remote.Deactivate(c, user, repo, server.Config.Server.PublicHost); err != nil && !errors.Is(err, remote.ErrNotFound) {
+		_ = c.AbortWithError(remoteErrorStatus(err), err)
 		return
 	}
 	c.JSON(200, repo)
@@ -240,7 +380,7 @@ func RepairRepo(c *gin.Context) {
 	}
 
 	// reconstruct the link
-	host := server.Config.Server.Host
+	host := server.Config.Server.PublicHost
 	link := fmt.Sprintf(
 		"%s/hook?access_token=%s",
 		host,
@@ -251,14 +391,14 @@ func RepairRepo(c *gin.Context) {
 		log.Trace().Err(err).Msgf("deactivate repo '%s' to repair failed", repo.FullName)
 	}
 	if err := remote.Activate(c, user, repo, link); err != nil {
-		c.String(500, err.Error())
+		c.String(remoteErrorStatus(err), err.Error())
 		return
 	}
 
 	from, err := remote.Repo(c, user, repo.Owner, repo.Name)
 	if err != nil {
 		log.Error().Err(err).Msgf("get repo '%s/%s' from remote", repo.Owner, repo.Name)
-		c.AbortWithStatus(http.StatusInternalServerError)
+		c.AbortWithStatus(remoteErrorStatus(err))
 		return
 	}
 	repo.Name = from.Name
@@ -271,6 +411,11 @@ func RepairRepo(c *gin.Context) {
 	if repo.IsSCMPrivate != from.IsSCMPrivate {
 		repo.ResetVisibility()
 	}
+	repo.IsMirror = from.IsMirror
+	repo.IsFork = from.IsFork
+	if from.Topics != nil {
+		repo.Topics = from.Topics
+	}
 	if err := _store.UpdateRepo(repo); err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -279,6 +424,53 @@ func RepairRepo(c *gin.Context) {
 	c.Writer.WriteHeader(http.StatusOK)
 }
 
+func RotateRepoSecret(c *gin.Context) {
+	_store := store.FromContext(c)
+	repo := session.Repo(c)
+	user := session.User(c)
+
+	rotator, ok := server.Config.Services.Remote.(remote.HookSecretRotator)
+	if !ok {
+		c.String(http.StatusNotImplemented, "remote does not support rotating hook secrets")
+		return
+	}
+
+	prev := *repo
+	repo.PrevHash = repo.Hash
+	repo.HashRotated = time.Now().Unix()
+	repo.Hash = base32.StdEncoding.EncodeToString(
+		securecookie.GenerateRandomKey(32),
+	)
+
+	sig, err := token.New(token.HookToken, repo.FullName).Sign(repo.Hash)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	link := fmt.Sprintf(
+		"%s/hook?access_token=%s",
+		server.Config.Server.PublicHost,
+		sig,
+	)
+
+	// store the new hash first, hooks sent with the previous secret until the
+	// remote is updated are accepted during the grace period
+	if err := _store.UpdateRepo(repo); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := rotator.RotateHookSecret(c, user, repo, link); err != nil {
+		if err := _store.UpdateRepo(&prev); err != nil {
+			log.Error().Err(err).Msgf("restore hash of repo '%s' after failed rotation", repo.FullName)
+		}
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
 func MoveRepo(c *gin.Context) {
 	remote := server.Config.Services.Remote
 	_store := store.FromContext(c)
@@ -300,7 +492,7 @@ func MoveRepo(c *gin.Context) {
 
 	from, err := remote.Repo(c, user, owner, name)
 	if err != nil {
-		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		_ = c.AbortWithError(remoteErrorStatus(err), err)
 		return
 	}
 	if !from.Perm.Admin {
@@ -334,7 +526,7 @@ func MoveRepo(c *gin.Context) {
 	}
 
 	// reconstruct the link
-	host := server.Config.Server.Host
+	host := server.Config.Server.PublicHost
 	link := fmt.Sprintf(
 		"%s/hook?access_token=%s",
 		host,
@@ -345,8 +537,23 @@ func MoveRepo(c *gin.Context) {
 		log.Trace().Err(err).Msgf("deactivate repo '%s' for move to activate later, got an error", repo.FullName)
 	}
 	if err := remote.Activate(c, user, repo, link); err != nil {
-		c.String(500, err.Error())
+		c.String(remoteErrorStatus(err), err.Error())
 		return
 	}
 	c.Writer.WriteHeader(http.StatusOK)
 }
+
+// remoteErrorStatus returns the http status code matching the error of the
+// remote, errors not typed by the remote are internal server errors.
+func remoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, remote.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, remote.ErrUnauthorized), errors.Is(err, remote.ErrInvalidToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, remote.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/woodpecker-ci/woodpecker/server"
+	"github.com/woodpecker-ci/woodpecker/server/logging"
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/plugins/registry"
+	"github.com/woodpecker-ci/woodpecker/server/plugins/secrets"
+	"github.com/woodpecker-ci/woodpecker/server/pubsub"
+	"github.com/woodpecker-ci/woodpecker/server/queue"
+	"github.com/woodpecker-ci/woodpecker/server/remote/mocks"
+	"github.com/woodpecker-ci/woodpecker/server/store"
+	"github.com/woodpecker-ci/woodpecker/server/store/datastore"
+)
+
+// initialBuildRemote is a remote resolving the head of every branch to the
+// same commit.
+type initialBuildRemote struct {
+	*mocks.Remote
+}
+
+func (r *initialBuildRemote) BranchHead(context.Context, *model.User, *model.Repo, string) (string, error) {
+	return "6b5b8f1c4d5e0d4b7c1f3a2e9d8c7b6a5f4e3d2c", nil
+}
+
+func TestPostRepoInitialBuild(t *testing.T) {
+	services, pipeline := server.Config.Services, server.Config.Pipeline
+	defer func() { server.Config.Services, server.Config.Pipeline = services, pipeline }()
+
+	_store, err := datastore.NewEngine(&store.Opts{Driver: "sqlite3", Config: filepath.Join(t.TempDir(), "woodpecker.sqlite")})
+	if !assert.NoError(t, err) || !assert.NoError(t, _store.Migrate()) {
+		t.FailNow()
+	}
+	defer _store.Close()
+
+	user := &model.User{Login: "octocat", Token: "token", Hash: "hash"}
+	assert.NoError(t, _store.CreateUser(user))
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", Branch: "main", Config: ".woodpecker.yml"}
+	assert.NoError(t, _store.CreateRepo(repo))
+
+	_remote := &initialBuildRemote{new(mocks.Remote)}
+	_remote.On("Activate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	_remote.On("Repo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+	_remote.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, ".woodpecker.yml").Return([]byte("pipeline:\n  build:\n    image: alpine\n    commands: [ 'true' ]\n"), nil)
+	_remote.On("Netrc", mock.Anything, mock.Anything).Return(&model.Netrc{}, nil)
+	_remote.On("Status", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Config.Services.Remote = _remote
+	server.Config.Services.Queue = queue.New(ctx)
+	server.Config.Services.Logs = logging.New()
+	server.Config.Services.Pubsub = pubsub.New()
+	server.Config.Services.Secrets = secrets.New(ctx, _store)
+	server.Config.Services.Registries = registry.New(_store)
+	server.Config.Services.Environ = nil
+	server.Config.Pipeline.InitialBuild = true
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/repos/octocat/hello-world", nil)
+	store.ToContext(c, _store)
+	c.Set("user", user)
+	c.Set("repo", repo)
+	PostRepo(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	builds, err := _store.GetBuildList(repo, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, builds, 1) {
+		assert.Equal(t, model.EventManual, builds[0].Event)
+		assert.Equal(t, "main", builds[0].Branch)
+	}
+	assert.Equal(t, 1, server.Config.Services.Queue.Info(ctx).Stats.Pending)
+}
@@ -15,12 +15,16 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/woodpecker-ci/woodpecker/server"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/router/middleware/session"
 	"github.com/woodpecker-ci/woodpecker/server/store"
 	"github.com/woodpecker-ci/woodpecker/version"
 )
@@ -34,6 +38,32 @@ func Health(c *gin.Context) {
 	c.String(200, "")
 }
 
+// RemoteHealth endpoint returns a 503 if the remote is unreachable and a 401
+// if the token of the logged in user was rejected. It requires a session, so
+// the token is always checked.
+func RemoteHealth(c *gin.Context) {
+	pinger, ok := server.Config.Services.Remote.(remote.Pinger)
+	if !ok {
+		c.String(http.StatusNotImplemented, "remote does not support health checks")
+		return
+	}
+
+	res, err := pinger.Ping(c, session.User(c))
+	switch {
+	case errors.Is(err, remote.ErrInvalidToken):
+		c.String(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, remote.ErrUnreachable):
+		c.String(http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		c.String(http.StatusInternalServerError, err.Error())
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"latency_ms": res.Latency.Milliseconds(),
+			"login":      res.Login,
+		})
+	}
+}
+
 // Version endpoint returns the server version and build information.
 func Version(c *gin.Context) {
 	c.JSON(200, gin.H{
@@ -39,6 +39,7 @@ var Config = struct {
 		Environ       model.EnvironService
 		Remote        remote.Remote
 		ConfigService configuration.ConfigService
+		Deliveries    model.DeliveryService
 	}
 	Storage struct {
 		// Users  model.UserStore
@@ -55,11 +56,13 @@ var Config = struct {
 		Cert           string
 		OAuthHost      string
 		Host           string
+		PublicHost     string
 		Port           string
 		Pass           string
 		Docs           string
 		StatusContext  string
 		SessionExpires time.Duration
+		MaxHooks       int
 		// Open bool
 		// Orgs map[string]struct{}
 		// Admins map[string]struct{}
@@ -69,6 +72,10 @@ var Config = struct {
 	}
 	Pipeline struct {
 		AuthenticatePublicRepos bool
+		InitialBuild            bool
+		IncludeRepos            []string
+		EventConfigs            bool
+		SkipTokens              []string
 		DefaultCloneImage       string
 		Limits                  model.ResourceLimit
 		Volumes                 []string
@@ -133,10 +133,12 @@ func (s *RPC) Update(c context.Context, id string, state rpc.State) error {
 		return err
 	}
 
-	if _, err = shared.UpdateProcStatus(s.store, *proc, state, build.Started); err != nil {
+	if proc, err = shared.UpdateProcStatus(s.store, *proc, state, build.Started); err != nil {
 		log.Error().Err(err).Msg("rpc.update: cannot update proc")
 	}
 
+	s.updateRemoteStepStatus(c, repo, build, pproc, proc)
+
 	if build.Procs, err = s.store.ProcList(build); err != nil {
 		log.Error().Err(err).Msg("can not get proc list from store")
 	}
@@ -350,7 +352,11 @@ func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
 		}
 	}
 
+	build.Procs = procs
 	s.updateRemoteStatus(c, repo, build, proc)
+	if !model.IsThereRunningStage(procs) {
+		s.updateRemoteSummary(c, repo, build)
+	}
 
 	if err := s.logger.Close(c, id); err != nil {
 		log.Error().Err(err).Msgf("done: cannot close build_id %d logger", proc.ID)
@@ -392,10 +398,69 @@ func (s *RPC) completeChildrenIfParentCompleted(procs []*model.Proc, completedPr
 }
 
 func (s *RPC) updateRemoteStatus(ctx context.Context, repo *model.Repo, build *model.Build, proc *model.Proc) {
+	user, err := s.remoteUser(ctx, repo)
+	if err != nil {
+		return
+	}
+
+	// only do status updates for parent procs
+	if proc != nil && proc.IsParent() {
+		err = s.remote.Status(ctx, user, repo, build, proc)
+		if err != nil {
+			log.Error().Err(err).Msgf("error setting commit status for %s/%d", repo.FullName, build.Number)
+		}
+	}
+}
+
+// updateRemoteStepStatus reports the status of a step if the remote supports it.
+func (s *RPC) updateRemoteStepStatus(ctx context.Context, repo *model.Repo, build *model.Build, parent, step *model.Proc) {
+	statuser, ok := s.remote.(remote.StepStatuser)
+	if !ok || parent == nil || step == nil {
+		return
+	}
+	if provider, ok := s.remote.(remote.CapabilitiesProvider); ok {
+		capabilities, err := provider.Capabilities(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("could not get capabilities of the remote")
+		} else if !capabilities.SupportsStatusPerStep {
+			return
+		}
+	}
+
+	user, err := s.remoteUser(ctx, repo)
+	if err != nil {
+		return
+	}
+
+	if err := statuser.StepStatus(ctx, user, repo, build, parent, step); err != nil {
+		log.Error().Err(err).Msgf("error setting commit status of step %s for %s/%d", step.Name, repo.FullName, build.Number)
+	}
+}
+
+// updateRemoteSummary posts the summary of the finished build to its pull
+// request if the remote supports it.
+func (s *RPC) updateRemoteSummary(ctx context.Context, repo *model.Repo, build *model.Build) {
+	summarizer, ok := s.remote.(remote.BuildSummarizer)
+	if !ok {
+		return
+	}
+
+	user, err := s.remoteUser(ctx, repo)
+	if err != nil {
+		return
+	}
+
+	if err := summarizer.Summary(ctx, user, repo, build); err != nil {
+		log.Error().Err(err).Msgf("error posting the build summary of %s/%d", repo.FullName, build.Number)
+	}
+}
+
+// remoteUser returns the owner of the repository with a refreshed token.
+func (s *RPC) remoteUser(ctx context.Context, repo *model.Repo) (*model.User, error) {
 	user, err := s.store.GetUser(repo.UserID)
 	if err != nil {
 		log.Error().Err(err).Msgf("can not get user with id '%d'", repo.UserID)
-		return
+		return nil, err
 	}
 
 	if refresher, ok := s.remote.(remote.Refresher); ok {
@@ -408,14 +473,7 @@ func (s *RPC) updateRemoteStatus(ctx context.Context, repo *model.Repo, build *m
 			}
 		}
 	}
-
-	// only do status updates for parent procs
-	if proc != nil && proc.IsParent() {
-		err = s.remote.Status(ctx, user, repo, build, proc)
-		if err != nil {
-			log.Error().Err(err).Msgf("error setting commit status for %s/%d", repo.FullName, build.Number)
-		}
-	}
+	return user, nil
 }
 
 func (s *RPC) notify(c context.Context, repo *model.Repo, build *model.Build, procs []*model.Proc) (err error) {
@@ -17,40 +17,66 @@ package model
 
 // swagger:model build
 type Build struct {
-	ID           int64        `json:"id"                      xorm:"pk autoincr 'build_id'"`
-	RepoID       int64        `json:"-"                       xorm:"UNIQUE(s) INDEX 'build_repo_id'"`
-	Number       int64        `json:"number"                  xorm:"UNIQUE(s) 'build_number'"`
-	Author       string       `json:"author"                  xorm:"INDEX 'build_author'"`
-	ConfigID     int64        `json:"-"                       xorm:"build_config_id"`
-	Parent       int64        `json:"parent"                  xorm:"build_parent"`
-	Event        WebhookEvent `json:"event"                   xorm:"build_event"`
-	Status       StatusValue  `json:"status"                  xorm:"INDEX 'build_status'"`
-	Error        string       `json:"error"                   xorm:"build_error"`
-	Enqueued     int64        `json:"enqueued_at"             xorm:"build_enqueued"`
-	Created      int64        `json:"created_at"              xorm:"build_created"`
-	Updated      int64        `json:"updated_at"              xorm:"updated NOT NULL DEFAULT 0 'updated'"`
-	Started      int64        `json:"started_at"              xorm:"build_started"`
-	Finished     int64        `json:"finished_at"             xorm:"build_finished"`
-	Deploy       string       `json:"deploy_to"               xorm:"build_deploy"`
-	Commit       string       `json:"commit"                  xorm:"build_commit"`
-	Branch       string       `json:"branch"                  xorm:"build_branch"`
-	Ref          string       `json:"ref"                     xorm:"build_ref"`
-	Refspec      string       `json:"refspec"                 xorm:"build_refspec"`
-	Remote       string       `json:"remote"                  xorm:"build_remote"`
-	Title        string       `json:"title"                   xorm:"build_title"`
-	Message      string       `json:"message"                 xorm:"build_message"`
-	Timestamp    int64        `json:"timestamp"               xorm:"build_timestamp"`
-	Sender       string       `json:"sender"                  xorm:"build_sender"`
-	Avatar       string       `json:"author_avatar"           xorm:"build_avatar"`
-	Email        string       `json:"author_email"            xorm:"build_email"`
-	Link         string       `json:"link_url"                xorm:"build_link"`
-	Signed       bool         `json:"signed"                  xorm:"build_signed"`   // deprecate
-	Verified     bool         `json:"verified"                xorm:"build_verified"` // deprecate
-	Reviewer     string       `json:"reviewed_by"             xorm:"build_reviewer"`
-	Reviewed     int64        `json:"reviewed_at"             xorm:"build_reviewed"`
-	Procs        []*Proc      `json:"procs,omitempty"         xorm:"-"`
-	Files        []*File      `json:"files,omitempty"         xorm:"-"`
-	ChangedFiles []string     `json:"changed_files,omitempty" xorm:"json 'changed_files'"`
+	ID            int64        `json:"id"                      xorm:"pk autoincr 'build_id'"`
+	RepoID        int64        `json:"-"                       xorm:"UNIQUE(s) INDEX 'build_repo_id'"`
+	Number        int64        `json:"number"                  xorm:"UNIQUE(s) 'build_number'"`
+	Author        string       `json:"author"                  xorm:"INDEX 'build_author'"`
+	ConfigID      int64        `json:"-"                       xorm:"build_config_id"`
+	Parent        int64        `json:"parent"                  xorm:"build_parent"`
+	Event         WebhookEvent `json:"event"                   xorm:"build_event"`
+	Action        string       `json:"action,omitempty"        xorm:"build_action"`
+	Status        StatusValue  `json:"status"                  xorm:"INDEX 'build_status'"`
+	Error         string       `json:"error"                   xorm:"build_error"`
+	Enqueued      int64        `json:"enqueued_at"             xorm:"build_enqueued"`
+	Created       int64        `json:"created_at"              xorm:"build_created"`
+	Updated       int64        `json:"updated_at"              xorm:"updated NOT NULL DEFAULT 0 'updated'"`
+	Started       int64        `json:"started_at"              xorm:"build_started"`
+	Finished      int64        `json:"finished_at"             xorm:"build_finished"`
+	Deploy        string       `json:"deploy_to"               xorm:"build_deploy"`
+	Commit        string       `json:"commit"                  xorm:"build_commit"`
+	Before        string       `json:"before,omitempty"        xorm:"build_before"`
+	BaseCommit    string       `json:"base_commit,omitempty"   xorm:"build_base_commit"`
+	MergeBase     string       `json:"merge_base,omitempty"    xorm:"build_merge_base"`
+	MergeCommit   string       `json:"merge_commit,omitempty"  xorm:"build_merge_commit"`
+	Branch        string       `json:"branch"                  xorm:"build_branch"`
+	Ref           string       `json:"ref"                     xorm:"build_ref"`
+	Refspec       string       `json:"refspec"                 xorm:"build_refspec"`
+	SourceBranch  string       `json:"source_branch,omitempty" xorm:"build_source_branch"`
+	PullRequest   int64        `json:"pull_request,omitempty"  xorm:"build_pull_request"`
+	Remote        string       `json:"remote"                  xorm:"build_remote"`
+	Title         string       `json:"title"                   xorm:"build_title"`
+	Message       string       `json:"message"                 xorm:"build_message"`
+	Timestamp     int64        `json:"timestamp"               xorm:"build_timestamp"`
+	Sender        string       `json:"sender"                  xorm:"build_sender"`
+	Avatar        string       `json:"author_avatar"           xorm:"build_avatar"`
+	Email         string       `json:"author_email"            xorm:"build_email"`
+	Committer     *Committer   `json:"committer,omitempty"     xorm:"json 'build_committer'"`
+	Link          string       `json:"link_url"                xorm:"build_link"`
+	Signed        bool         `json:"signed"                  xorm:"build_signed"`   // deprecate
+	Verified      bool         `json:"verified"                xorm:"build_verified"` // deprecate
+	Reviewer      string       `json:"reviewed_by"             xorm:"build_reviewer"`
+	Reviewed      int64        `json:"reviewed_at"             xorm:"build_reviewed"`
+	Approver      string       `json:"approved_by,omitempty"   xorm:"build_approver"`
+	Approved      int64        `json:"approved_at,omitempty"   xorm:"build_approved"`
+	ReviewState   ReviewState  `json:"review_state,omitempty"  xorm:"build_review_state"`
+	Procs         []*Proc      `json:"procs,omitempty"         xorm:"-"`
+	Files         []*File      `json:"files,omitempty"         xorm:"-"`
+	ChangedFiles  []string     `json:"changed_files,omitempty" xorm:"json 'changed_files'"`
+	Truncated     bool         `json:"changed_files_truncated,omitempty" xorm:"build_changed_files_truncated"`
+	IsPrerelease  bool         `json:"is_prerelease,omitempty" xorm:"build_is_prerelease"`
+	IsFirstTag    bool         `json:"is_first_tag,omitempty"  xorm:"build_is_first_tag"`
+	IsDraft       bool         `json:"is_draft,omitempty"      xorm:"build_is_draft"`
+	IsFork        bool         `json:"is_fork,omitempty"       xorm:"build_is_fork"`
+	Labels        []string     `json:"labels,omitempty"        xorm:"json 'build_labels'"`
+	Assignees     []string     `json:"assignees,omitempty"     xorm:"json 'build_assignees'"`
+	IsVerified    bool         `json:"is_verified,omitempty"   xorm:"build_is_verified"`
+	Signer        string       `json:"signer,omitempty"        xorm:"build_signer"`
+	Package       *Package     `json:"package,omitempty"       xorm:"json 'build_package'"`
+	DeletedRef    *DeletedRef  `json:"deleted_ref,omitempty"   xorm:"json 'build_deleted_ref'"`
+	BaseProtected *bool        `json:"base_protected,omitempty" xorm:"build_base_protected"`
+	Milestone     *Milestone   `json:"milestone,omitempty"     xorm:"json 'build_milestone'"`
+	Teams         []string     `json:"teams,omitempty"         xorm:"json 'build_teams'"`
+	Cron          string       `json:"cron,omitempty"          xorm:"build_cron"`
 }
 
 // TableName return database table name for xorm
@@ -17,15 +17,20 @@ package model
 type WebhookEvent string
 
 const (
-	EventPush   WebhookEvent = "push"
-	EventPull   WebhookEvent = "pull_request"
-	EventTag    WebhookEvent = "tag"
-	EventDeploy WebhookEvent = "deployment"
+	EventPush    WebhookEvent = "push"
+	EventPull    WebhookEvent = "pull_request"
+	EventTag     WebhookEvent = "tag"
+	EventDeploy  WebhookEvent = "deployment"
+	EventRelease WebhookEvent = "release"
+	EventCron    WebhookEvent = "cron"
+	EventPackage WebhookEvent = "package"
+	EventDelete  WebhookEvent = "delete"
+	EventManual  WebhookEvent = "manual"
 )
 
 func ValidateWebhookEvent(s WebhookEvent) bool {
 	switch s {
-	case EventPush, EventPull, EventTag, EventDeploy:
+	case EventPush, EventPull, EventTag, EventDeploy, EventRelease, EventCron, EventPackage, EventDelete, EventManual:
 		return true
 	default:
 		return false
@@ -65,3 +70,12 @@ const (
 	VisibilityPrivate  RepoVisibly = "private"
 	VisibilityInternal RepoVisibly = "internal"
 )
+
+// ReviewState represent the states of pull request reviews starting a build
+type ReviewState string
+
+const (
+	ReviewApproved ReviewState = "approved"
+	ReviewRejected ReviewState = "rejected"
+	ReviewComment  ReviewState = "comment"
+)
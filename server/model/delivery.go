@@ -0,0 +1,24 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// DeliveryService remembers the ids of received hook deliveries for a while,
+// so deliveries the remote sends again are not built twice.
+type DeliveryService interface {
+	// DeliveryAdd adds the id and reports whether it was not received before.
+	DeliveryAdd(id string) bool
+	// DeliveryRemove forgets the id, so a failed delivery can be sent again.
+	DeliveryRemove(id string)
+}
@@ -18,4 +18,6 @@ type Netrc struct {
 	Machine  string `json:"machine"`
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// Hosts are additional machines the credentials are used for.
+	Hosts []string `json:"hosts,omitempty"`
 }
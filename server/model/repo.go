@@ -18,33 +18,47 @@ package model
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
+// HashRotationGrace is the time hooks signed with the previous hash of a
+// repository are still accepted after rotating it.
+const HashRotationGrace = 5 * time.Minute
+
 // Repo represents a repository.
 //
 // swagger:model repo
 type Repo struct {
-	ID           int64       `json:"id,omitempty"             xorm:"pk autoincr 'repo_id'"`
-	UserID       int64       `json:"-"                        xorm:"repo_user_id"`
-	Owner        string      `json:"owner"                    xorm:"UNIQUE(name) 'repo_owner'"`
-	Name         string      `json:"name"                     xorm:"UNIQUE(name) 'repo_name'"`
-	FullName     string      `json:"full_name"                xorm:"UNIQUE 'repo_full_name'"`
-	Avatar       string      `json:"avatar_url,omitempty"     xorm:"varchar(500) 'repo_avatar'"`
-	Link         string      `json:"link_url,omitempty"       xorm:"varchar(1000) 'repo_link'"`
-	Clone        string      `json:"clone_url,omitempty"      xorm:"varchar(1000) 'repo_clone'"`
-	Branch       string      `json:"default_branch,omitempty" xorm:"varchar(500) 'repo_branch'"`
-	SCMKind      SCMKind     `json:"scm,omitempty"            xorm:"varchar(50) 'repo_scm'"`
-	Timeout      int64       `json:"timeout,omitempty"        xorm:"repo_timeout"`
-	Visibility   RepoVisibly `json:"visibility"               xorm:"varchar(10) 'repo_visibility'"`
-	IsSCMPrivate bool        `json:"private"                  xorm:"repo_private"`
-	IsTrusted    bool        `json:"trusted"                  xorm:"repo_trusted"`
-	IsStarred    bool        `json:"starred,omitempty"        xorm:"-"`
-	IsGated      bool        `json:"gated"                    xorm:"repo_gated"`
-	IsActive     bool        `json:"active"                   xorm:"repo_active"`
-	AllowPull    bool        `json:"allow_pr"                 xorm:"repo_allow_pr"`
-	Config       string      `json:"config_file"                 xorm:"varchar(500) 'repo_config_path'"`
-	Hash         string      `json:"-"                           xorm:"varchar(500) 'repo_hash'"`
-	Perm         *Perm       `json:"-"                           xorm:"-"`
+	ID            int64       `json:"id,omitempty"             xorm:"pk autoincr 'repo_id'"`
+	UserID        int64       `json:"-"                        xorm:"repo_user_id"`
+	Owner         string      `json:"owner"                    xorm:"UNIQUE(name) 'repo_owner'"`
+	Name          string      `json:"name"                     xorm:"UNIQUE(name) 'repo_name'"`
+	FullName      string      `json:"full_name"                xorm:"UNIQUE(full_name) 'repo_full_name'"`
+	Remote        string      `json:"remote,omitempty"         xorm:"UNIQUE(name) UNIQUE(full_name) varchar(255) 'repo_remote'"`
+	Avatar        string      `json:"avatar_url,omitempty"     xorm:"varchar(500) 'repo_avatar'"`
+	Link          string      `json:"link_url,omitempty"       xorm:"varchar(1000) 'repo_link'"`
+	Clone         string      `json:"clone_url,omitempty"      xorm:"varchar(1000) 'repo_clone'"`
+	Branch        string      `json:"default_branch,omitempty" xorm:"varchar(500) 'repo_branch'"`
+	SCMKind       SCMKind     `json:"scm,omitempty"            xorm:"varchar(50) 'repo_scm'"`
+	Timeout       int64       `json:"timeout,omitempty"        xorm:"repo_timeout"`
+	Visibility    RepoVisibly `json:"visibility"               xorm:"varchar(10) 'repo_visibility'"`
+	IsSCMPrivate  bool        `json:"private"                  xorm:"repo_private"`
+	IsMirror      bool        `json:"mirror"                   xorm:"repo_mirror"`
+	IsFork        bool        `json:"fork"                     xorm:"repo_fork"`
+	IsTrusted     bool        `json:"trusted"                  xorm:"repo_trusted"`
+	IsStarred     bool        `json:"starred,omitempty"        xorm:"-"`
+	IsGated       bool        `json:"gated"                    xorm:"repo_gated"`
+	IsActive      bool        `json:"active"                   xorm:"repo_active"`
+	IsPaused      bool        `json:"paused"                   xorm:"repo_paused"`
+	AllowPull     bool        `json:"allow_pr"                 xorm:"repo_allow_pr"`
+	Config        string      `json:"config_file"                 xorm:"varchar(500) 'repo_config_path'"`
+	Hash          string      `json:"-"                           xorm:"varchar(500) 'repo_hash'"`
+	PrevHash      string      `json:"-"                           xorm:"varchar(500) 'repo_prev_hash'"`
+	HashRotated   int64       `json:"-"                           xorm:"repo_hash_rotated"`
+	Topics        []string    `json:"topics,omitempty"            xorm:"json 'repo_topics'"`
+	HookEvents    []string    `json:"hook_events,omitempty"       xorm:"json 'repo_hook_events'"`
+	StatusContext string      `json:"status_context,omitempty"    xorm:"varchar(255) 'repo_status_context'"`
+	Perm          *Perm       `json:"-"                           xorm:"-"`
 }
 
 // TableName return database table name for xorm
@@ -59,6 +73,15 @@ func (r *Repo) ResetVisibility() {
 	}
 }
 
+// Hashes returns the hashes hooks of the repository may be signed with, the
+// previous hash is included during the grace period after a rotation.
+func (r *Repo) Hashes(now time.Time) []string {
+	if r.PrevHash == "" || now.Sub(time.Unix(r.HashRotated, 0)) > HashRotationGrace {
+		return []string{r.Hash}
+	}
+	return []string{r.Hash, r.PrevHash}
+}
+
 // ParseRepo parses the repository owner and name from a string.
 func ParseRepo(str string) (user, repo string, err error) {
 	parts := strings.Split(str, "/")
@@ -78,6 +101,12 @@ func (r *Repo) Update(from *Repo) {
 	r.SCMKind = from.SCMKind
 	r.Clone = from.Clone
 	r.Branch = from.Branch
+	r.IsMirror = from.IsMirror
+	r.IsFork = from.IsFork
+	// topics are nil if the remote did not provide them
+	if from.Topics != nil {
+		r.Topics = from.Topics
+	}
 	if from.IsSCMPrivate != r.IsSCMPrivate {
 		if from.IsSCMPrivate {
 			r.Visibility = VisibilityPrivate
@@ -90,10 +119,12 @@ func (r *Repo) Update(from *Repo) {
 
 // RepoPatch represents a repository patch object.
 type RepoPatch struct {
-	Config     *string `json:"config_file,omitempty"`
-	IsTrusted  *bool   `json:"trusted,omitempty"`
-	IsGated    *bool   `json:"gated,omitempty"`
-	Timeout    *int64  `json:"timeout,omitempty"`
-	Visibility *string `json:"visibility,omitempty"`
-	AllowPull  *bool   `json:"allow_pr,omitempty"`
+	Config        *string   `json:"config_file,omitempty"`
+	IsTrusted     *bool     `json:"trusted,omitempty"`
+	IsGated       *bool     `json:"gated,omitempty"`
+	Timeout       *int64    `json:"timeout,omitempty"`
+	Visibility    *string   `json:"visibility,omitempty"`
+	AllowPull     *bool     `json:"allow_pr,omitempty"`
+	HookEvents    *[]string `json:"hook_events,omitempty"`
+	StatusContext *string   `json:"status_context,omitempty"`
 }
@@ -0,0 +1,48 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepoHashes(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		repo   Repo
+		hashes []string
+	}{
+		{
+			repo:   Repo{Hash: "new"},
+			hashes: []string{"new"},
+		},
+		{
+			repo:   Repo{Hash: "new", PrevHash: "old", HashRotated: now.Add(-time.Minute).Unix()},
+			hashes: []string{"new", "old"},
+		},
+		{
+			repo:   Repo{Hash: "new", PrevHash: "old", HashRotated: now.Add(-HashRotationGrace - time.Minute).Unix()},
+			hashes: []string{"new"},
+		},
+	}
+
+	for _, test := range tests {
+		if hashes := test.repo.Hashes(now); !reflect.DeepEqual(hashes, test.hashes) {
+			t.Errorf("Want hashes %v, got %v", test.hashes, hashes)
+		}
+	}
+}
@@ -24,7 +24,13 @@ type Settings struct {
 }
 
 // IsAdmin returns true if the user is a member of the administrator list.
+// Users of another remote instance than the default one are listed as
+// <remote>/<login>, so the login alone does not grant admin rights on every
+// instance.
 func (c *Settings) IsAdmin(user *User) bool {
+	if user.Remote != "" {
+		return c.Admins[user.Remote+"/"+user.Login]
+	}
 	return c.Admins[user.Login]
 }
 
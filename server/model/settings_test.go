@@ -0,0 +1,36 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "testing"
+
+func TestSettingsIsAdmin(t *testing.T) {
+	settings := &Settings{Admins: map[string]bool{"alice": true, "codeberg/bob": true}}
+
+	tests := []struct {
+		user  User
+		admin bool
+	}{
+		{user: User{Login: "alice"}, admin: true},
+		{user: User{Login: "alice", Remote: "codeberg"}, admin: false},
+		{user: User{Login: "bob"}, admin: false},
+		{user: User{Login: "bob", Remote: "codeberg"}, admin: true},
+	}
+	for _, test := range tests {
+		if got := settings.IsAdmin(&test.user); got != test.admin {
+			t.Errorf("want admin %v of %s/%s, got %v", test.admin, test.user.Remote, test.user.Login, got)
+		}
+	}
+}
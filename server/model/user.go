@@ -68,6 +68,10 @@ type User struct {
 	// longer persisted in the database.
 	Admin bool `json:"admin,omitempty" xorm:"-"`
 
+	// Remote is the name of the remote instance the user logged in with, it
+	// is empty for the default instance.
+	Remote string `json:"remote,omitempty" xorm:"varchar(255) 'user_remote'"`
+
 	// Hash is a unique token used to sign tokens.
 	Hash string `json:"-" xorm:"UNIQUE varchar(500) 'user_hash'"`
 
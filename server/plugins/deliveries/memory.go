@@ -0,0 +1,72 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deliveries
+
+import (
+	"sync"
+	"time"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+// maxDeliveries caps the number of remembered deliveries, once reached the
+// expired ones are dropped.
+const maxDeliveries = 10000
+
+type memory struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// New returns a DeliveryService remembering the deliveries in memory for the
+// ttl. Deliveries are not shared between servers and forgotten on restarts.
+func New(ttl time.Duration) model.DeliveryService {
+	return &memory{
+		ttl:     ttl,
+		now:     time.Now,
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (m *memory) DeliveryAdd(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if expires, ok := m.expires[id]; ok && now.Before(expires) {
+		return false
+	}
+	if len(m.expires) >= maxDeliveries {
+		for id, expires := range m.expires {
+			if !now.Before(expires) {
+				delete(m.expires, id)
+			}
+		}
+	}
+	if len(m.expires) >= maxDeliveries {
+		m.expires = make(map[string]time.Time)
+	}
+	m.expires[id] = now.Add(m.ttl)
+	return true
+}
+
+func (m *memory) DeliveryRemove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.expires, id)
+}
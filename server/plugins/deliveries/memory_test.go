@@ -0,0 +1,65 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deliveries
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	now := time.Now()
+	m := New(time.Minute).(*memory)
+	m.now = func() time.Time { return now }
+
+	t.Run("first delivery", func(t *testing.T) {
+		if !m.DeliveryAdd("f6266f16-1bf3-46a5-9ea4-602e06ead473") {
+			t.Error("expected the first delivery to be new")
+		}
+	})
+
+	t.Run("duplicate delivery", func(t *testing.T) {
+		if m.DeliveryAdd("f6266f16-1bf3-46a5-9ea4-602e06ead473") {
+			t.Error("expected the duplicate delivery to be known")
+		}
+		if !m.DeliveryAdd("a9ee2ac4-9a5c-4bc8-a1e4-0c4c1b4b3a8f") {
+			t.Error("expected other deliveries to be new")
+		}
+	})
+
+	t.Run("removed delivery", func(t *testing.T) {
+		m.DeliveryRemove("a9ee2ac4-9a5c-4bc8-a1e4-0c4c1b4b3a8f")
+		if !m.DeliveryAdd("a9ee2ac4-9a5c-4bc8-a1e4-0c4c1b4b3a8f") {
+			t.Error("expected a removed delivery to be new again")
+		}
+	})
+
+	t.Run("expired delivery", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		if !m.DeliveryAdd("f6266f16-1bf3-46a5-9ea4-602e06ead473") {
+			t.Error("expected an expired delivery to be new again")
+		}
+	})
+
+	t.Run("capped deliveries", func(t *testing.T) {
+		for i := 0; i < maxDeliveries+1; i++ {
+			m.DeliveryAdd(fmt.Sprint(i))
+		}
+		if len(m.expires) > maxDeliveries {
+			t.Errorf("expected at most %d deliveries, got %d", maxDeliveries, len(m.expires))
+		}
+	})
+}
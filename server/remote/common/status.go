@@ -51,8 +51,8 @@ func GetBuildStatusDescription(status model.StatusValue) string {
 
 func GetBuildStatusLink(repo *model.Repo, build *model.Build, proc *model.Proc) string {
 	if proc == nil {
-		return fmt.Sprintf("%s/%s/build/%d", server.Config.Server.Host, repo.FullName, build.Number)
+		return fmt.Sprintf("%s/%s/build/%d", server.Config.Server.PublicHost, repo.FullName, build.Number)
 	}
 
-	return fmt.Sprintf("%s/%s/build/%d/%d", server.Config.Server.Host, repo.FullName, build.Number, proc.PID)
+	return fmt.Sprintf("%s/%s/build/%d/%d", server.Config.Server.PublicHost, repo.FullName, build.Number, proc.PID)
 }
@@ -14,6 +14,75 @@
 
 package remote
 
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature is returned by Hook if the signature of the hook does
+// not match its payload.
+var ErrInvalidSignature = errors.New("invalid hook signature")
+
+// ErrRepoDeleted is returned by Hook with the repository of a hook notifying
+// about the deletion of the repository, which creates no build.
+var ErrRepoDeleted = errors.New("repository deleted")
+
+// ErrFileNotFound is returned by File if the requested file does not exist.
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrUnpinnedRef is returned by RepoFile if the ref is neither a commit sha
+// nor a tag.
+var ErrUnpinnedRef = errors.New("ref is not pinned")
+
+// ErrDiffTooLarge is returned by Diff if the diff exceeds the maximum size.
+var ErrDiffTooLarge = errors.New("diff too large")
+
+// ErrUnreachable is returned by Ping if the remote could not be reached.
+var ErrUnreachable = errors.New("remote is unreachable")
+
+// ErrInvalidToken is returned by Ping if the remote rejected the user token,
+// and by API calls if an expired token could not be refreshed.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrNotFound is returned if the requested resource does not exist in the
+// remote or is not visible to the user.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnauthorized is returned if the remote rejected the credentials of a
+// request.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrForbidden is returned if the remote denied the user access to the
+// requested resource.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrInsufficientScope is returned if the remote rejected a request as the
+// token lacks a scope. The error is an *InsufficientScopeError naming the scope.
+var ErrInsufficientScope = errors.New("insufficient token scope")
+
+// InsufficientScopeError represents a request rejected by the remote as the
+// token lacks the required scope.
+type InsufficientScopeError struct {
+	Scope string
+	Err   error
+}
+
+// Error implements error interface.
+func (e *InsufficientScopeError) Error() string {
+	return fmt.Sprintf("token lacks the %s scope: %s", e.Scope, e.Err)
+}
+
+// Is reports whether the target is ErrInsufficientScope or ErrForbidden, as
+// the request was forbidden.
+func (e *InsufficientScopeError) Is(target error) bool {
+	return target == ErrInsufficientScope || target == ErrForbidden
+}
+
+// Unwrap returns the error of the remote.
+func (e *InsufficientScopeError) Unwrap() error {
+	return e.Err
+}
+
 // AuthError represents remote authentication error.
 type AuthError struct {
 	Err         string
@@ -0,0 +1,183 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// Diff streams the unified diff of the changes the build was triggered by as
+// one diff: the diff of a pull request against its merge base, the diff
+// between the previous and the pushed commit of a push, the diff against the
+// default branch of a push creating a branch, the diff against the previous
+// tag of a tag or release, or the diff of the commit otherwise. Reading more
+// than MaxDiffSize bytes fails with remote.ErrDiffTooLarge.
+func (c *Gitea) Diff(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) (io.ReadCloser, error) {
+	index, err := pullRequestIndex(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffURL string
+	if index != 0 {
+		diffURL = c.apiURL(r, "pulls", strconv.FormatInt(index, 10)+".diff")
+	} else {
+		base, err := c.diffBase(ctx, u, r, b)
+		if err != nil {
+			return nil, err
+		}
+		if base != "" {
+			// the API only serves the diffs of single commits
+			diffURL = c.webURL(r, "compare", base+"..."+b.Commit+".diff")
+		} else {
+			diffURL = c.apiURL(r, "git", "commits", b.Commit+".diff")
+		}
+	}
+
+	body, err := c.getDiff(ctx, u, diffURL)
+	if err != nil {
+		return nil, err
+	}
+	return &diffReader{body: body, max: c.MaxDiffSize}, nil
+}
+
+// diffBase returns the commit or ref the changes of a build which is not one
+// of a pull request are compared with, or an empty string to show the diff of
+// the commit of the build.
+func (c *Gitea) diffBase(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) (string, error) {
+	switch {
+	case b.Before != "":
+		return b.Before, nil
+	case b.Event == model.EventPush && r.Branch != "" && b.Branch != r.Branch:
+		return r.Branch, nil
+	case b.Event == model.EventTag || b.Event == model.EventRelease:
+		client, err := c.newClientUser(ctx, u)
+		if err != nil {
+			return "", err
+		}
+		_, tag := tagRef(b.Ref)
+		return previousTag(client, r, tag)
+	}
+	return "", nil
+}
+
+// pullRequestIndex returns the index of the pull request of the build, or
+// zero if the build is not one of a pull request.
+func pullRequestIndex(b *model.Build) (int64, error) {
+	if b.PullRequest != 0 {
+		return b.PullRequest, nil
+	}
+	if !strings.HasPrefix(b.Ref, "refs/pull/") {
+		return 0, nil
+	}
+	parts := strings.Split(b.Ref, "/")
+	index, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not get the pull request of ref %s: %w", b.Ref, err)
+	}
+	return index, nil
+}
+
+// apiURL returns the url of the API path of the repo.
+func (c *Gitea) apiURL(r *model.Repo, segments ...string) string {
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s/%s",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(r.Owner),
+		url.PathEscape(r.Name),
+		strings.Join(segments, "/"),
+	)
+}
+
+// webURL returns the url of the web path of the repo.
+func (c *Gitea) webURL(r *model.Repo, segments ...string) string {
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return fmt.Sprintf("%s/%s/%s/%s",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(r.Owner),
+		url.PathEscape(r.Name),
+		strings.Join(segments, "/"),
+	)
+}
+
+// getDiff requests the diff, which is not covered by the Gitea SDK, and
+// returns its body to be streamed. Redirects are not followed, as Gitea
+// redirects requests of web pages it does not authenticate to its login.
+func (c *Gitea) getDiff(ctx context.Context, u *model.User, diffURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, diffURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+u.Token)
+
+	client := c.newHTTPClientUser(ctx, u)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode < http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: could not get diff, Gitea requires a login", remote.ErrForbidden)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := fmt.Errorf("could not get diff: %s", resp.Status)
+		return nil, scopeError(&gitea.Response{Response: resp}, err, scopeReadRepository)
+	}
+	if c.MaxDiffSize > 0 && resp.ContentLength > c.MaxDiffSize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes exceed the maximum of %d bytes", remote.ErrDiffTooLarge, resp.ContentLength, c.MaxDiffSize)
+	}
+	return resp.Body, nil
+}
+
+// diffReader streams the diff until more than max bytes were read, then
+// reading fails with remote.ErrDiffTooLarge.
+type diffReader struct {
+	body io.ReadCloser
+	max  int64
+	read int64
+}
+
+func (r *diffReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+	if r.max > 0 && r.read > r.max {
+		return n - int(r.read-r.max), fmt.Errorf("%w: exceeds the maximum of %d bytes", remote.ErrDiffTooLarge, r.max)
+	}
+	return n, err
+}
+
+func (r *diffReader) Close() error {
+	return r.body.Close()
+}
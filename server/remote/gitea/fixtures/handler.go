@@ -15,7 +15,9 @@
 package fixtures
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,13 +29,45 @@ func Handler() http.Handler {
 
 	e := gin.New()
 	e.GET("/api/v1/repos/:owner/:name", getRepo)
-	e.GET("/api/v1/repos/:owner/:name/raw/:commit/:file", getRepoFile)
+	e.GET("/api/v1/repos/:owner/:name/raw/:commit/*file", getRepoFile)
+	e.GET("/api/v1/repos/:owner/:name/git/trees/:commit", getRepoTree)
+	e.GET("/api/v1/repos/:owner/:name/contents/*file", getRepoContents)
+	e.GET("/api/v1/repos/:owner/:name/branches", getRepoBranches)
+	e.GET("/api/v1/repos/:owner/:name/branches/:branch", getRepoBranch)
+	e.GET("/api/v1/repos/:owner/:name/branch_protections", listBranchProtections)
+	e.GET("/api/v1/repos/:owner/:name/collaborators", listCollaborators)
+	e.GET("/api/v1/repos/:owner/:name/collaborators/:collaborator/permission", getCollaboratorPermission)
+	e.GET("/api/v1/repos/:owner/:name/branch_protections/:branch", getBranchProtection)
 	e.POST("/api/v1/repos/:owner/:name/hooks", createRepoHook)
 	e.GET("/api/v1/repos/:owner/:name/hooks", listRepoHooks)
 	e.DELETE("/api/v1/repos/:owner/:name/hooks/:id", deleteRepoHook)
 	e.POST("/api/v1/repos/:owner/:name/statuses/:commit", createRepoCommitStatus)
+	e.GET("/api/v1/repos/:owner/:name/topics", listRepoTopics)
+	e.GET("/api/v1/repos/:owner/:name/pulls", listRepoPullRequests)
+	e.GET("/api/v1/repos/:owner/:name/pulls/:index", getPullRequestDiff)
+	e.GET("/api/v1/repos/:owner/:name/git/commits/:sha", getCommitDiff)
+	e.GET("/api/v1/repos/:owner/:name/tags", listRepoTags)
+	e.GET("/api/v1/repos/:owner/:name/tags/:tag", getRepoTag)
+	e.GET("/api/v1/repos/:owner/:name/compare/:basehead", compareCommits)
+	e.GET("/:owner/:name/compare/:basehead", getCompareDiff)
+	e.GET("/api/v1/repos/:owner/:name/commits", listRepoCommits)
+	e.GET("/api/v1/repos/:owner/:name/keys", listDeployKeys)
+	e.POST("/api/v1/repos/:owner/:name/keys", createDeployKey)
+	e.DELETE("/api/v1/repos/:owner/:name/keys/:id", deleteDeployKey)
+	e.GET("/api/v1/user", getUser)
 	e.GET("/api/v1/user/repos", getUserRepos)
+	e.GET("/api/v1/user/orgs", getUserOrgs)
 	e.GET("/api/v1/version", getVersion)
+	e.GET("/api/v1/users/:user/gpg_keys", listUserGPGKeys)
+	e.GET("/api/v1/users/:user/keys", listUserKeys)
+	e.GET("/api/v1/orgs/:org", getOrg)
+	e.GET("/api/v1/orgs/:org/members", listOrgMembers)
+	e.GET("/api/v1/orgs/:org/members/:user", checkOrgMember)
+	e.GET("/api/v1/orgs/:org/public_members", listPublicOrgMembers)
+	e.GET("/api/v1/orgs/:org/teams", listOrgTeams)
+	e.GET("/api/v1/teams/:id/members", listTeamMembers)
+	e.GET("/api/v1/teams/:id/members/:username", getTeamMember)
+	e.POST("/login/oauth/access_token", createAccessToken)
 
 	return e
 }
@@ -43,9 +77,15 @@ func listRepoHooks(c *gin.Context) {
 }
 
 func getRepo(c *gin.Context) {
+	if c.GetHeader("Authorization") == "token expired" {
+		c.String(401, "")
+		return
+	}
 	switch c.Param("name") {
 	case "repo_not_found":
 		c.String(404, "")
+	case "repo_no_access":
+		c.String(200, strings.ReplaceAll(repoPayload, `"pull": true`, `"pull": false`))
 	default:
 		c.String(200, repoPayload)
 	}
@@ -59,15 +99,365 @@ func createRepoCommitStatus(c *gin.Context) {
 }
 
 func getRepoFile(c *gin.Context) {
-	if c.Param("file") == "file_not_found" {
+	if c.Param("file") == "/file_not_found" {
 		c.String(404, "")
 	}
-	if c.Param("commit") == "v1.0.0" || c.Param("commit") == "9ecad50" {
+	switch c.Param("commit") {
+	case "v1.0.0", "9ecad50", "4b2626259b5a97b6b4eab5e6cca66adb986b672b":
 		c.String(200, repoFilePayload)
 	}
 	c.String(404, "")
 }
 
+func getUser(c *gin.Context) {
+	if c.GetHeader("Authorization") == "token invalid" {
+		c.String(401, `{"message":"token is required"}`)
+		return
+	}
+	c.String(200, userPayload)
+}
+
+func getRepoContents(c *gin.Context) {
+	switch c.Param("file") {
+	case "/.woodpecker.yml", "/.woodpecker/build.yml", "/.woodpecker/test.yml":
+		c.String(200, repoContentsPayload)
+	case "/link.yml":
+		c.String(200, repoContentsSymlinkPayload)
+	case "/large.yml":
+		c.String(200, repoContentsLargePayload)
+	case "/base64.yml":
+		c.String(200, repoContentsBase64Payload)
+	case "/plain.yml":
+		c.String(200, repoContentsPlainPayload)
+	case "/binary.yml":
+		c.String(200, repoContentsBinaryPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func listRepoTopics(c *gin.Context) {
+	if c.Param("name") == "empty_repo" {
+		c.String(200, `{"topics": []}`)
+		return
+	}
+	c.String(200, `{"topics": ["public", "go"]}`)
+}
+
+// listRepoPullRequests returns two pages of open pull requests, the first
+// one is full.
+func listRepoPullRequests(c *gin.Context) {
+	if c.Query("state") != "open" {
+		c.String(400, "")
+		return
+	}
+	var from, to int
+	switch c.Query("page") {
+	case "", "1":
+		from, to = 1, 50
+	case "2":
+		from, to = 51, 52
+	}
+	var pulls []string
+	for i := from; to > 0 && i <= to; i++ {
+		pulls = append(pulls, fmt.Sprintf(`{"number": %d, "title": "Pull request %d", "user": {"login": "gordon", "username": "gordon"}, "base": {"ref": "master"}, "head": {"ref": "feature-%d"}}`, i, i, i))
+	}
+	c.String(200, "["+strings.Join(pulls, ",")+"]")
+}
+
+// listOrgMembers lists 52 members over two pages
+func listOrgMembers(c *gin.Context) {
+	var from, to int
+	switch c.Query("page") {
+	case "", "1":
+		from, to = 1, 50
+	case "2":
+		from, to = 51, 52
+	}
+	var members []string
+	for i := from; to > 0 && i <= to; i++ {
+		members = append(members, fmt.Sprintf(`{"id": %d, "login": "member-%d", "username": "member-%d"}`, i, i, i))
+	}
+	c.String(200, "["+strings.Join(members, ",")+"]")
+}
+
+// listUserGPGKeys lists two GPG keys of gordon, the first one signs by its
+// subkey
+func listUserGPGKeys(c *gin.Context) {
+	switch c.Param("user") {
+	case "gordon":
+		if page := c.Query("page"); page != "" && page != "1" {
+			c.String(200, "[]")
+			return
+		}
+		c.String(200, listUserGPGKeysPayload)
+	case "octocat":
+		c.String(200, "[]")
+	default:
+		c.String(404, "")
+	}
+}
+
+// listUserKeys lists 52 SSH keys of gordon over two pages
+func listUserKeys(c *gin.Context) {
+	switch c.Param("user") {
+	case "gordon":
+	case "octocat":
+		c.String(200, "[]")
+		return
+	default:
+		c.String(404, "")
+		return
+	}
+	var from, to int
+	switch c.Query("page") {
+	case "", "1":
+		from, to = 1, 50
+	case "2":
+		from, to = 51, 52
+	}
+	var keys []string
+	for i := from; to > 0 && i <= to; i++ {
+		keys = append(keys, fmt.Sprintf(`{"id": %d, "key": "ssh-ed25519 AAAA%d", "title": "key-%d", "fingerprint": "SHA256:%d"}`, i, i, i, i))
+	}
+	c.String(200, "["+strings.Join(keys, ",")+"]")
+}
+
+// getOrg returns the organizations of the fixtures, other owners are users.
+func getOrg(c *gin.Context) {
+	switch c.Param("org") {
+	case "org", "restricted_org", "woodpecker-ci":
+		c.JSON(200, map[string]string{"username": c.Param("org")})
+	default:
+		c.Status(404)
+	}
+}
+
+func checkOrgMember(c *gin.Context) {
+	if c.Param("org") == "restricted_org" {
+		c.Status(404)
+		return
+	}
+	c.Status(204)
+}
+
+func listPublicOrgMembers(c *gin.Context) {
+	if page := c.Query("page"); page != "" && page != "1" {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, `[{"id": 1, "login": "member-1", "username": "member-1"}, {"id": 7, "login": "member-7", "username": "member-7"}]`)
+}
+
+func listOrgTeams(c *gin.Context) {
+	if c.Param("org") == "user_owner" {
+		c.String(404, "")
+		return
+	}
+	if page := c.Query("page"); page != "" && page != "1" {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, `[{"id": 1, "name": "Owners", "permission": "owner"}, {"id": 2, "name": "Maintainers", "permission": "admin"}, {"id": 3, "name": "Developers", "permission": "write"}]`)
+}
+
+func listTeamMembers(c *gin.Context) {
+	if page := c.Query("page"); page != "" && page != "1" {
+		c.String(200, "[]")
+		return
+	}
+	switch c.Param("id") {
+	case "1":
+		c.String(200, `[{"id": 1, "login": "member-1", "username": "member-1"}]`)
+	case "2":
+		c.String(200, `[{"id": 1, "login": "member-1", "username": "member-1"}, {"id": 2, "login": "member-2", "username": "member-2"}]`)
+	default:
+		c.String(200, `[{"id": 3, "login": "member-3", "username": "member-3"}]`)
+	}
+}
+
+func getTeamMember(c *gin.Context) {
+	members := map[string][]string{
+		"1": {"member-1"},
+		"2": {"member-1", "member-2"},
+		"3": {"member-3"},
+	}
+	for _, member := range members[c.Param("id")] {
+		if member == c.Param("username") {
+			c.String(200, fmt.Sprintf(`{"id": 1, "login": %q, "username": %q}`, member, member))
+			return
+		}
+	}
+	c.String(404, "")
+}
+
+func listRepoTags(c *gin.Context) {
+	if page := c.Query("page"); page != "" && page != "1" {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, listRepoTagsPayload)
+}
+
+func getRepoTag(c *gin.Context) {
+	switch c.Param("tag") {
+	case "v1.0.0":
+		c.String(200, repoTagLightweightPayload)
+	case "v1.1.0":
+		c.String(200, repoTagAnnotatedPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func listRepoCommits(c *gin.Context) {
+	switch c.Query("sha") {
+	case "master", "f05f642", "f05f642b892d59a0a9ef6a31f6c905a24b5db13a":
+		c.String(200, listRepoCommitsPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func compareCommits(c *gin.Context) {
+	switch c.Param("basehead") {
+	case "v1.0.0...ef98532add3b2feb7a137426bba1248724367df5",
+		"4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5":
+	default:
+		c.String(404, "")
+		return
+	}
+	c.String(200, comparePayload)
+}
+
+// getCompareDiff serves the diffs of the compare page, which redirects to the
+// login for the private repository.
+func getCompareDiff(c *gin.Context) {
+	if c.Param("name") == "private_repo" {
+		c.Redirect(http.StatusSeeOther, "/user/login")
+		return
+	}
+	switch c.Param("basehead") {
+	case "4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5.diff":
+		c.String(200, CompareDiffPayload)
+	case "master...ef98532add3b2feb7a137426bba1248724367df5.diff",
+		"v1.0.0...ef98532add3b2feb7a137426bba1248724367df5.diff":
+		c.String(200, CommitDiffPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func getPullRequestDiff(c *gin.Context) {
+	switch c.Param("index") {
+	case "1.diff":
+		c.String(200, PullRequestDiffPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func getCommitDiff(c *gin.Context) {
+	switch c.Param("sha") {
+	case "ef98532add3b2feb7a137426bba1248724367df5.diff":
+		c.String(200, CommitDiffPayload)
+	case "d0f4b1c9e2a7b8c3d6e5f4a3b2c1d0e9f8a7b6c5.diff":
+		c.String(200, FirstCommitDiffPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func getRepoTree(c *gin.Context) {
+	c.String(200, repoTreePayload)
+}
+
+// getUserOrgs returns two pages of orgs, the first one is full and both
+// contain the org "org-00" to check deduplication.
+func getUserOrgs(c *gin.Context) {
+	var orgs []string
+	switch c.Query("page") {
+	case "", "1":
+		for i := 49; i >= 0; i-- {
+			orgs = append(orgs, fmt.Sprintf(`{"id": %d, "username": "org-%02d"}`, i+1, i))
+		}
+	case "2":
+		orgs = []string{`{"id": 100, "username": "aaa"}`, `{"id": 1, "username": "org-00"}`}
+	}
+	c.String(200, "["+strings.Join(orgs, ",")+"]")
+}
+
+func getRepoBranches(c *gin.Context) {
+	page := c.Query("page")
+	if c.Param("name") == "empty_repo" || (page != "" && page != "1") {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, repoBranchesPayload)
+}
+
+func getRepoBranch(c *gin.Context) {
+	if c.Param("name") == "empty_repo" {
+		c.String(404, "")
+		return
+	}
+	switch c.Param("branch") {
+	case "master":
+		c.String(200, repoBranchPayload)
+	case "develop":
+		c.String(200, repoBranchUnprotectedPayload)
+	default:
+		c.String(404, "")
+	}
+}
+
+func listBranchProtections(c *gin.Context) {
+	page := c.Query("page")
+	if c.Param("name") == "empty_repo" || (page != "" && page != "1") {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, branchProtectionsPayload)
+}
+
+func getBranchProtection(c *gin.Context) {
+	if c.Param("branch") != "master" || c.Param("name") == "empty_repo" {
+		c.String(404, "")
+		return
+	}
+	c.String(200, branchProtectionPayload)
+}
+
+// listCollaborators returns the collaborators of hello-world, listing them
+// requires push access to other repos.
+func listCollaborators(c *gin.Context) {
+	if c.Param("name") != "hello-world" {
+		c.String(403, "")
+		return
+	}
+	page := c.Query("page")
+	if page != "" && page != "1" {
+		c.String(200, "[]")
+		return
+	}
+	c.String(200, collaboratorsPayload)
+}
+
+func getCollaboratorPermission(c *gin.Context) {
+	permissions := map[string]string{
+		"gordon":  "owner",
+		"octocat": "write",
+		"alice":   "read",
+		"bob":     "admin",
+	}
+	permission, ok := permissions[c.Param("collaborator")]
+	if !ok {
+		c.String(404, "")
+		return
+	}
+	c.JSON(200, map[string]string{"permission": permission})
+}
+
 func createRepoHook(c *gin.Context) {
 	in := struct {
 		Type string `json:"type"`
@@ -91,6 +481,36 @@ func deleteRepoHook(c *gin.Context) {
 	c.String(200, "{}")
 }
 
+func createAccessToken(c *gin.Context) {
+	if c.PostForm("grant_type") != "refresh_token" || c.PostForm("refresh_token") != "valid_refresh_token" {
+		c.JSON(400, gin.H{"error": "invalid_grant"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"access_token":  "refreshed",
+		"refresh_token": "new_refresh_token",
+		"token_type":    "bearer",
+		"expires_in":    3600,
+	})
+}
+
+func listDeployKeys(c *gin.Context) {
+	page := c.Query("page")
+	if page != "" && page != "1" {
+		c.String(200, "[]")
+	} else {
+		c.String(200, listDeployKeysPayload)
+	}
+}
+
+func createDeployKey(c *gin.Context) {
+	c.String(201, `{"id": 2, "title": "woodpecker", "read_only": true}`)
+}
+
+func deleteDeployKey(c *gin.Context) {
+	c.Status(204)
+}
+
 func getUserRepos(c *gin.Context) {
 	switch c.Request.Header.Get("Authorization") {
 	case "token repos_not_found":
@@ -109,6 +529,17 @@ func getVersion(c *gin.Context) {
 	c.JSON(200, map[string]interface{}{"version": "1.12"})
 }
 
+const listDeployKeysPayload = `
+[
+  {
+    "id": 1,
+    "key": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExisting",
+    "title": "woodpecker",
+    "read_only": true
+  }
+]
+`
+
 const listRepoHookPayloads = `
 [
   {
@@ -143,6 +574,353 @@ const repoPayload = `
 
 const repoFilePayload = `{ platform: linux/amd64 }`
 
+const userPayload = `
+{
+  "id": 1,
+  "login": "someuser",
+  "full_name": "Some User",
+  "email": "someuser@example.com"
+}
+`
+
+const repoTreePayload = `
+{
+  "sha": "9ecad50",
+  "tree": [
+    {
+      "path": ".woodpecker",
+      "type": "tree"
+    },
+    {
+      "path": ".woodpecker/test.yml",
+      "type": "blob"
+    },
+    {
+      "path": ".woodpecker/build.yml",
+      "type": "blob"
+    },
+    {
+      "path": ".woodpecker/scripts/run.sh",
+      "type": "blob"
+    },
+    {
+      "path": "README.md",
+      "type": "blob"
+    }
+  ]
+}
+`
+
+const repoContentsPayload = `
+{
+  "name": ".woodpecker.yml",
+  "path": ".woodpecker.yml",
+  "type": "file",
+  "size": 25
+}
+`
+
+const repoContentsSymlinkPayload = `
+{
+  "name": "link.yml",
+  "path": "link.yml",
+  "type": "symlink",
+  "size": 15,
+  "target": ".woodpecker.yml"
+}
+`
+
+const repoContentsLargePayload = `
+{
+  "name": "large.yml",
+  "path": "large.yml",
+  "type": "file",
+  "size": 104857600
+}
+`
+
+const repoContentsBase64Payload = `
+{
+  "name": "base64.yml",
+  "path": "base64.yml",
+  "type": "file",
+  "size": 25,
+  "encoding": "base64",
+  "content": "eyBwbGF0Zm9ybTogbGludXgvYXJtNjQgfQ=="
+}
+`
+
+const repoContentsPlainPayload = `
+{
+  "name": "plain.yml",
+  "path": "plain.yml",
+  "type": "file",
+  "size": 25,
+  "encoding": null,
+  "content": "{ platform: linux/arm64 }"
+}
+`
+
+const repoContentsBinaryPayload = `
+{
+  "name": "binary.yml",
+  "path": "binary.yml",
+  "type": "file",
+  "size": 11,
+  "encoding": "base64",
+  "content": "//4gcGxhdGZvcm0="
+}
+`
+
+const listRepoTagsPayload = `
+[
+  {
+    "name": "v1.1.0",
+    "commit": {
+      "sha": "ef98532add3b2feb7a137426bba1248724367df5"
+    }
+  },
+  {
+    "name": "v1.0.0",
+    "commit": {
+      "sha": "4b2626259b5a97b6b4eab5e6cca66adb986b672b"
+    }
+  }
+]
+`
+
+const repoTagLightweightPayload = `
+{
+  "name": "v1.0.0",
+  "message": "Initial commit",
+  "id": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "commit": {
+    "sha": "4b2626259b5a97b6b4eab5e6cca66adb986b672b"
+  }
+}
+`
+
+const repoTagAnnotatedPayload = `
+{
+  "name": "v1.1.0",
+  "message": "Release v1.1.0\n\nAdds support for tags.\n",
+  "id": "6fdc8e1b2a8a91e3c8d5f2a6b0e1d4c9f3a7b2e5",
+  "commit": {
+    "sha": "ef98532add3b2feb7a137426bba1248724367df5"
+  }
+}
+`
+
+const listRepoCommitsPayload = `
+[
+  {
+    "url": "http://gitea.golang.org/api/v1/repos/test_name/repo_name/git/commits/f05f642b892d59a0a9ef6a31f6c905a24b5db13a",
+    "sha": "f05f642b892d59a0a9ef6a31f6c905a24b5db13a",
+    "created": "2022-03-04T10:20:30Z",
+    "html_url": "http://gitea.golang.org/test_name/repo_name/commit/f05f642b892d59a0a9ef6a31f6c905a24b5db13a",
+    "commit": {
+      "message": "Update the readme\n",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "date": "2022-03-04T10:20:30Z"
+      }
+    },
+    "author": {
+      "id": 1,
+      "login": "gordon",
+      "avatar_url": "http://gitea.golang.org/avatars/1"
+    }
+  }
+]
+`
+
+const comparePayload = `
+{
+  "total_commits": 3,
+  "commits": [
+    {
+      "sha": "ef98532add3b2feb7a137426bba1248724367df5",
+      "files": [
+        {"filename": "CHANGELOG.md"},
+        {"filename": "main.go"}
+      ],
+      "parents": [
+        {"sha": "9ecad50cbc7b4a1d5bc1b2c3bd2e5489ef2e1f2a"}
+      ]
+    },
+    {
+      "sha": "5f1b0c1a8e8f3a3d7c2b6a4e9d0c8b7a6f5e4d3c",
+      "files": [],
+      "parents": [
+        {"sha": "4b2626259b5a97b6b4eab5e6cca66adb986b672b"},
+        {"sha": "9ecad50cbc7b4a1d5bc1b2c3bd2e5489ef2e1f2a"}
+      ]
+    },
+    {
+      "sha": "9ecad50cbc7b4a1d5bc1b2c3bd2e5489ef2e1f2a",
+      "files": [
+        {"filename": "CHANGELOG.md"}
+      ],
+      "parents": [
+        {"sha": "4b2626259b5a97b6b4eab5e6cca66adb986b672b"}
+      ]
+    }
+  ]
+}
+`
+
+const PullRequestDiffPayload = `diff --git a/README.md b/README.md
+index 5e1c309..6c3a3f2 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1,2 @@
+ # hello-world
++Hello World!
+`
+
+const CompareDiffPayload = `diff --git a/CHANGELOG.md b/CHANGELOG.md
+index 1a2b3c4..5d6e7f8 100644
+--- a/CHANGELOG.md
++++ b/CHANGELOG.md
+@@ -1 +1,2 @@
+ # Changelog
++- say hello world
+diff --git a/main.go b/main.go
+index 8c3b2a1..0d1e2f3 100644
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,3 @@ package main
+ func main() {
+-	println("hello")
++	println("hello world")
+ }
+`
+
+const CommitDiffPayload = `diff --git a/main.go b/main.go
+index 8c3b2a1..0d1e2f3 100644
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,3 @@ package main
+ func main() {
+-	println("hello")
++	println("hello world")
+ }
+`
+
+const FirstCommitDiffPayload = `diff --git a/README.md b/README.md
+new file mode 100644
+index 0000000..5e1c309
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++# hello-world
+`
+
+const repoBranchUnprotectedPayload = `
+{
+  "name": "develop",
+  "commit": {
+    "id": "c0cb3b0b5e6b8ef6ba3d4e9c3c7de9b8e9f5d3a1",
+    "message": "start developing\n",
+    "url": "http://localhost:3000/test_name/repo_name/commit/c0cb3b0b5e6b8ef6ba3d4e9c3c7de9b8e9f5d3a1"
+  },
+  "protected": false
+}
+`
+
+const repoBranchPayload = `
+{
+  "name": "master",
+  "commit": {
+    "id": "f05f642b892d59a0a9ef6a31f6c905a24b5db13a",
+    "message": "update README\n",
+    "url": "http://localhost:3000/test_name/repo_name/commit/f05f642b892d59a0a9ef6a31f6c905a24b5db13a"
+  },
+  "protected": true
+}
+`
+
+const repoBranchesPayload = `
+[
+  {
+    "name": "develop",
+    "commit": {
+      "id": "9ecad50"
+    }
+  },
+  {
+    "name": "master",
+    "commit": {
+      "id": "9ecad50"
+    }
+  }
+]
+`
+
+const collaboratorsPayload = `
+[
+  {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon"
+  },
+  {
+    "id": 2,
+    "login": "octocat",
+    "username": "octocat"
+  },
+  {
+    "id": 3,
+    "login": "alice",
+    "username": "alice"
+  },
+  {
+    "id": 4,
+    "login": "bob",
+    "username": "bob"
+  }
+]
+`
+
+const branchProtectionPayload = `
+{
+  "branch_name": "master",
+  "enable_status_check": true,
+  "status_check_contexts": [
+    "ci/woodpecker",
+    "ci/lint"
+  ]
+}
+`
+
+const branchProtectionsPayload = `
+[
+  {
+    "branch_name": "master",
+    "enable_status_check": true,
+    "status_check_contexts": [
+      "ci/woodpecker",
+      "ci/lint"
+    ]
+  },
+  {
+    "branch_name": "develop",
+    "enable_status_check": true,
+    "status_check_contexts": [
+      "ci/woodpecker"
+    ]
+  },
+  {
+    "branch_name": "release",
+    "enable_status_check": false,
+    "status_check_contexts": [
+      "ci/release"
+    ]
+  }
+]
+`
+
 const userRepoPayload = `
 [
   {
@@ -163,3 +941,32 @@ const userRepoPayload = `
   }
 ]
 `
+
+const listUserGPGKeysPayload = `
+[
+  {
+    "id": 1,
+    "primary_key_id": "",
+    "key_id": "3AA5C34371567BD2",
+    "public_key": "-----BEGIN PGP PUBLIC KEY BLOCK-----",
+    "emails": [
+      {"email": "gordon@golang.org", "verified": true},
+      {"email": "gordon@example.com", "verified": false}
+    ],
+    "subkeys": [
+      {"id": 2, "primary_key_id": "3AA5C34371567BD2", "key_id": "4BB6D45482678CE3", "can_sign": true}
+    ],
+    "can_sign": false,
+    "can_certify": true
+  },
+  {
+    "id": 3,
+    "primary_key_id": "",
+    "key_id": "5CC7E56593789DF4",
+    "public_key": "-----BEGIN PGP PUBLIC KEY BLOCK-----",
+    "emails": [],
+    "subkeys": [],
+    "can_sign": true
+  }
+]
+`
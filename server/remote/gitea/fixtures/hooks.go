@@ -26,6 +26,7 @@ const HookPush = `
       "id": "ef98532add3b2feb7a137426bba1248724367df5",
       "message": "bump\n",
       "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
       "author": {
         "name": "Gordon the Gopher",
         "email": "gordon@golang.org",
@@ -51,7 +52,256 @@ const HookPush = `
       "email": "gordon@golang.org",
       "username": "gordon"
     },
-    "private": true
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushSquashMerge is a sample Gitea push hook of a squash-merged pull
+// request, its single commit lists the files of all squashed commits.
+const HookPushSquashMerge = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "total_commits": 1,
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "Add the docs (#12)\n\n* add a docs page\n\n* link the docs\n\n* fix the changelog\n\nReviewed-on: http://gitea.golang.org/gordon/hello-world/pulls/12\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["docs/index.md", "docs/usage.md"],
+      "removed": ["docs.txt"],
+      "modified": ["README.md", "CHANGELOG.md"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushMultiRef is a sample Gitea push hook of the release branch created
+// by the same push as the master branch of HookPush. Gitea sends a hook per
+// pushed ref.
+const HookPushMultiRef = `
+{
+  "ref": "refs/heads/release",
+  "before": "0000000000000000000000000000000000000000",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushSigned is a sample Gitea push hook of a commit with a verified
+// signature
+const HookPushSigned = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "verification": {
+        "verified": true,
+        "reason": "",
+        "signature": "-----BEGIN PGP SIGNATURE-----\n\niQEzBAABCAAdFiEE\n-----END PGP SIGNATURE-----\n",
+        "signer": {
+          "name": "Gordon the Gopher",
+          "email": "gordon@golang.org",
+          "username": "gordon"
+        },
+        "payload": ""
+      },
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushUnsigned is a sample Gitea push hook of an unsigned commit
+const HookPushUnsigned = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "verification": {
+        "verified": false,
+        "reason": "gpg.error.not_signed_commit",
+        "signature": "",
+        "signer": null,
+        "payload": ""
+      },
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true,
+    "default_branch": "master"
   },
   "pusher": {
     "name": "gordon",
@@ -60,23 +310,1198 @@ const HookPush = `
     "login": "gordon"
   },
   "sender": {
-    "login": "gordon",
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushRenamed is a sample Gitea push hook of a repo renamed from
+// gordon/hello-world
+const HookPushRenamed = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-gopher/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-gopher/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-gopher",
+    "full_name": "gordon/hello-gopher",
+    "html_url": "http://gitea.golang.org/gordon/hello-gopher",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-gopher.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-gopher.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushTransferred is a sample Gitea push hook of a repo transferred from
+// gordon/hello-world
+const HookPushTransferred = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gophers/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gophers/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "timestamp": "2022-03-01T12:30:00+01:00",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gophers/hello-world",
+    "html_url": "http://gitea.golang.org/gophers/hello-world",
+    "ssh_url": "git@gitea.golang.org:gophers/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gophers/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gophers",
+      "email": "",
+      "username": "gophers"
+    },
+    "private": true,
+    "default_branch": "master"
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushNotes is a sample Gitea push hook for a git notes ref
+const HookPushNotes = `
+{
+  "ref": "refs/notes/commits",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushWiki is a sample Gitea push hook for a wiki page edit
+const HookPushWiki = `
+{
+  "ref": "refs/wiki/Home",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "Update page 'Home'\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "author": {
+        "name": "Gordon the Gopher",
+        "email": "gordon@golang.org",
+        "username": "gordon"
+      },
+      "added": [],
+      "removed": [],
+      "modified": ["Home.md"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushOtherAuthor is a sample Gitea push hook of a commit authored by
+// someone other than the pushing user
+const HookPushOtherAuthor = `
+{
+  "ref": "refs/heads/master",
+  "before": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "compare_url": "http://gitea.golang.org/gordon/hello-world/compare/4b2626259b5a97b6b4eab5e6cca66adb986b672b...ef98532add3b2feb7a137426bba1248724367df5",
+  "commits": [
+    {
+      "id": "ef98532add3b2feb7a137426bba1248724367df5",
+      "message": "bump\n",
+      "url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532add3b2feb7a137426bba1248724367df5",
+      "author": {
+        "name": "Gopher Jr",
+        "email": "gopher.jr@golang.org",
+        "username": ""
+      },
+      "added": ["CHANGELOG.md"],
+      "removed": [],
+      "modified": ["app/controller/application.rb"]
+    }
+  ],
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushBranchDelete is a sample Gitea push hook for a deleted branch
+const HookPushBranchDelete = `
+{
+  "ref": "refs/heads/feature/changes",
+  "before": "ef98532add3b2feb7a137426bba1248724367df5",
+  "after": "0000000000000000000000000000000000000000",
+  "compare_url": "",
+  "commits": [],
+  "head_commit": null,
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "description": "",
+    "website": "",
+    "watchers": 1,
+    "owner": {
+      "name": "gordon",
+      "email": "gordon@golang.org",
+      "username": "gordon"
+    },
+    "private": true
+  },
+  "pusher": {
+    "name": "gordon",
+    "email": "gordon@golang.org",
+    "username": "gordon",
+    "login": "gordon"
+  },
+  "sender": {
+    "login": "gordon",
+    "id": 1,
+    "username": "gordon",
+    "email": "gordon@golang.org",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}
+`
+
+// HookPushTag is a sample Gitea tag hook
+const HookPushTag = `{
+  "sha": "ef98532add3b2feb7a137426bba1248724367df5",
+  "secret": "l26Un7G7HXogLAvsyf2hOA4EMARSTsR3",
+  "ref": "v1.0.0",
+  "ref_type": "tag",
+  "repository": {
+    "id": 1,
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "description": "",
+    "private": true,
+    "fork": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master",
+    "created_at": "2015-10-22T19:32:44Z",
+    "updated_at": "2016-11-24T13:37:16Z"
+  },
+  "sender": {
+    "id": 1,
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookPushTagAnnotated is a sample Gitea tag hook for an annotated tag
+const HookPushTagAnnotated = `{
+  "sha": "",
+  "after": "ef98532add3b2feb7a137426bba1248724367df5",
+  "secret": "l26Un7G7HXogLAvsyf2hOA4EMARSTsR3",
+  "ref": "refs/tags/v1.0.0",
+  "ref_type": "tag",
+  "repository": {
+    "id": 1,
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "description": "",
+    "private": true,
+    "fork": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master",
+    "created_at": "2015-10-22T19:32:44Z",
+    "updated_at": "2016-11-24T13:37:16Z"
+  },
+  "sender": {
+    "id": 1,
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookPushTagDelete is a sample Gitea tag hook for a deleted tag
+const HookPushTagDelete = `{
+  "sha": "0000000000000000000000000000000000000000",
+  "secret": "l26Un7G7HXogLAvsyf2hOA4EMARSTsR3",
+  "ref": "v1.0.0",
+  "ref_type": "tag",
+  "repository": {
+    "id": 1,
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "description": "",
+    "private": true,
+    "fork": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master",
+    "created_at": "2015-10-22T19:32:44Z",
+    "updated_at": "2016-11-24T13:37:16Z"
+  },
+  "sender": {
+    "id": 1,
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookCreateBranch is a sample Gitea create hook for a new branch
+const HookCreateBranch = `{
+  "sha": "ef98532add3b2feb7a137426bba1248724367df5",
+  "ref": "feature/changes",
+  "ref_type": "branch",
+  "repository": {
+    "id": 1,
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "description": "",
+    "private": true,
+    "fork": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master",
+    "created_at": "2015-10-22T19:32:44Z",
+    "updated_at": "2016-11-24T13:37:16Z"
+  },
+  "sender": {
+    "id": 1,
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookDeleteBranch is a sample Gitea delete hook for a deleted branch
+const HookDeleteBranch = `{
+  "ref": "feature/changes",
+  "ref_type": "branch",
+  "pusher_type": "user",
+  "repository": {
+    "id": 1,
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "description": "",
+    "private": true,
+    "fork": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master",
+    "created_at": "2015-10-22T19:32:44Z",
+    "updated_at": "2016-11-24T13:37:16Z"
+  },
+  "sender": {
+    "id": 1,
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookPullRequest is a sample pull_request webhook payload
+const HookPullRequest = `{
+  "action": "opened",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestMilestone is a sample pull_request webhook payload of a pull
+// request added to a milestone
+const HookPullRequestMilestone = `{
+  "action": "milestoned",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "milestone": {
+      "id": 3,
+      "title": "v1.2.0",
+      "state": "open"
+    },
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestApproved is a sample pull_request_approved webhook payload
+const HookPullRequestApproved = `{
+  "action": "reviewed",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "updated_at": "2022-07-04T10:12:30+02:00",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "permissions": {
+      "admin": false,
+      "push": true,
+      "pull": true
+    },
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "review": {
+    "type": "pull_request_review_approved",
+    "content": "LGTM"
+  },
+  "sender": {
+      "id": 2,
+      "login": "reviewer",
+      "username": "reviewer",
+      "full_name": "Pull Request Reviewer",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestRejected is a sample pull_request_rejected webhook payload of
+// a review requesting changes
+const HookPullRequestRejected = `{
+  "action": "reviewed",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "updated_at": "2022-07-04T10:12:30+02:00",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "permissions": {
+      "admin": false,
+      "push": true,
+      "pull": true
+    },
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "review": {
+    "type": "pull_request_review_rejected",
+    "content": "Please fix the typo"
+  },
+  "sender": {
+      "id": 2,
+      "login": "reviewer",
+      "username": "reviewer",
+      "full_name": "Pull Request Reviewer",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestDraft is a sample pull_request webhook payload for a pull
+// request marked as work in progress
+const HookPullRequestDraft = `{
+  "action": "opened",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "WIP: Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestReady is a sample pull_request webhook payload sent when a
+// work in progress pull request is marked as ready
+const HookPullRequestReady = `{
+  "action": "edited",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "changes": {
+    "title": {
+      "from": "WIP: Update the README with new information"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestFork is a sample pull_request webhook payload for a pull
+// request opened from a fork
+const HookPullRequestFork = `{
+  "action": "opened",
+  "number": 2,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/2",
+    "state": "open",
+    "title": "Fix typo",
+    "body": "",
+    "user": {
+      "id": 2,
+      "username": "gopher",
+      "full_name": "Gopher",
+      "email": "gopher@golang.org",
+      "avatar_url": "http://gitea.golang.org/avatars/2"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d",
+      "repo": {
+        "id": 35129377,
+        "name": "hello-world",
+        "full_name": "gordon/hello-world",
+        "html_url": "http://gitea.golang.org/gordon/hello-world",
+        "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+        "private": false
+      }
+    },
+    "head": {
+      "label": "typo",
+      "ref": "typo",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+      "repo": {
+        "id": 35129378,
+        "name": "hello-world",
+        "full_name": "gopher/hello-world",
+        "html_url": "http://gitea.golang.org/gopher/hello-world",
+        "clone_url": "http://gitea.golang.org/gopher/hello-world.git",
+        "private": false
+      }
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": false,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+    "id": 2,
+    "login": "gopher",
+    "username": "gopher",
+    "full_name": "Gopher",
+    "email": "gopher@golang.org",
+    "avatar_url": "http://gitea.golang.org/avatars/2"
+  }
+}`
+
+// HookPullRequestLabelUpdated is a sample pull_request webhook payload sent
+// for a label change
+const HookPullRequestLabelUpdated = `{
+  "action": "label_updated",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestAssigned is a sample pull_request webhook payload sent when
+// a user is assigned to a pull request
+const HookPullRequestAssigned = `{
+  "action": "assigned",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "assignee": {
+      "id": 3,
+      "login": "security",
+      "username": "security"
+    },
+    "assignees": [
+      {
+        "id": 3,
+        "login": "security",
+        "username": "security"
+      },
+      {
+        "id": 4,
+        "login": "octocat",
+        "username": "octocat"
+      }
+    ],
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookPullRequestReopened is a sample pull_request webhook payload sent for a
+// reopened pull request
+const HookPullRequestReopened = `{
+  "action": "reopened",
+  "number": 1,
+  "pull_request": {
+    "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
+    "state": "open",
+    "title": "Update the README with new information",
+    "body": "please merge",
+    "user": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "base": {
+      "label": "master",
+      "ref": "master",
+      "sha": "9353195a19e45482665306e466c832c46560532d"
+    },
+    "head": {
+      "label": "feature/changes",
+      "ref": "feature/changes",
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+}`
+
+// HookRelease is a sample release webhook payload
+const HookRelease = `{
+  "action": "published",
+  "release": {
+    "id": 1,
+    "tag_name": "v1.0.0",
+    "target_commitish": "master",
+    "name": "Version 1.0.0",
+    "body": "first stable release",
+    "html_url": "http://gitea.golang.org/gordon/hello-world/releases/tag/v1.0.0",
+    "draft": false,
+    "prerelease": true,
+    "author": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
     "id": 1,
+    "login": "gordon",
     "username": "gordon",
+    "full_name": "Gordon the Gopher",
     "email": "gordon@golang.org",
-    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
   }
-}
-`
+}`
 
-// HookPushTag is a sample Gitea tag hook
-const HookPushTag = `{
-  "sha": "ef98532add3b2feb7a137426bba1248724367df5",
-  "secret": "l26Un7G7HXogLAvsyf2hOA4EMARSTsR3",
-  "ref": "v1.0.0",
-  "ref_type": "tag",
-  "repository": {
+// HookReleaseCommit is a sample release webhook payload for a release
+// targeting a commit instead of a branch
+const HookReleaseCommit = `{
+  "action": "published",
+  "release": {
     "id": 1,
+    "tag_name": "v1.0.0",
+    "target_commitish": "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+    "name": "Version 1.0.0",
+    "body": "first stable release",
+    "html_url": "http://gitea.golang.org/gordon/hello-world/releases/tag/v1.0.0",
+    "draft": false,
+    "prerelease": true,
+    "author": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
     "owner": {
       "id": 1,
       "username": "gordon",
@@ -84,20 +1509,61 @@ const HookPushTag = `{
       "email": "gordon@golang.org",
       "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
     },
+    "private": true,
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
+  },
+  "sender": {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon",
+    "full_name": "Gordon the Gopher",
+    "email": "gordon@golang.org",
+    "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  }
+}`
+
+// HookReleaseDraft is a sample release webhook payload for a draft release
+const HookReleaseDraft = `{
+  "action": "published",
+  "release": {
+    "id": 1,
+    "tag_name": "v1.0.0",
+    "target_commitish": "master",
+    "name": "Version 1.0.0",
+    "body": "first stable release",
+    "html_url": "http://gitea.golang.org/gordon/hello-world/releases/tag/v1.0.0",
+    "draft": true,
+    "prerelease": false,
+    "author": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "repository": {
+    "id": 35129377,
     "name": "hello-world",
     "full_name": "gordon/hello-world",
-    "description": "",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    },
     "private": true,
-    "fork": false,
     "html_url": "http://gitea.golang.org/gordon/hello-world",
-    "ssh_url": "git@gitea.golang.org:gordon/hello-world.git",
-    "clone_url": "http://gitea.golang.org/gordon/hello-world.git",
-    "default_branch": "master",
-    "created_at": "2015-10-22T19:32:44Z",
-    "updated_at": "2016-11-24T13:37:16Z"
+    "clone_url": "https://gitea.golang.org/gordon/hello-world.git",
+    "default_branch": "master"
   },
   "sender": {
     "id": 1,
+    "login": "gordon",
     "username": "gordon",
     "full_name": "Gordon the Gopher",
     "email": "gordon@golang.org",
@@ -105,13 +1571,185 @@ const HookPushTag = `{
   }
 }`
 
-// HookPullRequest is a sample pull_request webhook payload
-const HookPullRequest = `{
+// HookIssueCommentRebuild is a sample pull_request_comment webhook payload of
+// a rebuild command by a user with push access
+const HookIssueCommentRebuild = `{
+  "action": "created",
+  "issue": {
+    "id": 3,
+    "number": 1,
+    "title": "Update the README with new information",
+    "state": "open",
+    "pull_request": {
+      "merged": false,
+      "merged_at": null
+    }
+  },
+  "comment": {
+    "id": 8,
+    "body": "/rebuild please",
+    "user": {
+      "id": 2,
+      "login": "gordon",
+      "username": "gordon"
+    }
+  },
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "permissions": {
+      "admin": false,
+      "push": true,
+      "pull": true
+    },
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "sender": {
+    "id": 2,
+    "login": "gordon",
+    "username": "gordon",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  },
+  "is_pull": true
+}`
+
+// HookIssueCommentRebuildUnauthorized is a sample pull_request_comment webhook
+// payload of a rebuild command by a user without push access
+const HookIssueCommentRebuildUnauthorized = `{
+  "action": "created",
+  "issue": {
+    "id": 3,
+    "number": 1,
+    "title": "Update the README with new information",
+    "state": "open",
+    "pull_request": {
+      "merged": false,
+      "merged_at": null
+    }
+  },
+  "comment": {
+    "id": 8,
+    "body": "/rebuild",
+    "user": {
+      "id": 2,
+      "login": "stranger",
+      "username": "stranger"
+    }
+  },
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "permissions": {
+      "admin": false,
+      "push": false,
+      "pull": true
+    },
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "sender": {
+    "id": 2,
+    "login": "stranger",
+    "username": "stranger",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  },
+  "is_pull": true
+}`
+
+// HookIssueComment is a sample issue_comment webhook payload of a rebuild
+// command on an issue
+const HookIssueComment = `{
+  "action": "created",
+  "issue": {
+    "id": 3,
+    "number": 1,
+    "title": "Update the README with new information",
+    "state": "open",
+    "pull_request": null
+  },
+  "comment": {
+    "id": 8,
+    "body": "/rebuild",
+    "user": {
+      "id": 2,
+      "login": "gordon",
+      "username": "gordon"
+    }
+  },
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "permissions": {
+      "admin": false,
+      "push": true,
+      "pull": true
+    },
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+    }
+  },
+  "sender": {
+    "id": 2,
+    "login": "gordon",
+    "username": "gordon",
+    "avatar_url": "http://gitea.golang.org///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+  },
+  "is_pull": false
+}`
+
+// HookPullRequestLabels is a sample pull_request webhook payload of a pull
+// request with multiple labels
+const HookPullRequestLabels = `{
   "action": "opened",
   "number": 1,
   "pull_request": {
     "html_url": "http://gitea.golang.org/gordon/hello-world/pull/1",
     "state": "open",
+    "labels": [
+      {
+        "id": 1,
+        "name": "deploy",
+        "color": "00aabb"
+      },
+      {
+        "id": 2,
+        "name": "kind/bug",
+        "color": "ee0701"
+      },
+      {
+        "id": 3,
+        "name": "priority/high",
+        "color": "fbca04"
+      }
+    ],
+    "merge_base": "9353195a19e45482665306e466c832c46560532d",
     "title": "Update the README with new information",
     "body": "please merge",
     "user": {
@@ -157,3 +1795,129 @@ const HookPullRequest = `{
       "avatar_url": "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
     }
 }`
+
+// HookRepositoryDeleted is a sample Gitea repository webhook payload of a
+// deleted repository
+const HookRepositoryDeleted = `{
+  "action": "deleted",
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org"
+    }
+  },
+  "organization": {
+    "id": 1,
+    "username": "gordon"
+  },
+  "sender": {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon",
+    "email": "gordon@golang.org"
+  }
+}`
+
+// HookWiki is a sample Gitea wiki webhook payload
+const HookWiki = `{
+  "action": "edited",
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org"
+    }
+  },
+  "sender": {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon",
+    "email": "gordon@golang.org"
+  },
+  "page": "Home",
+  "comment": "Update page 'Home'"
+}`
+
+// HookPackage is a sample Gitea package webhook payload
+const HookPackage = `{
+  "action": "created",
+  "package": {
+    "id": 12,
+    "owner": {
+      "id": 1,
+      "username": "gordon"
+    },
+    "repository": {
+      "id": 1,
+      "name": "hello-world",
+      "full_name": "gordon/hello-world",
+      "html_url": "http://gitea.golang.org/gordon/hello-world",
+      "default_branch": "master"
+    },
+    "creator": {
+      "id": 1,
+      "login": "gordon",
+      "username": "gordon",
+      "email": "gordon@golang.org",
+      "avatar_url": "http://gitea.golang.org///1.png"
+    },
+    "type": "container",
+    "name": "hello-world",
+    "version": "1.0.0",
+    "html_url": "http://gitea.golang.org/gordon/-/packages/container/hello-world/1.0.0",
+    "created_at": "2022-09-14T16:36:05Z"
+  },
+  "repository": {
+    "id": 1,
+    "name": "hello-world",
+    "full_name": "gordon/hello-world",
+    "html_url": "http://gitea.golang.org/gordon/hello-world",
+    "private": true,
+    "default_branch": "master",
+    "owner": {
+      "id": 1,
+      "username": "gordon",
+      "full_name": "Gordon the Gopher",
+      "email": "gordon@golang.org"
+    }
+  },
+  "sender": {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon",
+    "email": "gordon@golang.org"
+  }
+}`
+
+// HookPackageNoRepo is a sample Gitea package webhook payload of a package
+// not linked to a repository
+const HookPackageNoRepo = `{
+  "action": "created",
+  "package": {
+    "id": 13,
+    "type": "npm",
+    "name": "hello",
+    "version": "0.1.0",
+    "html_url": "http://gitea.golang.org/gordon/-/packages/npm/hello/0.1.0"
+  },
+  "sender": {
+    "id": 1,
+    "login": "gordon",
+    "username": "gordon"
+  }
+}`
@@ -18,48 +18,238 @@
 package gitea
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
 	"code.gitea.io/sdk/gitea"
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/oauth2"
 
 	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
 	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/remote/common"
+	"github.com/woodpecker-ci/woodpecker/server/store"
+	"github.com/woodpecker-ci/woodpecker/shared/token"
+	"github.com/woodpecker-ci/woodpecker/shared/utils"
 )
 
 const (
 	authorizeTokenURL = "%s/login/oauth/authorize"
 	accessTokenURL    = "%s/login/oauth/access_token"
 	perPage           = 50
+
+	// maximum number of pull requests whose changed files are cached
+	changedFilesCacheSize = 100
+
+	// maximum number of users whose public keys are cached and how long
+	userKeysCacheSize = 100
+	userKeysTTL       = 10 * time.Minute
+
+	// maximum number of branches whose protection is cached and how long
+	protectionCacheSize = 100
+	protectionTTL       = time.Minute
+
+	// maximum number of users whose organization teams are cached and how long
+	teamsCacheSize = 100
+	teamsTTL       = 10 * time.Minute
+
+	defaultTimeout         = 10 * time.Second
+	defaultMaxChangedFiles = 500
+	defaultMaxMessageLen   = 2000
+	defaultMaxFileSize     = 5 << 20
+	defaultMaxDiffSize     = 10 << 20
+	maxSymlinkDepth        = 5
+	retryBackoff           = 500 * time.Millisecond
+	serverVersionTTL       = 10 * time.Minute
+
+	// title of the deploy key registered when activating a repository
+	deployKeyTitle = "woodpecker"
+
+	// user and default port of SSH clone urls derived from http clone urls
+	sshUser        = "git"
+	defaultSSHPort = 22
+)
+
+// scopes of Gitea tokens required by the API calls
+const (
+	scopeReadUser         = "read:user"
+	scopeReadOrganization = "read:organization"
+	scopeReadRepository   = "read:repository"
+	scopeWriteRepository  = "write:repository"
+	scopeReadIssue        = "read:issue"
+	scopeWriteIssue       = "write:issue"
+)
+
+// first Gitea versions sending the matching hooks
+var (
+	releaseHooksVersion = version.Must(version.NewVersion("1.7.0"))
+	reviewHooksVersion  = version.Must(version.NewVersion("1.9.0"))
+	draftPullsVersion   = version.Must(version.NewVersion("1.7.0"))
 )
 
+// TODO: inject organization and repository action secrets of Gitea into
+// pipelines. The Gitea API lists their names but never returns their values,
+// so there is nothing to inject until it does.
+
 type Gitea struct {
+	Name         string
 	URL          string
 	ClientID     string
 	ClientSecret string
 	SkipVerify   bool
+	Proxy        *url.URL
+	Timeout      time.Duration
+	Retries      int
+	RepoPageSize int
+	transport    *http.Transport
+	limiter      *rateLimiter
+
+	AvatarBaseURL  string
+	AvatarFallback string
+	AvatarGravatar bool
+
+	PullRequestActions    []string
+	SkipDraftPullRequests bool
+	EventMapping          map[model.WebhookEvent]model.WebhookEvent
+
+	MaxChangedFiles  int
+	MaxFileSize      int64
+	MaxDiffSize      int64
+	MaxMessageLen    int
+	TagChangedFiles  bool
+	TagMessages      bool
+	PushCompareFiles bool
+
+	RebuildCommand string
+	IgnoreBranches []string
+	DeployKey      string
+
+	CloneSSH   bool
+	SSHPort    int
+	NetrcHosts []string
+
+	StepStatuses  bool
+	statusContext *template.Template
+	summary       *template.Template
+
+	FetchTopics      bool
+	PackageDeletions bool
+	TagDeletions     bool
+	SenderTeams      bool
+	OrgHookSecret    string
+
+	changedFilesMu    sync.Mutex
+	changedFilesCache map[string][]string
+
+	userKeysMu    sync.Mutex
+	userKeysCache map[string]*userKeys
+
+	protectionMu    sync.Mutex
+	protectionCache map[string]*branchProtection
+
+	teamsMu    sync.Mutex
+	teamsCache map[string]*userTeams
+
+	versionMu      sync.Mutex
+	version        string
+	versionFetched time.Time
+}
+
+// userKeys are the cached public keys of a user.
+type userKeys struct {
+	keys    *remote.UserKeys
+	fetched time.Time
+}
+
+// userTeams are the cached teams of an organization a user is a member of.
+type userTeams struct {
+	teams   []string
+	fetched time.Time
+}
+
+// branchProtection is the cached protection state of a branch.
+type branchProtection struct {
+	protected bool
+	fetched   time.Time
 }
 
 // Opts defines configuration options.
 type Opts struct {
+	Name       string // Name of the instance if several Gitea instances are configured.
 	URL        string // Gitea server url.
 	Client     string // OAuth2 Client ID
 	Secret     string // OAuth2 Client Secret
 	SkipVerify bool   // Skip ssl verification.
+	CACert     string // PEM bundle or path to one of additionally trusted CA certificates.
+	Proxy      string // Url of the HTTP or SOCKS5 proxy, defaults to the proxy environment variables.
+
+	Timeout      time.Duration // Timeout of Gitea API calls, defaults to 10s.
+	Retries      int           // Number of retries of failing or rate limited Gitea API calls.
+	RepoPageSize int           // Number of repositories listed per API call when syncing, defaults to 50.
+
+	AvatarBaseURL  string // Base url relative avatar urls are resolved against.
+	AvatarFallback string // Avatar url used if Gitea provides none.
+	AvatarGravatar bool   // Derive the fallback avatar from the email via Gravatar.
+
+	PullRequestActions    []string // Pull request actions triggering builds, defaults to opened, synchronized and reopened.
+	SkipDraftPullRequests bool     // Do not build draft pull requests.
+	EventMapping          []string // Events builds are created with instead of the hook event, e.g. pull_request=push.
+
+	MaxChangedFiles  int   // Maximum number of changed files stored per build, defaults to 500.
+	MaxFileSize      int64 // Maximum size in bytes of fetched files, defaults to 5 MiB.
+	MaxDiffSize      int64 // Maximum size in bytes of streamed build diffs, defaults to 10 MiB.
+	MaxMessageLen    int   // Maximum number of characters of build titles and messages, defaults to 2000.
+	TagChangedFiles  bool  // Compare tags against the previous tag to get the changed files.
+	TagMessages      bool  // Use the message of annotated tags as message of tag builds.
+	PushCompareFiles bool  // Compare before and after of forced or truncated pushes to get the changed files.
+
+	RebuildCommand string // Pull request comment command triggering a rebuild, defaults to /rebuild.
+
+	StatusContextFormat string // Template of the commit status context, defaults to <status context>/<event>/<pipeline>.
+	StepStatuses        bool   // Report the status of every step.
+	Summary             bool   // Post a summary of finished builds as comment to their pull request.
+	SummaryTemplate     string // Template of the summary comment, defaults to a table of the pipeline states.
+
+	FetchTopics      bool   // Fetch the topics of repositories requested by name.
+	PackageDeletions bool   // Also build package events of deleted package versions.
+	TagDeletions     bool   // Also build delete events of deleted tags, not only of deleted branches.
+	SenderTeams      bool   // Look up the teams of the repository organization the sender of push and pull request builds is a member of.
+	OrgHookSecret    string // Secret organization hooks notifying about deleted repositories are signed with.
+
+	IgnoreBranches []string // Glob patterns of branches whose pushes are ignored.
+	DeployKey      string   // Public ssh key registered as deploy key when activating repositories.
+
+	CloneSSH   bool     // Clone repositories via SSH instead of http, e.g. with a private deploy key.
+	SSHPort    int      // Port of the Gitea SSH server used in SSH clone urls derived from http ones, defaults to 22.
+	NetrcHosts []string // Additional hosts the netrc authenticates against with the Gitea credentials, e.g. of submodules.
 }
 
 // New returns a Remote implementation that integrates with Gitea,
 // an open source Git service written in Go. See https://gitea.io/
 func New(opts Opts) (remote.Remote, error) {
+	// urls of API calls and links are appended to the url, which may include
+	// the sub path Gitea is hosted under
+	opts.URL = strings.TrimRight(opts.URL, "/")
 	u, err := url.Parse(opts.URL)
 	if err != nil {
 		return nil, err
@@ -68,11 +258,110 @@ func New(opts Opts) (remote.Remote, error) {
 	if err == nil {
 		u.Host = host
 	}
+	var proxy *url.URL
+	if opts.Proxy != "" {
+		proxy, err = url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy url %s: unsupported scheme %q", opts.Proxy, proxy.Scheme)
+		}
+	}
+	tlsConfig, err := newTLSConfig(opts.CACert, opts.SkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SkipVerify {
+		log.Warn().Msgf("ssl verification of %s is disabled, API calls and tokens are open to interception", opts.URL)
+	}
+	eventMapping, err := parseEventMapping(opts.EventMapping)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.RepoPageSize <= 0 {
+		opts.RepoPageSize = perPage
+	}
+	if opts.MaxChangedFiles <= 0 {
+		opts.MaxChangedFiles = defaultMaxChangedFiles
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultMaxFileSize
+	}
+	if opts.MaxDiffSize <= 0 {
+		opts.MaxDiffSize = defaultMaxDiffSize
+	}
+	if opts.MaxMessageLen <= 0 {
+		opts.MaxMessageLen = defaultMaxMessageLen
+	}
+	var statusContext *template.Template
+	if opts.StatusContextFormat != "" {
+		statusContext, err = template.New("context").Option("missingkey=error").Parse(opts.StatusContextFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status context format: %w", err)
+		}
+	}
+	var summary *template.Template
+	if opts.Summary {
+		if opts.SummaryTemplate == "" {
+			opts.SummaryTemplate = defaultSummaryTemplate
+		}
+		summary, err = template.New("summary").Option("missingkey=error").Parse(opts.SummaryTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid summary template: %w", err)
+		}
+	}
 	return &Gitea{
+		Name:         opts.Name,
 		URL:          opts.URL,
 		ClientID:     opts.Client,
 		ClientSecret: opts.Secret,
 		SkipVerify:   opts.SkipVerify,
+		Proxy:        proxy,
+		Timeout:      opts.Timeout,
+		Retries:      opts.Retries,
+		RepoPageSize: opts.RepoPageSize,
+		transport:    newTransport(proxy, tlsConfig),
+		limiter:      newRateLimiter(),
+
+		AvatarBaseURL:  opts.AvatarBaseURL,
+		AvatarFallback: opts.AvatarFallback,
+		AvatarGravatar: opts.AvatarGravatar,
+
+		PullRequestActions:    opts.PullRequestActions,
+		SkipDraftPullRequests: opts.SkipDraftPullRequests,
+		EventMapping:          eventMapping,
+
+		MaxChangedFiles:  opts.MaxChangedFiles,
+		MaxFileSize:      opts.MaxFileSize,
+		MaxDiffSize:      opts.MaxDiffSize,
+		MaxMessageLen:    opts.MaxMessageLen,
+		TagChangedFiles:  opts.TagChangedFiles,
+		TagMessages:      opts.TagMessages,
+		PushCompareFiles: opts.PushCompareFiles,
+
+		RebuildCommand: opts.RebuildCommand,
+		IgnoreBranches: opts.IgnoreBranches,
+		DeployKey:      opts.DeployKey,
+
+		CloneSSH:   opts.CloneSSH,
+		SSHPort:    opts.SSHPort,
+		NetrcHosts: opts.NetrcHosts,
+
+		StepStatuses:  opts.StepStatuses,
+		statusContext: statusContext,
+		summary:       summary,
+
+		FetchTopics:      opts.FetchTopics,
+		PackageDeletions: opts.PackageDeletions,
+		TagDeletions:     opts.TagDeletions,
+		SenderTeams:      opts.SenderTeams,
+		OrgHookSecret:    opts.OrgHookSecret,
 	}, nil
 }
 
@@ -101,11 +390,11 @@ func (c *Gitea) Login(ctx context.Context, w http.ResponseWriter, req *http.Requ
 	// get the OAuth code
 	code := req.FormValue("code")
 	if len(code) == 0 {
-		http.Redirect(w, req, config.AuthCodeURL("woodpecker"), http.StatusSeeOther)
+		http.Redirect(w, req, config.AuthCodeURL(c.oauthState()), http.StatusSeeOther)
 		return nil, nil
 	}
 
-	token, err := config.Exchange(ctx, code)
+	token, err := config.Exchange(context.WithValue(ctx, oauth2.HTTPClient, c.newHTTPClient()), code)
 	if err != nil {
 		return nil, err
 	}
@@ -114,9 +403,9 @@ func (c *Gitea) Login(ctx context.Context, w http.ResponseWriter, req *http.Requ
 	if err != nil {
 		return nil, err
 	}
-	account, _, err := client.GetMyUserInfo()
+	account, resp, err := client.GetMyUserInfo()
 	if err != nil {
-		return nil, err
+		return nil, scopeError(resp, err, scopeReadUser)
 	}
 
 	return &model.User{
@@ -125,10 +414,39 @@ func (c *Gitea) Login(ctx context.Context, w http.ResponseWriter, req *http.Requ
 		Expiry: token.Expiry.UTC().Unix(),
 		Login:  account.UserName,
 		Email:  account.Email,
-		Avatar: expandAvatar(c.URL, account.AvatarURL),
+		Avatar: c.userAvatar(account),
+		Remote: c.Name,
 	}, nil
 }
 
+// oauthState returns the state passed through the OAuth authorization, it
+// names the instance the user is redirected back from.
+func (c *Gitea) oauthState() string {
+	if c.Name == "" {
+		return "woodpecker"
+	}
+	return c.Name
+}
+
+// Avatar returns the current avatar of the Gitea user. It is empty if the user
+// has none or only the default placeholder, the configured fallback is not
+// applied so it does not replace the avatar stored at login.
+func (c *Gitea) Avatar(ctx context.Context, u *model.User) (string, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	account, resp, err := client.GetMyUserInfo()
+	if err != nil {
+		return "", scopeError(resp, err, scopeReadUser)
+	}
+	avatar := c.expandAvatar(c.URL, fixMalformedAvatar(account.AvatarURL))
+	if isDefaultAvatar(avatar) {
+		return "", nil
+	}
+	return avatar, nil
+}
+
 // Auth uses the Gitea oauth2 access token and refresh token to authenticate
 // a session and return the Gitea account login.
 func (c *Gitea) Auth(ctx context.Context, token, secret string) (string, error) {
@@ -136,9 +454,9 @@ func (c *Gitea) Auth(ctx context.Context, token, secret string) (string, error)
 	if err != nil {
 		return "", err
 	}
-	user, _, err := client.GetMyUserInfo()
+	user, resp, err := client.GetMyUserInfo()
 	if err != nil {
-		return "", err
+		return "", scopeError(resp, err, scopeReadUser)
 	}
 	return user.UserName, nil
 }
@@ -154,7 +472,7 @@ func (c *Gitea) Refresh(ctx context.Context, user *model.User) (bool, error) {
 			TokenURL: fmt.Sprintf(accessTokenURL, c.URL),
 		},
 	}
-	source := config.TokenSource(ctx, &oauth2.Token{RefreshToken: user.Secret})
+	source := config.TokenSource(context.WithValue(ctx, oauth2.HTTPClient, c.newHTTPClient()), &oauth2.Token{RefreshToken: user.Secret})
 
 	token, err := source.Token()
 	if err != nil || len(token.AccessToken) == 0 {
@@ -169,16 +487,17 @@ func (c *Gitea) Refresh(ctx context.Context, user *model.User) (bool, error) {
 
 // Teams is supported by the Gitea driver.
 func (c *Gitea) Teams(ctx context.Context, u *model.User) ([]*model.Team, error) {
-	client, err := c.newClientToken(ctx, u.Token)
+	client, err := c.newClientUser(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
 	teams := make([]*model.Team, 0, perPage)
+	seen := make(map[string]bool)
 
 	page := 1
 	for {
-		orgs, _, err := client.ListMyOrgs(
+		orgs, resp, err := client.ListMyOrgs(
 			gitea.ListOrgsOptions{
 				ListOptions: gitea.ListOptions{
 					Page:     page,
@@ -187,11 +506,16 @@ func (c *Gitea) Teams(ctx context.Context, u *model.User) ([]*model.Team, error)
 			},
 		)
 		if err != nil {
-			return nil, err
+			return nil, scopeError(resp, err, scopeReadOrganization)
 		}
 
 		for _, org := range orgs {
-			teams = append(teams, toTeam(org, c.URL))
+			// an org can show up twice if the list changes while paging
+			if seen[org.UserName] {
+				continue
+			}
+			seen[org.UserName] = true
+			teams = append(teams, c.toTeam(org, c.URL))
 		}
 
 		if len(orgs) < perPage {
@@ -200,6 +524,9 @@ func (c *Gitea) Teams(ctx context.Context, u *model.User) ([]*model.Team, error)
 		page++
 	}
 
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].Login < teams[j].Login
+	})
 	return teams, nil
 }
 
@@ -210,48 +537,76 @@ func (c *Gitea) TeamPerm(u *model.User, org string) (*model.Perm, error) {
 
 // Repo returns the named Gitea repository.
 func (c *Gitea) Repo(ctx context.Context, u *model.User, owner, name string) (*model.Repo, error) {
-	client, err := c.newClientToken(ctx, u.Token)
+	client, err := c.newClientUser(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	repo, _, err := client.GetRepo(owner, name)
+	repo, resp, err := client.GetRepo(owner, name)
 	if err != nil {
-		return nil, err
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+	r, err := c.toRepo(repo)
+	if err != nil || !c.FetchTopics {
+		return r, err
 	}
-	return toRepo(repo), nil
+
+	// topics are not part of the repository, fetching them costs another
+	// API call per repository
+	topics, resp, err := client.ListRepoTopics(owner, name, gitea.ListRepoTopicsOptions{})
+	if err != nil {
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+	r.Topics = toTopics(topics)
+	return r, nil
 }
 
 // Repos returns a list of all repositories for the Gitea account, including
 // organization repositories.
+// The repositories are listed in pages of the configured size, if listing a
+// page fails the repositories of the previous pages are returned with the
+// error.
 func (c *Gitea) Repos(ctx context.Context, u *model.User) ([]*model.Repo, error) {
-	repos := make([]*model.Repo, 0, perPage)
+	pageSize := c.RepoPageSize
+	if pageSize <= 0 {
+		pageSize = perPage
+	}
+	repos := make([]*model.Repo, 0, pageSize)
 
-	client, err := c.newClientToken(ctx, u.Token)
+	client, err := c.newClientUser(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
 	// Gitea SDK forces us to read repo list paginated.
-	page := 1
+	page, listed := 1, 0
 	for {
-		all, _, err := client.ListMyRepos(
+		all, resp, err := client.ListMyRepos(
 			gitea.ListReposOptions{
 				ListOptions: gitea.ListOptions{
 					Page:     page,
-					PageSize: perPage,
+					PageSize: pageSize,
 				},
 			},
 		)
 		if err != nil {
-			return nil, err
+			return repos, fmt.Errorf("could not list page %d of the repositories of %s: %w", page, u.Login, scopeError(resp, err, scopeReadRepository))
 		}
+		listed += len(all)
 
 		for _, repo := range all {
-			repos = append(repos, toRepo(repo))
+			r, err := c.toRepo(repo)
+			if err != nil {
+				log.Warn().Err(err).Msg("skip Gitea repository")
+				continue
+			}
+			repos = append(repos, r)
 		}
 
-		if len(all) < perPage {
+		// Gitea caps the page size, so the total count is preferred to tell
+		// whether this was the last page
+		total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+		if len(all) == 0 || (err == nil && listed >= total) || (err != nil && len(all) < pageSize) {
 			break
 		}
 		// Last page was not empty so more repos may be available - continue loop.
@@ -261,204 +616,1957 @@ func (c *Gitea) Repos(ctx context.Context, u *model.User) ([]*model.Repo, error)
 	return repos, nil
 }
 
-// Perm returns the user permissions for the named Gitea repository.
-func (c *Gitea) Perm(ctx context.Context, u *model.User, r *model.Repo) (*model.Perm, error) {
-	client, err := c.newClientToken(ctx, u.Token)
+// OrgMembers returns the members of the Gitea organization with their role,
+// which is taken from the owner and admin teams of the organization. Users
+// who are no members only see the public members, their roles are unknown.
+func (c *Gitea) OrgMembers(ctx context.Context, u *model.User, org string) (*remote.OrgMembers, error) {
+	client, err := c.newClientUser(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	repo, _, err := client.GetRepo(r.Owner, r.Name)
+	member, resp, err := client.CheckOrgMembership(org, u.Login)
 	if err != nil {
-		return nil, err
+		return nil, scopeError(resp, err, scopeReadOrganization)
 	}
-	return toPerm(repo.Permissions), nil
-}
 
-// File fetches the file from the Gitea repository and returns its contents.
-func (c *Gitea) File(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]byte, error) {
-	client, err := c.newClientToken(ctx, u.Token)
+	list := client.ListOrgMembership
+	if !member {
+		list = client.ListPublicOrgMembership
+	}
+	members := make([]*remote.OrgMember, 0, perPage)
+	for page := 1; ; page++ {
+		users, resp, err := list(org, gitea.ListOrgMembershipOption{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadOrganization)
+		}
+		for _, user := range users {
+			members = append(members, &remote.OrgMember{Login: user.UserName})
+		}
+		if len(users) < perPage {
+			break
+		}
+	}
+
+	if !member {
+		return &remote.OrgMembers{Members: members, Restricted: true}, nil
+	}
+
+	roles, err := c.orgRoles(client, org)
 	if err != nil {
 		return nil, err
 	}
+	for _, m := range members {
+		m.Role = remote.OrgRoleMember
+		if role, ok := roles[m.Login]; ok {
+			m.Role = role
+		}
+	}
+	return &remote.OrgMembers{Members: members}, nil
+}
+
+// orgRoles returns the owners and admins of the organization by their login,
+// they are the members of the teams with the owner or admin permission.
+func (c *Gitea) orgRoles(client *gitea.Client, org string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for page := 1; ; page++ {
+		teams, resp, err := client.ListOrgTeams(org, gitea.ListTeamsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadOrganization)
+		}
+
+		for _, team := range teams {
+			var role string
+			switch team.Permission {
+			case gitea.AccessModeOwner:
+				role = remote.OrgRoleOwner
+			case gitea.AccessModeAdmin:
+				role = remote.OrgRoleAdmin
+			default:
+				continue
+			}
+			for memberPage := 1; ; memberPage++ {
+				users, resp, err := client.ListTeamMembers(team.ID, gitea.ListTeamMembersOptions{
+					ListOptions: gitea.ListOptions{
+						Page:     memberPage,
+						PageSize: perPage,
+					},
+				})
+				if err != nil {
+					return nil, scopeError(resp, err, scopeReadOrganization)
+				}
+				for _, user := range users {
+					// owners are admins as well
+					if roles[user.UserName] != remote.OrgRoleOwner {
+						roles[user.UserName] = role
+					}
+				}
+				if len(users) < perPage {
+					break
+				}
+			}
+		}
 
-	cfg, _, err := client.GetFile(r.Owner, r.Name, b.Commit, f)
-	return cfg, err
+		if len(teams) < perPage {
+			break
+		}
+	}
+	return roles, nil
 }
 
-func (c *Gitea) Dir(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]*remote.FileMeta, error) {
-	var configs []*remote.FileMeta
+// UserKeys returns the public GPG and SSH keys of the Gitea user with the
+// login. The keys are cached per user for some minutes.
+func (c *Gitea) UserKeys(ctx context.Context, u *model.User, login string) (*remote.UserKeys, error) {
+	key := strings.ToLower(login)
 
-	client, err := c.newClientToken(ctx, u.Token)
-	if err != nil {
-		return nil, err
+	c.userKeysMu.Lock()
+	cached, ok := c.userKeysCache[key]
+	c.userKeysMu.Unlock()
+	if ok && time.Since(cached.fetched) < userKeysTTL {
+		return cached.keys, nil
 	}
 
-	// List files in repository. Path from root
-	tree, _, err := client.GetTrees(r.Owner, r.Name, b.Commit, true)
+	client, err := c.newClientUser(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	f = path.Clean(f) // We clean path and remove trailing slash
-	f += "/" + "*"    // construct pattern for match i.e. file in subdir
-	for _, e := range tree.Entries {
-		// Filter path matching pattern and type file (blob)
-		if m, _ := filepath.Match(f, e.Path); m && e.Type == "blob" {
-			data, err := c.File(ctx, u, r, b, e.Path)
-			if err != nil {
-				return nil, fmt.Errorf("multi-pipeline cannot get %s: %s", e.Path, err)
-			}
-
-			configs = append(configs, &remote.FileMeta{
-				Name: e.Path,
-				Data: data,
-			})
+	keys := &remote.UserKeys{
+		GPG: make([]*remote.GPGKey, 0),
+		SSH: make([]*remote.SSHKey, 0),
+	}
+	for page := 1; ; page++ {
+		gpgKeys, resp, err := client.ListGPGKeys(login, gitea.ListGPGKeysOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadUser)
+		}
+		for _, gpgKey := range gpgKeys {
+			keys.GPG = append(keys.GPG, toGPGKey(gpgKey))
+		}
+		if len(gpgKeys) < perPage {
+			break
+		}
+	}
+	for page := 1; ; page++ {
+		sshKeys, resp, err := client.ListPublicKeys(login, gitea.ListPublicKeysOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadUser)
+		}
+		for _, sshKey := range sshKeys {
+			keys.SSH = append(keys.SSH, toSSHKey(sshKey))
+		}
+		if len(sshKeys) < perPage {
+			break
 		}
 	}
 
-	return configs, nil
-}
-
-// Status is supported by the Gitea driver.
-func (c *Gitea) Status(ctx context.Context, user *model.User, repo *model.Repo, build *model.Build, proc *model.Proc) error {
-	client, err := c.newClientToken(ctx, user.Token)
-	if err != nil {
-		return err
+	c.userKeysMu.Lock()
+	if c.userKeysCache == nil || len(c.userKeysCache) >= userKeysCacheSize {
+		c.userKeysCache = make(map[string]*userKeys, userKeysCacheSize)
 	}
+	c.userKeysCache[key] = &userKeys{keys: keys, fetched: time.Now()}
+	c.userKeysMu.Unlock()
 
-	_, _, err = client.CreateStatus(
-		repo.Owner,
-		repo.Name,
-		build.Commit,
-		gitea.CreateStatusOption{
-			State:       getStatus(proc.State),
-			TargetURL:   common.GetBuildStatusLink(repo, build, proc),
-			Description: common.GetBuildStatusDescription(proc.State),
-			Context:     common.GetBuildStatusContext(repo, build, proc),
-		},
-	)
-	return err
+	return keys, nil
 }
 
-// Netrc returns a netrc file capable of authenticating Gitea requests and
-// cloning Gitea repositories. The netrc will use the global machine account
-// when configured.
-func (c *Gitea) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
-	login := ""
-	token := ""
-
-	if u != nil {
-		login = u.Login
-		token = u.Token
+// Collaborators returns the collaborators of the repository with their
+// permission, the owner of the repository is included as admin unless it is
+// an organization.
+func (c *Gitea) Collaborators(ctx context.Context, u *model.User, r *model.Repo) ([]*remote.Collaborator, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
 	}
 
-	host, err := common.ExtractHostFromCloneURL(r.Clone)
+	org, err := isOrg(client, r.Owner)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Netrc{
-		Login:    login,
-		Password: token,
-		Machine:  host,
-	}, nil
-}
-
-// Activate activates the repository by registering post-commit hooks with
-// the Gitea repository.
-func (c *Gitea) Activate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
-	config := map[string]string{
-		"url":          link,
-		"secret":       r.Hash,
-		"content_type": "json",
-	}
-	hook := gitea.CreateHookOption{
-		Type:   gitea.HookTypeGitea,
-		Config: config,
-		Events: []string{"push", "create", "pull_request"},
-		Active: true,
+	var collaborators []*remote.Collaborator
+	if !org {
+		collaborators = append(collaborators, &remote.Collaborator{
+			Login: r.Owner,
+			Perm:  accessModePerm(gitea.AccessModeOwner),
+		})
 	}
+	for page := 1; ; page++ {
+		users, resp, err := client.ListCollaborators(r.Owner, r.Name, gitea.ListCollaboratorsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadRepository)
+		}
 
-	client, err := c.newClientToken(ctx, u.Token)
-	if err != nil {
-		return err
-	}
-	_, response, err := client.CreateRepoHook(r.Owner, r.Name, hook)
-	if err != nil {
-		if response != nil {
-			if response.StatusCode == 404 {
-				return fmt.Errorf("Could not find repository")
+		for _, user := range users {
+			if strings.EqualFold(user.UserName, r.Owner) {
+				continue
 			}
-			if response.StatusCode == 200 {
-				return fmt.Errorf("Could not find repository, repository was probably renamed")
+			mode, err := c.collaboratorPermission(ctx, u, r, user.UserName)
+			if err != nil {
+				return nil, err
 			}
+			collaborators = append(collaborators, &remote.Collaborator{
+				Login: user.UserName,
+				Perm:  accessModePerm(mode),
+			})
+		}
+
+		if len(users) < perPage {
+			break
 		}
-		return err
 	}
-	return nil
+	return collaborators, nil
 }
 
-// Deactivate deactives the repository be removing repository push hooks from
-// the Gitea repository.
-func (c *Gitea) Deactivate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
-	client, err := c.newClientToken(ctx, u.Token)
+// collaboratorPermission returns the access mode of a collaborator using the
+// collaborator permission API, which is not covered by the Gitea SDK.
+func (c *Gitea) collaboratorPermission(ctx context.Context, u *model.User, r *model.Repo, login string) (gitea.AccessMode, error) {
+	permissionURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/collaborators/%s/permission",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(r.Owner),
+		url.PathEscape(r.Name),
+		url.PathEscape(login),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, permissionURL, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
+	req.Header.Set("Authorization", "token "+u.Token)
 
-	hooks, _, err := client.ListRepoHooks(r.Owner, r.Name, gitea.ListHooksOptions{})
+	resp, err := c.newHTTPClientUser(ctx, u).Do(req)
 	if err != nil {
-		return err
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("could not get the permission of %s: %s", login, resp.Status)
+		return "", scopeError(&gitea.Response{Response: resp}, err, scopeReadRepository)
 	}
 
-	hook := matchingHooks(hooks, link)
-	if hook != nil {
-		_, err := client.DeleteRepoHook(r.Owner, r.Name, hook.ID)
-		return err
+	permission := new(collaboratorPermission)
+	if err := json.NewDecoder(resp.Body).Decode(permission); err != nil {
+		return "", err
 	}
+	return permission.Permission, nil
+}
 
-	return nil
+// Perm returns the user permissions for the named Gitea repository.
+func (c *Gitea) Perm(ctx context.Context, u *model.User, r *model.Repo) (*model.Perm, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, resp, err := client.GetRepo(r.Owner, r.Name)
+	if err != nil {
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+	return toPerm(repo.Permissions), nil
 }
 
-// Branches returns the names of all branches for the named repository.
-func (c *Gitea) Branches(ctx context.Context, u *model.User, r *model.Repo) ([]string, error) {
-	token := ""
-	if u != nil {
-		token = u.Token
+// RepoFile fetches the file at the ref of another repository, e.g. a shared
+// pipeline template. Repositories the user can't read, including private
+// repositories hidden from the user, result in remote.ErrNotFound or
+// remote.ErrForbidden. The ref has to be a commit sha or a tag, other refs
+// result in remote.ErrUnpinnedRef.
+func (c *Gitea) RepoFile(ctx context.Context, u *model.User, owner, name, ref, f string) ([]byte, error) {
+	r := &model.Repo{Owner: owner, Name: name}
+	perm, err := c.Perm(ctx, u, r)
+	if errors.Is(err, remote.ErrNotFound) {
+		return nil, fmt.Errorf("%w: repository %s/%s does not exist or is private", remote.ErrNotFound, owner, name)
 	}
-	client, err := c.newClientToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
-
-	giteaBranches, _, err := client.ListRepoBranches(r.Owner, r.Name, gitea.ListRepoBranchesOptions{})
+	if !perm.Pull {
+		return nil, fmt.Errorf("%w: no read access to repository %s/%s", remote.ErrForbidden, owner, name)
+	}
+	sha, err := c.pinnedCommit(ctx, u, r, ref)
 	if err != nil {
 		return nil, err
 	}
+	return c.File(ctx, u, r, &model.Build{Commit: sha}, f)
+}
 
-	branches := make([]string, 0)
-	for _, branch := range giteaBranches {
-		branches = append(branches, branch.Name)
+// pinnedCommit returns the commit of a ref which is a full commit sha or a tag,
+// so the fetched file does not change along with a branch. A tag is resolved
+// to its commit, even if a branch of the same name exists.
+func (c *Gitea) pinnedCommit(ctx context.Context, u *model.User, r *model.Repo, ref string) (string, error) {
+	if isCommitSHA(ref) {
+		return ref, nil
 	}
-	return branches, nil
+
+	tag, err := c.getTag(ctx, u, r, strings.TrimPrefix(ref, refTagsPrefix))
+	if errors.Is(err, remote.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s of repository %s/%s is neither a commit sha nor a tag", remote.ErrUnpinnedRef, ref, r.Owner, r.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if tag.Commit == nil || tag.Commit.SHA == "" {
+		return "", fmt.Errorf("tag %s of repository %s/%s has no commit", ref, r.Owner, r.Name)
+	}
+	return tag.Commit.SHA, nil
+}
+
+// File fetches the file at the build commit. Symlinks are followed, files not
+// existing result in remote.ErrFileNotFound.
+func (c *Gitea) File(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]byte, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	name := f
+	for i := 0; ; i++ {
+		content, resp, err := client.GetContents(r.Owner, r.Name, b.Commit, name)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", remote.ErrFileNotFound, f)
+		}
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadRepository)
+		}
+
+		switch content.Type {
+		case "file":
+			if c.MaxFileSize > 0 && content.Size > c.MaxFileSize {
+				return nil, fmt.Errorf("file %s exceeds the maximum size of %d bytes", f, c.MaxFileSize)
+			}
+			data, ok, err := decodeContent(content)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if data, err = c.getRawFile(ctx, u.Token, r, b.Commit, name); err != nil {
+					return nil, err
+				}
+			}
+			// configs are parsed as YAML, which has to be valid UTF-8
+			if !utf8.Valid(data) {
+				return nil, fmt.Errorf("config %s is not valid UTF-8", f)
+			}
+			return data, nil
+		case "symlink":
+			if content.Target == nil || i >= maxSymlinkDepth {
+				return nil, fmt.Errorf("could not resolve symlink %s", f)
+			}
+			name = path.Join(path.Dir(name), *content.Target)
+			if name == ".." || strings.HasPrefix(name, "../") {
+				return nil, fmt.Errorf("%w: symlink %s points outside the repository", remote.ErrFileNotFound, f)
+			}
+		default:
+			return nil, fmt.Errorf("%s is not a file but a %s", f, content.Type)
+		}
+	}
+}
+
+// decodeContent returns the file content included in the contents response,
+// decoding it if Gitea reports an encoding. It reports false if the response
+// does not include the content.
+func decodeContent(content *gitea.ContentsResponse) ([]byte, bool, error) {
+	if content.Content == nil {
+		return nil, false, nil
+	}
+
+	encoding := ""
+	if content.Encoding != nil {
+		encoding = *content.Encoding
+	}
+	switch encoding {
+	case "":
+		return []byte(*content.Content), true, nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(*content.Content, "\n", ""))
+		if err != nil {
+			return nil, false, fmt.Errorf("could not decode content of %s: %w", content.Path, err)
+		}
+		return data, true, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported encoding %s of %s", encoding, content.Path)
+	}
+}
+
+// getRawFile streams the raw file content, reading at most MaxFileSize bytes
+// as the size reported by Gitea can differ for e.g. LFS files.
+func (c *Gitea) getRawFile(ctx context.Context, token string, r *model.Repo, ref, f string) ([]byte, error) {
+	segments := strings.Split(strings.TrimPrefix(f, "/"), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	rawURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/raw/%s/%s",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(r.Owner),
+		url.PathEscape(r.Name),
+		url.PathEscape(ref),
+		strings.Join(segments, "/"),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+token)
+
+	resp, err := c.newHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", remote.ErrFileNotFound, f)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("could not get file %s: %s", f, resp.Status)
+	}
+
+	body := io.Reader(resp.Body)
+	if c.MaxFileSize > 0 {
+		body = io.LimitReader(resp.Body, c.MaxFileSize+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxFileSize > 0 && int64(len(data)) > c.MaxFileSize {
+		return nil, fmt.Errorf("file %s exceeds the maximum size of %d bytes", f, c.MaxFileSize)
+	}
+	return data, nil
+}
+
+func (c *Gitea) Dir(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]*remote.FileMeta, error) {
+	var configs []*remote.FileMeta
+
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// List files in repository. Path from root
+	tree, resp, err := client.GetTrees(r.Owner, r.Name, b.Commit, true)
+	if err != nil {
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+
+	f = path.Clean(f) // We clean path and remove trailing slash
+	f += "/" + "*"    // construct pattern for match i.e. file in subdir
+	for _, e := range tree.Entries {
+		// Filter path matching pattern and type file (blob)
+		if m, _ := filepath.Match(f, e.Path); m && e.Type == "blob" {
+			data, err := c.File(ctx, u, r, b, e.Path)
+			if err != nil {
+				return nil, fmt.Errorf("multi-pipeline cannot get %s: %s", e.Path, err)
+			}
+
+			configs = append(configs, &remote.FileMeta{
+				Name: e.Path,
+				Data: data,
+			})
+		}
+	}
+	sort.Sort(remote.ByName(configs))
+
+	return configs, nil
+}
+
+// Ping checks that Gitea is reachable. If a user is given the current user
+// is requested with its token, otherwise the server version.
+func (c *Gitea) Ping(ctx context.Context, u *model.User) (*remote.PingResult, error) {
+	token := ""
+	if u != nil {
+		token = u.Token
+	}
+
+	start := time.Now()
+	client, err := c.newClientToken(ctx, token)
+	if err != nil {
+		return nil, pingError(nil, err)
+	}
+
+	result := new(remote.PingResult)
+	if u == nil {
+		_, resp, err := client.ServerVersion()
+		if err != nil {
+			return nil, pingError(resp, err)
+		}
+	} else {
+		user, resp, err := client.GetMyUserInfo()
+		if err != nil {
+			return nil, pingError(resp, err)
+		}
+		result.Login = user.UserName
+	}
+	result.Latency = time.Since(start)
+
+	return result, nil
+}
+
+// Capabilities returns the optional features supported by the version of the
+// Gitea server. Statuses per step are supported if they are enabled.
+func (c *Gitea) Capabilities(ctx context.Context) (*remote.Capabilities, error) {
+	v, err := c.serverVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	capabilities, err := capabilitiesForVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	capabilities.SupportsStatusPerStep = c.StepStatuses
+	return capabilities, nil
+}
+
+// serverVersion returns the version of the Gitea server. The version is cached
+// for serverVersionTTL, concurrent callers wait for a single request.
+func (c *Gitea) serverVersion(ctx context.Context) (string, error) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+
+	if c.version != "" && time.Since(c.versionFetched) < serverVersionTTL {
+		return c.version, nil
+	}
+
+	client, err := c.newClientToken(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	v, _, err := client.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	c.version, c.versionFetched = v, time.Now()
+	return v, nil
+}
+
+// helper function returning the capabilities of a Gitea version. Pre-releases
+// and development builds are treated like the release they precede.
+func capabilitiesForVersion(v string) (*remote.Capabilities, error) {
+	parsed, err := version.NewVersion(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Gitea version %q: %w", v, err)
+	}
+	segments := parsed.Segments()
+	core := version.Must(version.NewVersion(fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2])))
+
+	return &remote.Capabilities{
+		SupportsReviews:    core.GreaterThanOrEqual(reviewHooksVersion),
+		SupportsReleases:   core.GreaterThanOrEqual(releaseHooksVersion),
+		SupportsDraftPulls: core.GreaterThanOrEqual(draftPullsVersion),
+	}, nil
+}
+
+// helper function to wrap errors of a ping into the matching remote errors.
+func pingError(resp *gitea.Response, err error) error {
+	var urlErr *url.Error
+	switch {
+	case resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden):
+		return fmt.Errorf("%w: %s", remote.ErrInvalidToken, err)
+	case errors.As(err, &urlErr):
+		return fmt.Errorf("%w: %s", remote.ErrUnreachable, err)
+	}
+	return err
+}
+
+// helper function to wrap errors of requests Gitea rejected in the typed
+// errors of the remote package. Forbidden requests are reported as lacking the
+// scope if the message of Gitea names a scope, as scoped tokens lacking the
+// scope of an API call are rejected the same way as users lacking access.
+func scopeError(resp *gitea.Response, err error, scope string) error {
+	if err == nil || resp == nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		if message := resp.Header.Get(forbiddenMessageHeader); strings.Contains(strings.ToLower(message), "scope") {
+			return &remote.InsufficientScopeError{Scope: scope, Err: fmt.Errorf("%s: %s", err, message)}
+		}
+		return fmt.Errorf("%w: %s", remote.ErrForbidden, err)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", remote.ErrNotFound, err)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %s", remote.ErrUnauthorized, err)
+	}
+	return err
+}
+
+// Status is supported by the Gitea driver. Mirrors are read-only, so no
+// status is reported for them.
+func (c *Gitea) Status(ctx context.Context, user *model.User, repo *model.Repo, build *model.Build, proc *model.Proc) error {
+	if repo.IsMirror {
+		log.Debug().Msgf("skip reporting the status of %s to the mirror %s", build.Commit, repo.FullName)
+		return nil
+	}
+
+	client, err := c.newClientUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	// all pipelines report to the status context of the repo, so the state
+	// of the whole build is reported
+	state := proc.State
+	if repo.StatusContext != "" {
+		state = buildState(build, proc)
+	}
+
+	_, resp, err := client.CreateStatus(
+		repo.Owner,
+		repo.Name,
+		build.Commit,
+		gitea.CreateStatusOption{
+			State:       getStatus(state),
+			TargetURL:   getStatusLink(repo, build, proc),
+			Description: common.GetBuildStatusDescription(state),
+			Context:     c.getStatusContext(repo, build, proc),
+		},
+	)
+	return scopeError(resp, err, scopeWriteRepository)
+}
+
+// buildState returns the state of all pipelines of the build, with the state
+// of the given pipeline being the latest one. It is the state of the pipeline
+// if the procs of the build are not loaded.
+func buildState(build *model.Build, proc *model.Proc) model.StatusValue {
+	state := proc.State
+	if proc.Failing() {
+		return state
+	}
+	for _, p := range build.Procs {
+		if !p.IsParent() || p.PID == proc.PID {
+			continue
+		}
+		if p.Failing() {
+			return model.StatusFailure
+		}
+		if p.Running() {
+			state = model.StatusRunning
+		}
+	}
+	return state
+}
+
+// getStatusLink returns the link of the commit status of a pipeline. Failed
+// pipelines link to their first failed step, successful ones to the build.
+func getStatusLink(repo *model.Repo, build *model.Build, proc *model.Proc) string {
+	if proc.State == model.StatusSuccess {
+		return common.GetBuildStatusLink(repo, build, nil)
+	}
+	if proc.Failing() {
+		if step := failedStep(build, proc); step != nil {
+			return common.GetBuildStatusLink(repo, build, step)
+		}
+	}
+	return common.GetBuildStatusLink(repo, build, proc)
+}
+
+// failedStep returns the first failed step of the pipeline in the order of
+// execution, or nil if the procs of the build are not loaded.
+func failedStep(build *model.Build, proc *model.Proc) *model.Proc {
+	var failed *model.Proc
+	var visit func(procs []*model.Proc)
+	visit = func(procs []*model.Proc) {
+		for _, p := range procs {
+			if p.PPID == proc.PID && p.Failing() && (failed == nil || p.PID < failed.PID) {
+				failed = p
+			}
+			visit(p.Children)
+		}
+	}
+	visit(build.Procs)
+	return failed
+}
+
+// StepStatus reports the status of a pipeline step as its own commit status,
+// if enabled. Skipped steps and steps of mirrors are not reported. As all
+// pipelines share the status context of the repo, steps reported to it are
+// prefixed with the name of their pipeline.
+func (c *Gitea) StepStatus(ctx context.Context, user *model.User, repo *model.Repo, build *model.Build, parent, step *model.Proc) error {
+	if !c.StepStatuses || step.State == model.StatusSkipped || repo.IsMirror {
+		return nil
+	}
+
+	client, err := c.newClientUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	statusContext := c.getStatusContext(repo, build, parent)
+	if repo.StatusContext != "" {
+		statusContext += "/" + parent.Name
+	}
+
+	_, resp, err := client.CreateStatus(
+		repo.Owner,
+		repo.Name,
+		build.Commit,
+		gitea.CreateStatusOption{
+			State:       getStatus(step.State),
+			TargetURL:   common.GetBuildStatusLink(repo, build, step),
+			Description: common.GetBuildStatusDescription(step.State),
+			Context:     statusContext + "/" + step.Name,
+		},
+	)
+	return scopeError(resp, err, scopeWriteRepository)
+}
+
+// getStatusContext returns the commit status context of the pipeline, so each
+// pipeline of a build reports its own status. Repos with their own status
+// context, e.g. one required by a branch protection, report all pipelines to
+// it.
+func (c *Gitea) getStatusContext(repo *model.Repo, build *model.Build, proc *model.Proc) string {
+	if repo.StatusContext != "" {
+		return repo.StatusContext
+	}
+	if c.statusContext == nil {
+		return common.GetBuildStatusContext(repo, build, proc)
+	}
+
+	event := string(build.Event)
+	if build.Event == model.EventPull {
+		event = "pr"
+	}
+	pipeline := ""
+	if proc != nil {
+		pipeline = proc.Name
+	}
+
+	var buf strings.Builder
+	err := c.statusContext.Execute(&buf, map[string]string{
+		"context":  server.Config.Server.StatusContext,
+		"event":    event,
+		"pipeline": pipeline,
+		"owner":    repo.Owner,
+		"repo":     repo.Name,
+		"target":   build.Deploy,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("could not render the status context, use the default one")
+		return common.GetBuildStatusContext(repo, build, proc)
+	}
+	return buf.String()
+}
+
+// Netrc returns a netrc file capable of authenticating Gitea requests and
+// cloning Gitea repositories. The netrc will use the global machine account
+// when configured. The credentials are also used for the additional netrc
+// hosts, e.g. to fetch submodules.
+func (c *Gitea) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
+	login := ""
+	token := ""
+
+	if u != nil {
+		login = u.Login
+		token = u.Token
+	}
+
+	host, err := common.ExtractHostFromCloneURL(r.Clone)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	for _, h := range c.NetrcHosts {
+		if h != host {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return &model.Netrc{
+		Login:    login,
+		Password: token,
+		Machine:  host,
+		Hosts:    hosts,
+	}, nil
+}
+
+// Activate activates the repository by registering post-commit hooks with
+// the Gitea repository. Hooks already registered for the link are replaced, so
+// exactly one remains.
+func (c *Gitea) Activate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	config := map[string]string{
+		"url":          link,
+		"secret":       r.Hash,
+		"content_type": "json",
+	}
+	hook := gitea.CreateHookOption{
+		Type:   gitea.HookTypeGitea,
+		Config: config,
+		Events: hookEvents(r.HookEvents),
+		Active: true,
+	}
+
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	// hooks left behind by activating the repository before, e.g. after a
+	// migration, would create every build multiple times
+	hooks, resp, err := client.ListRepoHooks(r.Owner, r.Name, gitea.ListHooksOptions{})
+	if err != nil {
+		return scopeError(resp, err, scopeWriteRepository)
+	}
+	for _, stale := range allMatchingHooks(hooks, link) {
+		if resp, err := client.DeleteRepoHook(r.Owner, r.Name, stale.ID); err != nil {
+			return scopeError(resp, err, scopeWriteRepository)
+		}
+	}
+
+	_, response, err := client.CreateRepoHook(r.Owner, r.Name, hook)
+	if err != nil {
+		if response != nil {
+			if response.StatusCode == 404 {
+				return fmt.Errorf("%w: could not find repository %s", remote.ErrNotFound, r.FullName)
+			}
+			if response.StatusCode == 200 {
+				return fmt.Errorf("Could not find repository, repository was probably renamed")
+			}
+		}
+		return scopeError(response, err, scopeWriteRepository)
+	}
+
+	if c.DeployKey != "" {
+		return c.Deploy(ctx, u, r, &remote.DeployKey{Title: deployKeyTitle, Key: c.DeployKey})
+	}
+	return nil
+}
+
+// hookEvents returns the Gitea events subscribed to by the hook for the build
+// events of the repository. Build events Gitea sends no hook for are ignored,
+// all events creating builds are subscribed to if none is left.
+func hookEvents(events []string) []string {
+	giteaEvents := map[model.WebhookEvent]string{
+		model.EventPush:    hookPush,
+		model.EventTag:     hookCreated,
+		model.EventPull:    hookPullRequest,
+		model.EventRelease: hookRelease,
+		model.EventPackage: hookPackage,
+		model.EventDelete:  hookDelete,
+	}
+
+	subscribed := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		if giteaEvent, ok := giteaEvents[model.WebhookEvent(event)]; ok && !seen[giteaEvent] {
+			seen[giteaEvent] = true
+			subscribed = append(subscribed, giteaEvent)
+		}
+	}
+	if len(subscribed) == 0 {
+		return []string{hookPush, hookCreated, hookPullRequest, hookRelease}
+	}
+	return subscribed
+}
+
+// Deactivate deactives the repository be removing repository push hooks from
+// the Gitea repository. Build summaries of open pull requests are deleted.
+func (c *Gitea) Deactivate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	if c.DeployKey != "" {
+		if err := c.Undeploy(ctx, u, r, deployKeyTitle); err != nil {
+			return err
+		}
+	}
+
+	// stale summaries must not block deactivating the repository
+	if c.summary != nil {
+		if err := c.deleteSummaries(ctx, u, r); err != nil {
+			log.Warn().Err(err).Msgf("could not delete the build summaries of %s", r.FullName)
+		}
+	}
+
+	hooks, resp, err := client.ListRepoHooks(r.Owner, r.Name, gitea.ListHooksOptions{})
+	if err != nil {
+		return scopeError(resp, err, scopeWriteRepository)
+	}
+
+	hook := matchingHooks(hooks, link)
+	if hook != nil {
+		resp, err := client.DeleteRepoHook(r.Owner, r.Name, hook.ID)
+		return scopeError(resp, err, scopeWriteRepository)
+	}
+
+	return nil
+}
+
+// RotateHookSecret updates the url and secret of the hook registered for the
+// repository to the link and the current hash of the repository.
+func (c *Gitea) RotateHookSecret(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	hooks, resp, err := client.ListRepoHooks(r.Owner, r.Name, gitea.ListHooksOptions{})
+	if err != nil {
+		return scopeError(resp, err, scopeWriteRepository)
+	}
+
+	hook := matchingHooks(hooks, link)
+	if hook == nil {
+		return fmt.Errorf("no hook of %s registered for %s", r.FullName, link)
+	}
+
+	resp, err = client.EditRepoHook(r.Owner, r.Name, hook.ID, gitea.EditHookOption{
+		Config: map[string]string{
+			"url":          link,
+			"secret":       r.Hash,
+			"content_type": "json",
+		},
+		// events and the active state are reset if not sent
+		Events: hook.Events,
+		Active: &hook.Active,
+	})
+	return scopeError(resp, err, scopeWriteRepository)
+}
+
+// Deploy registers a read-only deploy key with the Gitea repository. As keys
+// can not be updated, an existing key with the same title but another key is
+// deleted first, nothing is done if it matches.
+func (c *Gitea) Deploy(ctx context.Context, u *model.User, r *model.Repo, key *remote.DeployKey) error {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	existing, err := findDeployKey(client, r, key.Title)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.Key == strings.TrimSpace(key.Key) && existing.ReadOnly {
+			return nil
+		}
+		if resp, err := client.DeleteDeployKey(r.Owner, r.Name, existing.ID); err != nil {
+			return scopeError(resp, err, scopeWriteRepository)
+		}
+	}
+
+	_, resp, err := client.CreateDeployKey(r.Owner, r.Name, gitea.CreateKeyOption{
+		Title:    key.Title,
+		Key:      strings.TrimSpace(key.Key),
+		ReadOnly: true,
+	})
+	return scopeError(resp, err, scopeWriteRepository)
+}
+
+// Undeploy removes the deploy key with the title from the Gitea repository.
+func (c *Gitea) Undeploy(ctx context.Context, u *model.User, r *model.Repo, title string) error {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	existing, err := findDeployKey(client, r, title)
+	if err != nil || existing == nil {
+		return err
+	}
+	resp, err := client.DeleteDeployKey(r.Owner, r.Name, existing.ID)
+	return scopeError(resp, err, scopeWriteRepository)
+}
+
+// helper function returning the deploy key of the repository with the title,
+// or nil if there is none.
+func findDeployKey(client *gitea.Client, r *model.Repo, title string) (*gitea.DeployKey, error) {
+	page := 1
+	for {
+		keys, resp, err := client.ListDeployKeys(r.Owner, r.Name, gitea.ListDeployKeysOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeWriteRepository)
+		}
+
+		for _, key := range keys {
+			if key.Title == title {
+				return key, nil
+			}
+		}
+
+		if len(keys) < perPage {
+			return nil, nil
+		}
+		page++
+	}
+}
+
+// Branches returns the names of all branches for the named repository.
+func (c *Gitea) Branches(ctx context.Context, u *model.User, r *model.Repo) ([]string, error) {
+	token := ""
+	if u != nil {
+		token = u.Token
+	}
+	client, err := c.newClientToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make([]string, 0)
+
+	page := 1
+	for {
+		giteaBranches, resp, err := client.ListRepoBranches(r.Owner, r.Name, gitea.ListRepoBranchesOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadRepository)
+		}
+
+		for _, branch := range giteaBranches {
+			// the default branch is listed first
+			if branch.Name == r.Branch {
+				branches = append([]string{branch.Name}, branches...)
+			} else {
+				branches = append(branches, branch.Name)
+			}
+		}
+
+		if len(giteaBranches) < perPage {
+			break
+		}
+		page++
+	}
+
+	return branches, nil
+}
+
+// PullRequests returns the open pull requests of the repository sorted by
+// number, newest first. If page is 0 all pages are returned.
+func (c *Gitea) PullRequests(ctx context.Context, u *model.User, r *model.Repo, page int) ([]*remote.PullRequest, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	pulls := make([]*remote.PullRequest, 0, perPage)
+	for p := page; ; p++ {
+		if p == 0 {
+			p = 1
+		}
+		giteaPulls, resp, err := client.ListRepoPullRequests(r.Owner, r.Name, gitea.ListPullRequestsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     p,
+				PageSize: perPage,
+			},
+			State: gitea.StateOpen,
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadRepository)
+		}
+
+		for _, pull := range giteaPulls {
+			pulls = append(pulls, toPullRequest(pull))
+		}
+
+		if page > 0 || len(giteaPulls) < perPage {
+			break
+		}
+	}
+
+	sort.Slice(pulls, func(i, j int) bool {
+		return pulls[i].Number > pulls[j].Number
+	})
+	return pulls, nil
+}
+
+// RequiredStatusChecks returns the status contexts required by the branch
+// protections of the repository.
+func (c *Gitea) RequiredStatusChecks(ctx context.Context, u *model.User, r *model.Repo) ([]string, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []string
+	for page := 1; ; page++ {
+		protections, resp, err := client.ListBranchProtections(r.Owner, r.Name, gitea.ListBranchProtectionsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadRepository)
+		}
+
+		for _, protection := range protections {
+			if protection.EnableStatusCheck {
+				checks = append(checks, protection.StatusCheckContexts...)
+			}
+		}
+
+		if len(protections) < perPage {
+			break
+		}
+	}
+	checks = utils.DedupStrings(checks)
+	sort.Strings(checks)
+	return checks, nil
+}
+
+// BranchHead returns the sha of the latest commit of the branch.
+func (c *Gitea) BranchHead(ctx context.Context, u *model.User, r *model.Repo, branch string) (string, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return "", err
+	}
+
+	b, resp, err := client.GetRepoBranch(r.Owner, r.Name, branch)
+	if err != nil {
+		return "", scopeError(resp, err, scopeReadRepository)
+	}
+	if b.Commit == nil {
+		return "", fmt.Errorf("branch %s of %s has no commit", branch, r.FullName)
+	}
+	return b.Commit.ID, nil
+}
+
+// CommitInfo returns the full sha and the metadata of the commit the ref, e.g.
+// a branch, a tag or a short sha, points to.
+func (c *Gitea) CommitInfo(ctx context.Context, u *model.User, r *model.Repo, ref string) (*remote.Commit, error) {
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, resp, err := client.ListRepoCommits(r.Owner, r.Name, gitea.ListCommitOptions{
+		ListOptions: gitea.ListOptions{PageSize: 1},
+		SHA:         ref,
+	})
+	if err != nil {
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+	if len(commits) == 0 || commits[0].CommitMeta == nil {
+		return nil, fmt.Errorf("%w: ref %s of %s", remote.ErrNotFound, ref, r.FullName)
+	}
+	return c.toCommit(r, commits[0]), nil
+}
+
+// Hook parses the incoming Gitea hook and returns the Repository and Build
+// details. If the hook is unsupported nil values are returned.
+func (c *Gitea) Hook(ctx context.Context, r *http.Request) (*model.Repo, *model.Build, error) {
+	payload, err := readHookPayload(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the signature is verified before the payload is decoded
+	if err := c.verifyHook(ctx, r, payload); err != nil {
+		return nil, nil, err
+	}
+
+	repo, build, err := c.readHook(ctx, r)
+	if errors.Is(err, remote.ErrRepoDeleted) {
+		return repo, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if build != nil && build.Commit == "" {
+		if build.Commit, err = c.resolveCommit(ctx, repo, build); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if build != nil && build.Action == actionRebuild {
+		if build, err = c.rebuildPullRequest(ctx, repo, build); err != nil || build == nil {
+			return nil, nil, err
+		}
+	}
+
+	if build != nil {
+		build.Avatar = c.fallbackAvatar(build.Avatar, build.Email)
+	}
+
+	if build != nil && build.Event == model.EventTag && c.TagChangedFiles {
+		_, tag := tagRef(build.Ref)
+		files, err := c.getChangedFilesForTag(ctx, repo, tag, build.Commit)
+		if err != nil {
+			log.Warn().Err(err).Msgf("could not get changed files for tag %s of %s", tag, repo.FullName)
+		} else if files == nil {
+			log.Debug().Msgf("tag %s is the first tag of %s, no changed files", tag, repo.FullName)
+			build.IsFirstTag = true
+		}
+		build.ChangedFiles, build.Truncated = c.capChangedFiles(files)
+	}
+
+	if build != nil && build.Event == model.EventTag && c.TagMessages {
+		_, tag := tagRef(build.Ref)
+		message, err := c.getTagMessage(ctx, repo, tag)
+		if err != nil {
+			log.Warn().Err(err).Msgf("could not get the message of tag %s of %s", tag, repo.FullName)
+		} else if message != "" {
+			build.Message = c.truncate(message)
+			build.Title = c.truncate(commitTitle(message))
+		}
+	}
+
+	if build != nil && build.Event == model.EventPush && c.PushCompareFiles {
+		if push, err := parsePush(bytes.NewReader(payload)); err == nil && needsCompare(push) {
+			files, err := c.getChangedFilesForPush(ctx, repo, push.Before, push.After)
+			if err != nil {
+				log.Warn().Err(err).Msgf("could not compare push %s...%s of %s, use the files of the pushed commits", push.Before, push.After, repo.FullName)
+			} else {
+				build.ChangedFiles, build.Truncated = c.capChangedFiles(files)
+			}
+		}
+	}
+
+	if build != nil && build.Event == model.EventPull {
+		build.BaseProtected = c.isProtectedBranch(ctx, repo, build.Branch)
+	}
+
+	if build != nil && (build.Event == model.EventPush || build.Event == model.EventPull) && c.SenderTeams {
+		teams, err := c.senderTeams(ctx, repo, build.Sender)
+		if err != nil {
+			log.Warn().Err(err).Msgf("could not get the teams of %s in %s", build.Sender, repo.Owner)
+		}
+		build.Teams = teams
+	}
+
+	if build != nil && build.Event == model.EventPull && len(build.ChangedFiles) == 0 {
+		index, err := strconv.ParseInt(strings.Split(build.Ref, "/")[2], 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		files, err := c.getChangedFilesForPR(ctx, repo, index, build.Commit)
+		if err != nil {
+			log.Warn().Err(err).Msgf("could not get changed files for PR %s#%d", repo.FullName, index)
+			files = []string{}
+		}
+		build.ChangedFiles, build.Truncated = c.capChangedFiles(files)
+	}
+
+	if build != nil {
+		c.mapEvent(build)
+	}
+	return repo, build, nil
+}
+
+// resolveCommit returns the commit of builds whose hook does not include it.
+// Releases are built at the commit of their tag, packages and deletions at
+// the head of the default branch. The signature of the hook was verified
+// against the stored repo, so failing to look up its owner fails the hook.
+func (c *Gitea) resolveCommit(ctx context.Context, repo *model.Repo, build *model.Build) (string, error) {
+	switch build.Event {
+	case model.EventRelease, model.EventPackage, model.EventDelete:
+	default:
+		return "", nil
+	}
+
+	user, owned, err := c.repoOwner(ctx, repo)
+	if err != nil {
+		return "", fmt.Errorf("could not get the owner of %s to resolve the commit: %w", repo.FullName, err)
+	}
+
+	if build.Event != model.EventRelease {
+		commit, err := c.BranchHead(ctx, user, owned, build.Branch)
+		if err != nil {
+			return "", fmt.Errorf("could not get the head of branch %s of %s: %w", build.Branch, repo.FullName, err)
+		}
+		return commit, nil
+	}
+
+	_, name := tagRef(build.Ref)
+	tag, err := c.getTag(ctx, user, owned, name)
+	if err != nil {
+		return "", fmt.Errorf("could not get the commit of tag %s of %s: %w", name, repo.FullName, err)
+	}
+	if tag.Commit == nil || tag.Commit.SHA == "" {
+		return "", fmt.Errorf("tag %s of %s has no commit", name, repo.FullName)
+	}
+	return tag.Commit.SHA, nil
+}
+
+// DeliveryID returns the id Gitea sent the hook with, which is kept when Gitea
+// sends the hook again.
+func (c *Gitea) DeliveryID(r *http.Request) string {
+	return r.Header.Get(hookDelivery)
+}
+
+// HookRepo returns the full name of the repository the hook was sent for.
+func (c *Gitea) HookRepo(r *http.Request) (string, string) {
+	repo, err := hookRepoOf(r)
+	if err != nil {
+		return "", ""
+	}
+	return c.Name, repo.FullName
+}
+
+// ParseHook parses the incoming Gitea hook like Hook, but neither verifies
+// its signature nor queries Gitea for rebuilt pull requests or changed files.
+func (c *Gitea) ParseHook(ctx context.Context, r *http.Request) (*remote.ParsedHook, error) {
+	if _, err := readHookPayload(r); err != nil {
+		return nil, err
+	}
+	repo, build, err := c.readHook(ctx, r)
+	if err != nil && !errors.Is(err, remote.ErrRepoDeleted) {
+		return nil, err
+	}
+
+	if build != nil {
+		build.Avatar = c.fallbackAvatar(build.Avatar, build.Email)
+		c.mapEvent(build)
+	}
+	return &remote.ParsedHook{Repo: repo, Build: build}, nil
+}
+
+// mapEvent replaces the event of the build by the configured one. Pull requests
+// from forks keep their event, so they never get the secrets of other events.
+func (c *Gitea) mapEvent(build *model.Build) {
+	event, ok := c.EventMapping[build.Event]
+	if !ok || (build.Event == model.EventPull && build.IsFork) {
+		return
+	}
+	build.Event = event
 }
 
-// Hook parses the incoming Gitea hook and returns the Repository and Build
-// details. If the hook is unsupported nil values are returned.
-func (c *Gitea) Hook(ctx context.Context, r *http.Request) (*model.Repo, *model.Build, error) {
-	return parseHook(r)
+// parseEventMapping parses a list of mappings of the form from=to. Only known
+// events can be mapped, but to any event name.
+func parseEventMapping(mappings []string) (map[model.WebhookEvent]model.WebhookEvent, error) {
+	mapping := make(map[model.WebhookEvent]model.WebhookEvent, len(mappings))
+	for _, m := range mappings {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid event mapping %q, expected from=to", m)
+		}
+		from := model.WebhookEvent(strings.TrimSpace(parts[0]))
+		if !model.ValidateWebhookEvent(from) {
+			return nil, fmt.Errorf("invalid event mapping %q: unknown event %s", m, from)
+		}
+		mapping[from] = model.WebhookEvent(strings.TrimSpace(parts[1]))
+	}
+	return mapping, nil
+}
+
+// readHook parses the hook whose body was replaced by its payload with
+// readHookPayload.
+func (c *Gitea) readHook(ctx context.Context, r *http.Request) (*model.Repo, *model.Build, error) {
+	repo, build, err := c.parseHook(ctx, r)
+	if err != nil && !errors.Is(err, remote.ErrRepoDeleted) {
+		return nil, nil, err
+	}
+	if repo != nil {
+		repo.Remote = c.Name
+	}
+	if err != nil {
+		return repo, nil, err
+	}
+	return repo, build, nil
+}
+
+// rebuildPullRequest completes the build of a rebuild command with the current
+// state of the pull request. Nil is returned if the pull request is not open.
+func (c *Gitea) rebuildPullRequest(ctx context.Context, repo *model.Repo, build *model.Build) (*model.Build, error) {
+	index, err := strconv.ParseInt(strings.Split(build.Ref, "/")[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	client, repo, err := c.newClientRepoOwner(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, resp, err := client.GetPullRequest(repo.Owner, repo.Name, index)
+	if err != nil {
+		return nil, scopeError(resp, err, scopeReadRepository)
+	}
+	if pr.State != gitea.StateOpen {
+		return nil, nil
+	}
+
+	return c.buildFromPullRequestRebuild(build, pr), nil
+}
+
+// newClientRepoOwner returns a client authenticated as the owner of the
+// repository, which is looked up in the store of the context.
+func (c *Gitea) newClientRepoOwner(ctx context.Context, repo *model.Repo) (*gitea.Client, *model.Repo, error) {
+	user, repo, err := c.repoOwner(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := c.newClientUser(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, repo, nil
+}
+
+// repoOwner returns the owner and the stored repo of a repo of the instance
+// from the store in the context.
+func (c *Gitea) repoOwner(ctx context.Context, repo *model.Repo) (*model.User, *model.Repo, error) {
+	_store, ok := store.TryFromContext(ctx)
+	if !ok {
+		return nil, nil, fmt.Errorf("could not get store from context")
+	}
+
+	repo, err := _store.GetRepoRemoteName(c.Name, repo.FullName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := _store.GetUser(repo.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, repo, nil
+}
+
+// verifyHook verifies the signature of the hook payload before it is decoded.
+// Repository hooks are sent by organization hooks and signed with their
+// secret, all others with the secret of the repository they name.
+func (c *Gitea) verifyHook(ctx context.Context, r *http.Request, payload []byte) error {
+	if r.Header.Get(hookEvent) == hookRepository {
+		return c.checkOrgSignature(payload, r.Header)
+	}
+	repo, err := hookRepoOfPayload(payload)
+	if err != nil || repo.FullName == "" {
+		return fmt.Errorf("%w: hook names no repository", remote.ErrInvalidSignature)
+	}
+	return c.checkSignature(ctx, r, repo.FullName, payload)
+}
+
+// hookTokenRepo returns the stored repo named by the hook token of the
+// request, which may be signed with the previous hash of the repo shortly
+// after rotating it. The body of the request is not read.
+func (c *Gitea) hookTokenRepo(r *http.Request, _store store.Store) (*model.Repo, error) {
+	req := r.Clone(r.Context())
+	req.Body = http.NoBody
+
+	var repo *model.Repo
+	_, err := token.ParseRequest(req, func(t *token.Token) (string, error) {
+		var err error
+		if repo, err = _store.GetRepoRemoteName(c.Name, t.Text); err != nil {
+			return "", err
+		}
+		return repo.Hash, nil
+	})
+	if err != nil && repo != nil {
+		for _, hash := range repo.Hashes(time.Now())[1:] {
+			if _, err = token.ParseRequest(req, func(*token.Token) (string, error) {
+				return hash, nil
+			}); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// checkOrgSignature verifies the hook was signed with the secret of the
+// organization hooks. Without secret all hooks are rejected.
+func (c *Gitea) checkOrgSignature(body []byte, header http.Header) error {
+	if c.OrgHookSecret == "" {
+		return fmt.Errorf("%w: no organization hook secret configured", remote.ErrInvalidSignature)
+	}
+	for _, sig := range hookSignaturesOf(header) {
+		if verifySignature(sig.newHash, c.OrgHookSecret, body, sig.sig) {
+			return nil
+		}
+	}
+	return remote.ErrInvalidSignature
+}
+
+// checkSignature verifies the hook was signed with the secret registered when
+// activating the repository, or with the previous one shortly after rotating it.
+// Any of the signatures returned by hookSignaturesOf has to match.
+func (c *Gitea) checkSignature(ctx context.Context, r *http.Request, name string, body []byte) error {
+	_store, ok := store.TryFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: no store to verify the hook of %s", remote.ErrInvalidSignature, name)
+	}
+
+	repo, err := _store.GetRepoRemoteName(c.Name, name)
+	if err != nil {
+		// hooks of renamed or transferred repos still carry the token of
+		// the name the repo had when it was activated
+		if repo, err = c.hookTokenRepo(r, _store); err != nil {
+			return fmt.Errorf("%w: unknown repository %s", remote.ErrInvalidSignature, name)
+		}
+	}
+
+	if repo.Hash == "" {
+		return fmt.Errorf("%w: no secret to verify the hook of %s", remote.ErrInvalidSignature, repo.FullName)
+	}
+	sigs := hookSignaturesOf(r.Header)
+	for _, hash := range repo.Hashes(time.Now()) {
+		for _, sig := range sigs {
+			if verifySignature(sig.newHash, hash, body, sig.sig) {
+				return nil
+			}
+		}
+	}
+	return remote.ErrInvalidSignature
+}
+
+// getChangedFilesForPR returns the files changed by the commits of a pull
+// request. The Gitea API is queried with the token of the repository owner,
+// the result is cached per pull request head commit.
+func (c *Gitea) getChangedFilesForPR(ctx context.Context, repo *model.Repo, index int64, sha string) ([]string, error) {
+	key := fmt.Sprintf("%s#%d@%s", repo.FullName, index, sha)
+
+	c.changedFilesMu.Lock()
+	files, ok := c.changedFilesCache[key]
+	c.changedFilesMu.Unlock()
+	if ok {
+		return files, nil
+	}
+
+	client, repo, err := c.newClientRepoOwner(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	files = make([]string, 0)
+	page := 1
+	for {
+		commits, _, err := client.ListPullRequestCommits(repo.Owner, repo.Name, index, gitea.ListPullRequestCommitsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commit := range commits {
+			for _, file := range commit.Files {
+				files = append(files, file.Filename)
+			}
+		}
+
+		if len(commits) < perPage {
+			break
+		}
+		page++
+	}
+	files = utils.DedupStrings(files)
+
+	c.changedFilesMu.Lock()
+	if c.changedFilesCache == nil || len(c.changedFilesCache) >= changedFilesCacheSize {
+		c.changedFilesCache = make(map[string][]string, changedFilesCacheSize)
+	}
+	c.changedFilesCache[key] = files
+	c.changedFilesMu.Unlock()
+
+	return files, nil
+}
+
+// isProtectedBranch returns whether the branch of the repo is protected, as
+// reported by Gitea for the branch, so protections matching the branch by a
+// pattern count as well. It is nil if the branch could not be looked up.
+// Results are cached for a minute.
+func (c *Gitea) isProtectedBranch(ctx context.Context, repo *model.Repo, branch string) *bool {
+	key := repo.FullName + "@" + branch
+
+	c.protectionMu.Lock()
+	cached, ok := c.protectionCache[key]
+	c.protectionMu.Unlock()
+	if ok && time.Since(cached.fetched) < protectionTTL {
+		protected := cached.protected
+		return &protected
+	}
+
+	client, repo, err := c.newClientRepoOwner(ctx, repo)
+	if err != nil {
+		log.Debug().Err(err).Msgf("could not look up the protection of branch %s", branch)
+		return nil
+	}
+
+	b, _, err := client.GetRepoBranch(repo.Owner, repo.Name, branch)
+	if err != nil {
+		log.Debug().Err(err).Msgf("could not look up the protection of branch %s of %s", branch, repo.FullName)
+		return nil
+	}
+	protected := b.Protected
+
+	c.protectionMu.Lock()
+	if c.protectionCache == nil || len(c.protectionCache) >= protectionCacheSize {
+		c.protectionCache = make(map[string]*branchProtection, protectionCacheSize)
+	}
+	c.protectionCache[key] = &branchProtection{protected: protected, fetched: time.Now()}
+	c.protectionMu.Unlock()
+
+	return &protected
+}
+
+// senderTeams returns the names of the teams of the repository organization
+// the sender is a member of. It is empty if the sender is a member of no team
+// or the repository is owned by a user. Results are cached per user and
+// organization for some minutes.
+func (c *Gitea) senderTeams(ctx context.Context, repo *model.Repo, sender string) ([]string, error) {
+	key := strings.ToLower(repo.Owner + "/" + sender)
+
+	c.teamsMu.Lock()
+	cached, ok := c.teamsCache[key]
+	c.teamsMu.Unlock()
+	if ok && time.Since(cached.fetched) < teamsTTL {
+		return cached.teams, nil
+	}
+
+	client, repo, err := c.newClientRepoOwner(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]string, 0)
+	for page := 1; ; page++ {
+		orgTeams, resp, err := client.ListOrgTeams(repo.Owner, gitea.ListTeamsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// the owner is a user without teams
+			break
+		}
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadOrganization)
+		}
+
+		for _, team := range orgTeams {
+			member, err := isTeamMember(client, team.ID, sender)
+			if err != nil {
+				return nil, err
+			}
+			if member {
+				teams = append(teams, team.Name)
+			}
+		}
+
+		if len(orgTeams) < perPage {
+			break
+		}
+	}
+
+	c.teamsMu.Lock()
+	if c.teamsCache == nil || len(c.teamsCache) >= teamsCacheSize {
+		c.teamsCache = make(map[string]*userTeams, teamsCacheSize)
+	}
+	c.teamsCache[key] = &userTeams{teams: teams, fetched: time.Now()}
+	c.teamsMu.Unlock()
+
+	return teams, nil
+}
+
+// isTeamMember returns whether the user is a member of the team.
+func isTeamMember(client *gitea.Client, team int64, login string) (bool, error) {
+	_, resp, err := client.GetTeamMember(team, login)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, scopeError(resp, err, scopeReadOrganization)
+	}
+	return true, nil
+}
+
+// helper function returning whether the owner is an organization instead of
+// a user.
+func isOrg(client *gitea.Client, owner string) (bool, error) {
+	_, resp, err := client.GetOrg(owner)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, scopeError(resp, err, scopeReadOrganization)
+	}
+	return true, nil
+}
+
+// getChangedFilesForTag returns the files changed since the tag preceding the
+// tag, as listed by Gitea newest first. If there is no previous tag nil is
+// returned. The Gitea API is queried with the token of the repository owner.
+func (c *Gitea) getChangedFilesForTag(ctx context.Context, repo *model.Repo, tag, sha string) ([]string, error) {
+	user, repo, err := c.repoOwner(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.newClientUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := previousTag(client, repo, tag)
+	if err != nil || previous == "" {
+		return nil, err
+	}
+	return c.compareFiles(ctx, user, repo, previous, sha)
+}
+
+// getTagMessage returns the message of an annotated tag, or an empty message
+// for lightweight tags. The Gitea API is queried with the token of the
+// repository owner.
+func (c *Gitea) getTagMessage(ctx context.Context, repo *model.Repo, tag string) (string, error) {
+	user, repo, err := c.repoOwner(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := c.getTag(ctx, user, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	// Gitea reports the commit message for lightweight tags, which have no
+	// tag object of their own
+	if t.Commit == nil || t.ID == t.Commit.SHA {
+		return "", nil
+	}
+	return strings.TrimSpace(t.Message), nil
+}
+
+// getChangedFilesForPush returns the files changed between the commits before
+// and after a push. The Gitea API is queried with the token of the repository
+// owner.
+func (c *Gitea) getChangedFilesForPush(ctx context.Context, repo *model.Repo, before, after string) ([]string, error) {
+	user, repo, err := c.repoOwner(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.compareFiles(ctx, user, repo, before, after)
+}
+
+// CompareFiles returns the sorted files changed between the base and head
+// commit, capped to the maximum number of changed files.
+func (c *Gitea) CompareFiles(ctx context.Context, user *model.User, repo *model.Repo, base, head string) ([]string, bool, error) {
+	files, err := c.compareFiles(ctx, user, repo, base, head)
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Strings(files)
+	files, truncated := c.capChangedFiles(files)
+	return files, truncated, nil
+}
+
+// compareFiles returns the files changed by the commits between base and head.
+func (c *Gitea) compareFiles(ctx context.Context, user *model.User, repo *model.Repo, base, head string) ([]string, error) {
+	compare, err := c.compare(ctx, user, repo, base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, commit := range compare.Commits {
+		for _, file := range commit.Files {
+			files = append(files, file.Filename)
+		}
+	}
+	return utils.DedupStrings(files), nil
+}
+
+// compare returns the commits between base and head, newest first, using the
+// compare API, which is not covered by the Gitea SDK.
+func (c *Gitea) compare(ctx context.Context, user *model.User, repo *model.Repo, base, head string) (*compareResponse, error) {
+	compareURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/compare/%s...%s",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(repo.Owner),
+		url.PathEscape(repo.Name),
+		url.PathEscape(base),
+		url.PathEscape(head),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, compareURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+user.Token)
+
+	resp, err := c.newHTTPClientUser(ctx, user).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("could not compare %s with %s: %s", head, base, resp.Status)
+		return nil, scopeError(&gitea.Response{Response: resp}, err, scopeReadRepository)
+	}
+
+	compare := new(compareResponse)
+	if err := json.NewDecoder(resp.Body).Decode(compare); err != nil {
+		return nil, err
+	}
+	return compare, nil
+}
+
+// getTag returns the tag of the repository.
+func (c *Gitea) getTag(ctx context.Context, user *model.User, repo *model.Repo, tag string) (*gitea.Tag, error) {
+	// the SDK only supports getting a tag for Gitea 1.15 and later, although
+	// the endpoint exists since 1.12
+	tagURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/tags/%s",
+		strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(repo.Owner),
+		url.PathEscape(repo.Name),
+		url.PathEscape(tag),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tagURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+user.Token)
+
+	resp, err := c.newHTTPClientUser(ctx, user).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("could not get tag %s: %s", tag, resp.Status)
+		return nil, scopeError(&gitea.Response{Response: resp}, err, scopeReadRepository)
+	}
+
+	t := new(gitea.Tag)
+	if err := json.NewDecoder(resp.Body).Decode(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// previousTag returns the tag listed after the tag by Gitea, which lists tags
+// newest first. An empty name is returned for the first tag.
+func previousTag(client *gitea.Client, repo *model.Repo, tag string) (string, error) {
+	found := false
+	for page := 1; ; page++ {
+		tags, resp, err := client.ListRepoTags(repo.Owner, repo.Name, gitea.ListRepoTagsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return "", scopeError(resp, err, scopeReadRepository)
+		}
+
+		for _, t := range tags {
+			if found {
+				return t.Name, nil
+			}
+			found = t.Name == tag
+		}
+
+		if len(tags) < perPage {
+			return "", nil
+		}
+	}
+}
+
+// fallbackAvatar returns the avatar unchanged unless it is empty or the Gitea
+// default placeholder, in which case the configured fallback is returned.
+func (c *Gitea) fallbackAvatar(avatar, email string) string {
+	if avatar != "" && !isDefaultAvatar(avatar) {
+		return avatar
+	}
+	if c.AvatarGravatar && email != "" {
+		return gravatar(email, c.AvatarFallback)
+	}
+	if c.AvatarFallback != "" {
+		return c.AvatarFallback
+	}
+	return avatar
 }
 
 // helper function to return the Gitea client with Token
 func (c *Gitea) newClientToken(ctx context.Context, token string) (*gitea.Client, error) {
-	httpClient := &http.Client{}
-	if c.SkipVerify {
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	return gitea.NewClient(c.URL, gitea.SetToken(token), gitea.SetHTTPClient(c.newHTTPClient()), gitea.SetContext(ctx))
+}
+
+// helper function to return the Gitea client authenticated as the user. If
+// Gitea rejects the OAuth2 token as expired, it is refreshed and stored.
+func (c *Gitea) newClientUser(ctx context.Context, u *model.User) (*gitea.Client, error) {
+	return gitea.NewClient(c.URL, gitea.SetToken(u.Token), gitea.SetHTTPClient(c.newHTTPClientUser(ctx, u)), gitea.SetContext(ctx))
+}
+
+// helper function to return the http client used for requests of the user
+// not covered by the Gitea SDK.
+func (c *Gitea) newHTTPClientUser(ctx context.Context, u *model.User) *http.Client {
+	httpClient := c.newHTTPClient()
+	httpClient.Transport = &refreshTransport{
+		next: httpClient.Transport,
+		user: u,
+		refresh: func(u *model.User) error {
+			return c.refreshUser(ctx, u)
+		},
+	}
+	return httpClient
+}
+
+// refreshUser refreshes the OAuth2 token of the user and updates the user in
+// the store of the context. The returned error wraps remote.ErrInvalidToken.
+func (c *Gitea) refreshUser(ctx context.Context, u *model.User) error {
+	ok, err := c.Refresh(ctx, u)
+	if err != nil {
+		return fmt.Errorf("%w: could not refresh the token of %s: %s", remote.ErrInvalidToken, u.Login, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: could not refresh the token of %s", remote.ErrInvalidToken, u.Login)
+	}
+
+	if _store, ok := store.TryFromContext(ctx); ok {
+		if err := _store.UpdateUser(u); err != nil {
+			log.Error().Err(err).Msgf("could not store the refreshed token of %s", u.Login)
 		}
 	}
-	return gitea.NewClient(c.URL, gitea.SetToken(token), gitea.SetHTTPClient(httpClient), gitea.SetContext(ctx))
+	return nil
+}
+
+// helper function to return the http client used for Gitea API calls.
+func (c *Gitea) newHTTPClient() *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if c.transport != nil {
+		transport = c.transport
+	}
+	if c.limiter != nil {
+		transport = &rateLimitTransport{next: transport, limiter: c.limiter}
+	}
+	return &http.Client{
+		Timeout: c.Timeout,
+		Transport: &messageTransport{
+			next: &retryTransport{
+				next:    transport,
+				retries: c.Retries,
+				backoff: retryBackoff,
+			},
+		},
+	}
 }
 
 // getStatus is a helper function that converts a Woodpecker
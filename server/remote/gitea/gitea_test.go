@@ -15,15 +15,38 @@
 package gitea
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"code.gitea.io/sdk/gitea"
 	"github.com/franela/goblin"
 	"github.com/gin-gonic/gin"
 
+	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+	"github.com/woodpecker-ci/woodpecker/server/store"
+	"github.com/woodpecker-ci/woodpecker/shared/token"
 )
 
 func Test_gitea(t *testing.T) {
@@ -57,6 +80,23 @@ func Test_gitea(t *testing.T) {
 			})
 		})
 
+		g.Describe("Resolving a fallback avatar", func() {
+			g.It("Should keep existing avatars", func() {
+				remote, _ := New(Opts{AvatarFallback: "https://example.com/a.png"})
+				g.Assert(remote.(*Gitea).fallbackAvatar("http://gitea.io/avatars/1", "")).Equal("http://gitea.io/avatars/1")
+			})
+			g.It("Should return the fallback for empty and default avatars", func() {
+				remote, _ := New(Opts{AvatarFallback: "https://example.com/a.png"})
+				g.Assert(remote.(*Gitea).fallbackAvatar("", "")).Equal("https://example.com/a.png")
+				g.Assert(remote.(*Gitea).fallbackAvatar("http://gitea.io/assets/img/avatar_default.png", "")).Equal("https://example.com/a.png")
+			})
+			g.It("Should derive the fallback from the email", func() {
+				remote, _ := New(Opts{AvatarGravatar: true})
+				g.Assert(remote.(*Gitea).fallbackAvatar("", "gordon@golang.org")).Equal("https://www.gravatar.com/avatar/b86d5c587c72ff919ebf8cd47a923592")
+				g.Assert(remote.(*Gitea).fallbackAvatar("", "")).Equal("")
+			})
+		})
+
 		g.Describe("Generating a netrc file", func() {
 			g.It("Should return a netrc with the user token", func() {
 				remote, _ := New(Opts{})
@@ -72,6 +112,14 @@ func Test_gitea(t *testing.T) {
 				g.Assert(netrc.Login).Equal("")
 				g.Assert(netrc.Password).Equal("")
 			})
+			g.It("Should return a netrc with the additional hosts", func() {
+				remote, _ := New(Opts{NetrcHosts: []string{"gitea.com", "git.company.com", "codeberg.org"}})
+				netrc, _ := remote.Netrc(fakeUser, fakeRepo)
+				g.Assert(netrc.Machine).Equal("gitea.com")
+				g.Assert(netrc.Hosts).Equal([]string{"git.company.com", "codeberg.org"})
+				g.Assert(netrc.Login).Equal(fakeUser.Login)
+				g.Assert(netrc.Password).Equal(fakeUser.Token)
+			})
 		})
 
 		g.Describe("Requesting a repository", func() {
@@ -87,7 +135,21 @@ func Test_gitea(t *testing.T) {
 			})
 			g.It("Should handle a not found error", func() {
 				_, err := c.Repo(ctx, fakeUser, fakeRepoNotFound.Owner, fakeRepoNotFound.Name)
-				g.Assert(err).IsNotNil()
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+			g.It("Should only return the repository topics if enabled", func() {
+				repo, err := c.Repo(ctx, fakeUser, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(err).IsNil()
+				g.Assert(repo.Topics == nil).IsTrue()
+
+				topics, _ := New(Opts{URL: s.URL, FetchTopics: true})
+				repo, err = topics.Repo(ctx, fakeUser, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(err).IsNil()
+				g.Assert(repo.Topics).Equal([]string{"public", "go"})
+
+				repo, err = topics.Repo(ctx, fakeUser, fakeRepoEmpty.Owner, fakeRepoEmpty.Name)
+				g.Assert(err).IsNil()
+				g.Assert(repo.Topics).Equal([]string{})
 			})
 		})
 
@@ -105,6 +167,306 @@ func Test_gitea(t *testing.T) {
 			})
 		})
 
+		g.Describe("Listing the members of an organization", func() {
+			g.It("Should return all pages of members with their role", func() {
+				members, err := c.(remote.OrgMemberLister).OrgMembers(ctx, fakeUser, "test_org")
+				g.Assert(err).IsNil()
+				g.Assert(members.Restricted).IsFalse()
+				g.Assert(len(members.Members)).Equal(52)
+				g.Assert(*members.Members[0]).Equal(remote.OrgMember{Login: "member-1", Role: remote.OrgRoleOwner})
+				g.Assert(*members.Members[1]).Equal(remote.OrgMember{Login: "member-2", Role: remote.OrgRoleAdmin})
+				g.Assert(*members.Members[2]).Equal(remote.OrgMember{Login: "member-3", Role: remote.OrgRoleMember})
+				g.Assert(members.Members[51].Login).Equal("member-52")
+			})
+			g.It("Should only return the public members to non members", func() {
+				members, err := c.(remote.OrgMemberLister).OrgMembers(ctx, fakeUser, "restricted_org")
+				g.Assert(err).IsNil()
+				g.Assert(members.Restricted).IsTrue()
+				g.Assert(len(members.Members)).Equal(2)
+				g.Assert(*members.Members[1]).Equal(remote.OrgMember{Login: "member-7"})
+			})
+		})
+
+		g.Describe("Listing the collaborators of a repository", func() {
+			g.It("Should return the collaborators with their permission", func() {
+				repo := &model.Repo{Owner: "gordon", Name: "hello-world"}
+				collaborators, err := c.(remote.CollaboratorLister).Collaborators(ctx, fakeUser, repo)
+				g.Assert(err).IsNil()
+				g.Assert(len(collaborators)).Equal(4)
+				perms := make(map[string]model.Perm)
+				for _, collaborator := range collaborators {
+					perms[collaborator.Login] = *collaborator.Perm
+				}
+				g.Assert(perms["gordon"]).Equal(model.Perm{Pull: true, Push: true, Admin: true})
+				g.Assert(perms["octocat"]).Equal(model.Perm{Pull: true, Push: true})
+				g.Assert(perms["alice"]).Equal(model.Perm{Pull: true})
+				g.Assert(perms["bob"]).Equal(model.Perm{Pull: true, Push: true, Admin: true})
+			})
+			g.It("Should return the owner as admin", func() {
+				repo := &model.Repo{Owner: "Gordon", Name: "hello-world"}
+				collaborators, err := c.(remote.CollaboratorLister).Collaborators(ctx, fakeUser, repo)
+				g.Assert(err).IsNil()
+				g.Assert(collaborators[0].Login).Equal("Gordon")
+				g.Assert(collaborators[0].Perm.Admin).IsTrue()
+			})
+			g.It("Should not return the organization owning the repository", func() {
+				repo := &model.Repo{Owner: "woodpecker-ci", Name: "hello-world"}
+				collaborators, err := c.(remote.CollaboratorLister).Collaborators(ctx, fakeUser, repo)
+				g.Assert(err).IsNil()
+				g.Assert(len(collaborators)).Equal(4)
+				for _, collaborator := range collaborators {
+					g.Assert(collaborator.Login == "woodpecker-ci").IsFalse()
+				}
+			})
+			g.It("Should fail as forbidden if the collaborators can not be listed", func() {
+				_, err := c.(remote.CollaboratorLister).Collaborators(ctx, fakeUser, fakeRepo)
+				g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+				g.Assert(errors.Is(err, remote.ErrInsufficientScope)).IsFalse()
+			})
+		})
+
+		g.Describe("Listing the public keys of a user", func() {
+			g.It("Should return all pages of GPG and SSH keys", func() {
+				keys, err := c.(remote.KeyLister).UserKeys(ctx, fakeUser, "gordon")
+				g.Assert(err).IsNil()
+				g.Assert(len(keys.GPG)).Equal(2)
+				g.Assert(*keys.GPG[0]).Equal(remote.GPGKey{
+					KeyID:     "3AA5C34371567BD2",
+					SubKeyIDs: []string{"4BB6D45482678CE3"},
+					PublicKey: "-----BEGIN PGP PUBLIC KEY BLOCK-----",
+					Emails:    []string{"gordon@golang.org"},
+					CanSign:   true,
+				})
+				g.Assert(keys.GPG[1].KeyID).Equal("5CC7E56593789DF4")
+				g.Assert(keys.GPG[1].SubKeyIDs == nil).IsTrue()
+				g.Assert(len(keys.SSH)).Equal(52)
+				g.Assert(*keys.SSH[0]).Equal(remote.SSHKey{Title: "key-1", Key: "ssh-ed25519 AAAA1", Fingerprint: "SHA256:1"})
+				g.Assert(keys.SSH[51].Title).Equal("key-52")
+			})
+			g.It("Should return empty lists for users without keys", func() {
+				keys, err := c.(remote.KeyLister).UserKeys(ctx, fakeUser, "octocat")
+				g.Assert(err).IsNil()
+				g.Assert(keys.GPG).Equal([]*remote.GPGKey{})
+				g.Assert(keys.SSH).Equal([]*remote.SSHKey{})
+			})
+			g.It("Should cache the keys per user", func() {
+				remote, _ := New(Opts{URL: s.URL})
+				cached := remote.(*Gitea)
+				first, err := cached.UserKeys(ctx, fakeUser, "gordon")
+				g.Assert(err).IsNil()
+				second, err := cached.UserKeys(ctx, fakeUser, "Gordon")
+				g.Assert(err).IsNil()
+				g.Assert(first == second).IsTrue()
+				cached.userKeysCache["gordon"].fetched = time.Now().Add(-userKeysTTL)
+				third, err := cached.UserKeys(ctx, fakeUser, "gordon")
+				g.Assert(err).IsNil()
+				g.Assert(first == third).IsFalse()
+			})
+			g.It("Should fail for unknown users", func() {
+				_, err := c.(remote.KeyLister).UserKeys(ctx, fakeUser, "unknown")
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Gitea hosted under a sub path", func() {
+			sub := httptest.NewServer(http.StripPrefix("/git", fixtures.Handler()))
+			g.After(func() {
+				sub.Close()
+			})
+			c, _ := New(Opts{URL: sub.URL + "/git/"})
+
+			g.It("Should call the API below the sub path", func() {
+				repo, err := c.Repo(ctx, fakeUser, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(err).IsNil()
+				g.Assert(repo.FullName).Equal(fakeRepo.FullName)
+
+				raw, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, ".woodpecker.yml")
+				g.Assert(err).IsNil()
+				g.Assert(string(raw)).Equal("{ platform: linux/amd64 }")
+			})
+			g.It("Should redirect to the authorization below the sub path", func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/authorize", nil)
+				_, err := c.Login(ctx, w, req)
+				g.Assert(err).IsNil()
+				g.Assert(strings.HasPrefix(w.Header().Get("Location"), sub.URL+"/git/login/oauth/authorize?")).IsTrue()
+			})
+		})
+
+		g.Describe("Fetching the avatar of a user", func() {
+			avatar := "/avatars/1"
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/user" {
+					_, _ = fmt.Fprintf(w, `{"id": 1, "login": "someuser", "avatar_url": %q}`, avatar)
+					return
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+
+			g.It("Should expand the avatar", func() {
+				avatar = "/avatars/1"
+				c, _ := New(Opts{URL: recorder.URL})
+				url, err := c.(remote.AvatarFetcher).Avatar(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(url).Equal(recorder.URL + "/avatars/1")
+			})
+			g.It("Should fix a malformed avatar", func() {
+				avatar = recorder.URL + "///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87"
+				c, _ := New(Opts{URL: recorder.URL})
+				url, err := c.(remote.AvatarFetcher).Avatar(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(url).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
+			})
+			g.It("Should not return the fallback for the default avatar", func() {
+				avatar = "/assets/img/avatar_default.png"
+				c, _ := New(Opts{URL: recorder.URL, AvatarFallback: "https://example.com/avatar.png"})
+				url, err := c.(remote.AvatarFetcher).Avatar(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(url).Equal("")
+			})
+			g.It("Should fail for invalid tokens", func() {
+				_, err := c.(remote.AvatarFetcher).Avatar(ctx, &model.User{Token: "invalid"})
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Posting a build summary", func() {
+			var mu sync.Mutex
+			var comments []*gitea.Comment
+			var created, edited, deleted int
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				prefix := "/api/v1/repos/test_name/repo_name/issues/"
+				if !strings.HasPrefix(r.URL.Path, prefix) {
+					fixtures.Handler().ServeHTTP(w, r)
+					return
+				}
+				var opt gitea.EditIssueCommentOption
+				_ = json.NewDecoder(r.Body).Decode(&opt)
+				path := strings.TrimPrefix(r.URL.Path, prefix)
+				switch {
+				case path == "1/comments" && r.Method == "GET":
+					_ = json.NewEncoder(w).Encode(comments)
+				case strings.HasSuffix(path, "/comments") && r.Method == "GET":
+					_, _ = w.Write([]byte("[]"))
+				case path == "1/comments" && r.Method == "POST":
+					created++
+					comment := &gitea.Comment{ID: int64(len(comments) + 1), Body: opt.Body, Poster: &gitea.User{UserName: "someuser"}}
+					comments = append(comments, comment)
+					_ = json.NewEncoder(w).Encode(comment)
+				case strings.HasPrefix(path, "comments/"):
+					id, _ := strconv.ParseInt(strings.TrimPrefix(path, "comments/"), 10, 64)
+					for i, comment := range comments {
+						if comment.ID != id {
+							continue
+						}
+						if r.Method == "DELETE" {
+							deleted++
+							comments = append(comments[:i], comments[i+1:]...)
+							w.WriteHeader(204)
+							return
+						}
+						edited++
+						comment.Body = opt.Body
+						_ = json.NewEncoder(w).Encode(comment)
+						return
+					}
+					w.WriteHeader(404)
+				default:
+					w.WriteHeader(404)
+				}
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				mu.Lock()
+				defer mu.Unlock()
+				comments = []*gitea.Comment{{ID: 1, Body: "LGTM", Poster: &gitea.User{UserName: "gordon"}}}
+				created, edited, deleted = 0, 0, 0
+			})
+
+			build := &model.Build{
+				Number:      2,
+				Event:       model.EventPull,
+				Status:      model.StatusFailure,
+				Ref:         "refs/pull/1/head",
+				PullRequest: 1,
+				Commit:      "9ecad50aef1e2c0c0a1bc2d4d9d7a1cde5a6e3f1",
+				Procs: []*model.Proc{
+					{PID: 1, Name: "build", State: model.StatusSuccess},
+					{PID: 2, PPID: 1, Name: "compile", State: model.StatusSuccess},
+					{PID: 3, Name: "test", State: model.StatusFailure},
+					{PID: 4, PPID: 3, Name: "unit", State: model.StatusFailure},
+				},
+			}
+
+			g.It("Should create the summary of the first build", func() {
+				c, _ := New(Opts{URL: recorder.URL, Summary: true})
+				err := c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, build)
+				g.Assert(err).IsNil()
+				g.Assert(created).Equal(1)
+				g.Assert(edited).Equal(0)
+				g.Assert(len(comments)).Equal(2)
+				g.Assert(strings.HasPrefix(comments[1].Body, "<!-- woodpecker-summary -->\n")).IsTrue()
+				g.Assert(strings.Contains(comments[1].Body, "[#2](/test_name/repo_name/build/2) of 9ecad50a: failure")).IsTrue()
+				g.Assert(strings.Contains(comments[1].Body, "| [build](/test_name/repo_name/build/2) | success |")).IsTrue()
+				g.Assert(strings.Contains(comments[1].Body, "| [test](/test_name/repo_name/build/2/4) | failure |")).IsTrue()
+				g.Assert(strings.Contains(comments[1].Body, "compile")).IsFalse()
+			})
+			g.It("Should update the summary of later builds", func() {
+				c, _ := New(Opts{URL: recorder.URL, Summary: true, SummaryTemplate: "build {{ .number }} {{ .status }}"})
+				err := c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, build)
+				g.Assert(err).IsNil()
+
+				next := *build
+				next.Number = 3
+				next.Status = model.StatusSuccess
+				err = c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, &next)
+				g.Assert(err).IsNil()
+				g.Assert(created).Equal(1)
+				g.Assert(edited).Equal(1)
+				g.Assert(len(comments)).Equal(2)
+				g.Assert(comments[0].Body).Equal("LGTM")
+				g.Assert(comments[1].Body).Equal("<!-- woodpecker-summary -->\nbuild 3 success")
+
+				err = c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, &next)
+				g.Assert(err).IsNil()
+				g.Assert(edited).Equal(1)
+			})
+			g.It("Should not summarize unfinished builds and other events", func() {
+				c, _ := New(Opts{URL: recorder.URL, Summary: true})
+				running := *build
+				running.Status = model.StatusRunning
+				g.Assert(c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, &running)).IsNil()
+				push := *build
+				push.Event = model.EventPush
+				g.Assert(c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, &push)).IsNil()
+				g.Assert(created).Equal(0)
+			})
+			g.It("Should not summarize builds unless enabled", func() {
+				c, _ := New(Opts{URL: recorder.URL})
+				g.Assert(c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, build)).IsNil()
+				g.Assert(created).Equal(0)
+			})
+			g.It("Should reject invalid templates", func() {
+				_, err := New(Opts{URL: recorder.URL, Summary: true, SummaryTemplate: "{{ .number"})
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should delete the summary when deactivating the repository", func() {
+				c, _ := New(Opts{URL: recorder.URL, Summary: true})
+				g.Assert(c.(remote.BuildSummarizer).Summary(ctx, fakeUser, fakeRepo, build)).IsNil()
+				g.Assert(c.Deactivate(ctx, fakeUser, fakeRepo, "http://localhost")).IsNil()
+				g.Assert(deleted).Equal(1)
+				g.Assert(len(comments)).Equal(1)
+				g.Assert(comments[0].Body).Equal("LGTM")
+			})
+		})
+
 		g.Describe("Requesting a repository list", func() {
 			g.It("Should return the repository list", func() {
 				repos, err := c.Repos(ctx, fakeUser)
@@ -119,6 +481,91 @@ func Test_gitea(t *testing.T) {
 			})
 		})
 
+		g.Describe("Syncing a repository list in pages", func() {
+			const total = 5
+			var (
+				requests []string
+				limited  bool
+				failPage string
+			)
+			// lists at most 2 repositories per page, like a Gitea with a
+			// capped page size
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/user/repos" {
+					fixtures.Handler().ServeHTTP(w, r)
+					return
+				}
+				requests = append(requests, r.URL.Query().Get("page")+"/"+r.URL.Query().Get("limit"))
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				if page == 2 && !limited {
+					limited = true
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				if r.URL.Query().Get("page") == failPage {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				repos := []string{}
+				for i := (page-1)*2 + 1; i <= page*2 && i <= total; i++ {
+					repos = append(repos, fmt.Sprintf(`{"id":%d,"name":"repo_%d","full_name":"test_name/repo_%d","owner":{"login":"test_name"}}`, i, i, i))
+				}
+				w.Header().Set("X-Total-Count", strconv.Itoa(total))
+				_, _ = io.WriteString(w, "["+strings.Join(repos, ",")+"]")
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				requests, limited, failPage = nil, true, ""
+			})
+
+			g.It("Should list all pages with the configured page size", func() {
+				c, _ := New(Opts{URL: recorder.URL, RepoPageSize: 2})
+				repos, err := c.Repos(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(len(repos)).Equal(total)
+				g.Assert(repos[4].FullName).Equal("test_name/repo_5")
+				g.Assert(requests).Equal([]string{"1/2", "2/2", "3/2"})
+			})
+			g.It("Should list all pages if Gitea caps the page size", func() {
+				c, _ := New(Opts{URL: recorder.URL, RepoPageSize: 100})
+				repos, err := c.Repos(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(len(repos)).Equal(total)
+				g.Assert(len(requests)).Equal(3)
+			})
+			g.It("Should wait for the rate limit to reset", func() {
+				limited = false
+				c, _ := New(Opts{URL: recorder.URL, RepoPageSize: 2, Retries: 1})
+				repos, err := c.Repos(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(len(repos)).Equal(total)
+				g.Assert(requests).Equal([]string{"1/2", "2/2", "2/2", "3/2"})
+			})
+			g.It("Should return the repositories synced before an error", func() {
+				failPage = "3"
+				c, _ := New(Opts{URL: recorder.URL, RepoPageSize: 2})
+				repos, err := c.Repos(ctx, fakeUser)
+				g.Assert(err).IsNotNil()
+				g.Assert(len(repos)).Equal(4)
+			})
+		})
+
+		g.Describe("Requesting branches", func() {
+			g.It("Should return the branches with the default branch first", func() {
+				branches, err := c.Branches(ctx, fakeUser, fakeRepo)
+				g.Assert(err).IsNil()
+				g.Assert(branches).Equal([]string{"master", "develop"})
+			})
+			g.It("Should return no branches of an empty repository", func() {
+				branches, err := c.Branches(ctx, fakeUser, fakeRepoEmpty)
+				g.Assert(err).IsNil()
+				g.Assert(branches).Equal([]string{})
+			})
+		})
+
 		g.It("Should register repository hooks", func() {
 			err := c.Activate(ctx, fakeUser, fakeRepo, "http://localhost")
 			g.Assert(err).IsNil()
@@ -135,11 +582,1329 @@ func Test_gitea(t *testing.T) {
 			g.Assert(string(raw)).Equal("{ platform: linux/amd64 }")
 		})
 
+		g.It("Should return the files of a repository folder", func() {
+			files, err := c.Dir(ctx, fakeUser, fakeRepo, fakeBuild, ".woodpecker")
+			g.Assert(err).IsNil()
+			g.Assert(len(files)).Equal(2)
+			g.Assert(files[0].Name).Equal(".woodpecker/build.yml")
+			g.Assert(files[1].Name).Equal(".woodpecker/test.yml")
+			g.Assert(string(files[0].Data)).Equal("{ platform: linux/amd64 }")
+		})
+
+		g.It("Should decode the content of a repository file", func() {
+			raw, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, "base64.yml")
+			g.Assert(err).IsNil()
+			g.Assert(string(raw)).Equal("{ platform: linux/arm64 }")
+
+			raw, err = c.File(ctx, fakeUser, fakeRepo, fakeBuild, "plain.yml")
+			g.Assert(err).IsNil()
+			g.Assert(string(raw)).Equal("{ platform: linux/arm64 }")
+		})
+
+		g.It("Should fail for repository files which are not valid UTF-8", func() {
+			_, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, "binary.yml")
+			g.Assert(err).IsNotNil()
+			g.Assert(err.Error()).Equal("config binary.yml is not valid UTF-8")
+		})
+
+		g.It("Should follow a symlinked repository file", func() {
+			raw, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, "link.yml")
+			g.Assert(err).IsNil()
+			g.Assert(string(raw)).Equal("{ platform: linux/amd64 }")
+		})
+
+		g.It("Should return a not found error for a missing file", func() {
+			_, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, "file_not_found")
+			g.Assert(errors.Is(err, remote.ErrFileNotFound)).IsTrue()
+		})
+
+		g.It("Should return a file of another repository", func() {
+			raw, err := c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "templates", "v1.0.0", ".woodpecker.yml")
+			g.Assert(err).IsNil()
+			g.Assert(string(raw)).Equal("{ platform: linux/amd64 }")
+		})
+
+		g.It("Should only return files at a commit sha or a tag", func() {
+			_, err := c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "templates", "refs/tags/v1.0.0", ".woodpecker.yml")
+			g.Assert(err).IsNil()
+			_, err = c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "templates", "4b2626259b5a97b6b4eab5e6cca66adb986b672b", ".woodpecker.yml")
+			g.Assert(err).IsNil()
+			_, err = c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "templates", "master", ".woodpecker.yml")
+			g.Assert(errors.Is(err, remote.ErrUnpinnedRef)).IsTrue()
+		})
+
+		g.It("Should fail for files of missing or unreadable repositories", func() {
+			_, err := c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "repo_not_found", "v1.0.0", ".woodpecker.yml")
+			g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			_, err = c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "repo_no_access", "v1.0.0", ".woodpecker.yml")
+			g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+			_, err = c.(remote.RepoFileFetcher).RepoFile(ctx, fakeUser, "gordon", "templates", "v1.0.0", "file_not_found")
+			g.Assert(errors.Is(err, remote.ErrFileNotFound)).IsTrue()
+		})
+
+		g.It("Should fail for files exceeding the maximum size", func() {
+			_, err := c.File(ctx, fakeUser, fakeRepo, fakeBuild, "large.yml")
+			g.Assert(err).IsNotNil()
+			g.Assert(errors.Is(err, remote.ErrFileNotFound)).IsFalse()
+
+			small, _ := New(Opts{URL: s.URL, SkipVerify: true, MaxFileSize: 10})
+			_, err = small.File(ctx, fakeUser, fakeRepo, fakeBuild, ".woodpecker.yml")
+			g.Assert(err).IsNotNil()
+		})
+
+		g.It("Should return all teams sorted and deduplicated", func() {
+			teams, err := c.Teams(ctx, fakeUser)
+			g.Assert(err).IsNil()
+			g.Assert(len(teams)).Equal(51)
+			g.Assert(teams[0].Login).Equal("aaa")
+			g.Assert(teams[1].Login).Equal("org-00")
+			g.Assert(teams[2].Login).Equal("org-01")
+			g.Assert(teams[50].Login).Equal("org-49")
+		})
+
+		g.Describe("Requesting the head of a branch", func() {
+			g.It("Should return the latest commit", func() {
+				commit, err := c.(remote.BranchHeadResolver).BranchHead(ctx, fakeUser, fakeRepo, "master")
+				g.Assert(err).IsNil()
+				g.Assert(commit).Equal("f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+			})
+			g.It("Should handle a not found error", func() {
+				_, err := c.(remote.BranchHeadResolver).BranchHead(ctx, fakeUser, fakeRepo, "missing")
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.It("Should return the delivery id of a hook", func() {
+			req, _ := http.NewRequest("POST", "/hook", strings.NewReader(fixtures.HookPush))
+			g.Assert(c.(remote.DeliveryIdentifier).DeliveryID(req)).Equal("")
+			req.Header.Set(hookDelivery, "f6266f16-1bf3-46a5-9ea4-602e06ead473")
+			g.Assert(c.(remote.DeliveryIdentifier).DeliveryID(req)).Equal("f6266f16-1bf3-46a5-9ea4-602e06ead473")
+		})
+
+		g.It("Should return the repository of a hook without consuming it", func() {
+			req, _ := http.NewRequest("POST", "/hook", strings.NewReader(fixtures.HookPush))
+			_, name := c.(remote.HookRepoNamer).HookRepo(req)
+			g.Assert(name).Equal("gordon/hello-world")
+			body, _ := io.ReadAll(req.Body)
+			g.Assert(string(body)).Equal(fixtures.HookPush)
+
+			form := url.Values{"payload": {fixtures.HookPush}}.Encode()
+			req, _ = http.NewRequest("POST", "/hook", strings.NewReader(form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			_, name = c.(remote.HookRepoNamer).HookRepo(req)
+			g.Assert(name).Equal("gordon/hello-world")
+
+			req, _ = http.NewRequest("POST", "/hook", strings.NewReader("{"))
+			_, name = c.(remote.HookRepoNamer).HookRepo(req)
+			g.Assert(name).Equal("")
+		})
+
+		g.Describe("Resolving a commit", func() {
+			g.It("Should resolve a short sha", func() {
+				commit, err := c.(remote.CommitResolver).CommitInfo(ctx, fakeUser, fakeRepo, "f05f642")
+				g.Assert(err).IsNil()
+				g.Assert(commit.SHA).Equal("f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+				g.Assert(commit.Message).Equal("Update the readme\n")
+				g.Assert(commit.Author).Equal("gordon")
+				g.Assert(commit.Email).Equal("gordon@golang.org")
+				g.Assert(commit.Avatar).Equal("http://gitea.golang.org/avatars/1")
+				g.Assert(commit.Link).Equal("http://gitea.golang.org/test_name/repo_name/commit/f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+				g.Assert(commit.Timestamp).Equal(int64(1646389230))
+			})
+			g.It("Should resolve a branch", func() {
+				commit, err := c.(remote.CommitResolver).CommitInfo(ctx, fakeUser, fakeRepo, "master")
+				g.Assert(err).IsNil()
+				g.Assert(commit.SHA).Equal("f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+			})
+			g.It("Should return a not found error for unknown refs", func() {
+				_, err := c.(remote.CommitResolver).CommitInfo(ctx, fakeUser, fakeRepo, "missing")
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+		})
+
+		g.Describe("Pinging the remote", func() {
+			g.It("Should return the authenticated user", func() {
+				res, err := c.(remote.Pinger).Ping(ctx, fakeUser)
+				g.Assert(err).IsNil()
+				g.Assert(res.Login).Equal("someuser")
+			})
+			g.It("Should succeed without user", func() {
+				res, err := c.(remote.Pinger).Ping(ctx, nil)
+				g.Assert(err).IsNil()
+				g.Assert(res.Login).Equal("")
+			})
+			g.It("Should return an invalid token error", func() {
+				_, err := c.(remote.Pinger).Ping(ctx, &model.User{Token: "invalid"})
+				g.Assert(errors.Is(err, remote.ErrInvalidToken)).IsTrue()
+			})
+			g.It("Should return an unreachable error", func() {
+				unreachable := httptest.NewServer(nil)
+				unreachable.Close()
+				c, _ := New(Opts{URL: unreachable.URL})
+				_, err := c.(remote.Pinger).Ping(ctx, fakeUser)
+				g.Assert(errors.Is(err, remote.ErrUnreachable)).IsTrue()
+			})
+		})
+
+		g.Describe("Requesting the capabilities", func() {
+			g.It("Should return the capabilities of the server", func() {
+				capabilities, err := c.(remote.CapabilitiesProvider).Capabilities(ctx)
+				g.Assert(err).IsNil()
+				g.Assert(capabilities.SupportsReviews).IsTrue()
+				g.Assert(capabilities.SupportsStatusPerStep).IsFalse()
+			})
+			g.It("Should support statuses per step if enabled", func() {
+				c, _ := New(Opts{URL: s.URL, StepStatuses: true})
+				capabilities, err := c.(remote.CapabilitiesProvider).Capabilities(ctx)
+				g.Assert(err).IsNil()
+				g.Assert(capabilities.SupportsStatusPerStep).IsTrue()
+			})
+			g.It("Should map versions to capabilities", func() {
+				capabilities, err := capabilitiesForVersion("1.6.4")
+				g.Assert(err).IsNil()
+				g.Assert(*capabilities).Equal(remote.Capabilities{})
+
+				capabilities, err = capabilitiesForVersion("1.8.0+dev-12-g1234567")
+				g.Assert(err).IsNil()
+				g.Assert(*capabilities).Equal(remote.Capabilities{SupportsReleases: true, SupportsDraftPulls: true})
+
+				capabilities, err = capabilitiesForVersion("1.9.0-rc1")
+				g.Assert(err).IsNil()
+				g.Assert(capabilities.SupportsReviews).IsTrue()
+			})
+			g.It("Should fail for invalid versions", func() {
+				_, err := capabilitiesForVersion("development")
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Requesting the server version", func() {
+			var requests int32
+			mock := fixtures.Handler()
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/version" {
+					atomic.AddInt32(&requests, 1)
+				}
+				mock.ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				s.Close()
+			})
+			g.BeforeEach(func() {
+				atomic.StoreInt32(&requests, 0)
+			})
+
+			// the Gitea SDK requests the version itself when creating a client,
+			// so every fetch may cost more than one request
+			var perFetch int32
+			g.Before(func() {
+				c, _ := New(Opts{URL: s.URL})
+				_, _ = c.(*Gitea).serverVersion(ctx)
+				perFetch = atomic.LoadInt32(&requests)
+			})
+
+			g.It("Should cache the version", func() {
+				c, _ := New(Opts{URL: s.URL})
+				for i := 0; i < 3; i++ {
+					v, err := c.(*Gitea).serverVersion(ctx)
+					g.Assert(err).IsNil()
+					g.Assert(v).Equal("1.12")
+				}
+				g.Assert(atomic.LoadInt32(&requests)).Equal(perFetch)
+			})
+			g.It("Should request the version again once the cache expired", func() {
+				c, _ := New(Opts{URL: s.URL})
+				_, _ = c.(*Gitea).serverVersion(ctx)
+				c.(*Gitea).versionFetched = time.Now().Add(-serverVersionTTL)
+				v, err := c.(*Gitea).serverVersion(ctx)
+				g.Assert(err).IsNil()
+				g.Assert(v).Equal("1.12")
+				g.Assert(atomic.LoadInt32(&requests)).Equal(2 * perFetch)
+			})
+			g.It("Should request the version once for concurrent callers", func() {
+				c, _ := New(Opts{URL: s.URL})
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, _ = c.(remote.CapabilitiesProvider).Capabilities(ctx)
+					}()
+				}
+				wg.Wait()
+				g.Assert(atomic.LoadInt32(&requests)).Equal(perFetch)
+			})
+		})
+
+		g.Describe("Requesting the status context", func() {
+			repo := &model.Repo{Owner: "gordon", Name: "hello-world"}
+			build := &model.Build{Event: model.EventPull, Commit: "9ecad50"}
+			g.It("Should return a context per pipeline", func() {
+				test := c.(*Gitea).getStatusContext(repo, build, &model.Proc{Name: "test"})
+				lint := c.(*Gitea).getStatusContext(repo, build, &model.Proc{Name: "lint"})
+				g.Assert(test).Equal("/pr/test")
+				g.Assert(lint).Equal("/pr/lint")
+			})
+			g.It("Should return the configured context per pipeline", func() {
+				c, err := New(Opts{URL: s.URL, StatusContextFormat: "ci/{{ .owner }}/{{ .event }}/{{ .pipeline }}"})
+				g.Assert(err).IsNil()
+				test := c.(*Gitea).getStatusContext(repo, build, &model.Proc{Name: "test"})
+				lint := c.(*Gitea).getStatusContext(repo, build, &model.Proc{Name: "lint"})
+				g.Assert(test).Equal("ci/gordon/pr/test")
+				g.Assert(lint).Equal("ci/gordon/pr/lint")
+			})
+			g.It("Should return a context per deploy target", func() {
+				c, err := New(Opts{URL: s.URL, StatusContextFormat: "ci/{{ .event }}{{ with .target }}/{{ . }}{{ end }}"})
+				g.Assert(err).IsNil()
+				deploy := &model.Build{Event: model.EventDeploy, Deploy: "production"}
+				g.Assert(c.(*Gitea).getStatusContext(repo, deploy, &model.Proc{Name: "test"})).Equal("ci/deployment/production")
+				g.Assert(c.(*Gitea).getStatusContext(repo, build, &model.Proc{Name: "test"})).Equal("ci/pr")
+			})
+			g.It("Should fail for an invalid format", func() {
+				_, err := New(Opts{URL: s.URL, StatusContextFormat: "{{ .pipeline"})
+				g.Assert(err).IsNotNil()
+			})
+		})
+
 		g.It("Should return nil from send build status", func() {
 			err := c.Status(ctx, fakeUser, fakeRepo, fakeBuild, fakeProc)
 			g.Assert(err).IsNil()
 		})
 
+		g.Describe("Requesting the status link", func() {
+			repo := &model.Repo{FullName: "gordon/hello-world"}
+			procs := []*model.Proc{
+				{PID: 1, Name: "test", State: model.StatusFailure},
+				{PID: 2, PPID: 1, Name: "clone", State: model.StatusSuccess},
+				{PID: 3, PPID: 1, Name: "lint", State: model.StatusSuccess},
+				{PID: 5, PPID: 1, Name: "deploy", State: model.StatusFailure},
+				{PID: 4, PPID: 1, Name: "build", State: model.StatusFailure},
+			}
+			build := &model.Build{Number: 123, Procs: procs}
+			g.It("Should link to the first failed step", func() {
+				link := getStatusLink(repo, build, procs[0])
+				g.Assert(strings.HasSuffix(link, "/gordon/hello-world/build/123/4")).IsTrue()
+			})
+			g.It("Should link to the build if successful", func() {
+				link := getStatusLink(repo, build, &model.Proc{PID: 1, State: model.StatusSuccess})
+				g.Assert(strings.HasSuffix(link, "/gordon/hello-world/build/123")).IsTrue()
+			})
+			g.It("Should link to the pipeline if the procs are not loaded", func() {
+				link := getStatusLink(repo, &model.Build{Number: 123}, procs[0])
+				g.Assert(strings.HasSuffix(link, "/gordon/hello-world/build/123/1")).IsTrue()
+			})
+		})
+
+		g.Describe("Sending step statuses", func() {
+			var statuses []gitea.CreateStatusOption
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && strings.Contains(r.URL.Path, "/statuses/") {
+					var status gitea.CreateStatusOption
+					_ = json.NewDecoder(r.Body).Decode(&status)
+					statuses = append(statuses, status)
+					r.Body = io.NopCloser(strings.NewReader("{}"))
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				statuses = nil
+			})
+
+			build := &model.Build{Number: 3, Event: model.EventPush, Commit: "9ecad50"}
+			parent := &model.Proc{PID: 1, Name: "test"}
+			sendStates := func(c remote.Remote, states ...model.StatusValue) {
+				for _, state := range states {
+					step := &model.Proc{PID: 2, PPID: 1, Name: "build", State: state}
+					err := c.(remote.StepStatuser).StepStatus(ctx, fakeUser, fakeRepo, build, parent, step)
+					g.Assert(err).IsNil()
+				}
+			}
+
+			g.It("Should report pending and success", func() {
+				c, _ := New(Opts{URL: recorder.URL, StepStatuses: true})
+				sendStates(c, model.StatusPending, model.StatusSuccess)
+				g.Assert(len(statuses)).Equal(2)
+				g.Assert(statuses[0].State).Equal(gitea.StatusPending)
+				g.Assert(statuses[1].State).Equal(gitea.StatusSuccess)
+				g.Assert(statuses[1].Context).Equal("/push/test/build")
+				g.Assert(strings.HasSuffix(statuses[1].TargetURL, "/test_name/repo_name/build/3/2")).IsTrue()
+			})
+			g.It("Should report pending and failure", func() {
+				c, _ := New(Opts{URL: recorder.URL, StepStatuses: true})
+				sendStates(c, model.StatusPending, model.StatusFailure)
+				g.Assert(len(statuses)).Equal(2)
+				g.Assert(statuses[0].State).Equal(gitea.StatusPending)
+				g.Assert(statuses[1].State).Equal(gitea.StatusFailure)
+			})
+			g.It("Should not report skipped steps", func() {
+				c, _ := New(Opts{URL: recorder.URL, StepStatuses: true})
+				sendStates(c, model.StatusSkipped)
+				g.Assert(len(statuses)).Equal(0)
+			})
+			g.It("Should not report if disabled", func() {
+				c, _ := New(Opts{URL: recorder.URL})
+				sendStates(c, model.StatusPending, model.StatusSuccess)
+				g.Assert(len(statuses)).Equal(0)
+			})
+			g.It("Should not report statuses of mirrors", func() {
+				c, _ := New(Opts{URL: recorder.URL, StepStatuses: true})
+				mirror := *fakeRepo
+				mirror.IsMirror = true
+				step := &model.Proc{PID: 2, PPID: 1, Name: "build", State: model.StatusSuccess}
+				g.Assert(c.(remote.StepStatuser).StepStatus(ctx, fakeUser, &mirror, build, parent, step)).IsNil()
+				g.Assert(c.Status(ctx, fakeUser, &mirror, build, parent)).IsNil()
+				g.Assert(len(statuses)).Equal(0)
+
+				fork := *fakeRepo
+				fork.IsFork = true
+				g.Assert(c.Status(ctx, fakeUser, &fork, build, parent)).IsNil()
+				g.Assert(len(statuses)).Equal(1)
+			})
+			g.It("Should report to the status context of the repo", func() {
+				c, _ := New(Opts{URL: recorder.URL, StepStatuses: true, StatusContextFormat: "ci/{{ .pipeline }}"})
+				repo := *fakeRepo
+				repo.StatusContext = "ci/woodpecker"
+				g.Assert(c.Status(ctx, fakeUser, &repo, build, &model.Proc{PID: 1, Name: "test", State: model.StatusRunning})).IsNil()
+				step := &model.Proc{PID: 2, PPID: 1, Name: "build", State: model.StatusSuccess}
+				g.Assert(c.(remote.StepStatuser).StepStatus(ctx, fakeUser, &repo, build, parent, step)).IsNil()
+				g.Assert(c.(remote.StepStatuser).StepStatus(ctx, fakeUser, &repo, build, &model.Proc{PID: 3, Name: "lint"}, &model.Proc{PID: 4, PPID: 3, Name: "build", State: model.StatusSuccess})).IsNil()
+				g.Assert(len(statuses)).Equal(3)
+				g.Assert(statuses[0].Context).Equal("ci/woodpecker")
+				g.Assert(statuses[1].Context).Equal("ci/woodpecker/test/build")
+				g.Assert(statuses[2].Context).Equal("ci/woodpecker/lint/build")
+			})
+			g.It("Should report the state of all pipelines to the status context of the repo", func() {
+				c, _ := New(Opts{URL: recorder.URL})
+				repo := *fakeRepo
+				repo.StatusContext = "ci/woodpecker"
+				build := &model.Build{Number: 3, Event: model.EventPush, Commit: "9ecad50", Procs: []*model.Proc{
+					{PID: 1, Name: "test", State: model.StatusRunning},
+					{PID: 2, PPID: 1, Name: "unit", State: model.StatusRunning},
+					{PID: 3, Name: "lint", State: model.StatusRunning},
+				}}
+				g.Assert(c.Status(ctx, fakeUser, &repo, build, &model.Proc{PID: 1, Name: "test", State: model.StatusSuccess})).IsNil()
+				build.Procs[0].State = model.StatusSuccess
+				g.Assert(c.Status(ctx, fakeUser, &repo, build, &model.Proc{PID: 3, Name: "lint", State: model.StatusFailure})).IsNil()
+				build.Procs[2].State = model.StatusFailure
+				g.Assert(c.Status(ctx, fakeUser, &repo, build, &model.Proc{PID: 1, Name: "test", State: model.StatusSuccess})).IsNil()
+				g.Assert(len(statuses)).Equal(3)
+				g.Assert(statuses[0].State).Equal(gitea.StatusPending)
+				g.Assert(statuses[1].State).Equal(gitea.StatusFailure)
+				g.Assert(statuses[2].State).Equal(gitea.StatusFailure)
+				for _, status := range statuses {
+					g.Assert(status.Context).Equal("ci/woodpecker")
+				}
+			})
+		})
+
+		g.Describe("Requesting the required status checks", func() {
+			g.It("Should return the contexts of enabled status checks", func() {
+				checks, err := c.(remote.StatusCheckLister).RequiredStatusChecks(ctx, fakeUser, fakeRepo)
+				g.Assert(err).IsNil()
+				g.Assert(checks).Equal([]string{"ci/lint", "ci/woodpecker"})
+			})
+			g.It("Should return no contexts for unprotected repos", func() {
+				checks, err := c.(remote.StatusCheckLister).RequiredStatusChecks(ctx, fakeUser, fakeRepoEmpty)
+				g.Assert(err).IsNil()
+				g.Assert(len(checks)).Equal(0)
+			})
+		})
+
+		g.Describe("Using an expired token", func() {
+			g.It("Should refresh the token and retry", func() {
+				users := &userStore{}
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, users)
+				user := &model.User{Login: "someuser", Token: "expired", Secret: "valid_refresh_token"}
+
+				repo, err := c.Repo(ginCtx, user, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(err).IsNil()
+				g.Assert(repo.FullName).Equal("test_name/repo_name")
+				g.Assert(user.Token).Equal("refreshed")
+				g.Assert(user.Secret).Equal("new_refresh_token")
+				g.Assert(len(users.updated)).Equal(1)
+				g.Assert(users.updated[0].Token).Equal("refreshed")
+			})
+			g.It("Should return an invalid token error if the refresh fails", func() {
+				users := &userStore{}
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, users)
+				user := &model.User{Login: "someuser", Token: "expired", Secret: "revoked_refresh_token"}
+
+				_, err := c.Repo(ginCtx, user, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(errors.Is(err, remote.ErrInvalidToken)).IsTrue()
+				g.Assert(user.Token).Equal("expired")
+				g.Assert(len(users.updated)).Equal(0)
+			})
+			g.It("Should not refresh tokens without refresh token", func() {
+				_, err := c.Repo(ctx, &model.User{Token: "expired"}, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(err).IsNotNil()
+				g.Assert(errors.Is(err, remote.ErrInvalidToken)).IsFalse()
+			})
+		})
+
+		g.Describe("Using a token with insufficient scope", func() {
+			forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/version" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"message": "token does not have at least one of required scope(s): [read:repository]"}`))
+					return
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				forbidden.Close()
+			})
+
+			c, _ := New(Opts{URL: forbidden.URL})
+			scope := func(err error) string {
+				var scopeErr *remote.InsufficientScopeError
+				g.Assert(errors.Is(err, remote.ErrInsufficientScope)).IsTrue()
+				g.Assert(errors.As(err, &scopeErr)).IsTrue()
+				return scopeErr.Scope
+			}
+			g.It("Should name the scope to read repositories", func() {
+				_, err := c.Repo(ctx, fakeUser, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(scope(err)).Equal("read:repository")
+			})
+			g.It("Should name the scope to read organizations", func() {
+				_, err := c.Teams(ctx, fakeUser)
+				g.Assert(scope(err)).Equal("read:organization")
+			})
+			g.It("Should name the scope to send statuses", func() {
+				err := c.Status(ctx, fakeUser, fakeRepo, fakeBuild, fakeProc)
+				g.Assert(scope(err)).Equal("write:repository")
+			})
+			g.It("Should name the scope to register hooks", func() {
+				err := c.Activate(ctx, fakeUser, fakeRepo, "http://localhost")
+				g.Assert(scope(err)).Equal("write:repository")
+			})
+			g.It("Should not wrap other errors", func() {
+				c, _ := New(Opts{URL: s.URL})
+				_, err := c.Repo(ctx, fakeUser, fakeRepoNotFound.Owner, fakeRepoNotFound.Name)
+				g.Assert(err).IsNotNil()
+				g.Assert(errors.Is(err, remote.ErrInsufficientScope)).IsFalse()
+			})
+			g.It("Should report forbidden requests as forbidden", func() {
+				_, err := c.Repo(ctx, fakeUser, fakeRepo.Owner, fakeRepo.Name)
+				g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+			})
+		})
+
+		g.Describe("Typing errors of rejected requests", func() {
+			rejected := func(status int) *gitea.Response {
+				return &gitea.Response{Response: &http.Response{StatusCode: status}}
+			}
+			sdkErr := errors.New("rejected")
+
+			g.It("Should type not found requests", func() {
+				err := scopeError(rejected(http.StatusNotFound), sdkErr, scopeReadRepository)
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+			g.It("Should type unauthorized requests", func() {
+				err := scopeError(rejected(http.StatusUnauthorized), sdkErr, scopeReadRepository)
+				g.Assert(errors.Is(err, remote.ErrUnauthorized)).IsTrue()
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsFalse()
+			})
+			g.It("Should type forbidden requests", func() {
+				err := scopeError(rejected(http.StatusForbidden), sdkErr, scopeReadRepository)
+				g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+				g.Assert(errors.Is(err, remote.ErrInsufficientScope)).IsFalse()
+			})
+			g.It("Should type forbidden requests naming a scope", func() {
+				resp := rejected(http.StatusForbidden)
+				resp.Header = http.Header{forbiddenMessageHeader: {"token does not have at least one of required scope(s): [read:repository]"}}
+				err := scopeError(resp, sdkErr, scopeReadRepository)
+				g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+				g.Assert(errors.Is(err, remote.ErrInsufficientScope)).IsTrue()
+			})
+			g.It("Should keep other errors", func() {
+				g.Assert(scopeError(rejected(http.StatusBadGateway), sdkErr, scopeReadRepository)).Equal(sdkErr)
+				g.Assert(scopeError(nil, sdkErr, scopeReadRepository)).Equal(sdkErr)
+				g.Assert(scopeError(rejected(http.StatusNotFound), nil, scopeReadRepository)).IsNil()
+			})
+			g.It("Should type a hook of a missing repository as not found", func() {
+				missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/hooks") {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fixtures.Handler().ServeHTTP(w, r)
+				}))
+				defer missing.Close()
+
+				c, _ := New(Opts{URL: missing.URL})
+				err := c.Activate(ctx, fakeUser, fakeRepo, "http://localhost")
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+		})
+
+		g.Describe("Subscribing to hook events", func() {
+			var created []gitea.CreateHookOption
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/hooks") {
+					body, _ := io.ReadAll(r.Body)
+					var hook gitea.CreateHookOption
+					_ = json.Unmarshal(body, &hook)
+					created = append(created, hook)
+					r.Body = io.NopCloser(bytes.NewReader(body))
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				created = nil
+			})
+
+			c, _ := New(Opts{URL: recorder.URL})
+			g.It("Should subscribe to all build events by default", func() {
+				err := c.Activate(ctx, fakeUser, fakeRepo, "http://localhost")
+				g.Assert(err).IsNil()
+				g.Assert(len(created)).Equal(1)
+				g.Assert(created[0].Events).Equal([]string{"push", "create", "pull_request", "release"})
+			})
+			g.It("Should subscribe to the configured events", func() {
+				repo := *fakeRepo
+				repo.HookEvents = []string{"pull_request", "push", "deployment"}
+				err := c.Activate(ctx, fakeUser, &repo, "http://localhost")
+				g.Assert(err).IsNil()
+				g.Assert(len(created)).Equal(1)
+				g.Assert(created[0].Events).Equal([]string{"pull_request", "push"})
+			})
+			g.It("Should fall back to all events without Gitea events", func() {
+				g.Assert(hookEvents([]string{"deployment", "cron"})).Equal([]string{"push", "create", "pull_request", "release"})
+				g.Assert(hookEvents([]string{"tag", "tag"})).Equal([]string{"create"})
+				g.Assert(hookEvents([]string{"push", "package"})).Equal([]string{"push", "package"})
+				g.Assert(hookEvents([]string{"delete"})).Equal([]string{"delete"})
+			})
+		})
+
+		g.Describe("Activating a repository with duplicate hooks", func() {
+			var requests []string
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "/hooks") {
+					requests = append(requests, r.Method+" "+r.URL.Path)
+				}
+				if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/hooks") {
+					_, _ = io.WriteString(w, `[
+						{"id": 1, "type": "gitea", "config": {"url": "http://localhost/hook?access_token=1"}},
+						{"id": 2, "type": "gitea", "config": {"url": "http://localhost/hook?access_token=2"}},
+						{"id": 3, "type": "gitea", "config": {"url": "http://localhost/hook?access_token=3"}},
+						{"id": 4, "type": "gitea", "config": {"url": "http://ci.example.com/hook?access_token=4"}}
+					]`)
+					return
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+
+			g.It("Should replace all hooks of the link by one", func() {
+				c, _ := New(Opts{URL: recorder.URL})
+				err := c.Activate(ctx, fakeUser, fakeRepo, "http://localhost")
+				g.Assert(err).IsNil()
+				g.Assert(requests).Equal([]string{
+					"GET /api/v1/repos/test_name/repo_name/hooks",
+					"DELETE /api/v1/repos/test_name/repo_name/hooks/1",
+					"DELETE /api/v1/repos/test_name/repo_name/hooks/2",
+					"DELETE /api/v1/repos/test_name/repo_name/hooks/3",
+					"POST /api/v1/repos/test_name/repo_name/hooks",
+				})
+			})
+		})
+
+		g.Describe("Running behind a proxy rewriting the host", func() {
+			var deleted []string
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/hooks"):
+					_, _ = io.WriteString(w, `[
+						{"id": 1, "type": "gitea", "config": {"url": "http://woodpecker:8000/hook?access_token=1"}},
+						{"id": 2, "type": "gitea", "config": {"url": "https://ci.example.com/hook?access_token=2"}}
+					]`)
+				case r.Method == http.MethodDelete:
+					deleted = append(deleted, r.URL.Path)
+				default:
+					fixtures.Handler().ServeHTTP(w, r)
+				}
+			}))
+			publicHost := server.Config.Server.PublicHost
+			g.Before(func() {
+				server.Config.Server.PublicHost = "https://ci.example.com"
+			})
+			g.After(func() {
+				server.Config.Server.PublicHost = publicHost
+				recorder.Close()
+			})
+
+			// requests reach the server with its internal host
+			ginCtx := &gin.Context{Request: httptest.NewRequest(http.MethodPost, "http://woodpecker:8000/api/repos/test_name/repo_name", nil)}
+			ginCtx.Request.Header.Set("X-Forwarded-Host", "woodpecker:8000")
+
+			g.It("Should remove the hook registered with the canonical url", func() {
+				c, _ := New(Opts{URL: recorder.URL})
+				err := c.Deactivate(ginCtx, fakeUser, fakeRepo, server.Config.Server.PublicHost)
+				g.Assert(err).IsNil()
+				g.Assert(deleted).Equal([]string{"/api/v1/repos/test_name/repo_name/hooks/2"})
+			})
+			g.It("Should link statuses to the canonical url", func() {
+				build := &model.Build{Number: 3, Event: model.EventPush, Commit: "9ecad50"}
+				proc := &model.Proc{PID: 1, Name: "test", State: model.StatusRunning}
+				g.Assert(getStatusLink(fakeRepo, build, proc)).Equal("https://ci.example.com/test_name/repo_name/build/3/1")
+			})
+		})
+
+		g.Describe("Rotating the hook secret", func() {
+			var edits []gitea.EditHookOption
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPatch {
+					var edit gitea.EditHookOption
+					_ = json.NewDecoder(r.Body).Decode(&edit)
+					edits = append(edits, edit)
+					_, _ = io.WriteString(w, `{"id": 1}`)
+					return
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				edits = nil
+			})
+
+			c, _ := New(Opts{URL: recorder.URL})
+			rotator := c.(remote.HookSecretRotator)
+			g.It("Should update the url and secret of the hook", func() {
+				repo := *fakeRepo
+				repo.Hash = "new"
+				err := rotator.RotateHookSecret(ctx, fakeUser, &repo, "http://localhost/hook?access_token=new")
+				g.Assert(err).IsNil()
+				g.Assert(len(edits)).Equal(1)
+				g.Assert(edits[0].Config["url"]).Equal("http://localhost/hook?access_token=new")
+				g.Assert(edits[0].Config["secret"]).Equal("new")
+				g.Assert(edits[0].Active != nil).IsTrue()
+			})
+			g.It("Should fail without a registered hook", func() {
+				err := rotator.RotateHookSecret(ctx, fakeUser, fakeRepo, "http://ci.example.com/hook?access_token=new")
+				g.Assert(err).IsNotNil()
+				g.Assert(len(edits)).Equal(0)
+			})
+		})
+
+		g.Describe("Verifying hook signatures", func() {
+			body := []byte(fixtures.HookPush)
+			sign := func(newHash func() hash.Hash, secret string) string {
+				mac := hmac.New(newHash, []byte(secret))
+				_, _ = mac.Write(body)
+				return hex.EncodeToString(mac.Sum(nil))
+			}
+			headers := func(kv ...string) http.Header {
+				header := http.Header{}
+				for i := 0; i < len(kv); i += 2 {
+					header.Set(kv[i], kv[i+1])
+				}
+				return header
+			}
+			storeCtx := func(rotated time.Time) context.Context {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{
+					"gordon/hello-world": {FullName: "gordon/hello-world", Hash: "new", PrevHash: "old", HashRotated: rotated.Unix()},
+					"gordon/no-secret":   {FullName: "gordon/no-secret"},
+				}})
+				return ginCtx
+			}
+			request := func(link string, header http.Header) *http.Request {
+				req, _ := http.NewRequest("POST", link, bytes.NewReader(body))
+				req.Header = header
+				return req
+			}
+			checkHeader := func(rotated time.Time, header http.Header) error {
+				return c.(*Gitea).checkSignature(storeCtx(rotated), request("/hook", header), "gordon/hello-world", body)
+			}
+			check := func(rotated time.Time, sig string) error {
+				return checkHeader(rotated, headers(hookSignature, sig))
+			}
+
+			g.It("Should accept the current secret", func() {
+				g.Assert(check(time.Now().Add(-time.Hour), sign(sha256.New, "new"))).IsNil()
+			})
+			g.It("Should accept the previous secret during the grace period", func() {
+				g.Assert(check(time.Now().Add(-time.Minute), sign(sha256.New, "old"))).IsNil()
+			})
+			g.It("Should reject the previous secret after the grace period", func() {
+				err := check(time.Now().Add(-model.HashRotationGrace-time.Minute), sign(sha256.New, "old"))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject other secrets", func() {
+				err := check(time.Now(), sign(sha256.New, "other"))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should accept a SHA-256 signature", func() {
+				g.Assert(checkHeader(time.Now(), headers(hookSignature256, sign(sha256.New, "new")))).IsNil()
+				g.Assert(checkHeader(time.Now(), headers(hookSignature256, "sha256="+sign(sha256.New, "new")))).IsNil()
+			})
+			g.It("Should accept a legacy SHA-1 signature", func() {
+				g.Assert(check(time.Now(), sign(sha1.New, "new"))).IsNil()
+				g.Assert(check(time.Now(), "sha1="+sign(sha1.New, "new"))).IsNil()
+			})
+			g.It("Should fall back to the legacy signature", func() {
+				err := checkHeader(time.Now(), headers(
+					hookSignature256, sign(sha256.New, "new"),
+					hookSignature, sign(sha1.New, "other"),
+				))
+				g.Assert(err).IsNil()
+				err = checkHeader(time.Now(), headers(
+					hookSignature256, sign(sha256.New, "other"),
+					hookSignature, sign(sha1.New, "new"),
+				))
+				g.Assert(err).IsNil()
+				err = checkHeader(time.Now(), headers(
+					hookSignature256, sign(sha256.New, "other"),
+					hookSignature, sign(sha1.New, "other"),
+				))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject mismatching signatures", func() {
+				err := checkHeader(time.Now(), headers(hookSignature256, sign(sha256.New, "other")))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+				err = check(time.Now(), sign(sha1.New, "other"))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+				err = checkHeader(time.Now(), headers(hookSignature256, sign(sha1.New, "new")))
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject hooks without signature", func() {
+				err := checkHeader(time.Now(), http.Header{})
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject hooks without store to verify them", func() {
+				err := c.(*Gitea).checkSignature(ctx, request("/hook", headers(hookSignature, sign(sha256.New, "new"))), "gordon/hello-world", body)
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject hooks of unknown repositories", func() {
+				err := c.(*Gitea).checkSignature(storeCtx(time.Now()), request("/hook", headers(hookSignature, sign(sha256.New, "new"))), "gordon/unknown", body)
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject hooks of repositories without secret", func() {
+				err := c.(*Gitea).checkSignature(storeCtx(time.Now()), request("/hook", headers(hookSignature, sign(sha256.New, ""))), "gordon/no-secret", body)
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should verify hooks of renamed repositories with the repository of the hook token", func() {
+				for _, hash := range []string{"new", "old"} {
+					hookToken, _ := token.New(token.HookToken, "gordon/hello-world").Sign(hash)
+					req := request("/hook?access_token="+hookToken, headers(hookSignature, sign(sha256.New, "new")))
+					g.Assert(c.(*Gitea).checkSignature(storeCtx(time.Now()), req, "gordon/renamed", body)).IsNil()
+				}
+				hookToken, _ := token.New(token.HookToken, "gordon/hello-world").Sign("other")
+				req := request("/hook?access_token="+hookToken, headers(hookSignature, sign(sha256.New, "new")))
+				err := c.(*Gitea).checkSignature(storeCtx(time.Now()), req, "gordon/renamed", body)
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should verify the signature before decoding the hook", func() {
+				req, _ := http.NewRequest("POST", "/hook", strings.NewReader(`{"repository": {"full_name": "gordon/hello-world"}, "commits": "invalid"}`))
+				req.Header.Set(hookEvent, hookPush)
+				_, _, err := c.Hook(storeCtx(time.Now()), req)
+				g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+			})
+			g.It("Should reject oversized hooks", func() {
+				req, _ := http.NewRequest("POST", "/hook", io.MultiReader(strings.NewReader(fixtures.HookPush), strings.NewReader(strings.Repeat(" ", maxHookSize))))
+				req.Header.Set(hookEvent, hookPush)
+				_, _, err := c.Hook(ctx, req)
+				g.Assert(err).IsNotNil()
+				_, name := c.(remote.HookRepoNamer).HookRepo(req)
+				g.Assert(name).Equal("")
+			})
+		})
+
+		g.Describe("Deploying keys", func() {
+			var requests []string
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet && strings.Contains(r.URL.Path, "/keys") {
+					requests = append(requests, r.Method+" "+r.URL.Path)
+				}
+				fixtures.Handler().ServeHTTP(w, r)
+			}))
+			g.After(func() {
+				recorder.Close()
+			})
+			g.BeforeEach(func() {
+				requests = nil
+			})
+
+			c, _ := New(Opts{URL: recorder.URL})
+			deployer := c.(remote.Deployer)
+			g.It("Should create a deploy key", func() {
+				err := deployer.Deploy(ctx, fakeUser, fakeRepo, &remote.DeployKey{Title: "other", Key: "ssh-ed25519 AAAA"})
+				g.Assert(err).IsNil()
+				g.Assert(requests).Equal([]string{"POST /api/v1/repos/test_name/repo_name/keys"})
+			})
+			g.It("Should not recreate an existing deploy key", func() {
+				err := deployer.Deploy(ctx, fakeUser, fakeRepo, &remote.DeployKey{Title: "woodpecker", Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExisting\n"})
+				g.Assert(err).IsNil()
+				g.Assert(len(requests)).Equal(0)
+			})
+			g.It("Should replace a changed deploy key", func() {
+				err := deployer.Deploy(ctx, fakeUser, fakeRepo, &remote.DeployKey{Title: "woodpecker", Key: "ssh-ed25519 AAAA"})
+				g.Assert(err).IsNil()
+				g.Assert(requests).Equal([]string{
+					"DELETE /api/v1/repos/test_name/repo_name/keys/1",
+					"POST /api/v1/repos/test_name/repo_name/keys",
+				})
+			})
+			g.It("Should delete a deploy key", func() {
+				err := deployer.Undeploy(ctx, fakeUser, fakeRepo, "woodpecker")
+				g.Assert(err).IsNil()
+				g.Assert(requests).Equal([]string{"DELETE /api/v1/repos/test_name/repo_name/keys/1"})
+			})
+			g.It("Should ignore deleting a missing deploy key", func() {
+				err := deployer.Undeploy(ctx, fakeUser, fakeRepo, "other")
+				g.Assert(err).IsNil()
+				g.Assert(len(requests)).Equal(0)
+			})
+		})
+
+		g.Describe("Requesting open pull requests", func() {
+			lister := c.(remote.PullRequestLister)
+
+			g.It("Should return all pages, newest first", func() {
+				pulls, err := lister.PullRequests(ctx, fakeUser, fakeRepo, 0)
+				g.Assert(err).IsNil()
+				g.Assert(len(pulls)).Equal(52)
+				g.Assert(pulls[0].Number).Equal(int64(52))
+				g.Assert(pulls[51].Number).Equal(int64(1))
+			})
+			g.It("Should return a single page", func() {
+				pulls, err := lister.PullRequests(ctx, fakeUser, fakeRepo, 2)
+				g.Assert(err).IsNil()
+				g.Assert(len(pulls)).Equal(2)
+				g.Assert(pulls[0].Number).Equal(int64(52))
+				g.Assert(pulls[1].Number).Equal(int64(51))
+			})
+			g.It("Should map the pull request", func() {
+				pulls, _ := lister.PullRequests(ctx, fakeUser, fakeRepo, 2)
+				g.Assert(*pulls[1]).Equal(remote.PullRequest{
+					Number:  51,
+					Title:   "Pull request 51",
+					BaseRef: "master",
+					HeadRef: "feature-51",
+					Author:  "gordon",
+				})
+			})
+		})
+
+		g.Describe("Requesting the changed files of a pull request", func() {
+			g.It("Should return the cached files", func() {
+				c.(*Gitea).changedFilesCache = map[string][]string{
+					"test_name/repo_name#1@9ecad50": {"README.md"},
+				}
+				files, err := c.(*Gitea).getChangedFilesForPR(ctx, fakeRepo, 1, "9ecad50")
+				g.Assert(err).IsNil()
+				g.Assert(files).Equal([]string{"README.md"})
+			})
+			g.It("Should fail without a store", func() {
+				_, err := c.(*Gitea).getChangedFilesForPR(ctx, fakeRepo, 2, "9ecad50")
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Requesting the protection of the base branch", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world"}, user: fakeUser})
+			hook := func(c remote.Remote, payload string) *model.Build {
+				req := newSignedHook(hookPullRequest, payload)
+				_, build, err := c.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				return build
+			}
+
+			g.It("Should flag pull requests to a protected branch", func() {
+				c, _ := New(Opts{URL: s.URL})
+				build := hook(c, fixtures.HookPullRequest)
+				g.Assert(build.BaseProtected != nil && *build.BaseProtected).IsTrue()
+			})
+			g.It("Should flag pull requests to an unprotected branch", func() {
+				c, _ := New(Opts{URL: s.URL})
+				payload := strings.ReplaceAll(fixtures.HookPullRequest, `"ref": "master"`, `"ref": "develop"`)
+				build := hook(c, payload)
+				g.Assert(build.Branch).Equal("develop")
+				g.Assert(build.BaseProtected != nil && !*build.BaseProtected).IsTrue()
+			})
+			g.It("Should cache the protection", func() {
+				var requests int32
+				counter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.Contains(r.URL.Path, "/branches/") {
+						atomic.AddInt32(&requests, 1)
+					}
+					fixtures.Handler().ServeHTTP(w, r)
+				}))
+				defer counter.Close()
+				c, _ := New(Opts{URL: counter.URL})
+				repo := &model.Repo{FullName: "gordon/hello-world"}
+				g.Assert(*c.(*Gitea).isProtectedBranch(ginCtx, repo, "master")).IsTrue()
+				g.Assert(*c.(*Gitea).isProtectedBranch(ginCtx, repo, "master")).IsTrue()
+				g.Assert(atomic.LoadInt32(&requests)).Equal(int32(1))
+			})
+			g.It("Should leave the flag unset if the branch is not found", func() {
+				c, _ := New(Opts{URL: s.URL})
+				payload := strings.ReplaceAll(fixtures.HookPullRequest, `"ref": "master"`, `"ref": "branch_not_found"`)
+				g.Assert(hook(c, payload).BaseProtected == nil).IsTrue()
+			})
+			g.It("Should leave the flag unset without a store", func() {
+				g.Assert(c.(*Gitea).isProtectedBranch(ctx, fakeRepo, "master") == nil).IsTrue()
+			})
+		})
+
+		g.Describe("Requesting the teams of the sender", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world", IsActive: true}, user: fakeUser})
+			hook := func(c remote.Remote, sender string) *model.Build {
+				payload := strings.ReplaceAll(fixtures.HookPush, `"gordon"`, `"`+sender+`"`)
+				req := newSignedHook(hookPush, payload)
+				_, build, err := c.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				return build
+			}
+
+			g.It("Should return the teams of a member of two teams", func() {
+				c, _ := New(Opts{URL: s.URL, SenderTeams: true})
+				build := hook(c, "member-1")
+				g.Assert(build.Sender).Equal("member-1")
+				g.Assert(build.Teams).Equal([]string{"Owners", "Maintainers"})
+			})
+			g.It("Should return no teams of a non-member", func() {
+				c, _ := New(Opts{URL: s.URL, SenderTeams: true})
+				teams, err := c.(*Gitea).senderTeams(ginCtx, &model.Repo{FullName: "gordon/hello-world"}, "stranger")
+				g.Assert(err).IsNil()
+				g.Assert(teams).Equal([]string{})
+			})
+			g.It("Should return no teams of a repository owned by a user", func() {
+				c, _ := New(Opts{URL: s.URL, SenderTeams: true})
+				userCtx := &gin.Context{}
+				store.ToContext(userCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "user_owner", Name: "hello-world", FullName: "user_owner/hello-world"}, user: fakeUser})
+				teams, err := c.(*Gitea).senderTeams(userCtx, &model.Repo{FullName: "user_owner/hello-world"}, "member-1")
+				g.Assert(err).IsNil()
+				g.Assert(teams).Equal([]string{})
+			})
+			g.It("Should only look up the teams if enabled", func() {
+				c, _ := New(Opts{URL: s.URL})
+				g.Assert(hook(c, "member-1").Teams == nil).IsTrue()
+			})
+			g.It("Should cache the teams", func() {
+				var requests int32
+				counter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasSuffix(r.URL.Path, "/teams") {
+						atomic.AddInt32(&requests, 1)
+					}
+					fixtures.Handler().ServeHTTP(w, r)
+				}))
+				defer counter.Close()
+				c, _ := New(Opts{URL: counter.URL, SenderTeams: true})
+				repo := &model.Repo{FullName: "gordon/hello-world"}
+				_, _ = c.(*Gitea).senderTeams(ginCtx, repo, "member-1")
+				teams, err := c.(*Gitea).senderTeams(ginCtx, repo, "member-1")
+				g.Assert(err).IsNil()
+				g.Assert(teams).Equal([]string{"Owners", "Maintainers"})
+				g.Assert(atomic.LoadInt32(&requests)).Equal(int32(1))
+			})
+		})
+
+		g.Describe("Requesting the changed files of a tag", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name"}, user: fakeUser})
+
+			g.It("Should return the files changed since the previous tag", func() {
+				files, err := c.(*Gitea).getChangedFilesForTag(ginCtx, fakeRepo, "v1.1.0", "ef98532add3b2feb7a137426bba1248724367df5")
+				g.Assert(err).IsNil()
+				sort.Strings(files)
+				g.Assert(files).Equal([]string{"CHANGELOG.md", "main.go"})
+			})
+			g.It("Should return no files for the first tag", func() {
+				files, err := c.(*Gitea).getChangedFilesForTag(ginCtx, fakeRepo, "v1.0.0", "4b2626259b5a97b6b4eab5e6cca66adb986b672b")
+				g.Assert(err).IsNil()
+				g.Assert(files == nil).IsTrue()
+			})
+			g.It("Should only compare tags if enabled", func() {
+				hook := func(c remote.Remote) *model.Build {
+					req := newSignedHook(hookCreated, strings.Replace(fixtures.HookPushTag, `"v1.0.0"`, `"v1.1.0"`, 1))
+					_, build, err := c.Hook(ginCtx, req)
+					g.Assert(err).IsNil()
+					return build
+				}
+				g.Assert(len(hook(c).ChangedFiles)).Equal(0)
+
+				tags, _ := New(Opts{URL: s.URL, TagChangedFiles: true})
+				build := hook(tags)
+				g.Assert(len(build.ChangedFiles)).Equal(2)
+				g.Assert(build.IsFirstTag).IsFalse()
+			})
+			g.It("Should mark the build of the first tag", func() {
+				req := newSignedHook(hookCreated, fixtures.HookPushTag)
+				tags, _ := New(Opts{URL: s.URL, TagChangedFiles: true})
+				_, build, err := tags.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				g.Assert(len(build.ChangedFiles)).Equal(0)
+				g.Assert(build.IsFirstTag).IsTrue()
+			})
+		})
+
+		g.Describe("Requesting the message of a tag", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name"}, user: fakeUser})
+			messages, _ := New(Opts{URL: s.URL, TagMessages: true})
+			hook := func(c remote.Remote, tag string) *model.Build {
+				req := newSignedHook(hookCreated, strings.Replace(fixtures.HookPushTag, `"v1.0.0"`, `"`+tag+`"`, 1))
+				_, build, err := c.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				return build
+			}
+
+			g.It("Should use the message of an annotated tag", func() {
+				build := hook(messages, "v1.1.0")
+				g.Assert(build.Message).Equal("Release v1.1.0\n\nAdds support for tags.")
+				g.Assert(build.Title).Equal("Release v1.1.0")
+			})
+			g.It("Should keep the generated message of a lightweight tag", func() {
+				build := hook(messages, "v1.0.0")
+				g.Assert(build.Message).Equal("created tag v1.0.0")
+				g.Assert(build.Title).Equal("")
+			})
+			g.It("Should keep the generated message if the tag is not found", func() {
+				g.Assert(hook(messages, "v2.0.0").Message).Equal("created tag v2.0.0")
+			})
+			g.It("Should only look up tags if enabled", func() {
+				g.Assert(hook(c, "v1.1.0").Message).Equal("created tag v1.1.0")
+			})
+		})
+
+		g.Describe("Resolving the commit of hooks without commit", func() {
+			hook := func(event, payload string) (*model.Build, error) {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world"}, user: fakeUser})
+				req := newSignedHook(event, payload)
+				_, build, err := c.Hook(ginCtx, req)
+				return build, err
+			}
+
+			g.It("Should use the commit of the tag of a release", func() {
+				build, err := hook(hookRelease, fixtures.HookRelease)
+				g.Assert(err).IsNil()
+				g.Assert(build.Commit).Equal("4b2626259b5a97b6b4eab5e6cca66adb986b672b")
+			})
+			g.It("Should fail if the tag of a release is not found", func() {
+				_, err := hook(hookRelease, strings.Replace(fixtures.HookRelease, `"tag_name": "v1.0.0"`, `"tag_name": "v2.0.0"`, 1))
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should fail if the owner of the repository is not found", func() {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &ownerlessStore{ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world"}}})
+				_, _, err := c.Hook(ginCtx, newSignedHook(hookRelease, fixtures.HookRelease))
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should use the head of the default branch for a package", func() {
+				build, err := hook(hookPackage, fixtures.HookPackage)
+				g.Assert(err).IsNil()
+				g.Assert(build.Commit).Equal("f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+			})
+			g.It("Should use the head of the default branch for a deletion", func() {
+				build, err := hook(hookDelete, fixtures.HookDeleteBranch)
+				g.Assert(err).IsNil()
+				g.Assert(build.Event).Equal(model.EventDelete)
+				g.Assert(build.Commit).Equal("f05f642b892d59a0a9ef6a31f6c905a24b5db13a")
+			})
+		})
+
+		g.Describe("Requesting the changed files of a push", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name", IsActive: true}, user: fakeUser})
+			compare, _ := New(Opts{URL: s.URL, PushCompareFiles: true})
+			hook := func(c remote.Remote, payload string) []string {
+				req := newSignedHook(hookPush, payload)
+				_, build, err := c.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				files := build.ChangedFiles
+				sort.Strings(files)
+				return files
+			}
+			forced := strings.Replace(fixtures.HookPush, `"ref": "refs/heads/master",`, `"ref": "refs/heads/master", "forced": true,`, 1)
+
+			g.It("Should use the files of the commits of a normal push", func() {
+				g.Assert(hook(compare, fixtures.HookPush)).Equal([]string{"CHANGELOG.md", "app/controller/application.rb"})
+			})
+			g.It("Should compare before and after of a forced push", func() {
+				g.Assert(hook(compare, forced)).Equal([]string{"CHANGELOG.md", "main.go"})
+			})
+			g.It("Should compare before and after of a truncated push", func() {
+				truncated := strings.Replace(fixtures.HookPush, `"ref": "refs/heads/master",`, `"ref": "refs/heads/master", "total_commits": 20,`, 1)
+				g.Assert(hook(compare, truncated)).Equal([]string{"CHANGELOG.md", "main.go"})
+			})
+			g.It("Should use the files of the commit of a squash merge", func() {
+				g.Assert(hook(compare, fixtures.HookPushSquashMerge)).Equal([]string{"CHANGELOG.md", "README.md", "docs.txt", "docs/index.md", "docs/usage.md"})
+			})
+			g.It("Should compare before and after of a push without file lists", func() {
+				payload := strings.NewReplacer(
+					`"added": ["docs/index.md", "docs/usage.md"],`, "",
+					`"removed": ["docs.txt"],`, "",
+					`"modified": ["README.md", "CHANGELOG.md"]`, `"url": "http://gitea.golang.org/gordon/hello-world/commit/ef98532"`,
+				).Replace(fixtures.HookPushSquashMerge)
+				g.Assert(hook(compare, payload)).Equal([]string{"CHANGELOG.md", "main.go"})
+			})
+			g.It("Should only compare if enabled", func() {
+				g.Assert(hook(c, forced)).Equal([]string{"CHANGELOG.md", "app/controller/application.rb"})
+			})
+		})
+
+		g.Describe("Comparing commits", func() {
+			base, head := "4b2626259b5a97b6b4eab5e6cca66adb986b672b", "ef98532add3b2feb7a137426bba1248724367df5"
+			g.It("Should return the changed files", func() {
+				files, truncated, err := c.(remote.Comparer).CompareFiles(ctx, fakeUser, fakeRepo, base, head)
+				g.Assert(err).IsNil()
+				g.Assert(files).Equal([]string{"CHANGELOG.md", "main.go"})
+				g.Assert(truncated).IsFalse()
+			})
+			g.It("Should cap the changed files", func() {
+				capped, _ := New(Opts{URL: s.URL, MaxChangedFiles: 1})
+				files, truncated, err := capped.(remote.Comparer).CompareFiles(ctx, fakeUser, fakeRepo, base, head)
+				g.Assert(err).IsNil()
+				g.Assert(files).Equal([]string{"CHANGELOG.md"})
+				g.Assert(truncated).IsTrue()
+			})
+			g.It("Should return an error for unknown commits", func() {
+				_, _, err := c.(remote.Comparer).CompareFiles(ctx, fakeUser, fakeRepo, "0000000", head)
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+		})
+
+		g.Describe("Requesting the diff of a build", func() {
+			diff := func(c remote.Remote, build *model.Build) (string, error) {
+				r, err := c.(remote.Differ).Diff(ctx, fakeUser, fakeRepo, build)
+				if err != nil {
+					return "", err
+				}
+				defer r.Close()
+				data, err := io.ReadAll(r)
+				return string(data), err
+			}
+			push := &model.Build{
+				Event:  model.EventPush,
+				Ref:    "refs/heads/master",
+				Before: "4b2626259b5a97b6b4eab5e6cca66adb986b672b",
+				Commit: "ef98532add3b2feb7a137426bba1248724367df5",
+			}
+
+			g.It("Should return the diff of a pull request", func() {
+				data, err := diff(c, &model.Build{Event: model.EventPull, Ref: "refs/pull/1/head", Commit: "ef98532"})
+				g.Assert(err).IsNil()
+				g.Assert(data).Equal(fixtures.PullRequestDiffPayload)
+			})
+			g.It("Should return one diff of the pushed commits", func() {
+				data, err := diff(c, push)
+				g.Assert(err).IsNil()
+				g.Assert(data).Equal(fixtures.CompareDiffPayload)
+			})
+			g.It("Should return the diff against the default branch of a push creating a branch", func() {
+				data, err := diff(c, &model.Build{Event: model.EventPush, Ref: "refs/heads/feature", Branch: "feature", Commit: "ef98532add3b2feb7a137426bba1248724367df5"})
+				g.Assert(err).IsNil()
+				g.Assert(data).Equal(fixtures.CommitDiffPayload)
+			})
+			g.It("Should return the diff against the previous tag of a tag", func() {
+				data, err := diff(c, &model.Build{Event: model.EventTag, Ref: "refs/tags/v1.1.0", Commit: "ef98532add3b2feb7a137426bba1248724367df5"})
+				g.Assert(err).IsNil()
+				g.Assert(data).Equal(fixtures.CommitDiffPayload)
+			})
+			g.It("Should return the diff of the commit of a push to the default branch without previous commit", func() {
+				data, err := diff(c, &model.Build{Event: model.EventPush, Ref: "refs/heads/master", Branch: "master", Commit: "d0f4b1c9e2a7b8c3d6e5f4a3b2c1d0e9f8a7b6c5"})
+				g.Assert(err).IsNil()
+				g.Assert(data).Equal(fixtures.FirstCommitDiffPayload)
+			})
+			g.It("Should fail if Gitea redirects to the login", func() {
+				_, err := c.(remote.Differ).Diff(ctx, fakeUser, &model.Repo{Owner: "test_name", Name: "private_repo"}, push)
+				g.Assert(errors.Is(err, remote.ErrForbidden)).IsTrue()
+			})
+			g.It("Should fail if the diff exceeds the maximum size", func() {
+				capped, _ := New(Opts{URL: s.URL, MaxDiffSize: 10})
+				_, err := diff(capped, &model.Build{Event: model.EventPull, Ref: "refs/pull/1/head"})
+				g.Assert(errors.Is(err, remote.ErrDiffTooLarge)).IsTrue()
+			})
+			g.It("Should stop streaming at the maximum size", func() {
+				r := &diffReader{body: io.NopCloser(strings.NewReader("abcdefg")), max: 5}
+				data, err := io.ReadAll(r)
+				g.Assert(errors.Is(err, remote.ErrDiffTooLarge)).IsTrue()
+				g.Assert(string(data)).Equal("abcde")
+			})
+			g.It("Should handle a not found error", func() {
+				_, err := diff(c, &model.Build{Event: model.EventPull, Ref: "refs/pull/2/head"})
+				g.Assert(errors.Is(err, remote.ErrNotFound)).IsTrue()
+			})
+		})
+
+		g.Describe("Mapping events", func() {
+			ginCtx := &gin.Context{}
+			store.ToContext(ginCtx, &ownerStore{repo: &model.Repo{UserID: 1, Hash: hookSecret, Owner: "gordon", Name: "hello-world", FullName: "gordon/hello-world"}, user: fakeUser})
+			hook := func(c remote.Remote, payload string) *model.Build {
+				req := newSignedHook(hookPullRequest, payload)
+				_, build, err := c.Hook(ginCtx, req)
+				g.Assert(err).IsNil()
+				return build
+			}
+			g.It("Should keep the events by default", func() {
+				g.Assert(hook(c, fixtures.HookPullRequest).Event).Equal(model.EventPull)
+			})
+			g.It("Should map the events as configured", func() {
+				c, err := New(Opts{URL: s.URL, EventMapping: []string{"pull_request=review", "push=push"}})
+				g.Assert(err).IsNil()
+				g.Assert(hook(c, fixtures.HookPullRequest).Event).Equal(model.WebhookEvent("review"))
+			})
+			g.It("Should not map pull requests from forks", func() {
+				c, _ := New(Opts{URL: s.URL, EventMapping: []string{"pull_request=push"}})
+				g.Assert(hook(c, fixtures.HookPullRequestFork).Event).Equal(model.EventPull)
+			})
+			g.It("Should fail for invalid mappings", func() {
+				_, err := New(Opts{URL: s.URL, EventMapping: []string{"pull_request"}})
+				g.Assert(err).IsNotNil()
+				_, err = New(Opts{URL: s.URL, EventMapping: []string{"issue=push"}})
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Parsing a hook without creating a build", func() {
+			parse := func(event, payload string) map[string]map[string]interface{} {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{
+					"gordon/hello-world": {FullName: "gordon/hello-world", Hash: "secret", IsActive: true},
+				}})
+				req, _ := http.NewRequest("POST", "/api/debug/hook", strings.NewReader(payload))
+				req.Header.Set(hookEvent, event)
+
+				parsed, err := c.(remote.HookParser).ParseHook(ginCtx, req)
+				g.Assert(err).IsNil()
+				raw, err := json.Marshal(parsed)
+				g.Assert(err).IsNil()
+				result := map[string]map[string]interface{}{}
+				g.Assert(json.Unmarshal(raw, &result)).IsNil()
+				return result
+			}
+			g.It("Should return the repo and build of a push without a signature", func() {
+				result := parse(hookPush, fixtures.HookPush)
+				g.Assert(result["repo"]["full_name"]).Equal("gordon/hello-world")
+				g.Assert(result["build"]["event"]).Equal("push")
+				g.Assert(result["build"]["ref"]).Equal("refs/heads/master")
+				g.Assert(result["build"]["commit"]).Equal("ef98532add3b2feb7a137426bba1248724367df5")
+				g.Assert(result["build"]["author_avatar"]).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
+			})
+			g.It("Should return the repo and build of a pull request", func() {
+				result := parse(hookPullRequest, fixtures.HookPullRequest)
+				g.Assert(result["repo"]["full_name"]).Equal("gordon/hello-world")
+				g.Assert(result["build"]["event"]).Equal("pull_request")
+				g.Assert(result["build"]["ref"]).Equal("refs/pull/1/head")
+			})
+		})
+
 		g.Describe("Given an authentication request", func() {
 			g.It("Should redirect to login form")
 			g.It("Should create an access token")
@@ -171,6 +1936,13 @@ var (
 		Owner:    "test_name",
 		Name:     "repo_name",
 		FullName: "test_name/repo_name",
+		Branch:   "master",
+	}
+
+	fakeRepoEmpty = &model.Repo{
+		Owner:    "test_name",
+		Name:     "empty_repo",
+		FullName: "test_name/empty_repo",
 	}
 
 	fakeRepoNotFound = &model.Repo{
@@ -188,3 +1960,58 @@ var (
 		State: model.StatusSuccess,
 	}
 )
+
+// ownerStore is a store returning the repo and its owner.
+type ownerStore struct {
+	store.Store
+	repo *model.Repo
+	user *model.User
+}
+
+func (s *ownerStore) GetRepoRemoteName(string, string) (*model.Repo, error) {
+	return s.repo, nil
+}
+
+func (s *ownerStore) GetUser(int64) (*model.User, error) {
+	return s.user, nil
+}
+
+// ownerlessStore is an ownerStore whose repository owner does not exist.
+type ownerlessStore struct {
+	ownerStore
+}
+
+func (s *ownerlessStore) GetUser(int64) (*model.User, error) {
+	return nil, errors.New("user does not exist")
+}
+
+// hookSecret is the secret test hooks are signed with.
+const hookSecret = "secret"
+
+// newSignedHook returns a request of a hook of the event signed with
+// hookSecret.
+func newSignedHook(event, payload string) *http.Request {
+	req, _ := http.NewRequest("POST", "/hook", strings.NewReader(payload))
+	req.Header.Set(hookEvent, event)
+	signHook(req, payload)
+	return req
+}
+
+// signHook signs the request with hookSecret as Gitea signs the payload.
+func signHook(req *http.Request, payload string) {
+	mac := hmac.New(sha256.New, []byte(hookSecret))
+	_, _ = mac.Write([]byte(payload))
+	req.Header.Set(hookSignature256, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// userStore is a store recording updated users.
+type userStore struct {
+	store.Store
+	updated []*model.User
+}
+
+func (s *userStore) UpdateUser(user *model.User) error {
+	updated := *user
+	s.updated = append(s.updated, &updated)
+	return nil
+}
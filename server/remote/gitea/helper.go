@@ -15,36 +15,123 @@
 package gitea
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
+	"net"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"code.gitea.io/sdk/gitea"
 
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/shared/utils"
 )
 
 // helper function that converts a Gitea repository to a Woodpecker repository.
-func toRepo(from *gitea.Repository) *model.Repo {
-	name := strings.Split(from.FullName, "/")[1]
-	avatar := expandAvatar(
+// An error is returned if the full name is not of the form owner/name.
+func (c *Gitea) toRepo(from *gitea.Repository) (*model.Repo, error) {
+	parts := strings.Split(from.FullName, "/")
+	name := parts[len(parts)-1]
+	if len(parts) < 2 || parts[0] == "" || name == "" {
+		return nil, fmt.Errorf("unexpected repository full name '%s'", from.FullName)
+	}
+
+	owner := parts[0]
+	ownerAvatar := ""
+	if from.Owner != nil {
+		if from.Owner.UserName != "" {
+			owner = from.Owner.UserName
+		}
+		ownerAvatar = from.Owner.AvatarURL
+	}
+
+	avatar := c.expandAvatar(
 		from.HTMLURL,
-		from.Owner.AvatarURL,
+		ownerAvatar,
 	)
 	return &model.Repo{
 		SCMKind:      model.RepoGit,
 		Name:         name,
-		Owner:        from.Owner.UserName,
+		Owner:        owner,
 		FullName:     from.FullName,
 		Avatar:       avatar,
 		Link:         from.HTMLURL,
 		IsSCMPrivate: from.Private,
-		Clone:        from.CloneURL,
+		IsMirror:     from.Mirror,
+		IsFork:       from.Fork,
+		Clone:        c.cloneURL(from),
 		Branch:       from.DefaultBranch,
+		Remote:       c.Name,
+	}, nil
+}
+
+// helper function that returns the topics of a Gitea repository, which are
+// empty but not nil for repositories without topics.
+func toTopics(from []string) []string {
+	topics := make([]string, 0, len(from))
+	for _, topic := range from {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// helper function that converts a Gitea pull request to a Woodpecker pull request.
+func toPullRequest(from *gitea.PullRequest) *remote.PullRequest {
+	pull := &remote.PullRequest{
+		Number: from.Index,
+		Title:  from.Title,
+	}
+	if from.Base != nil {
+		pull.BaseRef = from.Base.Ref
+	}
+	if from.Head != nil {
+		pull.HeadRef = from.Head.Ref
+	}
+	if from.Poster != nil {
+		pull.Author = from.Poster.UserName
+	}
+	return pull
+}
+
+// helper function that converts a Gitea GPG key to a public key with the ids
+// of its subkeys and its verified email addresses.
+func toGPGKey(from *gitea.GPGKey) *remote.GPGKey {
+	key := &remote.GPGKey{
+		KeyID:     from.KeyID,
+		PublicKey: from.PublicKey,
+		CanSign:   from.CanSign,
+	}
+	for _, sub := range from.SubsKey {
+		key.SubKeyIDs = append(key.SubKeyIDs, sub.KeyID)
+		key.CanSign = key.CanSign || sub.CanSign
+	}
+	for _, email := range from.Emails {
+		if email.Verified {
+			key.Emails = append(key.Emails, email.Email)
+		}
+	}
+	return key
+}
+
+// helper function that converts a Gitea public key to an SSH key.
+func toSSHKey(from *gitea.PublicKey) *remote.SSHKey {
+	return &remote.SSHKey{
+		Title:       from.Title,
+		Key:         from.Key,
+		Fingerprint: from.Fingerprint,
 	}
 }
 
@@ -57,17 +144,54 @@ func toPerm(from *gitea.Permission) *model.Perm {
 	}
 }
 
+// helper function that converts a Gitea access mode to a Woodpecker permission.
+func accessModePerm(mode gitea.AccessMode) *model.Perm {
+	switch mode {
+	case gitea.AccessModeOwner, gitea.AccessModeAdmin:
+		return &model.Perm{Pull: true, Push: true, Admin: true}
+	case gitea.AccessModeWrite:
+		return &model.Perm{Pull: true, Push: true}
+	case gitea.AccessModeRead:
+		return &model.Perm{Pull: true}
+	default:
+		return &model.Perm{}
+	}
+}
+
 // helper function that converts a Gitea team to a Woodpecker team.
-func toTeam(from *gitea.Organization, link string) *model.Team {
+func (c *Gitea) toTeam(from *gitea.Organization, link string) *model.Team {
 	return &model.Team{
 		Login:  from.UserName,
-		Avatar: expandAvatar(link, from.AvatarURL),
+		Avatar: c.expandAvatar(link, from.AvatarURL),
 	}
 }
 
+// branchRef returns the full ref and the name of a branch, Gitea sends both as
+// ref depending on the event.
+func branchRef(ref string) (string, string) {
+	branch := strings.TrimPrefix(ref, refHeadsPrefix)
+	return refHeadsPrefix + branch, branch
+}
+
+// tagRef returns the full ref and the name of a tag, Gitea sends both as ref
+// depending on the event.
+func tagRef(ref string) (string, string) {
+	tag := strings.TrimPrefix(ref, refTagsPrefix)
+	return refTagsPrefix + tag, tag
+}
+
+// isCommitSHA reports whether the ref is a full SHA-1 or SHA-256 commit sha.
+func isCommitSHA(ref string) bool {
+	if len(ref) != hex.EncodedLen(sha1.Size) && len(ref) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(ref)
+	return err == nil
+}
+
 // helper function that extracts the Build data from a Gitea push hook
-func buildFromPush(hook *pushHook) *model.Build {
-	avatar := expandAvatar(
+func (c *Gitea) buildFromPush(hook *pushHook) *model.Build {
+	avatar := c.expandAvatar(
 		hook.Repo.URL,
 		fixMalformedAvatar(hook.Sender.Avatar),
 	)
@@ -81,31 +205,103 @@ func buildFromPush(hook *pushHook) *model.Build {
 	}
 
 	message := ""
+	email := hook.Sender.Email
 	link := hook.Compare
 	if len(hook.Commits) > 0 {
-		message = hook.Commits[0].Message
+		message = strings.ReplaceAll(hook.Commits[0].Message, "\r\n", "\n")
+
+		// prefer the commit author over the pushing user
+		commitAuthor := hook.Commits[0].Author
+		if commitAuthor.Email != "" {
+			email = commitAuthor.Email
+		}
+		if commitAuthor.Username != "" {
+			author = commitAuthor.Username
+		} else if commitAuthor.Name != "" {
+			author = commitAuthor.Name
+		}
+	}
+
+	// the committer differs from the author e.g. for applied patches, Gitea
+	// versions not sending the committer are treated as committed by the author
+	committer := &model.Committer{Name: author, Email: email}
+	if len(hook.Commits) > 0 {
+		commitCommitter := hook.Commits[0].Committer
+		if commitCommitter.Username != "" {
+			committer.Name = commitCommitter.Username
+		} else if commitCommitter.Name != "" {
+			committer.Name = commitCommitter.Name
+		}
+		if commitCommitter.Email != "" {
+			committer.Email = commitCommitter.Email
+		}
 	}
 
 	if len(hook.Commits) == 1 {
 		link = hook.Commits[0].URL
 	}
 
+	timestamp := hook.HeadCommit.Timestamp
+	if timestamp == "" && len(hook.Commits) > 0 {
+		timestamp = hook.Commits[0].Timestamp
+	}
+
+	files, truncated := c.capChangedFiles(getChangedFilesFromPushHook(hook))
+	verified, signer := headVerification(hook)
+	ref, branch := branchRef(hook.Ref)
+
+	// pushes creating a branch have no previous commit
+	before := hook.Before
+	if before == zeroSha {
+		before = ""
+	}
+
 	return &model.Build{
 		Event:        model.EventPush,
 		Commit:       hook.After,
-		Ref:          hook.Ref,
+		Before:       before,
+		Ref:          ref,
 		Link:         link,
-		Branch:       strings.TrimPrefix(hook.Ref, "refs/heads/"),
-		Message:      message,
+		Branch:       branch,
+		Title:        c.truncate(commitTitle(message)),
+		Message:      c.truncate(message),
 		Avatar:       avatar,
 		Author:       author,
-		Email:        hook.Sender.Email,
-		Timestamp:    time.Now().UTC().Unix(),
+		Email:        email,
+		Committer:    committer,
+		Timestamp:    commitTimestamp(timestamp),
 		Sender:       sender,
-		ChangedFiles: getChangedFilesFromPushHook(hook),
+		ChangedFiles: files,
+		Truncated:    truncated,
+		IsVerified:   verified,
+		Signer:       signer,
 	}
 }
 
+// headVerification returns whether the signature of the head commit of the push
+// was verified by Gitea and the user it was signed by. Commits without
+// verification info are unverified.
+func headVerification(hook *pushHook) (bool, string) {
+	verification := hook.HeadCommit.Verification
+	for _, commit := range hook.Commits {
+		if verification == nil && commit.ID == hook.After {
+			verification = commit.Verification
+		}
+	}
+	if verification == nil || !verification.Verified {
+		return false, ""
+	}
+
+	signer := ""
+	if verification.Signer != nil {
+		signer = verification.Signer.Username
+		if signer == "" {
+			signer = verification.Signer.Name
+		}
+	}
+	return true, signer
+}
+
 func getChangedFilesFromPushHook(hook *pushHook) []string {
 	// assume a capacity of 4 changed files per commit
 	files := make([]string, 0, len(hook.Commits)*4)
@@ -118,9 +314,60 @@ func getChangedFilesFromPushHook(hook *pushHook) []string {
 	return utils.DedupStrings(files)
 }
 
+// needsCompare reports whether the changed files of a push can't be derived
+// from its commits, as it was forced, not all commits are listed or a commit
+// lacks its file lists. Pushes creating a branch have nothing to compare with.
+func needsCompare(hook *pushHook) bool {
+	if hook.Before == "" || hook.Before == zeroSha {
+		return false
+	}
+	if hook.Forced || hook.TotalCommits > len(hook.Commits) {
+		return true
+	}
+	for _, commit := range hook.Commits {
+		// a commit changes files, so lists missing altogether were dropped
+		if commit.Added == nil && commit.Removed == nil && commit.Modified == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// capChangedFiles truncates the deduplicated list of changed files to the
+// configured maximum and reports whether files were dropped.
+func (c *Gitea) capChangedFiles(files []string) ([]string, bool) {
+	if c.MaxChangedFiles <= 0 || len(files) <= c.MaxChangedFiles {
+		return files, false
+	}
+	return files[:c.MaxChangedFiles], true
+}
+
+// helper function that converts a Gitea commit to a resolved commit. The
+// author is the Gitea user of the commit author if known.
+func (c *Gitea) toCommit(r *model.Repo, from *gitea.Commit) *remote.Commit {
+	commit := &remote.Commit{
+		SHA:       from.SHA,
+		Link:      from.HTMLURL,
+		Timestamp: from.Created.UTC().Unix(),
+	}
+	if from.RepoCommit != nil {
+		commit.Message = from.RepoCommit.Message
+		if from.RepoCommit.Author != nil {
+			commit.Author = from.RepoCommit.Author.Name
+			commit.Email = from.RepoCommit.Author.Email
+			commit.Timestamp = commitTimestamp(from.RepoCommit.Author.Date)
+		}
+	}
+	if from.Author != nil {
+		commit.Author = from.Author.UserName
+		commit.Avatar = c.expandAvatar(r.Link, from.Author.AvatarURL)
+	}
+	return commit
+}
+
 // helper function that extracts the Build data from a Gitea tag hook
-func buildFromTag(hook *pushHook) *model.Build {
-	avatar := expandAvatar(
+func (c *Gitea) buildFromTag(hook *pushHook) *model.Build {
+	avatar := c.expandAvatar(
 		hook.Repo.URL,
 		fixMalformedAvatar(hook.Sender.Avatar),
 	)
@@ -133,23 +380,67 @@ func buildFromTag(hook *pushHook) *model.Build {
 		sender = hook.Sender.Login
 	}
 
+	ref, tag := tagRef(hook.Ref)
+
+	// tags are no branches, so tag builds have none
 	return &model.Build{
 		Event:     model.EventTag,
-		Commit:    hook.Sha,
-		Ref:       fmt.Sprintf("refs/tags/%s", hook.Ref),
-		Link:      fmt.Sprintf("%s/src/tag/%s", hook.Repo.URL, hook.Ref),
-		Branch:    fmt.Sprintf("refs/tags/%s", hook.Ref),
-		Message:   fmt.Sprintf("created tag %s", hook.Ref),
+		Commit:    tagSha(hook),
+		Ref:       ref,
+		Link:      fmt.Sprintf("%s/src/tag/%s", hook.Repo.URL, tag),
+		Message:   fmt.Sprintf("created tag %s", tag),
+		Avatar:    avatar,
+		Author:    author,
+		Sender:    sender,
+		Timestamp: commitTimestamp(hook.HeadCommit.Timestamp),
+	}
+}
+
+// helper function that extracts the Build data from a Gitea delete hook. As the
+// deleted ref can not be cloned anymore the build runs for the head of the
+// default branch, which is resolved by Hook.
+func (c *Gitea) buildFromDelete(hook *pushHook) *model.Build {
+	avatar := c.expandAvatar(
+		hook.Repo.URL,
+		fixMalformedAvatar(hook.Sender.Avatar),
+	)
+	author := hook.Sender.Login
+	if author == "" {
+		author = hook.Sender.Username
+	}
+	sender := hook.Sender.Username
+	if sender == "" {
+		sender = hook.Sender.Login
+	}
+
+	_, name := branchRef(hook.Ref)
+	if hook.RefType == refTag {
+		_, name = tagRef(hook.Ref)
+	}
+	title := fmt.Sprintf("Delete %s %s", hook.RefType, name)
+	ref, branch := branchRef(hook.Repo.Branch)
+
+	return &model.Build{
+		Event:     model.EventDelete,
+		Ref:       ref,
+		Link:      hook.Repo.URL,
+		Branch:    branch,
+		Title:     c.truncate(title),
+		Message:   c.truncate(title),
 		Avatar:    avatar,
 		Author:    author,
 		Sender:    sender,
 		Timestamp: time.Now().UTC().Unix(),
+		DeletedRef: &model.DeletedRef{
+			Type: hook.RefType,
+			Name: name,
+		},
 	}
 }
 
 // helper function that extracts the Build data from a Gitea pull_request hook
-func buildFromPullRequest(hook *pullRequestHook) *model.Build {
-	avatar := expandAvatar(
+func (c *Gitea) buildFromPullRequest(hook *pullRequestHook) *model.Build {
+	avatar := c.expandAvatar(
 		hook.Repo.URL,
 		fixMalformedAvatar(hook.PullRequest.User.Avatar),
 	)
@@ -157,25 +448,146 @@ func buildFromPullRequest(hook *pullRequestHook) *model.Build {
 	if sender == "" {
 		sender = hook.Sender.Login
 	}
+	_, base := branchRef(hook.PullRequest.Base.Ref)
 	build := &model.Build{
 		Event:   model.EventPull,
+		Action:  hook.Action,
 		Commit:  hook.PullRequest.Head.Sha,
 		Link:    hook.PullRequest.URL,
 		Ref:     fmt.Sprintf("refs/pull/%d/head", hook.Number),
-		Branch:  hook.PullRequest.Base.Ref,
-		Message: hook.PullRequest.Title,
+		Branch:  base,
+		Message: c.truncate(hook.PullRequest.Title),
 		Author:  hook.PullRequest.User.Username,
 		Avatar:  avatar,
 		Sender:  sender,
-		Title:   hook.PullRequest.Title,
-		Refspec: fmt.Sprintf("%s:%s",
+		Title:   c.truncate(hook.PullRequest.Title),
+		Refspec: pullRefspec(hook.Number,
 			hook.PullRequest.Head.Ref,
 			hook.PullRequest.Base.Ref,
 		),
+		SourceBranch: sourceBranch(hook.PullRequest.Head.Ref),
+		PullRequest:  hook.Number,
+		IsDraft:      isDraftPullRequest(hook),
+		Labels:       labelsFromPullRequest(hook),
+		Assignees:    assigneesFromPullRequest(hook),
+		Milestone:    milestoneFromPullRequest(hook),
+		BaseCommit:   hook.PullRequest.Base.Sha,
+		MergeBase:    hook.PullRequest.MergeBase,
+		MergeCommit:  hook.PullRequest.MergeSha,
+		// the payload does not contain the time of the head commit
+		Timestamp: time.Now().UTC().Unix(),
+	}
+
+	// pull requests from forks are fetched from the head repository if the
+	// pull request ref of the base repository is unavailable
+	if head := hook.PullRequest.Head.Repo; head.ID != 0 && head.ID != hook.PullRequest.Base.Repo.ID {
+		build.IsFork = true
+		if !hasPullRef(hook.Number) && hasSourceBranch(hook.PullRequest.Head.Ref) {
+			build.Remote = head.CloneURL
+		}
 	}
 	return build
 }
 
+// pullRefspec returns the refspec of a pull request build. The ref
+// refs/pull/<n>/head, which Gitea maintains in the base repository, is
+// fetched as it is still valid once the branch of the contributor is deleted.
+// The branch of the contributor is only fetched if the pull ref is unavailable.
+func pullRefspec(number int64, head, base string) string {
+	if hasPullRef(number) || !hasSourceBranch(head) {
+		head = fmt.Sprintf("refs/pull/%d/head", number)
+	}
+	return fmt.Sprintf("%s:%s", head, base)
+}
+
+// hasPullRef reports whether Gitea maintains the ref refs/pull/<n>/head of a
+// pull request, which requires the number of the pull request.
+func hasPullRef(number int64) bool {
+	return number > 0
+}
+
+// sourceBranch returns the branch of the contributor, or an empty string if
+// the branch or its repository was deleted.
+func sourceBranch(head string) string {
+	if !hasSourceBranch(head) {
+		return ""
+	}
+	return head
+}
+
+// hasSourceBranch reports whether the head ref of a pull request names the
+// branch of the contributor. Gitea reports the pull request ref instead if the
+// branch or its repository was deleted.
+func hasSourceBranch(head string) bool {
+	return head != "" && !strings.HasPrefix(head, "refs/pull/")
+}
+
+// helper function that extracts the Build data from a Gitea pull request
+// review hook. The state of the review is recorded on the build, an approving
+// reviewer also as its approver. Gitea updates the pull request when a review
+// is submitted, so its update time is the time of the approval.
+func (c *Gitea) buildFromPullRequestReview(hook *pullRequestHook, state model.ReviewState) *model.Build {
+	build := c.buildFromPullRequest(hook)
+	build.ReviewState = state
+	if state != model.ReviewApproved {
+		build.Action = actionReviewed
+		return build
+	}
+	build.Action = actionApproved
+	build.Approver = hook.Sender.Username
+	if build.Approver == "" {
+		build.Approver = hook.Sender.Login
+	}
+	if t, err := time.Parse(time.RFC3339, hook.PullRequest.Updated); err == nil {
+		build.Approved = t.UTC().Unix()
+	}
+	return build
+}
+
+// helper function that extracts the Build data from a Gitea release hook
+func (c *Gitea) buildFromRelease(hook *releaseHook) *model.Build {
+	avatar := c.expandAvatar(
+		hook.Repo.URL,
+		fixMalformedAvatar(hook.Release.Author.Avatar),
+	)
+	author := hook.Release.Author.Login
+	if author == "" {
+		author = hook.Release.Author.Username
+	}
+	sender := hook.Sender.Username
+	if sender == "" {
+		sender = hook.Sender.Login
+	}
+
+	message := hook.Release.Title
+	if hook.Release.Note != "" {
+		message += "\n\n" + hook.Release.Note
+	}
+
+	ref, _ := tagRef(hook.Release.TagName)
+	// releases may target a commit instead of a branch, their builds have no
+	// branch like the builds of tags
+	branch := ""
+	if !isCommitSHA(hook.Release.Target) {
+		_, branch = branchRef(hook.Release.Target)
+	}
+
+	return &model.Build{
+		Event:        model.EventRelease,
+		Ref:          ref,
+		Link:         hook.Release.URL,
+		Branch:       branch,
+		Title:        c.truncate(hook.Release.Title),
+		Message:      c.truncate(message),
+		Avatar:       avatar,
+		Author:       author,
+		Email:        hook.Release.Author.Email,
+		Sender:       sender,
+		IsPrerelease: hook.Release.Prerelease,
+		Timestamp:    time.Now().UTC().Unix(),
+	}
+}
+
 // helper function that extracts the Repository data from a Gitea push hook
 func repoFromPush(hook *pushHook) *model.Repo {
 	return &model.Repo{
@@ -183,6 +595,9 @@ func repoFromPush(hook *pushHook) *model.Repo {
 		Owner:    hook.Repo.Owner.Username,
 		FullName: hook.Repo.FullName,
 		Link:     hook.Repo.URL,
+		Branch:   hook.Repo.Branch,
+
+		IsSCMPrivate: hook.Repo.Private,
 	}
 }
 
@@ -193,9 +608,253 @@ func repoFromPullRequest(hook *pullRequestHook) *model.Repo {
 		Owner:    hook.Repo.Owner.Username,
 		FullName: hook.Repo.FullName,
 		Link:     hook.Repo.URL,
+		Branch:   hook.Repo.Branch,
+
+		IsSCMPrivate: hook.Repo.Private,
+	}
+}
+
+// helper function that extracts the Repository data from a Gitea comment hook
+func repoFromIssueComment(hook *issueCommentHook) *model.Repo {
+	return &model.Repo{
+		Name:     hook.Repo.Name,
+		Owner:    hook.Repo.Owner.Username,
+		FullName: hook.Repo.FullName,
+		Link:     hook.Repo.URL,
+		Branch:   hook.Repo.Branch,
+
+		IsSCMPrivate: hook.Repo.Private,
+	}
+}
+
+// helper function that extracts the partial Build data of a rebuild command
+// from a Gitea comment hook, see buildFromPullRequestRebuild.
+func buildFromIssueComment(hook *issueCommentHook) *model.Build {
+	sender := hook.Comment.User.Username
+	if sender == "" {
+		sender = hook.Comment.User.Login
+	}
+	return &model.Build{
+		Event:  model.EventPull,
+		Action: actionRebuild,
+		Ref:    fmt.Sprintf("refs/pull/%d/head", hook.Issue.Number),
+		Sender: sender,
+	}
+}
+
+// helper function that returns the label names of the pull request.
+func labelsFromPullRequest(hook *pullRequestHook) []string {
+	labels := make([]string, 0, len(hook.PullRequest.Labels))
+	for _, label := range hook.PullRequest.Labels {
+		labels = append(labels, label.Name)
+	}
+	return labels
+}
+
+// helper function that returns the logins of the assignees of the pull
+// request.
+func assigneesFromPullRequest(hook *pullRequestHook) []string {
+	assignees := make([]string, 0, len(hook.PullRequest.Assignees))
+	for _, assignee := range hook.PullRequest.Assignees {
+		login := assignee.Login
+		if login == "" {
+			login = assignee.Username
+		}
+		assignees = append(assignees, login)
+	}
+	return assignees
+}
+
+// helper function that returns the milestone of the pull request, or nil if it
+// has none.
+func milestoneFromPullRequest(hook *pullRequestHook) *model.Milestone {
+	if hook.PullRequest.Milestone == nil {
+		return nil
+	}
+	return &model.Milestone{
+		ID:    hook.PullRequest.Milestone.ID,
+		Title: hook.PullRequest.Milestone.Title,
+	}
+}
+
+// helper function that completes the Build of a rebuild command with the
+// current state of the Gitea pull request.
+func (c *Gitea) buildFromPullRequestRebuild(from *model.Build, pr *gitea.PullRequest) *model.Build {
+	build := &model.Build{
+		Event:   model.EventPull,
+		Action:  from.Action,
+		Ref:     from.Ref,
+		Sender:  from.Sender,
+		Link:    pr.HTMLURL,
+		Message: c.truncate(pr.Title),
+		Title:   c.truncate(pr.Title),
+		IsDraft: isDraftTitle(pr.Title),
+		Labels:  make([]string, 0, len(pr.Labels)),
+
+		Assignees:   make([]string, 0, len(pr.Assignees)),
+		PullRequest: pr.Index,
+		MergeBase:   pr.MergeBase,
+		Timestamp:   time.Now().UTC().Unix(),
+	}
+	// labels are taken from the pull request as they may have changed
+	for _, label := range pr.Labels {
+		build.Labels = append(build.Labels, label.Name)
+	}
+	for _, assignee := range pr.Assignees {
+		build.Assignees = append(build.Assignees, assignee.UserName)
+	}
+	if pr.Milestone != nil {
+		build.Milestone = &model.Milestone{ID: pr.Milestone.ID, Title: pr.Milestone.Title}
+	}
+	if pr.Poster != nil {
+		build.Author = pr.Poster.UserName
+		build.Avatar = c.expandAvatar(pr.HTMLURL, fixMalformedAvatar(pr.Poster.AvatarURL))
+	}
+	if pr.MergedCommitID != nil {
+		build.MergeCommit = *pr.MergedCommitID
+	}
+	if pr.Head != nil && pr.Base != nil {
+		build.Commit = pr.Head.Sha
+		build.Branch = pr.Base.Ref
+		build.BaseCommit = pr.Base.Sha
+		build.Refspec = pullRefspec(pr.Index, pr.Head.Ref, pr.Base.Ref)
+		build.SourceBranch = sourceBranch(pr.Head.Ref)
+
+		// pull requests from forks are fetched from the head repository if
+		// the pull request ref of the base repository is unavailable
+		if head, base := pr.Head.Repository, pr.Base.Repository; head != nil && base != nil && head.ID != 0 && head.ID != base.ID {
+			build.IsFork = true
+			if !hasPullRef(pr.Index) && hasSourceBranch(pr.Head.Ref) {
+				build.Remote = head.CloneURL
+			}
+		}
+	}
+	return build
+}
+
+// helper function that extracts the Build data from a Gitea package hook. As
+// packages have no commit the build runs for the head of the default branch,
+// which is resolved by Hook.
+func (c *Gitea) buildFromPackage(hook *packageHook) *model.Build {
+	avatar := c.expandAvatar(
+		hook.Repo.URL,
+		fixMalformedAvatar(hook.Package.Creator.Avatar),
+	)
+	author := hook.Package.Creator.Login
+	if author == "" {
+		author = hook.Package.Creator.Username
+	}
+	sender := hook.Sender.Username
+	if sender == "" {
+		sender = hook.Sender.Login
+	}
+
+	title := fmt.Sprintf("Package %s %s %s", hook.Package.Name, hook.Package.Version, hook.Action)
+	ref, branch := branchRef(hook.Repo.Branch)
+
+	return &model.Build{
+		Event:     model.EventPackage,
+		Ref:       ref,
+		Link:      hook.Package.URL,
+		Branch:    branch,
+		Title:     c.truncate(title),
+		Message:   c.truncate(title),
+		Avatar:    avatar,
+		Author:    author,
+		Email:     hook.Package.Creator.Email,
+		Sender:    sender,
+		Timestamp: time.Now().UTC().Unix(),
+		Package: &model.Package{
+			Type:    hook.Package.Type,
+			Name:    hook.Package.Name,
+			Version: hook.Package.Version,
+			Action:  hook.Action,
+		},
+	}
+}
+
+// helper function that extracts the Repository data from a Gitea package hook
+func repoFromPackage(hook *packageHook) *model.Repo {
+	return &model.Repo{
+		Name:     hook.Repo.Name,
+		Owner:    hook.Repo.Owner.Username,
+		FullName: hook.Repo.FullName,
+		Link:     hook.Repo.URL,
+		Branch:   hook.Repo.Branch,
+
+		IsSCMPrivate: hook.Repo.Private,
 	}
 }
 
+func repoFromRepository(hook *repositoryHook) *model.Repo {
+	return &model.Repo{
+		Name:     hook.Repo.Name,
+		Owner:    hook.Repo.Owner.Username,
+		FullName: hook.Repo.FullName,
+		Link:     hook.Repo.URL,
+	}
+}
+
+// helper function that extracts the Repository data from a Gitea release hook
+func repoFromRelease(hook *releaseHook) *model.Repo {
+	return &model.Repo{
+		Name:     hook.Repo.Name,
+		Owner:    hook.Repo.Owner.Username,
+		FullName: hook.Repo.FullName,
+		Link:     hook.Repo.URL,
+		Branch:   hook.Repo.Branch,
+
+		IsSCMPrivate: hook.Repo.Private,
+	}
+}
+
+// commitTimestamp returns the unix time of a commit timestamp sent by Gitea,
+// falling back to the current time if the timestamp is missing or invalid.
+func commitTimestamp(timestamp string) int64 {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil || t.IsZero() {
+		return time.Now().UTC().Unix()
+	}
+	return t.UTC().Unix()
+}
+
+// truncate shortens titles and messages longer than the configured maximum
+// number of characters and ends them with an ellipsis. Messages are cut at the
+// end of the last line which fits, so the first line is kept where possible.
+func (c *Gitea) truncate(message string) string {
+	runes := []rune(message)
+	if c.MaxMessageLen <= 0 || len(runes) <= c.MaxMessageLen {
+		return message
+	}
+	cut := string(runes[:c.MaxMessageLen-1])
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		return strings.TrimRight(cut[:i], "\r\n") + "\n…"
+	}
+	return cut + "…"
+}
+
+// commitTitle returns the first line of a commit message.
+func commitTitle(message string) string {
+	title := strings.SplitN(message, "\n", 2)[0]
+	return strings.TrimSpace(title)
+}
+
+// isDeletePush reports whether the push or tag hook was sent for a deleted ref.
+func isDeletePush(hook *pushHook) bool {
+	return hook.After == zeroSha || hook.Sha == zeroSha
+}
+
+// tagSha returns the commit sha of a tag hook. Depending on the Gitea version
+// and the tag type the sha is not always sent in the same field.
+func tagSha(hook *pushHook) string {
+	for _, sha := range []string{hook.Sha, hook.After, hook.HeadCommit.ID} {
+		if sha != "" && sha != zeroSha {
+			return sha
+		}
+	}
+	return ""
+}
+
 // helper function that parses a push hook from a read closer.
 func parsePush(r io.Reader) (*pushHook, error) {
 	push := new(pushHook)
@@ -209,24 +868,121 @@ func parsePullRequest(r io.Reader) (*pullRequestHook, error) {
 	return pr, err
 }
 
+func parseRelease(r io.Reader) (*releaseHook, error) {
+	release := new(releaseHook)
+	err := json.NewDecoder(r).Decode(release)
+	return release, err
+}
+
+func parseIssueComment(r io.Reader) (*issueCommentHook, error) {
+	comment := new(issueCommentHook)
+	err := json.NewDecoder(r).Decode(comment)
+	return comment, err
+}
+
+func parsePackage(r io.Reader) (*packageHook, error) {
+	pkg := new(packageHook)
+	err := json.NewDecoder(r).Decode(pkg)
+	return pkg, err
+}
+
+func parseRepository(r io.Reader) (*repositoryHook, error) {
+	repository := new(repositoryHook)
+	err := json.NewDecoder(r).Decode(repository)
+	return repository, err
+}
+
+func parseWiki(r io.Reader) (*wikiHook, error) {
+	wiki := new(wikiHook)
+	err := json.NewDecoder(r).Decode(wiki)
+	return wiki, err
+}
+
+// userAvatar returns the avatar url of the Gitea account, fixed and expanded
+// like the avatars of hooks.
+func (c *Gitea) userAvatar(account *gitea.User) string {
+	return c.fallbackAvatar(c.expandAvatar(c.URL, fixMalformedAvatar(account.AvatarURL)), account.Email)
+}
+
 // fixMalformedAvatar is a helper function that fixes an avatar url if malformed
-// (currently a known bug with gitea)
-func fixMalformedAvatar(url string) string {
-	index := strings.Index(url, "///")
-	if index != -1 {
-		return url[index+1:]
+// (currently a known bug with gitea). Duplicate slashes are only normalized in
+// the path, the "://" of the scheme is kept.
+func fixMalformedAvatar(rawurl string) string {
+	// "//avatars/1" is a path with a duplicate slash, not a protocol relative url
+	if strings.HasPrefix(rawurl, "//avatars/") {
+		return rawurl[1:]
 	}
-	index = strings.Index(url, "//avatars/")
-	if index != -1 {
-		return strings.Replace(url, "//avatars/", "/avatars/", -1)
+
+	aurl, err := parseAvatarURL(rawurl)
+	if err != nil {
+		return rawurl
 	}
-	return url
+
+	switch {
+	case aurl.Host != "" && strings.HasPrefix(aurl.Path, "///"):
+		// Gitea prefixed a protocol relative url with its own url,
+		// e.g. http://gitea.golang.org///1.gravatar.com/avatar/...
+		aurl = &url.URL{
+			Path:     "//" + strings.TrimLeft(aurl.Path, "/"),
+			RawQuery: aurl.RawQuery,
+		}
+		return aurl.String()
+	case aurl.Scheme != "" && aurl.Host == "":
+		// the host is missing, e.g. https:///avatars/1 which is a path on the
+		// Gitea server, or https:///1.gravatar.com/avatar/... where the host
+		// ended up in the path
+		parts := strings.SplitN(strings.TrimLeft(aurl.Path, "/"), "/", 2)
+		if len(parts) == 2 && strings.Contains(parts[0], ".") {
+			aurl.Host = parts[0]
+			aurl.Path = "/" + parts[1]
+		} else {
+			aurl.Scheme = ""
+		}
+	}
+
+	aurl.Path = dedupSlashes(aurl.Path)
+	// keep the original encoding, e.g. of an escaped slash
+	aurl.RawPath = dedupSlashes(aurl.RawPath)
+	return aurl.String()
 }
 
-// expandAvatar is a helper function that converts a relative avatar URL to the
-// absolute url.
-func expandAvatar(repo, rawurl string) string {
+// parseAvatarURL parses the avatar url, a percent sign not starting an escape
+// sequence is escaped instead of failing.
+func parseAvatarURL(rawurl string) (*url.URL, error) {
 	aurl, err := url.Parse(rawurl)
+	if err == nil {
+		return aurl, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(rawurl); i++ {
+		if rawurl[i] == '%' && (i+2 >= len(rawurl) || !isHex(rawurl[i+1]) || !isHex(rawurl[i+2])) {
+			b.WriteString("%25")
+			continue
+		}
+		b.WriteByte(rawurl[i])
+	}
+	return url.Parse(b.String())
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// dedupSlashes replaces consecutive slashes in the url path with a single one.
+func dedupSlashes(p string) string {
+	for strings.Contains(p, "//") {
+		p = strings.ReplaceAll(p, "//", "/")
+	}
+	return p
+}
+
+// expandAvatar is a helper function that converts a relative avatar URL to the
+// absolute url. Absolute avatar urls are returned as is. Relative ones are
+// resolved against the configured AvatarBaseURL if set, otherwise against
+// the given repository or server link.
+func (c *Gitea) expandAvatar(repo, rawurl string) string {
+	aurl, err := parseAvatarURL(rawurl)
 	if err != nil {
 		return rawurl
 	}
@@ -235,29 +991,212 @@ func expandAvatar(repo, rawurl string) string {
 		return aurl.String()
 	}
 
+	base := repo
+	if c.AvatarBaseURL != "" {
+		base = c.AvatarBaseURL
+	}
+
 	// Resolve to base
-	burl, err := url.Parse(repo)
+	burl, err := url.Parse(base)
 	if err != nil {
 		return rawurl
 	}
+	// instances hosted under a sub path serve avatars below it, Gitea either
+	// includes the sub path in the avatar url or not
+	if sub := c.basePath(); c.AvatarBaseURL == "" && sub != "" && aurl.Host == "" && strings.HasPrefix(aurl.Path, "/") && !hasPathPrefix(aurl.Path, sub) {
+		aurl.Path = sub + aurl.Path
+		if aurl.RawPath != "" {
+			aurl.RawPath = sub + aurl.RawPath
+		}
+	}
 	aurl = burl.ResolveReference(aurl)
 
 	return aurl.String()
 }
 
-// helper function to return matching hooks.
+// basePath returns the path Gitea is hosted under without trailing slash, it
+// is empty for instances hosted at the root of their host.
+func (c *Gitea) basePath() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+// cloneURL returns the url the repository is cloned from, its SSH url if
+// preferred. If Gitea only provides one of the urls, the other one is derived
+// from it.
+func (c *Gitea) cloneURL(from *gitea.Repository) string {
+	if c.CloneSSH {
+		if from.SSHURL != "" {
+			return from.SSHURL
+		}
+		return c.toSSHURL(from.CloneURL)
+	}
+	if from.CloneURL != "" {
+		return from.CloneURL
+	}
+	return c.toHTTPURL(from.SSHURL)
+}
+
+// toSSHURL rewrites an http clone url to the SSH clone url of the Gitea SSH
+// server. The sub path Gitea is hosted under is dropped. Urls of other forms
+// are returned as is.
+func (c *Gitea) toSSHURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return rawurl
+	}
+	p := u.Path
+	if sub := c.basePath(); sub != "" && hasPathPrefix(p, sub) {
+		p = strings.TrimPrefix(p, sub)
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	if c.SSHPort == 0 || c.SSHPort == defaultSSHPort {
+		return fmt.Sprintf("%s@%s:%s", sshUser, u.Hostname(), p)
+	}
+	return fmt.Sprintf("ssh://%s@%s/%s", sshUser, net.JoinHostPort(u.Hostname(), strconv.Itoa(c.SSHPort)), p)
+}
+
+// toHTTPURL rewrites an SSH clone url, either ssh://user@host:port/path or
+// user@host:path, to the http clone url below the url of Gitea. Urls of other
+// forms are returned as is.
+func (c *Gitea) toHTTPURL(rawurl string) string {
+	var host, p string
+	if strings.HasPrefix(rawurl, "ssh://") {
+		u, err := url.Parse(rawurl)
+		if err != nil || u.Host == "" {
+			return rawurl
+		}
+		host, p = u.Hostname(), u.Path
+	} else {
+		at := strings.Index(rawurl, "@")
+		colon := strings.Index(rawurl, ":")
+		if at <= 0 || colon < at || strings.Contains(rawurl[:colon], "/") {
+			return rawurl
+		}
+		host, p = rawurl[at+1:colon], rawurl[colon+1:]
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	if c.URL == "" {
+		return "https://" + host + "/" + p
+	}
+	return c.URL + "/" + p
+}
+
+// isDefaultAvatar reports whether the avatar url points to the placeholder
+// image Gitea serves for users without an avatar.
+func isDefaultAvatar(rawurl string) bool {
+	aurl, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(aurl.Path, "/avatar_default.png")
+}
+
+// gravatar is a helper function that returns the Gravatar url for the email
+// address. If a default is given, Gravatar redirects to it when no image is
+// registered for the address.
+func gravatar(email, def string) string {
+	hash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	link := fmt.Sprintf("https://www.gravatar.com/avatar/%x", hash)
+	if def != "" {
+		link += "?d=" + url.QueryEscape(def)
+	}
+	return link
+}
+
+// hookSig is a signature of a hook and the hash it was computed with.
+type hookSig struct {
+	newHash func() hash.Hash
+	sig     string
+}
+
+// hookSignaturesOf returns the signatures of the hook in the order they are
+// checked. The SHA-256 signature of the X-Gitea-Signature-256 header comes
+// first. The X-Gitea-Signature header carries a SHA-256 signature as well, or
+// a legacy SHA-1 one which is told apart by its prefix or length.
+func hookSignaturesOf(header http.Header) []hookSig {
+	var sigs []hookSig
+	if sig := header.Get(hookSignature256); sig != "" {
+		sigs = append(sigs, hookSig{sha256.New, strings.TrimPrefix(sig, "sha256=")})
+	}
+	sig := header.Get(hookSignature)
+	if sig == "" && len(sigs) != 0 {
+		return sigs
+	}
+	if strings.HasPrefix(sig, "sha1=") || len(sig) == hex.EncodedLen(sha1.Size) {
+		return append(sigs, hookSig{sha1.New, strings.TrimPrefix(sig, "sha1=")})
+	}
+	return append(sigs, hookSig{sha256.New, strings.TrimPrefix(sig, "sha256=")})
+}
+
+// verifySignature reports whether sig is the hex encoded HMAC of the body
+// using the given hash and secret.
+func verifySignature(newHash func() hash.Hash, secret string, body []byte, sig string) bool {
+	decoded, err := hex.DecodeString(sig)
+	if err != nil || len(decoded) == 0 {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	_, _ = mac.Write(body)
+	return hmac.Equal(decoded, mac.Sum(nil))
+}
+
+// helper function to return matching hooks. A hook matches if scheme and host
+// are equal and its path lies below the path of the given url, query and
+// trailing slashes are ignored. If no hook matches that way but exactly one
+// hook is registered for the host, it is returned to stay compatible with
+// single instance setups whose url has changed.
 func matchingHooks(hooks []*gitea.Hook, rawurl string) *gitea.Hook {
+	matches, hostMatches := matchHooks(hooks, rawurl)
+	if len(matches) != 0 {
+		return matches[0]
+	}
+	if len(hostMatches) == 1 {
+		return hostMatches[0]
+	}
+	return nil
+}
+
+// helper function to return all hooks matching the url like matchingHooks,
+// without falling back to hooks only matching the host.
+func allMatchingHooks(hooks []*gitea.Hook, rawurl string) []*gitea.Hook {
+	matches, _ := matchHooks(hooks, rawurl)
+	return matches
+}
+
+// matchHooks returns the hooks matching scheme, host and path of the url and
+// the hooks only matching its host.
+func matchHooks(hooks []*gitea.Hook, rawurl string) (matches, hostMatches []*gitea.Hook) {
 	link, err := url.Parse(rawurl)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
+
 	for _, hook := range hooks {
 		if val, ok := hook.Config["url"]; ok {
 			hookurl, err := url.Parse(val)
-			if err == nil && hookurl.Host == link.Host {
-				return hook
+			if err != nil || hookurl.Host != link.Host {
+				continue
+			}
+			if strings.EqualFold(hookurl.Scheme, link.Scheme) && hasPathPrefix(hookurl.Path, link.Path) {
+				matches = append(matches, hook)
+				continue
 			}
+			hostMatches = append(hostMatches, hook)
 		}
 	}
-	return nil
+	return matches, hostMatches
+}
+
+// hasPathPrefix reports whether the url path p lies below prefix, comparing
+// whole path segments only.
+func hasPathPrefix(p, prefix string) bool {
+	p = strings.TrimRight(p, "/") + "/"
+	prefix = strings.TrimRight(prefix, "/") + "/"
+	return strings.HasPrefix(p, prefix)
 }
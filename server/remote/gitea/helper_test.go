@@ -16,17 +16,25 @@ package gitea
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/franela/goblin"
 
+	"github.com/woodpecker-ci/woodpecker/pipeline/frontend/yaml/constraint"
 	"github.com/woodpecker-ci/woodpecker/server/model"
 	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
 	"github.com/woodpecker-ci/woodpecker/shared/utils"
 )
 
 func Test_parse(t *testing.T) {
+	c := new(Gitea)
 	g := goblin.Goblin(t)
 	g.Describe("Gitea", func() {
 		g.It("Should parse push hook payload", func() {
@@ -98,16 +106,186 @@ func Test_parse(t *testing.T) {
 		g.It("Should return a Build struct from a push hook", func() {
 			buf := bytes.NewBufferString(fixtures.HookPush)
 			hook, _ := parsePush(buf)
-			build := buildFromPush(hook)
+			build := c.buildFromPush(hook)
 			g.Assert(build.Event).Equal(model.EventPush)
 			g.Assert(build.Commit).Equal(hook.After)
+			g.Assert(build.Before).Equal(hook.Before)
 			g.Assert(build.Ref).Equal(hook.Ref)
 			g.Assert(build.Link).Equal(hook.Commits[0].URL)
 			g.Assert(build.Branch).Equal("master")
+			g.Assert(build.PullRequest).Equal(int64(0))
 			g.Assert(build.Message).Equal(hook.Commits[0].Message)
 			g.Assert(build.Avatar).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
 			g.Assert(build.Author).Equal(hook.Sender.Login)
 			g.Assert(utils.EqualStringSlice(build.ChangedFiles, []string{"CHANGELOG.md", "app/controller/application.rb"})).IsTrue()
+			g.Assert(build.Timestamp).Equal(int64(1646134200))
+		})
+
+		g.It("Should not return the previous commit of a push creating a branch", func() {
+			hook, _ := parsePush(bytes.NewBufferString(fixtures.HookPush))
+			hook.Before = zeroSha
+			g.Assert(c.buildFromPush(hook).Before).Equal("")
+		})
+
+		g.It("Should return the verification of the head commit from a push hook", func() {
+			for payload, want := range map[string][2]interface{}{
+				fixtures.HookPushSigned:   {true, "gordon"},
+				fixtures.HookPushUnsigned: {false, ""},
+				fixtures.HookPush:         {false, ""},
+			} {
+				hook, _ := parsePush(bytes.NewBufferString(payload))
+				build := c.buildFromPush(hook)
+				g.Assert(build.IsVerified).Equal(want[0])
+				g.Assert(build.Signer).Equal(want[1])
+			}
+		})
+
+		g.It("Should split the commit message of a push hook", func() {
+			for _, test := range []struct {
+				message, title, body string
+			}{
+				{"fix build\n\nthe build was broken\n", "fix build", "fix build\n\nthe build was broken\n"},
+				{"fix build\r\n\r\nthe build was broken", "fix build", "fix build\n\nthe build was broken"},
+				{"fix build", "fix build", "fix build"},
+				{"", "", ""},
+			} {
+				buf := bytes.NewBufferString(fixtures.HookPush)
+				hook, _ := parsePush(buf)
+				hook.Commits[0].Message = test.message
+				build := c.buildFromPush(hook)
+				g.Assert(build.Title).Equal(test.title)
+				g.Assert(build.Message).Equal(test.body)
+			}
+		})
+
+		g.It("Should truncate long commit messages of a push hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPush)
+			hook, _ := parsePush(buf)
+			hook.Commits[0].Message = "fix build\n\nthe build was broken\nby the last commit"
+
+			build := (&Gitea{MaxMessageLen: 100}).buildFromPush(hook)
+			g.Assert(build.Title).Equal("fix build")
+			g.Assert(build.Message).Equal("fix build\n\nthe build was broken\nby the last commit")
+
+			build = (&Gitea{MaxMessageLen: 40}).buildFromPush(hook)
+			g.Assert(build.Title).Equal("fix build")
+			g.Assert(build.Message).Equal("fix build\n\nthe build was broken\n…")
+
+			build = (&Gitea{MaxMessageLen: 8}).buildFromPush(hook)
+			g.Assert(build.Title).Equal("fix bui…")
+			g.Assert(build.Message).Equal("fix bui…")
+		})
+
+		g.It("Should truncate long pull request titles by characters", func() {
+			buf := bytes.NewBufferString(fixtures.HookPullRequest)
+			hook, _ := parsePullRequest(buf)
+			hook.PullRequest.Title = strings.Repeat("ä", 30)
+
+			build := (&Gitea{MaxMessageLen: 30}).buildFromPullRequest(hook)
+			g.Assert(build.Title).Equal(hook.PullRequest.Title)
+
+			build = (&Gitea{MaxMessageLen: 10}).buildFromPullRequest(hook)
+			g.Assert(build.Title).Equal(strings.Repeat("ä", 9) + "…")
+			g.Assert(build.Message).Equal(build.Title)
+		})
+
+		g.It("Should fall back to the current time without commit timestamp", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushBranchDelete)
+			hook, _ := parsePush(buf)
+			before := time.Now().Unix()
+			build := c.buildFromPush(hook)
+			g.Assert(build.Timestamp >= before).IsTrue()
+		})
+
+		g.It("Should return the head commit timestamp from a tag hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushTag)
+			hook, _ := parsePush(buf)
+			hook.HeadCommit.Timestamp = "2022-03-01T11:30:00Z"
+			build := c.buildFromTag(hook)
+			g.Assert(build.Timestamp).Equal(int64(1646134200))
+		})
+
+		g.It("Should cap the changed files of a large push", func() {
+			commits := make([]string, 0, 100)
+			for i := 0; i < 100; i++ {
+				files := make([]string, 0, 10)
+				for j := 0; j < 10; j++ {
+					files = append(files, fmt.Sprintf("%q", fmt.Sprintf("dir%d/file%d", i, j)))
+				}
+				list := strings.Join(files, ",")
+				// every file is reported twice to check the cap counts unique files
+				commits = append(commits, fmt.Sprintf(`{"added":[%s],"modified":[%s]}`, list, list))
+			}
+			payload := fmt.Sprintf(`{"ref":"refs/heads/master","commits":[%s]}`, strings.Join(commits, ","))
+			hook, err := parsePush(bytes.NewBufferString(payload))
+			g.Assert(err).IsNil()
+
+			build := (&Gitea{MaxChangedFiles: 500}).buildFromPush(hook)
+			g.Assert(len(build.ChangedFiles)).Equal(500)
+			g.Assert(build.Truncated).IsTrue()
+
+			build = (&Gitea{MaxChangedFiles: 1000}).buildFromPush(hook)
+			g.Assert(len(build.ChangedFiles)).Equal(1000)
+			g.Assert(build.Truncated).IsFalse()
+		})
+
+		g.It("Should match path filters against the files of a squash merge", func() {
+			hook, err := parsePush(bytes.NewBufferString(fixtures.HookPushSquashMerge))
+			g.Assert(err).IsNil()
+			build := c.buildFromPush(hook)
+			g.Assert(len(build.ChangedFiles)).Equal(5)
+
+			docs := constraint.Path{Include: []string{"docs/**"}}
+			g.Assert(docs.Match(build.ChangedFiles, build.Message)).IsTrue()
+			noDocs := constraint.Path{Exclude: []string{"docs/**"}}
+			g.Assert(noDocs.Match(build.ChangedFiles, build.Message)).IsFalse()
+			code := constraint.Path{Include: []string{"**/*.go"}}
+			g.Assert(code.Match(build.ChangedFiles, build.Message)).IsFalse()
+		})
+
+		g.It("Should return the commit author from a push hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushOtherAuthor)
+			hook, _ := parsePush(buf)
+			build := c.buildFromPush(hook)
+			g.Assert(build.Author).Equal("Gopher Jr")
+			g.Assert(build.Email).Equal("gopher.jr@golang.org")
+			g.Assert(build.Sender).Equal(hook.Sender.Username)
+		})
+
+		g.It("Should return the committer distinct from the author from a push hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushOtherAuthor)
+			hook, _ := parsePush(buf)
+			hook.Commits[0].Committer.Name = "Gordon the Gopher"
+			hook.Commits[0].Committer.Email = "gordon@golang.org"
+			hook.Commits[0].Committer.Username = "gordon"
+			build := c.buildFromPush(hook)
+			g.Assert(build.Author).Equal("Gopher Jr")
+			g.Assert(build.Email).Equal("gopher.jr@golang.org")
+			g.Assert(build.Committer).Equal(&model.Committer{Name: "gordon", Email: "gordon@golang.org"})
+		})
+
+		g.It("Should return the committer identical to the author from a push hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushOtherAuthor)
+			hook, _ := parsePush(buf)
+			hook.Commits[0].Committer.Name = hook.Commits[0].Author.Name
+			hook.Commits[0].Committer.Email = hook.Commits[0].Author.Email
+			build := c.buildFromPush(hook)
+			g.Assert(build.Committer).Equal(&model.Committer{Name: build.Author, Email: build.Email})
+		})
+
+		g.It("Should fall back to the author without committer in a push hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPush)
+			hook, _ := parsePush(buf)
+			build := c.buildFromPush(hook)
+			g.Assert(build.Committer).Equal(&model.Committer{Name: "gordon", Email: "gordon@golang.org"})
+		})
+
+		g.It("Should return the sender from a push hook without commits", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushBranchDelete)
+			hook, _ := parsePush(buf)
+			build := c.buildFromPush(hook)
+			g.Assert(build.Author).Equal(hook.Sender.Login)
+			g.Assert(build.Email).Equal(hook.Sender.Email)
 		})
 
 		g.It("Should return a Repo struct from a push hook", func() {
@@ -118,35 +296,219 @@ func Test_parse(t *testing.T) {
 			g.Assert(repo.Owner).Equal(hook.Repo.Owner.Username)
 			g.Assert(repo.FullName).Equal("gordon/hello-world")
 			g.Assert(repo.Link).Equal(hook.Repo.URL)
+			g.Assert(repo.Branch).Equal("master")
+			g.Assert(repo.IsSCMPrivate).IsTrue()
+		})
+
+		g.It("Should return a Repo struct from a push hook without default branch", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushBranchDelete)
+			hook, _ := parsePush(buf)
+			repo := repoFromPush(hook)
+			g.Assert(repo.FullName).Equal("gordon/hello-world")
+			g.Assert(repo.Branch).Equal("")
 		})
 
 		g.It("Should return a Build struct from a tag hook", func() {
 			buf := bytes.NewBufferString(fixtures.HookPushTag)
 			hook, _ := parsePush(buf)
-			build := buildFromTag(hook)
+			build := c.buildFromTag(hook)
 			g.Assert(build.Event).Equal(model.EventTag)
 			g.Assert(build.Commit).Equal(hook.Sha)
 			g.Assert(build.Ref).Equal("refs/tags/v1.0.0")
-			g.Assert(build.Branch).Equal("refs/tags/v1.0.0")
+			g.Assert(build.Branch).Equal("")
 			g.Assert(build.Link).Equal("http://gitea.golang.org/gordon/hello-world/src/tag/v1.0.0")
 			g.Assert(build.Message).Equal("created tag v1.0.0")
 		})
 
+		g.It("Should return a Build struct from an annotated tag hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPushTagAnnotated)
+			hook, _ := parsePush(buf)
+			build := c.buildFromTag(hook)
+			g.Assert(build.Event).Equal(model.EventTag)
+			g.Assert(build.Commit).Equal("ef98532add3b2feb7a137426bba1248724367df5")
+			g.Assert(build.Ref).Equal("refs/tags/v1.0.0")
+			g.Assert(build.Branch).Equal("")
+			g.Assert(build.Link).Equal("http://gitea.golang.org/gordon/hello-world/src/tag/v1.0.0")
+			g.Assert(build.Message).Equal("created tag v1.0.0")
+		})
+
+		g.It("Should return the full ref and a clean branch for push and tag builds", func() {
+			push, _ := parsePush(bytes.NewBufferString(fixtures.HookPush))
+			pushBuild := c.buildFromPush(push)
+			g.Assert(pushBuild.Ref).Equal("refs/heads/master")
+			g.Assert(pushBuild.Branch).Equal("master")
+
+			tag, _ := parsePush(bytes.NewBufferString(fixtures.HookPushTag))
+			tagBuild := c.buildFromTag(tag)
+			g.Assert(tagBuild.Ref).Equal("refs/tags/v1.0.0")
+			g.Assert(tagBuild.Branch).Equal("")
+
+			// Gitea sends the tag name or the full ref depending on its version
+			tag.Ref = "refs/tags/v1.0.0"
+			g.Assert(c.buildFromTag(tag).Ref).Equal(tagBuild.Ref)
+
+			release, _ := parseRelease(bytes.NewBufferString(fixtures.HookRelease))
+			release.Release.Target = "refs/heads/master"
+			releaseBuild := c.buildFromRelease(release)
+			g.Assert(releaseBuild.Ref).Equal("refs/tags/v1.0.0")
+			g.Assert(releaseBuild.Branch).Equal("master")
+
+			release, _ = parseRelease(bytes.NewBufferString(fixtures.HookReleaseCommit))
+			releaseBuild = c.buildFromRelease(release)
+			g.Assert(releaseBuild.Ref).Equal("refs/tags/v1.0.0")
+			g.Assert(releaseBuild.Branch).Equal("")
+		})
+
 		g.It("Should return a Build struct from a pull_request hook", func() {
 			buf := bytes.NewBufferString(fixtures.HookPullRequest)
 			hook, _ := parsePullRequest(buf)
-			build := buildFromPullRequest(hook)
+			build := c.buildFromPullRequest(hook)
 			g.Assert(build.Event).Equal(model.EventPull)
+			g.Assert(build.Action).Equal("opened")
 			g.Assert(build.Commit).Equal(hook.PullRequest.Head.Sha)
 			g.Assert(build.Ref).Equal("refs/pull/1/head")
+			g.Assert(build.PullRequest).Equal(int64(1))
 			g.Assert(build.Link).Equal(hook.PullRequest.URL)
 			g.Assert(build.Branch).Equal("master")
-			g.Assert(build.Refspec).Equal("feature/changes:master")
+			g.Assert(build.Refspec).Equal("refs/pull/1/head:master")
+			g.Assert(build.SourceBranch).Equal("feature/changes")
+			g.Assert(build.BaseCommit).Equal("9353195a19e45482665306e466c832c46560532d")
+			g.Assert(build.MergeBase).Equal("9353195a19e45482665306e466c832c46560532d")
+			g.Assert(build.MergeCommit).Equal("")
+			g.Assert(build.Remote).Equal("")
+			g.Assert(build.IsFork).IsFalse()
 			g.Assert(build.Message).Equal(hook.PullRequest.Title)
 			g.Assert(build.Avatar).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
 			g.Assert(build.Author).Equal(hook.PullRequest.User.Username)
 		})
 
+		g.It("Should fetch the pull request ref of the base from a pull_request hook of a fork", func() {
+			buf := bytes.NewBufferString(fixtures.HookPullRequestFork)
+			hook, _ := parsePullRequest(buf)
+			build := c.buildFromPullRequest(hook)
+			g.Assert(build.Remote).Equal("")
+			g.Assert(build.Refspec).Equal("refs/pull/2/head:master")
+			g.Assert(build.SourceBranch).Equal("typo")
+			g.Assert(build.IsFork).IsTrue()
+
+			repo := repoFromPullRequest(hook)
+			g.Assert(repo.FullName).Equal("gordon/hello-world")
+		})
+
+		g.It("Should fetch the pull request ref if the source branch was deleted", func() {
+			payload := strings.Replace(fixtures.HookPullRequest, `"ref": "feature/changes"`, `"ref": "refs/pull/1/head"`, 1)
+			hook, _ := parsePullRequest(bytes.NewBufferString(payload))
+			build := c.buildFromPullRequest(hook)
+			g.Assert(build.Ref).Equal("refs/pull/1/head")
+			g.Assert(build.Refspec).Equal("refs/pull/1/head:master")
+			g.Assert(build.SourceBranch).Equal("")
+		})
+
+		g.It("Should fetch the source branch of a fork without pull request ref", func() {
+			build := c.buildFromPullRequestRebuild(&model.Build{Event: model.EventPull, Action: actionRebuild}, &gitea.PullRequest{
+				Base: &gitea.PRBranchInfo{Ref: "master", Repository: &gitea.Repository{ID: 1}},
+				Head: &gitea.PRBranchInfo{Ref: "typo", Repository: &gitea.Repository{ID: 2, CloneURL: "http://gitea.golang.org/gopher/hello-world.git"}},
+			})
+			g.Assert(build.Refspec).Equal("typo:master")
+			g.Assert(build.Remote).Equal("http://gitea.golang.org/gopher/hello-world.git")
+		})
+
+		g.It("Should fetch the pull request ref from the base if the fork branch was deleted", func() {
+			payload := strings.Replace(fixtures.HookPullRequestFork, `"ref": "typo"`, `"ref": "refs/pull/2/head"`, 1)
+			hook, _ := parsePullRequest(bytes.NewBufferString(payload))
+			build := c.buildFromPullRequest(hook)
+			g.Assert(build.Refspec).Equal("refs/pull/2/head:master")
+			g.Assert(build.Remote).Equal("")
+			g.Assert(build.IsFork).IsTrue()
+		})
+
+		g.It("Should fetch the pull request ref of a rebuild if the source branch was deleted", func() {
+			from := &model.Build{Event: model.EventPull, Action: actionRebuild, Ref: "refs/pull/1/head"}
+			build := c.buildFromPullRequestRebuild(from, &gitea.PullRequest{
+				Index: 1,
+				Base:  &gitea.PRBranchInfo{Ref: "master", Repository: &gitea.Repository{ID: 1}},
+				Head:  &gitea.PRBranchInfo{Ref: "refs/pull/1/head", Repository: &gitea.Repository{ID: 2, CloneURL: "http://gitea.golang.org/gopher/hello-world.git"}},
+			})
+			g.Assert(build.Refspec).Equal("refs/pull/1/head:master")
+			g.Assert(build.Remote).Equal("")
+		})
+
+		g.It("Should take the current milestone of a rebuilt pull request", func() {
+			from := &model.Build{Event: model.EventPull, Action: actionRebuild, Ref: "refs/pull/1/head"}
+			pr := &gitea.PullRequest{
+				Index: 1,
+				Base:  &gitea.PRBranchInfo{Ref: "master", Repository: &gitea.Repository{ID: 1}},
+				Head:  &gitea.PRBranchInfo{Ref: "feature/changes", Repository: &gitea.Repository{ID: 1}},
+			}
+			g.Assert(c.buildFromPullRequestRebuild(from, pr).Milestone == nil).IsTrue()
+
+			pr.Milestone = &gitea.Milestone{ID: 4, Title: "v1.3.0"}
+			g.Assert(*c.buildFromPullRequestRebuild(from, pr).Milestone).Equal(model.Milestone{ID: 4, Title: "v1.3.0"})
+		})
+
+		g.It("Should return a Build struct from a release hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookRelease)
+			hook, _ := parseRelease(buf)
+			build := c.buildFromRelease(hook)
+			g.Assert(build.Event).Equal(model.EventRelease)
+			g.Assert(build.Ref).Equal("refs/tags/v1.0.0")
+			g.Assert(build.Branch).Equal("master")
+			g.Assert(build.Link).Equal(hook.Release.URL)
+			g.Assert(build.Title).Equal("Version 1.0.0")
+			g.Assert(build.Message).Equal("Version 1.0.0\n\nfirst stable release")
+			g.Assert(build.Avatar).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
+			g.Assert(build.Author).Equal(hook.Release.Author.Login)
+			g.Assert(build.IsPrerelease).IsTrue()
+		})
+
+		g.It("Should return a Build struct from a rebuild command and pull request", func() {
+			merged := "d1a8f5e3b2e4a6f8c0d2e4f6a8b0c2d4e6f8a0b2"
+			from := &model.Build{Event: model.EventPull, Action: actionRebuild, Ref: "refs/pull/1/head", Sender: "gordon"}
+			build := c.buildFromPullRequestRebuild(from, &gitea.PullRequest{
+				Index:          1,
+				HTMLURL:        "http://gitea.golang.org/gordon/hello-world/pulls/1",
+				Title:          "Update the README with new information",
+				Poster:         &gitea.User{UserName: "gordon"},
+				MergedCommitID: &merged,
+				Base:           &gitea.PRBranchInfo{Ref: "master", Sha: "4b2626259b5a97b6b4eab5e6cca66adb986b672b", Repository: &gitea.Repository{ID: 1}},
+				Head:           &gitea.PRBranchInfo{Ref: "feature/changes", Sha: "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", Repository: &gitea.Repository{ID: 2, CloneURL: "http://gitea.golang.org/gopher/hello-world.git"}},
+			})
+			g.Assert(build.Event).Equal(model.EventPull)
+			g.Assert(build.Action).Equal(actionRebuild)
+			g.Assert(build.Ref).Equal("refs/pull/1/head")
+			g.Assert(build.PullRequest).Equal(int64(1))
+			g.Assert(build.Sender).Equal("gordon")
+			g.Assert(build.Author).Equal("gordon")
+			g.Assert(build.Commit).Equal("0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c")
+			g.Assert(build.BaseCommit).Equal("4b2626259b5a97b6b4eab5e6cca66adb986b672b")
+			g.Assert(build.MergeCommit).Equal(merged)
+			g.Assert(build.Branch).Equal("master")
+			g.Assert(build.Refspec).Equal("refs/pull/1/head:master")
+			g.Assert(build.SourceBranch).Equal("feature/changes")
+			g.Assert(build.Remote).Equal("")
+			g.Assert(build.Labels).Equal([]string{})
+		})
+
+		g.It("Should return the current labels from a rebuild command and pull request", func() {
+			from := &model.Build{Event: model.EventPull, Action: actionRebuild, Ref: "refs/pull/1/head"}
+			build := c.buildFromPullRequestRebuild(from, &gitea.PullRequest{
+				Labels: []*gitea.Label{{Name: "deploy"}},
+			})
+			g.Assert(build.Labels).Equal([]string{"deploy"})
+		})
+
+		g.It("Should return the labels from a pull_request hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPullRequestLabels)
+			hook, _ := parsePullRequest(buf)
+			build := c.buildFromPullRequest(hook)
+			g.Assert(build.Labels).Equal([]string{"deploy", "kind/bug", "priority/high"})
+
+			buf = bytes.NewBufferString(fixtures.HookPullRequest)
+			hook, _ = parsePullRequest(buf)
+			build = c.buildFromPullRequest(hook)
+			g.Assert(build.Labels).Equal([]string{})
+		})
+
 		g.It("Should return a Repo struct from a pull_request hook", func() {
 			buf := bytes.NewBufferString(fixtures.HookPullRequest)
 			hook, _ := parsePullRequest(buf)
@@ -155,6 +517,16 @@ func Test_parse(t *testing.T) {
 			g.Assert(repo.Owner).Equal(hook.Repo.Owner.Username)
 			g.Assert(repo.FullName).Equal("gordon/hello-world")
 			g.Assert(repo.Link).Equal(hook.Repo.URL)
+			g.Assert(repo.Branch).Equal("master")
+			g.Assert(repo.IsSCMPrivate).IsTrue()
+		})
+
+		g.It("Should return a public Repo struct from a pull_request hook", func() {
+			buf := bytes.NewBufferString(fixtures.HookPullRequestFork)
+			hook, _ := parsePullRequest(buf)
+			repo := repoFromPullRequest(hook)
+			g.Assert(repo.Branch).Equal("master")
+			g.Assert(repo.IsSCMPrivate).IsFalse()
 		})
 
 		g.It("Should return a Perm struct from a Gitea Perm", func() {
@@ -189,7 +561,7 @@ func Test_parse(t *testing.T) {
 				AvatarURL: "/avatars/1",
 			}
 
-			to := toTeam(from, "http://localhost:80")
+			to := c.toTeam(from, "http://localhost:80")
 			g.Assert(to.Login).Equal(from.UserName)
 			g.Assert(to.Avatar).Equal("http://localhost:80/avatars/1")
 		})
@@ -206,7 +578,8 @@ func Test_parse(t *testing.T) {
 				Private:       true,
 				DefaultBranch: "master",
 			}
-			repo := toRepo(&from)
+			repo, err := c.toRepo(&from)
+			g.Assert(err).IsNil()
 			g.Assert(repo.FullName).Equal(from.FullName)
 			g.Assert(repo.Owner).Equal(from.Owner.UserName)
 			g.Assert(repo.Name).Equal("hello-world")
@@ -215,6 +588,108 @@ func Test_parse(t *testing.T) {
 			g.Assert(repo.Clone).Equal(from.CloneURL)
 			g.Assert(repo.Avatar).Equal(from.Owner.AvatarURL)
 			g.Assert(repo.IsSCMPrivate).Equal(from.Private)
+			g.Assert(repo.IsMirror).IsFalse()
+			g.Assert(repo.IsFork).IsFalse()
+		})
+
+		g.It("Should return the SSH clone url of a Gitea Repo if preferred", func() {
+			from := gitea.Repository{
+				FullName: "gophers/hello-world",
+				CloneURL: "https://gitea.golang.org/gophers/hello-world.git",
+				SSHURL:   "ssh://gitea@gitea.golang.org:2222/gophers/hello-world.git",
+			}
+			repo, err := (&Gitea{CloneSSH: true}).toRepo(&from)
+			g.Assert(err).IsNil()
+			g.Assert(repo.Clone).Equal(from.SSHURL)
+
+			repo, err = (&Gitea{}).toRepo(&from)
+			g.Assert(err).IsNil()
+			g.Assert(repo.Clone).Equal(from.CloneURL)
+		})
+
+		g.It("Should derive the missing clone url of a Gitea Repo", func() {
+			repo, err := (&Gitea{CloneSSH: true}).toRepo(&gitea.Repository{
+				FullName: "gophers/hello-world",
+				CloneURL: "https://gitea.golang.org/gophers/hello-world.git",
+			})
+			g.Assert(err).IsNil()
+			g.Assert(repo.Clone).Equal("git@gitea.golang.org:gophers/hello-world.git")
+
+			repo, err = (&Gitea{URL: "https://gitea.golang.org/git"}).toRepo(&gitea.Repository{
+				FullName: "gophers/hello-world",
+				SSHURL:   "git@gitea.golang.org:gophers/hello-world.git",
+			})
+			g.Assert(err).IsNil()
+			g.Assert(repo.Clone).Equal("https://gitea.golang.org/git/gophers/hello-world.git")
+		})
+
+		g.It("Should rewrite http clone urls to SSH", func() {
+			c := &Gitea{URL: "https://gitea.golang.org/git"}
+			g.Assert(c.toSSHURL("https://gitea.golang.org/git/gophers/hello-world.git")).Equal("git@gitea.golang.org:gophers/hello-world.git")
+			g.Assert(c.toSSHURL("http://gitea.golang.org:3000/gophers/hello-world.git")).Equal("git@gitea.golang.org:gophers/hello-world.git")
+
+			c = &Gitea{SSHPort: 2222}
+			g.Assert(c.toSSHURL("https://gitea.golang.org/gophers/hello-world.git")).Equal("ssh://git@gitea.golang.org:2222/gophers/hello-world.git")
+			g.Assert(c.toSSHURL("https://[::1]/gophers/hello-world.git")).Equal("ssh://git@[::1]:2222/gophers/hello-world.git")
+		})
+
+		g.It("Should rewrite SSH clone urls to http", func() {
+			c := &Gitea{URL: "https://gitea.golang.org"}
+			g.Assert(c.toHTTPURL("git@gitea.golang.org:gophers/hello-world.git")).Equal("https://gitea.golang.org/gophers/hello-world.git")
+			g.Assert(c.toHTTPURL("ssh://git@gitea.golang.org:2222/gophers/hello-world.git")).Equal("https://gitea.golang.org/gophers/hello-world.git")
+			g.Assert((&Gitea{}).toHTTPURL("git@gitea.golang.org:gophers/hello-world.git")).Equal("https://gitea.golang.org/gophers/hello-world.git")
+		})
+
+		g.It("Should pass through clone urls of the other form", func() {
+			c := &Gitea{URL: "https://gitea.golang.org", SSHPort: 2222}
+			for _, rawurl := range []string{
+				"git@gitea.golang.org:gophers/hello-world.git",
+				"ssh://git@gitea.golang.org:2222/gophers/hello-world.git",
+				"",
+			} {
+				g.Assert(c.toSSHURL(rawurl)).Equal(rawurl)
+			}
+			for _, rawurl := range []string{
+				"https://gitea.golang.org/gophers/hello-world.git",
+				"http://user@gitea.golang.org:3000/gophers/hello-world.git",
+				"",
+			} {
+				g.Assert(c.toHTTPURL(rawurl)).Equal(rawurl)
+			}
+		})
+
+		g.It("Should return the mirror and fork flags of a Gitea Repo", func() {
+			repo, err := c.toRepo(&gitea.Repository{FullName: "gophers/hello-world", Mirror: true})
+			g.Assert(err).IsNil()
+			g.Assert(repo.IsMirror).IsTrue()
+			g.Assert(repo.IsFork).IsFalse()
+
+			repo, err = c.toRepo(&gitea.Repository{FullName: "gophers/hello-world", Fork: true})
+			g.Assert(err).IsNil()
+			g.Assert(repo.IsMirror).IsFalse()
+			g.Assert(repo.IsFork).IsTrue()
+		})
+
+		g.It("Should handle malformed Gitea Repo names", func() {
+			for _, fullName := range []string{"hello-world", "", "/", "gophers/"} {
+				from := gitea.Repository{
+					FullName: fullName,
+					Owner:    &gitea.User{UserName: "gordon"},
+				}
+				repo, err := c.toRepo(&from)
+				g.Assert(err).IsNotNil()
+				g.Assert(repo == nil).IsTrue()
+			}
+		})
+
+		g.It("Should return a Repo struct from a Gitea Repo without owner", func() {
+			from := gitea.Repository{
+				FullName: "gophers/hello-world",
+			}
+			repo, err := c.toRepo(&from)
+			g.Assert(err).IsNil()
+			g.Assert(repo.Owner).Equal("gophers")
+			g.Assert(repo.Name).Equal("hello-world")
 		})
 
 		g.It("Should correct a malformed avatar url", func() {
@@ -238,6 +713,30 @@ func Test_parse(t *testing.T) {
 					"http://gitea.golang.org//avatars/1",
 					"http://gitea.golang.org/avatars/1",
 				},
+				{
+					"https://gitea.golang.org//avatars//1",
+					"https://gitea.golang.org/avatars/1",
+				},
+				{
+					"https:///avatars/1",
+					"/avatars/1",
+				},
+				{
+					"http:///1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87",
+					"http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87",
+				},
+				{
+					"//avatars/1",
+					"/avatars/1",
+				},
+				{
+					"https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87?d=identicon",
+					"https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87?d=identicon",
+				},
+				{
+					"",
+					"",
+				},
 			}
 
 			for _, url := range urls {
@@ -267,9 +766,166 @@ func Test_parse(t *testing.T) {
 
 			repo := "http://gitea.io/foo/bar"
 			for _, url := range urls {
-				got := expandAvatar(repo, url.Before)
+				got := c.expandAvatar(repo, url.Before)
 				g.Assert(got).Equal(url.After)
 			}
 		})
+
+		g.It("Should escape the expanded avatar url", func() {
+			repo := "http://gitea.io/foo/bar"
+			for before, after := range map[string]string{
+				"/avatars/my avatar.png":        "http://gitea.io/avatars/my%20avatar.png",
+				"/avatars/a+b.png":              "http://gitea.io/avatars/a+b.png",
+				"/avatars/müller.png":           "http://gitea.io/avatars/m%C3%BCller.png",
+				"/avatars/my%20avatar.png":      "http://gitea.io/avatars/my%20avatar.png",
+				"/avatars/a%2Fb.png":            "http://gitea.io/avatars/a%2Fb.png",
+				"/avatars/100%.png":             "http://gitea.io/avatars/100%25.png",
+				"http://gitea.io/avatars/a%20b": "http://gitea.io/avatars/a%20b",
+			} {
+				g.Assert(c.expandAvatar(repo, fixMalformedAvatar(before))).Equal(after)
+			}
+		})
+
+		g.It("Should expand the avatar url of an instance hosted under a sub path", func() {
+			c := &Gitea{URL: "https://example.com/git/"}
+			repo := "https://example.com/git/foo/bar"
+			for before, after := range map[string]string{
+				"/avatars/1":                  "https://example.com/git/avatars/1",
+				"/git/avatars/1":              "https://example.com/git/avatars/1",
+				"/gitea/avatars/1":            "https://example.com/git/gitea/avatars/1",
+				"/avatars/my avatar.png":      "https://example.com/git/avatars/my%20avatar.png",
+				"/avatars/a%2Fb.png":          "https://example.com/git/avatars/a%2Fb.png",
+				"//1.gravatar.com/avatar/123": "https://1.gravatar.com/avatar/123",
+				"https://cdn.example.com/a/1": "https://cdn.example.com/a/1",
+			} {
+				g.Assert(c.expandAvatar(repo, fixMalformedAvatar(before))).Equal(after)
+			}
+		})
+
+		g.It("Should link the tags of an instance hosted under a sub path", func() {
+			c := &Gitea{URL: "https://example.com/git"}
+			payload := strings.ReplaceAll(fixtures.HookPushTag, "http://gitea.golang.org/", "https://example.com/git/")
+			payload = strings.ReplaceAll(payload, "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87", "/avatars/1")
+			hook, _ := parsePush(bytes.NewBufferString(payload))
+			build := c.buildFromTag(hook)
+			g.Assert(build.Link).Equal("https://example.com/git/gordon/hello-world/src/tag/v1.0.0")
+			g.Assert(build.Avatar).Equal("https://example.com/git/avatars/1")
+		})
+
+		g.It("Should expand the avatar url against the avatar base url", func() {
+			c := &Gitea{AvatarBaseURL: "https://cdn.gitea.io/"}
+			g.Assert(c.expandAvatar("http://gitea.io/foo/bar", "/avatars/1")).Equal("https://cdn.gitea.io/avatars/1")
+			g.Assert(c.expandAvatar("http://gitea.io/foo/bar", "http://gitea.io/avatars/1")).Equal("http://gitea.io/avatars/1")
+		})
+
+		g.It("Should verify the hook signature", func() {
+			body := []byte(`{"ref":"refs/heads/master"}`)
+			sig := "18bd702ca7dab5713101db346ec6cd6768820c090515db9744deff53bc95ff52"
+			g.Assert(verifySignature(sha256.New, "secret", body, sig)).IsTrue()
+			g.Assert(verifySignature(sha256.New, "other", body, sig)).IsFalse()
+			g.Assert(verifySignature(sha256.New, "secret", []byte(`{}`), sig)).IsFalse()
+			g.Assert(verifySignature(sha256.New, "secret", body, "")).IsFalse()
+			g.Assert(verifySignature(sha256.New, "secret", body, "not hex")).IsFalse()
+			g.Assert(verifySignature(sha1.New, "secret", body, sig)).IsFalse()
+		})
+
+		g.It("Should negotiate the hash of the hook signature", func() {
+			sha256Sig := strings.Repeat("a", 64)
+			sha1Sig := strings.Repeat("b", 40)
+
+			sigs := hookSignaturesOf(http.Header{hookSignature256: {"sha256=" + sha256Sig}, hookSignature: {sha1Sig}})
+			g.Assert(len(sigs)).Equal(2)
+			g.Assert(sigs[0].newHash().Size()).Equal(sha256.Size)
+			g.Assert(sigs[0].sig).Equal(sha256Sig)
+			g.Assert(sigs[1].newHash().Size()).Equal(sha1.Size)
+			g.Assert(sigs[1].sig).Equal(sha1Sig)
+
+			sigs = hookSignaturesOf(http.Header{hookSignature256: {sha256Sig}})
+			g.Assert(len(sigs)).Equal(1)
+			g.Assert(sigs[0].sig).Equal(sha256Sig)
+
+			sigs = hookSignaturesOf(http.Header{hookSignature: {sha256Sig}})
+			g.Assert(len(sigs)).Equal(1)
+			g.Assert(sigs[0].newHash().Size()).Equal(sha256.Size)
+			g.Assert(sigs[0].sig).Equal(sha256Sig)
+
+			sigs = hookSignaturesOf(http.Header{hookSignature: {sha1Sig}})
+			g.Assert(sigs[0].newHash().Size()).Equal(sha1.Size)
+			g.Assert(sigs[0].sig).Equal(sha1Sig)
+
+			sigs = hookSignaturesOf(http.Header{hookSignature: {"sha1=" + sha1Sig}})
+			g.Assert(sigs[0].newHash().Size()).Equal(sha1.Size)
+			g.Assert(sigs[0].sig).Equal(sha1Sig)
+		})
+
+		g.It("Should detect the default avatar", func() {
+			g.Assert(isDefaultAvatar("http://gitea.io/assets/img/avatar_default.png")).IsTrue()
+			g.Assert(isDefaultAvatar("http://gitea.io/avatars/1")).IsFalse()
+			g.Assert(isDefaultAvatar("")).IsFalse()
+		})
+
+		g.It("Should return a gravatar url", func() {
+			g.Assert(gravatar(" Gordon@golang.org", "")).Equal("https://www.gravatar.com/avatar/b86d5c587c72ff919ebf8cd47a923592")
+			g.Assert(gravatar("gordon@golang.org", "https://example.com/a.png")).Equal("https://www.gravatar.com/avatar/b86d5c587c72ff919ebf8cd47a923592?d=https%3A%2F%2Fexample.com%2Fa.png")
+		})
+
+		g.It("Should return the matching hook", func() {
+			hooks := []*gitea.Hook{
+				{ID: 1, Config: map[string]string{"url": "http://ci.example.com/ci1/hook?access_token=a"}},
+				{ID: 2, Config: map[string]string{"url": "http://ci.example.com/ci2/hook?access_token=b"}},
+				{ID: 3, Config: map[string]string{"url": "https://woodpecker.example.com/hook?access_token=c"}},
+			}
+			tests := []struct {
+				Link string
+				ID   int64
+			}{
+				{"http://ci.example.com/ci1/hook?access_token=x", 1},
+				{"http://ci.example.com/ci2/", 2},
+				{"http://ci.example.com/ci2", 2},
+				{"https://woodpecker.example.com", 3},
+				{"https://woodpecker.example.com/hook", 3},
+				// host only fallback for single instance setups
+				{"http://woodpecker.example.com/hook", 3},
+			}
+			for _, test := range tests {
+				hook := matchingHooks(hooks, test.Link)
+				g.Assert(hook).IsNotNil()
+				g.Assert(hook.ID).Equal(test.ID)
+			}
+		})
+
+		g.It("Should match hooks registered with the canonical url", func() {
+			hooks := []*gitea.Hook{
+				{ID: 1, Config: map[string]string{"url": "http://woodpecker:8000/hook?access_token=1"}},
+				{ID: 2, Config: map[string]string{"url": "https://ci.example.com/hook?access_token=2"}},
+			}
+			hook := matchingHooks(hooks, "https://ci.example.com")
+			g.Assert(hook).IsNotNil()
+			g.Assert(hook.ID).Equal(int64(2))
+		})
+
+		g.It("Should return all hooks matching the url", func() {
+			hooks := []*gitea.Hook{
+				{ID: 1, Config: map[string]string{"url": "http://ci.example.com/hook?access_token=1"}},
+				{ID: 2, Config: map[string]string{"url": "http://ci.example.com/ci2/hook"}},
+				{ID: 3, Config: map[string]string{"url": "http://ci.example.com/hook?access_token=3"}},
+			}
+			matches := allMatchingHooks(hooks, "http://ci.example.com/hook")
+			g.Assert(len(matches)).Equal(2)
+			g.Assert(matches[0].ID).Equal(int64(1))
+			g.Assert(matches[1].ID).Equal(int64(3))
+			g.Assert(len(allMatchingHooks(hooks, "http://localhost"))).Equal(0)
+		})
+
+		g.It("Should not match hooks of other instances", func() {
+			hooks := []*gitea.Hook{
+				{ID: 1, Config: map[string]string{"url": "http://ci.example.com/ci1/hook"}},
+				{ID: 2, Config: map[string]string{"url": "http://ci.example.com/ci2/hook"}},
+			}
+			g.Assert(matchingHooks(hooks, "http://ci.example.com/ci")).IsNil()
+			g.Assert(matchingHooks(hooks, "http://ci.example.com/ci3/hook")).IsNil()
+			g.Assert(matchingHooks(hooks, "http://localhost/ci1/hook")).IsNil()
+			g.Assert(matchingHooks(hooks, "%gh&%ij")).IsNil()
+		})
 	})
 }
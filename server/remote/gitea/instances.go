@@ -0,0 +1,392 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// Instances is a Remote integrating several Gitea instances, each with its own
+// url and OAuth2 application. Calls for a repository are sent to the instance
+// hosting it, calls for a user to the instance the user logged in with. The
+// first instance is the default one, e.g. for users logged in before several
+// instances were configured.
+type Instances struct {
+	instances []*Gitea
+}
+
+// NewInstances returns a Remote implementation that integrates with several
+// Gitea instances. The names of the instances have to be unique, only the
+// default instance, which is the first one, may be unnamed.
+func NewInstances(opts ...Opts) (remote.Remote, error) {
+	if len(opts) == 0 {
+		return nil, errors.New("no gitea instance configured")
+	}
+
+	names := make(map[string]bool, len(opts))
+	instances := make([]*Gitea, 0, len(opts))
+	for i, o := range opts {
+		if o.Name == "" && i != 0 {
+			return nil, fmt.Errorf("gitea instance %s has no name", o.URL)
+		}
+		if names[o.Name] {
+			return nil, fmt.Errorf("gitea instance name %q is not unique", o.Name)
+		}
+		names[o.Name] = true
+
+		instance, err := New(o)
+		if err != nil {
+			return nil, fmt.Errorf("gitea instance %q: %w", o.Name, err)
+		}
+		instances = append(instances, instance.(*Gitea))
+	}
+	return &Instances{instances: instances}, nil
+}
+
+// forUser returns the instance the user logged in with, users of unknown
+// instances belong to the default instance.
+func (m *Instances) forUser(u *model.User) *Gitea {
+	if u != nil {
+		for _, instance := range m.instances {
+			if instance.Name == u.Remote {
+				return instance
+			}
+		}
+	}
+	return m.instances[0]
+}
+
+// forRepo returns the instance hosting the repository. Repositories matching
+// no instance, e.g. if only the name is known, belong to the instance of the
+// user.
+func (m *Instances) forRepo(u *model.User, r *model.Repo) *Gitea {
+	if r != nil {
+		if instance := m.forLink(r.Link); instance != nil {
+			return instance
+		}
+	}
+	return m.forUser(u)
+}
+
+// forLink returns the instance the link lies below, the most specific one if
+// instances are hosted under sub paths of the same host.
+func (m *Instances) forLink(rawurl string) *Gitea {
+	link, err := url.Parse(rawurl)
+	if err != nil || link.Host == "" {
+		return nil
+	}
+
+	var match *Gitea
+	for _, instance := range m.instances {
+		base, err := url.Parse(instance.URL)
+		if err != nil || !strings.EqualFold(base.Host, link.Host) || !hasPathPrefix(link.Path, base.Path) {
+			continue
+		}
+		if match == nil || len(instance.basePath()) > len(match.basePath()) {
+			match = instance
+		}
+	}
+	return match
+}
+
+// forHook returns the instance a hook was sent by, found by the url of the
+// repository in its payload. The body of the request is kept for the instance
+// to parse.
+func (m *Instances) forHook(r *http.Request) (*Gitea, error) {
+	repo, err := hookRepoOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := m.forLink(repo.URL)
+	if instance == nil {
+		return nil, fmt.Errorf("no gitea instance configured for repository %s", repo.URL)
+	}
+	return instance, nil
+}
+
+// Login authenticates the user with the instance selected by the remote
+// parameter, the instance redirecting back after authorization is named by
+// the OAuth state.
+func (m *Instances) Login(ctx context.Context, w http.ResponseWriter, req *http.Request) (*model.User, error) {
+	if req.FormValue("code") != "" || req.FormValue("error") != "" {
+		state := req.FormValue("state")
+		for _, instance := range m.instances {
+			if instance.oauthState() == state {
+				return instance.Login(ctx, w, req)
+			}
+		}
+		return nil, fmt.Errorf("no gitea instance for OAuth state %q", state)
+	}
+
+	name := req.FormValue("remote")
+	for _, instance := range m.instances {
+		if instance.Name == name {
+			return instance.Login(ctx, w, req)
+		}
+	}
+	return nil, fmt.Errorf("unknown gitea instance %q", name)
+}
+
+// Auth returns the login of the token's user at the first instance accepting
+// the token.
+func (m *Instances) Auth(ctx context.Context, token, secret string) (string, error) {
+	login, _, err := m.AuthInstance(ctx, token, secret)
+	return login, err
+}
+
+// AuthInstance returns the login of the token's user and the name of the first
+// instance accepting the token.
+func (m *Instances) AuthInstance(ctx context.Context, token, secret string) (string, string, error) {
+	var err error
+	for _, instance := range m.instances {
+		var login string
+		if login, err = instance.Auth(ctx, token, secret); err == nil {
+			return login, instance.Name, nil
+		}
+	}
+	return "", "", err
+}
+
+// Refresh refreshes the token of the user at the instance the user logged in
+// with.
+func (m *Instances) Refresh(ctx context.Context, u *model.User) (bool, error) {
+	return m.forUser(u).Refresh(ctx, u)
+}
+
+// Avatar returns the current avatar of the user.
+func (m *Instances) Avatar(ctx context.Context, u *model.User) (string, error) {
+	return m.forUser(u).Avatar(ctx, u)
+}
+
+// Teams returns the organizations of the user.
+func (m *Instances) Teams(ctx context.Context, u *model.User) ([]*model.Team, error) {
+	return m.forUser(u).Teams(ctx, u)
+}
+
+// OrgMembers returns the members of the organization at the instance of the
+// user.
+func (m *Instances) OrgMembers(ctx context.Context, u *model.User, org string) (*remote.OrgMembers, error) {
+	return m.forUser(u).OrgMembers(ctx, u, org)
+}
+
+// UserKeys returns the public keys of the user with the login at the instance
+// of the user.
+func (m *Instances) UserKeys(ctx context.Context, u *model.User, login string) (*remote.UserKeys, error) {
+	return m.forUser(u).UserKeys(ctx, u, login)
+}
+
+// Repo returns the repository from the instance of the user.
+func (m *Instances) Repo(ctx context.Context, u *model.User, owner, name string) (*model.Repo, error) {
+	return m.forUser(u).Repo(ctx, u, owner, name)
+}
+
+// Repos returns the repositories of the user at the instance of the user.
+func (m *Instances) Repos(ctx context.Context, u *model.User) ([]*model.Repo, error) {
+	return m.forUser(u).Repos(ctx, u)
+}
+
+// Perm returns the permissions of the user for the repository.
+func (m *Instances) Perm(ctx context.Context, u *model.User, r *model.Repo) (*model.Perm, error) {
+	return m.forRepo(u, r).Perm(ctx, u, r)
+}
+
+// File fetches the file from the repository.
+func (m *Instances) File(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]byte, error) {
+	return m.forRepo(u, r).File(ctx, u, r, b, f)
+}
+
+// Dir fetches the folder from the repository.
+func (m *Instances) Dir(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, f string) ([]*remote.FileMeta, error) {
+	return m.forRepo(u, r).Dir(ctx, u, r, b, f)
+}
+
+// Status reports the status of the build to the repository.
+func (m *Instances) Status(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, p *model.Proc) error {
+	return m.forRepo(u, r).Status(ctx, u, r, b, p)
+}
+
+// StepStatus reports the status of the step to the repository.
+func (m *Instances) StepStatus(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, parent, step *model.Proc) error {
+	return m.forRepo(u, r).StepStatus(ctx, u, r, b, parent, step)
+}
+
+// Summary posts the summary of the build to its pull request.
+func (m *Instances) Summary(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) error {
+	return m.forRepo(u, r).Summary(ctx, u, r, b)
+}
+
+// Netrc returns the netrc file to clone the repository.
+func (m *Instances) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
+	return m.forRepo(u, r).Netrc(u, r)
+}
+
+// Activate registers the hook with the repository.
+func (m *Instances) Activate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	return m.forRepo(u, r).Activate(ctx, u, r, link)
+}
+
+// Deactivate removes the hooks matching the link from the repository.
+func (m *Instances) Deactivate(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	return m.forRepo(u, r).Deactivate(ctx, u, r, link)
+}
+
+// RotateHookSecret replaces the link and secret of the hook of the repository.
+func (m *Instances) RotateHookSecret(ctx context.Context, u *model.User, r *model.Repo, link string) error {
+	return m.forRepo(u, r).RotateHookSecret(ctx, u, r, link)
+}
+
+// Deploy registers the deploy key with the repository.
+func (m *Instances) Deploy(ctx context.Context, u *model.User, r *model.Repo, key *remote.DeployKey) error {
+	return m.forRepo(u, r).Deploy(ctx, u, r, key)
+}
+
+// Undeploy removes the deploy key from the repository.
+func (m *Instances) Undeploy(ctx context.Context, u *model.User, r *model.Repo, title string) error {
+	return m.forRepo(u, r).Undeploy(ctx, u, r, title)
+}
+
+// Branches returns the branches of the repository.
+func (m *Instances) Branches(ctx context.Context, u *model.User, r *model.Repo) ([]string, error) {
+	return m.forRepo(u, r).Branches(ctx, u, r)
+}
+
+// BranchHead returns the latest commit of the branch.
+func (m *Instances) BranchHead(ctx context.Context, u *model.User, r *model.Repo, branch string) (string, error) {
+	return m.forRepo(u, r).BranchHead(ctx, u, r, branch)
+}
+
+// PullRequests returns the open pull requests of the repository.
+func (m *Instances) PullRequests(ctx context.Context, u *model.User, r *model.Repo, page int) ([]*remote.PullRequest, error) {
+	return m.forRepo(u, r).PullRequests(ctx, u, r, page)
+}
+
+// CommitInfo returns the commit the ref points to.
+func (m *Instances) CommitInfo(ctx context.Context, u *model.User, r *model.Repo, ref string) (*remote.Commit, error) {
+	return m.forRepo(u, r).CommitInfo(ctx, u, r, ref)
+}
+
+// Diff streams the diff of the changes the build was triggered by.
+func (m *Instances) Diff(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) (io.ReadCloser, error) {
+	return m.forRepo(u, r).Diff(ctx, u, r, b)
+}
+
+// CompareFiles returns the files changed between the commits.
+func (m *Instances) CompareFiles(ctx context.Context, u *model.User, r *model.Repo, base, head string) ([]string, bool, error) {
+	return m.forRepo(u, r).CompareFiles(ctx, u, r, base, head)
+}
+
+// RepoFile returns the file at the ref of another repository.
+func (m *Instances) RepoFile(ctx context.Context, u *model.User, owner, name, ref, f string) ([]byte, error) {
+	return m.forUser(u).RepoFile(ctx, u, owner, name, ref, f)
+}
+
+// Collaborators returns the collaborators of the repository with their
+// permission.
+func (m *Instances) Collaborators(ctx context.Context, u *model.User, r *model.Repo) ([]*remote.Collaborator, error) {
+	return m.forRepo(u, r).Collaborators(ctx, u, r)
+}
+
+// RequiredStatusChecks returns the status contexts required by the branch
+// protections of the repository.
+func (m *Instances) RequiredStatusChecks(ctx context.Context, u *model.User, r *model.Repo) ([]string, error) {
+	return m.forRepo(u, r).RequiredStatusChecks(ctx, u, r)
+}
+
+// Hook parses the hook with the instance it was sent by.
+func (m *Instances) Hook(ctx context.Context, r *http.Request) (*model.Repo, *model.Build, error) {
+	instance, err := m.forHook(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return instance.Hook(ctx, r)
+}
+
+// DeliveryID returns the id the hook was sent with. The ids are unique across
+// instances.
+func (m *Instances) DeliveryID(r *http.Request) string {
+	return r.Header.Get(hookDelivery)
+}
+
+// HookRepo returns the name of the instance the hook was sent by and the full
+// name of the repository it was sent for.
+func (m *Instances) HookRepo(r *http.Request) (string, string) {
+	instance, err := m.forHook(r)
+	if err != nil {
+		return "", ""
+	}
+	return instance.HookRepo(r)
+}
+
+// ParseHook parses the hook with the instance it was sent by, without
+// verifying its signature.
+func (m *Instances) ParseHook(ctx context.Context, r *http.Request) (*remote.ParsedHook, error) {
+	instance, err := m.forHook(r)
+	if err != nil {
+		return nil, err
+	}
+	return instance.ParseHook(ctx, r)
+}
+
+// Capabilities returns the features supported by all instances. Statuses per
+// step are supported if any instance enabled them, each instance only reports
+// them if enabled.
+func (m *Instances) Capabilities(ctx context.Context) (*remote.Capabilities, error) {
+	var caps *remote.Capabilities
+	for _, instance := range m.instances {
+		c, err := instance.Capabilities(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("gitea instance %q: %w", instance.Name, err)
+		}
+		if caps == nil {
+			caps = c
+			continue
+		}
+		caps.SupportsReviews = caps.SupportsReviews && c.SupportsReviews
+		caps.SupportsReleases = caps.SupportsReleases && c.SupportsReleases
+		caps.SupportsStatusPerStep = caps.SupportsStatusPerStep || c.SupportsStatusPerStep
+		caps.SupportsDraftPulls = caps.SupportsDraftPulls && c.SupportsDraftPulls
+	}
+	return caps, nil
+}
+
+// Ping checks the instance of the user, or all instances if no user is given.
+// The latency of the slowest instance is returned.
+func (m *Instances) Ping(ctx context.Context, u *model.User) (*remote.PingResult, error) {
+	if u != nil {
+		return m.forUser(u).Ping(ctx, u)
+	}
+
+	result := new(remote.PingResult)
+	for _, instance := range m.instances {
+		r, err := instance.Ping(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("gitea instance %q: %w", instance.Name, err)
+		}
+		if r.Latency > result.Latency {
+			result.Latency = r.Latency
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/franela/goblin"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+func Test_instances(t *testing.T) {
+	ctx := context.Background()
+	g := goblin.Goblin(t)
+	g.Describe("Gitea instances", func() {
+		r, err := NewInstances(
+			Opts{URL: "http://gitea.golang.org", Client: "golang"},
+			Opts{Name: "example", URL: "https://gitea.example.com/gitea/", Client: "example"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := r.(*Instances)
+
+		g.Describe("Creating instances", func() {
+			g.It("Should require names for all but the default instance", func() {
+				_, err := NewInstances(Opts{URL: "http://gitea.golang.org"}, Opts{URL: "https://gitea.example.com"})
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should require unique names", func() {
+				_, err := NewInstances(Opts{Name: "a", URL: "http://gitea.golang.org"}, Opts{Name: "a", URL: "https://gitea.example.com"})
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should require an instance", func() {
+				_, err := NewInstances()
+				g.Assert(err).IsNotNil()
+			})
+		})
+
+		g.Describe("Routing", func() {
+			g.It("Should route users to the instance they logged in with", func() {
+				g.Assert(m.forUser(&model.User{Remote: "example"}).Name).Equal("example")
+				g.Assert(m.forUser(&model.User{}).Name).Equal("")
+				g.Assert(m.forUser(&model.User{Remote: "unknown"}).Name).Equal("")
+				g.Assert(m.forUser(nil).Name).Equal("")
+			})
+			g.It("Should route repositories to the instance hosting them", func() {
+				user := &model.User{Remote: "example"}
+				g.Assert(m.forRepo(user, &model.Repo{Link: "http://gitea.golang.org/gordon/hello-world"}).Name).Equal("")
+				g.Assert(m.forRepo(nil, &model.Repo{Link: "https://gitea.example.com/gitea/gordon/hello-world"}).Name).Equal("example")
+				g.Assert(m.forRepo(user, &model.Repo{}).Name).Equal("example")
+			})
+			g.It("Should not route links below other paths of the host", func() {
+				g.Assert(m.forLink("https://gitea.example.com/other/gordon/hello-world") == nil).IsTrue()
+			})
+		})
+
+		g.Describe("Parsing a hook", func() {
+			g.It("Should parse the hook with the instance it was sent by", func() {
+				payload := strings.ReplaceAll(fixtures.HookDeleteBranch, "http://gitea.golang.org/", "https://gitea.example.com/gitea/")
+				payload = strings.ReplaceAll(payload, "https://secure.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87", "/avatars/1")
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header.Set(hookEvent, hookDelete)
+				parsed, err := m.ParseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(parsed.Repo.Link).Equal("https://gitea.example.com/gitea/gordon/hello-world")
+				g.Assert(parsed.Repo.Remote).Equal("example")
+				// the sub path of the instance is added to relative avatars
+				g.Assert(parsed.Build.Avatar).Equal("https://gitea.example.com/gitea/avatars/1")
+			})
+			g.It("Should parse form-encoded hooks", func() {
+				body := url.Values{hookFormPayload: {fixtures.HookDeleteBranch}}.Encode()
+				req, _ := http.NewRequest("POST", "/hook", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Set(hookEvent, hookDelete)
+				parsed, err := m.ParseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(parsed.Repo.Link).Equal("http://gitea.golang.org/gordon/hello-world")
+				g.Assert(parsed.Repo.Remote).Equal("")
+			})
+			g.It("Should fail for hooks of unknown instances", func() {
+				payload := strings.ReplaceAll(fixtures.HookDeleteBranch, "http://gitea.golang.org/", "https://gitea.unknown.com/")
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header.Set(hookEvent, hookDelete)
+				_, err := m.ParseHook(ctx, req)
+				g.Assert(err).IsNotNil()
+			})
+			g.It("Should name the instance and repository of a hook", func() {
+				payload := strings.ReplaceAll(fixtures.HookDeleteBranch, "http://gitea.golang.org/", "https://gitea.example.com/gitea/")
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				instance, name := m.HookRepo(req)
+				g.Assert(instance).Equal("example")
+				g.Assert(name).Equal("gordon/hello-world")
+
+				req, _ = http.NewRequest("POST", "/hook", strings.NewReader(fixtures.HookDeleteBranch))
+				instance, name = m.HookRepo(req)
+				g.Assert(instance).Equal("")
+				g.Assert(name).Equal("gordon/hello-world")
+			})
+		})
+
+		g.Describe("Logging in", func() {
+			g.It("Should redirect to the selected instance", func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/authorize?remote=example", nil)
+				user, err := m.Login(ctx, w, req)
+				g.Assert(err).IsNil()
+				g.Assert(user == nil).IsTrue()
+				location, _ := url.Parse(w.Header().Get("Location"))
+				g.Assert(location.Host + location.Path).Equal("gitea.example.com/gitea/login/oauth/authorize")
+				g.Assert(location.Query().Get("client_id")).Equal("example")
+				g.Assert(location.Query().Get("state")).Equal("example")
+			})
+			g.It("Should redirect to the default instance", func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/authorize", nil)
+				_, err := m.Login(ctx, w, req)
+				g.Assert(err).IsNil()
+				location, _ := url.Parse(w.Header().Get("Location"))
+				g.Assert(location.Host).Equal("gitea.golang.org")
+				g.Assert(location.Query().Get("state")).Equal("woodpecker")
+			})
+			g.It("Should fail for unknown instances", func() {
+				req, _ := http.NewRequest("GET", "/authorize?remote=unknown", nil)
+				_, err := m.Login(ctx, httptest.NewRecorder(), req)
+				g.Assert(err).IsNotNil()
+				req, _ = http.NewRequest("GET", "/authorize?code=abc&state=unknown", nil)
+				_, err = m.Login(ctx, httptest.NewRecorder(), req)
+				g.Assert(err).IsNotNil()
+			})
+		})
+	})
+}
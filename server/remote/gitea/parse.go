@@ -15,52 +15,230 @@
 package gitea
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/rs/zerolog/log"
+
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/store"
 )
 
 const (
-	hookEvent       = "X-Gitea-Event"
-	hookPush        = "push"
-	hookCreated     = "create"
-	hookPullRequest = "pull_request"
+	hookEvent        = "X-Gitea-Event"
+	hookSignature    = "X-Gitea-Signature"
+	hookSignature256 = "X-Gitea-Signature-256"
+	hookDelivery     = "X-Gitea-Delivery"
+	hookFormPayload  = "payload"
+	hookPush         = "push"
+	hookCreated      = "create"
+	hookDelete       = "delete"
+	hookPullRequest  = "pull_request"
+	hookRelease      = "release"
+	hookWiki         = "wiki"
+	hookPackage      = "package"
+	hookRepository   = "repository"
+
+	hookIssueComment       = "issue_comment"
+	hookPullRequestComment = "pull_request_comment"
+
+	hookPullRequestApproved      = "pull_request_approved"
+	hookPullRequestRejected      = "pull_request_rejected"
+	hookPullRequestReviewComment = "pull_request_review_comment"
 
-	actionOpen = "opened"
-	actionSync = "synchronized"
+	actionOpen      = "opened"
+	actionSync      = "synchronized"
+	actionReopen    = "reopened"
+	actionEdited    = "edited"
+	actionPublished = "published"
+	actionUpdated   = "updated"
+	actionCreated   = "created"
+	actionRebuild   = "rebuild"
+	actionApproved  = "approved"
+	actionReviewed  = "reviewed"
+	actionDeleted   = "deleted"
+
+	actionAssigned   = "assigned"
+	actionUnassigned = "unassigned"
 
 	stateOpen = "open"
 
 	refBranch = "branch"
 	refTag    = "tag"
+
+	// prefixes of the full refs of branches and tags
+	refHeadsPrefix = "refs/heads/"
+	refTagsPrefix  = "refs/tags/"
+
+	// prefix of refs Gitea sends for pushes to the wiki of a repository
+	refWikiPrefix = "refs/wiki/"
+
+	// sha Gitea sends as "after" for pushes deleting a ref
+	zeroSha = "0000000000000000000000000000000000000000"
 )
 
 // parseHook parses a Gitea hook from an http.Request request and returns
 // Repo and Build detail. If a hook type is unsupported nil values are returned.
-func parseHook(r *http.Request) (*model.Repo, *model.Build, error) {
+func (c *Gitea) parseHook(ctx context.Context, r *http.Request) (*model.Repo, *model.Build, error) {
 	switch r.Header.Get(hookEvent) {
 	case hookPush:
-		return parsePushHook(r.Body)
+		return c.parsePushHook(ctx, r.Body)
 	case hookCreated:
-		return parseCreatedHook(r.Body)
+		return c.parseCreatedHook(r.Body)
+	case hookDelete:
+		return c.parseDeleteHook(r.Body)
 	case hookPullRequest:
-		return parsePullRequestHook(r.Body)
+		return c.parsePullRequestHook(r.Body)
+	case hookRelease:
+		return c.parseReleaseHook(r.Body)
+	case hookIssueComment, hookPullRequestComment:
+		return c.parseIssueCommentHook(r.Body)
+	case hookPullRequestApproved:
+		return c.parsePullRequestReviewHook(r.Body, model.ReviewApproved)
+	case hookPullRequestRejected:
+		return c.parsePullRequestReviewHook(r.Body, model.ReviewRejected)
+	case hookPullRequestReviewComment:
+		return c.parsePullRequestReviewHook(r.Body, model.ReviewComment)
+	case hookWiki:
+		return parseWikiHook(r.Body)
+	case hookPackage:
+		return c.parsePackageHook(r.Body)
+	case hookRepository:
+		return parseRepositoryHook(r.Body)
 	}
 	return nil, nil, nil
 }
 
+// maxHookSize is the maximum size of a hook body in bytes. Gitea sends at most
+// the last commits of a push, so payloads stay far below it.
+const maxHookSize = 10 << 20
+
+// readHookBody reads the body of a hook of at most maxHookSize bytes. Larger
+// bodies are rejected and left to be read again, so they are rejected again.
+func readHookBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxHookSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxHookSize {
+		r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		return nil, fmt.Errorf("hook body exceeds %d bytes", maxHookSize)
+	}
+	return body, nil
+}
+
+// hookPayload returns the JSON payload of a hook body. Hooks with the content
+// type application/x-www-form-urlencoded send it in the payload parameter,
+// all other bodies are returned as is.
+func hookPayload(contentType string, body []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/x-www-form-urlencoded" {
+		return body, nil
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := values[hookFormPayload]; !ok {
+		return nil, fmt.Errorf("form-encoded hook has no %s parameter", hookFormPayload)
+	}
+	return []byte(values.Get(hookFormPayload)), nil
+}
+
+// hookRepo is the repository a hook was sent for.
+type hookRepo struct {
+	FullName string `json:"full_name"`
+	URL      string `json:"html_url"`
+}
+
+// hookRepoOf returns the repository a hook was sent for without parsing
+// the hook further. The body of the request is restored to be read again.
+func hookRepoOf(r *http.Request) (*hookRepo, error) {
+	body, err := readHookBody(r)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	payload, err := hookPayload(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, err
+	}
+	return hookRepoOfPayload(payload)
+}
+
+// hookRepoOfPayload returns the repository named by the payload of a hook,
+// only the repository is decoded.
+func hookRepoOfPayload(payload []byte) (*hookRepo, error) {
+	hook := new(struct {
+		Repo hookRepo `json:"repository"`
+	})
+	if err := json.Unmarshal(payload, hook); err != nil {
+		return nil, err
+	}
+	return &hook.Repo, nil
+}
+
+// readHookPayload reads the JSON payload of a hook and replaces the body of
+// the request with it. Gitea signs the payload itself, also if it is sent
+// form-encoded.
+func readHookPayload(r *http.Request) ([]byte, error) {
+	body, err := readHookBody(r)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := hookPayload(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	return payload, nil
+}
+
 // parsePushHook parses a push hook and returns the Repo and Build details.
-// If the commit type is unsupported nil values are returned.
-func parsePushHook(payload io.Reader) (repo *model.Repo, build *model.Build, err error) {
+// If the commit type is unsupported nil values are returned. Gitea sends a hook
+// per ref also if several refs are pushed at once, so the payload only holds
+// the ref it is sent for.
+func (c *Gitea) parsePushHook(ctx context.Context, payload io.Reader) (repo *model.Repo, build *model.Build, err error) {
 	push, err := parsePush(payload)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// ignore push events for tags
-	if strings.HasPrefix(push.Ref, "refs/tags/") {
+	// wiki edits are no code changes and their refs can't be built
+	if strings.HasPrefix(push.Ref, refWikiPrefix) {
+		log.Debug().Msgf("ignore wiki push %s to %s", push.Ref, push.Repo.FullName)
+		return nil, nil, nil
+	}
+
+	// pushes without a branch would fail to clone an empty ref, so they are
+	// built for the default branch instead
+	if strings.TrimSpace(strings.TrimPrefix(push.Ref, refHeadsPrefix)) == "" {
+		if push.Repo.Branch == "" {
+			log.Warn().Msgf("ignore push to %s without branch and default branch, raw ref %q", push.Repo.FullName, push.Ref)
+			return nil, nil, nil
+		}
+		log.Warn().Msgf("push to %s without branch, raw ref %q, use the default branch %s", push.Repo.FullName, push.Ref, push.Repo.Branch)
+		push.Ref = refHeadsPrefix + push.Repo.Branch
+	}
+
+	// ignore push events for tags, they are handled by the create hook, and
+	// for refs that are not branches like refs/notes/*
+	if !strings.HasPrefix(push.Ref, refHeadsPrefix) {
+		if !strings.HasPrefix(push.Ref, refTagsPrefix) {
+			log.Debug().Msgf("ignore push to unsupported ref %s", push.Ref)
+		}
 		return nil, nil, nil
 	}
 
@@ -69,30 +247,146 @@ func parsePushHook(payload io.Reader) (repo *model.Repo, build *model.Build, err
 		return nil, nil, nil
 	}
 
+	// ignore push events for deleted branches, there is nothing to clone
+	if isDeletePush(push) {
+		return nil, nil, nil
+	}
+
+	if c.isIgnoredPush(ctx, push) {
+		return nil, nil, nil
+	}
+
 	repo = repoFromPush(push)
-	build = buildFromPush(push)
+	build = c.buildFromPush(push)
 	return repo, build, err
 }
 
+// isIgnoredPush reports whether the push is dropped before creating a build,
+// as its branch matches an ignored pattern or the repository is not active.
+// Without a store in the context only the branch is checked. Pushes to unknown
+// repositories are passed on, as the repository may have been renamed.
+func (c *Gitea) isIgnoredPush(ctx context.Context, push *pushHook) bool {
+	branch := strings.TrimPrefix(push.Ref, refHeadsPrefix)
+	for _, pattern := range c.IgnoreBranches {
+		if ok, _ := doublestar.Match(pattern, branch); ok {
+			log.Debug().Msgf("ignore push to branch %s of %s matching %s", branch, push.Repo.FullName, pattern)
+			return true
+		}
+	}
+
+	_store, ok := store.TryFromContext(ctx)
+	if !ok {
+		return false
+	}
+	repo, err := _store.GetRepoRemoteName(c.Name, push.Repo.FullName)
+	if err == nil && !repo.IsActive {
+		log.Debug().Msgf("ignore push to inactive repository %s", push.Repo.FullName)
+		return true
+	}
+	return false
+}
+
+// parsePackageHook parses a package hook and returns the Repo and Build
+// details. Deleted package versions are only built if enabled, packages not
+// linked to a repository are ignored.
+func (c *Gitea) parsePackageHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	pkg, err := parsePackage(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pkg.Repo == nil {
+		log.Debug().Msgf("ignore package %s %s without repository", pkg.Package.Name, pkg.Package.Version)
+		return nil, nil, nil
+	}
+	if pkg.Action == actionDeleted && !c.PackageDeletions {
+		log.Debug().Msgf("ignore deleted package %s %s of %s", pkg.Package.Name, pkg.Package.Version, pkg.Repo.FullName)
+		return nil, nil, nil
+	}
+	if pkg.Action != actionCreated && pkg.Action != actionDeleted {
+		return nil, nil, nil
+	}
+
+	return repoFromPackage(pkg), c.buildFromPackage(pkg), nil
+}
+
+// parseRepositoryHook parses a repository hook. The repository of a deleted
+// repository is returned with remote.ErrRepoDeleted, other actions are
+// ignored.
+func parseRepositoryHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	repository, err := parseRepository(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if repository.Action != actionDeleted {
+		return nil, nil, nil
+	}
+	return repoFromRepository(repository), nil, remote.ErrRepoDeleted
+}
+
+// parseWikiHook parses a wiki hook. Wiki edits never create a build, so nil
+// values are returned unless the payload is malformed.
+func parseWikiHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	wiki, err := parseWiki(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debug().Msgf("ignore wiki event %s of page %s in %s", wiki.Action, wiki.Page, wiki.Repo.FullName)
+	return nil, nil, nil
+}
+
 // parseCreatedHook parses a push hook and returns the Repo and Build details.
 // If the commit type is unsupported nil values are returned.
-func parseCreatedHook(payload io.Reader) (repo *model.Repo, build *model.Build, err error) {
+func (c *Gitea) parseCreatedHook(payload io.Reader) (repo *model.Repo, build *model.Build, err error) {
 	push, err := parsePush(payload)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// new branches are built by the push hook Gitea sends alongside
+	if push.RefType == refBranch {
+		log.Debug().Msgf("ignore creation of branch %s of %s, its push is built", push.Ref, push.Repo.FullName)
+		return nil, nil, nil
+	}
 	if push.RefType != refTag {
 		return nil, nil, nil
 	}
 
+	// ignore deleted tags, there is nothing to clone
+	if isDeletePush(push) {
+		return nil, nil, nil
+	}
+
 	repo = repoFromPush(push)
-	build = buildFromTag(push)
+	build = c.buildFromTag(push)
 	return repo, build, nil
 }
 
+// parseDeleteHook parses a delete hook and returns the Repo and Build details,
+// e.g. to tear down the environment of a deleted branch. Deleted tags are only
+// built if enabled.
+func (c *Gitea) parseDeleteHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	push, err := parsePush(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch push.RefType {
+	case refBranch:
+	case refTag:
+		if !c.TagDeletions {
+			log.Debug().Msgf("ignore deleted tag %s of %s", push.Ref, push.Repo.FullName)
+			return nil, nil, nil
+		}
+	default:
+		return nil, nil, nil
+	}
+
+	return repoFromPush(push), c.buildFromDelete(push), nil
+}
+
 // parsePullRequestHook parses a pull_request hook and returns the Repo and Build details.
-func parsePullRequestHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+func (c *Gitea) parsePullRequestHook(payload io.Reader) (*model.Repo, *model.Build, error) {
 	var (
 		repo  *model.Repo
 		build *model.Build
@@ -103,15 +397,151 @@ func parsePullRequestHook(payload io.Reader) (*model.Repo, *model.Build, error)
 		return nil, nil, err
 	}
 
+	// removing an assignee never requires a build, even if the action is
+	// enabled
+	if pr.Action == actionUnassigned {
+		log.Debug().Msgf("ignore unassignment of pull request %d of %s", pr.Number, pr.Repo.FullName)
+		return nil, nil, nil
+	}
+
+	// a draft marked as ready has to be built, as its code changes were skipped
+	readied := c.SkipDraftPullRequests && pr.Action == actionEdited &&
+		isDraftTitle(pr.Changes.Title.From) && !isDraftPullRequest(pr)
+
 	// Don't trigger builds for non-code changes, or if PR is not open
-	if pr.Action != actionOpen && pr.Action != actionSync {
+	if !readied && !c.isPullRequestActionEnabled(pr.Action) {
 		return nil, nil, nil
 	}
 	if pr.PullRequest.State != stateOpen {
 		return nil, nil, nil
 	}
+	if c.SkipDraftPullRequests && isDraftPullRequest(pr) {
+		return nil, nil, nil
+	}
 
 	repo = repoFromPullRequest(pr)
-	build = buildFromPullRequest(pr)
+	build = c.buildFromPullRequest(pr)
 	return repo, build, err
 }
+
+// parsePullRequestReviewHook parses the hook of an approving pull request
+// review and returns the Repo and Build details with the reviewer. Reviews of
+// users without read access are ignored.
+func (c *Gitea) parsePullRequestReviewHook(payload io.Reader, state model.ReviewState) (*model.Repo, *model.Build, error) {
+	pr, err := parsePullRequest(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pr.PullRequest.State != stateOpen {
+		return nil, nil, nil
+	}
+
+	// the repository permissions of the payload are the ones of the reviewer
+	if !toPerm(&pr.Repo.Permissions).Pull {
+		log.Debug().Msgf("ignore review of %s without read access to %s", pr.Sender.Login, pr.Repo.FullName)
+		return nil, nil, nil
+	}
+
+	return repoFromPullRequest(pr), c.buildFromPullRequestReview(pr, state), nil
+}
+
+// parseReleaseHook parses a release hook and returns the Repo and Build details.
+// Drafts and releases that were deleted do not trigger a build.
+func (c *Gitea) parseReleaseHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	release, err := parseRelease(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if release.Action != actionPublished && release.Action != actionUpdated {
+		return nil, nil, nil
+	}
+	if release.Release.Draft {
+		return nil, nil, nil
+	}
+
+	return repoFromRelease(release), c.buildFromRelease(release), nil
+}
+
+// parseIssueCommentHook parses a comment hook and returns the Repo and a
+// partial Build of the pull request, if the comment is a rebuild command of a
+// user with push access. The build is completed by Hook with the current state
+// of the pull request.
+func (c *Gitea) parseIssueCommentHook(payload io.Reader) (*model.Repo, *model.Build, error) {
+	comment, err := parseIssueComment(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if comment.Action != actionCreated {
+		return nil, nil, nil
+	}
+	if !comment.IsPull && comment.Issue.PullRequest == nil {
+		return nil, nil, nil
+	}
+	if !c.isRebuildCommand(comment.Comment.Body) {
+		return nil, nil, nil
+	}
+
+	// the repository permissions of the payload are the ones of the commenter
+	if !toPerm(&comment.Repo.Permissions).Push {
+		log.Debug().Msgf("ignore rebuild command of %s without push access to %s", comment.Comment.User.Login, comment.Repo.FullName)
+		return nil, nil, nil
+	}
+
+	return repoFromIssueComment(comment), buildFromIssueComment(comment), nil
+}
+
+// isRebuildCommand reports whether the comment starts with the rebuild command.
+func (c *Gitea) isRebuildCommand(body string) bool {
+	command := c.RebuildCommand
+	if command == "" {
+		command = defaultRebuildCommand
+	}
+	fields := strings.Fields(body)
+	return len(fields) > 0 && fields[0] == command
+}
+
+// defaultRebuildCommand is the comment command triggering a rebuild of a pull
+// request if no other command is configured.
+const defaultRebuildCommand = "/rebuild"
+
+// defaultPullRequestActions are the pull request actions changing code, which
+// trigger builds if no other actions are configured.
+var defaultPullRequestActions = []string{actionOpen, actionSync, actionReopen}
+
+// isPullRequestActionEnabled reports whether a pull_request hook with the
+// given action should trigger a build.
+func (c *Gitea) isPullRequestActionEnabled(action string) bool {
+	actions := c.PullRequestActions
+	if len(actions) == 0 {
+		actions = defaultPullRequestActions
+	}
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// draftPrefixes are the title prefixes Gitea uses by default to mark a pull
+// request as work in progress.
+var draftPrefixes = []string{"WIP:", "[WIP]"}
+
+// isDraftPullRequest reports whether the pull request is a draft.
+func isDraftPullRequest(pr *pullRequestHook) bool {
+	return pr.PullRequest.Draft || isDraftTitle(pr.PullRequest.Title)
+}
+
+// isDraftTitle reports whether the pull request title marks it as work in
+// progress.
+func isDraftTitle(title string) bool {
+	for _, prefix := range draftPrefixes {
+		if len(title) >= len(prefix) && strings.EqualFold(title[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -16,17 +16,31 @@ package gitea
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/franela/goblin"
+	"github.com/gin-gonic/gin"
 
 	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+	"github.com/woodpecker-ci/woodpecker/server/store"
 	"github.com/woodpecker-ci/woodpecker/shared/utils"
 )
 
 func Test_parser(t *testing.T) {
+	c := new(Gitea)
+	ctx := context.Background()
 	g := goblin.Goblin(t)
 	g.Describe("Gitea parser", func() {
 		g.It("should ignore unsupported hook events", func() {
@@ -34,7 +48,7 @@ func Test_parser(t *testing.T) {
 			req, _ := http.NewRequest("POST", "/hook", buf)
 			req.Header = http.Header{}
 			req.Header.Set(hookEvent, "issues")
-			r, b, err := parseHook(req)
+			r, b, err := c.parseHook(ctx, req)
 			g.Assert(r).IsNil()
 			g.Assert(b).IsNil()
 			g.Assert(err).IsNil()
@@ -45,7 +59,7 @@ func Test_parser(t *testing.T) {
 				req, _ := http.NewRequest("POST", "/hook", buf)
 				req.Header = http.Header{}
 				req.Header.Set(hookEvent, hookPush)
-				r, b, err := parseHook(req)
+				r, b, err := c.parseHook(ctx, req)
 				g.Assert(err).IsNil()
 				g.Assert(r).IsNotNil()
 				g.Assert(b).IsNotNil()
@@ -53,5 +67,591 @@ func Test_parser(t *testing.T) {
 				g.Assert(utils.EqualStringSlice(b.ChangedFiles, []string{"CHANGELOG.md", "app/controller/application.rb"})).IsTrue()
 			})
 		})
+		g.Describe("given the hooks of a push of multiple refs", func() {
+			g.It("should build every ref of the push once", func() {
+				var builds []*model.Build
+				for _, payload := range []string{fixtures.HookPush, fixtures.HookPushMultiRef} {
+					req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+					req.Header = http.Header{}
+					req.Header.Set(hookEvent, hookPush)
+					_, b, err := c.parseHook(ctx, req)
+					g.Assert(err).IsNil()
+					builds = append(builds, b)
+				}
+				g.Assert(len(builds)).Equal(2)
+				g.Assert(builds[0].Branch).Equal("master")
+				g.Assert(builds[1].Branch).Equal("release")
+				g.Assert(builds[1].Ref).Equal("refs/heads/release")
+				g.Assert(builds[0].Commit).Equal(builds[1].Commit)
+			})
+		})
+		g.Describe("given a push hook without branch", func() {
+			push := func(ref, defaultBranch string) *model.Build {
+				payload := strings.Replace(fixtures.HookPush, `"ref": "refs/heads/master"`, `"ref": "`+ref+`"`, 1)
+				payload = strings.Replace(payload, `"default_branch": "master"`, `"default_branch": "`+defaultBranch+`"`, 1)
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPush)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return b
+			}
+			g.It("should use the default branch for an empty ref", func() {
+				b := push("", "main")
+				g.Assert(b.Branch).Equal("main")
+				g.Assert(b.Ref).Equal("refs/heads/main")
+			})
+			g.It("should use the default branch for a malformed ref", func() {
+				b := push("refs/heads/ ", "main")
+				g.Assert(b.Branch).Equal("main")
+				g.Assert(b.Ref).Equal("refs/heads/main")
+			})
+			g.It("should keep the branch of a valid ref", func() {
+				b := push("refs/heads/feature", "main")
+				g.Assert(b.Branch).Equal("feature")
+				g.Assert(b.Ref).Equal("refs/heads/feature")
+			})
+			g.It("should not return a build without default branch", func() {
+				g.Assert(push("refs/heads/", "") == nil).IsTrue()
+			})
+		})
+		g.Describe("given a push hook to an ignored branch", func() {
+			push := func(c *Gitea, ctx context.Context) *model.Build {
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(fixtures.HookPush))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPush)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return b
+			}
+			g.It("should not return a build", func() {
+				c := &Gitea{IgnoreBranches: []string{"renovate/**", "mas*"}}
+				g.Assert(push(c, ctx) == nil).IsTrue()
+			})
+			g.It("should return a build for other branches", func() {
+				c := &Gitea{IgnoreBranches: []string{"renovate/**"}}
+				g.Assert(push(c, ctx) == nil).IsFalse()
+			})
+			g.It("should not return a build for inactive repositories", func() {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{"gordon/hello-world": {IsActive: false}}})
+				g.Assert(push(c, ginCtx) == nil).IsTrue()
+
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{"gordon/hello-world": {IsActive: true}}})
+				g.Assert(push(c, ginCtx) == nil).IsFalse()
+			})
+			g.It("should return a build for unknown repositories", func() {
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{}})
+				g.Assert(push(c, ginCtx) == nil).IsFalse()
+			})
+		})
+		g.Describe("given a form-encoded hook", func() {
+			g.It("should return the same build as for a JSON hook", func() {
+				c, _ := New(Opts{URL: "http://localhost:3000"})
+				ginCtx := &gin.Context{}
+				store.ToContext(ginCtx, &repoStore{repos: map[string]*model.Repo{"gordon/hello-world": {FullName: "gordon/hello-world", Hash: hookSecret, IsActive: true}}})
+				hook := func(contentType, body string) *model.Build {
+					req, _ := http.NewRequest("POST", "/hook", strings.NewReader(body))
+					req.Header = http.Header{}
+					req.Header.Set(hookEvent, hookPush)
+					req.Header.Set("Content-Type", contentType)
+					signHook(req, fixtures.HookPush)
+					_, b, err := c.Hook(ginCtx, req)
+					g.Assert(err).IsNil()
+					g.Assert(b).IsNotNil()
+					// deduplicating the changed files does not keep their order
+					sort.Strings(b.ChangedFiles)
+					return b
+				}
+				fromJSON := hook("application/json", fixtures.HookPush)
+				fromForm := hook("application/x-www-form-urlencoded", url.Values{"payload": {fixtures.HookPush}}.Encode())
+				g.Assert(fromForm).Equal(fromJSON)
+			})
+			g.It("should fail without payload", func() {
+				_, err := hookPayload("application/x-www-form-urlencoded; charset=utf-8", []byte("secret=foo"))
+				g.Assert(err).IsNotNil()
+			})
+			g.It("should return JSON bodies as is", func() {
+				payload, err := hookPayload("application/json", []byte(fixtures.HookPush))
+				g.Assert(err).IsNil()
+				g.Assert(string(payload)).Equal(fixtures.HookPush)
+			})
+		})
+		g.Describe("given a pull_request hook", func() {
+			g.It("should extract repository and build details", func() {
+				for _, payload := range []string{fixtures.HookPullRequest, fixtures.HookPullRequestReopened} {
+					buf := bytes.NewBufferString(payload)
+					req, _ := http.NewRequest("POST", "/hook", buf)
+					req.Header = http.Header{}
+					req.Header.Set(hookEvent, hookPullRequest)
+					r, b, err := c.parseHook(ctx, req)
+					g.Assert(err).IsNil()
+					g.Assert(r).IsNotNil()
+					g.Assert(b).IsNotNil()
+					g.Assert(b.Event).Equal(model.EventPull)
+				}
+			})
+			g.It("should ignore label changes", func() {
+				buf := bytes.NewBufferString(fixtures.HookPullRequestLabelUpdated)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequest)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+			g.It("should use the configured actions", func() {
+				c := &Gitea{PullRequestActions: []string{"label_updated"}}
+				buf := bytes.NewBufferString(fixtures.HookPullRequestLabelUpdated)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequest)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Action).Equal("label_updated")
+			})
+		})
+		g.Describe("given a pull_request assign hook", func() {
+			parse := func(c *Gitea, payload string) *model.Build {
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequest)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return b
+			}
+			unassigned := strings.Replace(fixtures.HookPullRequestAssigned, `"action": "assigned"`, `"action": "unassigned"`, 1)
+			g.It("should ignore assignments by default", func() {
+				g.Assert(parse(c, fixtures.HookPullRequestAssigned)).IsNil()
+			})
+			g.It("should build assignments if enabled", func() {
+				c := &Gitea{PullRequestActions: []string{"assigned"}}
+				b := parse(c, fixtures.HookPullRequestAssigned)
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Event).Equal(model.EventPull)
+				g.Assert(b.Action).Equal("assigned")
+				g.Assert(b.Assignees).Equal([]string{"security", "octocat"})
+			})
+			g.It("should never build unassignments", func() {
+				c := &Gitea{PullRequestActions: []string{"assigned", "unassigned"}}
+				g.Assert(parse(c, unassigned)).IsNil()
+			})
+			g.It("should return the assignees of other actions", func() {
+				b := parse(c, strings.Replace(fixtures.HookPullRequestAssigned, `"action": "assigned"`, `"action": "opened"`, 1))
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Assignees).Equal([]string{"security", "octocat"})
+			})
+		})
+
+		g.Describe("given a pull_request milestone hook", func() {
+			parse := func(c *Gitea, payload string) *model.Build {
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequest)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return b
+			}
+			g.It("should ignore milestone changes by default", func() {
+				g.Assert(parse(c, fixtures.HookPullRequestMilestone)).IsNil()
+			})
+			g.It("should build milestone changes with the new milestone if enabled", func() {
+				c := &Gitea{PullRequestActions: []string{"milestoned"}}
+				b := parse(c, fixtures.HookPullRequestMilestone)
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Action).Equal("milestoned")
+				g.Assert(*b.Milestone).Equal(model.Milestone{ID: 3, Title: "v1.2.0"})
+
+				remilestoned := strings.NewReplacer(`"id": 3,`, `"id": 4,`, `"title": "v1.2.0"`, `"title": "v1.3.0"`).Replace(fixtures.HookPullRequestMilestone)
+				g.Assert(*parse(c, remilestoned).Milestone).Equal(model.Milestone{ID: 4, Title: "v1.3.0"})
+			})
+			g.It("should leave the milestone empty if there is none", func() {
+				b := parse(c, fixtures.HookPullRequest)
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Milestone == nil).IsTrue()
+			})
+		})
+
+		g.Describe("given a draft pull_request hook", func() {
+			parse := func(c *Gitea, payload string) *model.Build {
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequest)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return b
+			}
+			g.It("should build drafts by default", func() {
+				b := parse(c, fixtures.HookPullRequestDraft)
+				g.Assert(b).IsNotNil()
+				g.Assert(b.IsDraft).IsTrue()
+				g.Assert(parse(c, fixtures.HookPullRequestReady)).IsNil()
+			})
+			g.It("should skip drafts until they are ready", func() {
+				c := &Gitea{SkipDraftPullRequests: true}
+				g.Assert(parse(c, fixtures.HookPullRequestDraft)).IsNil()
+				b := parse(c, fixtures.HookPullRequestReady)
+				g.Assert(b).IsNotNil()
+				g.Assert(b.IsDraft).IsFalse()
+				g.Assert(parse(c, fixtures.HookPullRequest)).IsNotNil()
+			})
+		})
+		g.Describe("given push hooks for different refs", func() {
+			g.It("should only return a build for branches", func() {
+				for payload, want := range map[string]bool{
+					fixtures.HookPush:      true,
+					fixtures.HookPushNotes: false,
+				} {
+					buf := bytes.NewBufferString(payload)
+					req, _ := http.NewRequest("POST", "/hook", buf)
+					req.Header = http.Header{}
+					req.Header.Set(hookEvent, hookPush)
+					_, b, err := c.parseHook(ctx, req)
+					g.Assert(err).IsNil()
+					g.Assert(b != nil).Equal(want)
+				}
+			})
+		})
+		g.Describe("given a wiki hook", func() {
+			g.It("should ignore wiki events", func() {
+				buf := bytes.NewBufferString(fixtures.HookWiki)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookWiki)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+			g.It("should ignore wiki pushes", func() {
+				buf := bytes.NewBufferString(fixtures.HookPushWiki)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPush)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+			g.It("should fail on a malformed wiki event", func() {
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString("{"))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookWiki)
+				_, _, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNotNil()
+			})
+		})
+		g.Describe("given a repository hook", func() {
+			hook := func(payload string) (*model.Repo, *model.Build, error) {
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookRepository)
+				return c.parseHook(ctx, req)
+			}
+			g.It("should return the deleted repository", func() {
+				r, b, err := hook(fixtures.HookRepositoryDeleted)
+				g.Assert(errors.Is(err, remote.ErrRepoDeleted)).IsTrue()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(r.Owner).Equal("gordon")
+				g.Assert(b == nil).IsTrue()
+			})
+			g.It("should ignore created repositories", func() {
+				r, b, err := hook(strings.Replace(fixtures.HookRepositoryDeleted, `"deleted"`, `"created"`, 1))
+				g.Assert(err).IsNil()
+				g.Assert(r == nil).IsTrue()
+				g.Assert(b == nil).IsTrue()
+			})
+			g.It("should return the deleted repository signed by the organization hook from Hook", func() {
+				mac := hmac.New(sha256.New, []byte("secret"))
+				_, _ = mac.Write([]byte(fixtures.HookRepositoryDeleted))
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(fixtures.HookRepositoryDeleted))
+				req.Header.Set(hookEvent, hookRepository)
+				req.Header.Set(hookSignature256, hex.EncodeToString(mac.Sum(nil)))
+				r, b, err := (&Gitea{OrgHookSecret: "secret"}).Hook(ctx, req)
+				g.Assert(errors.Is(err, remote.ErrRepoDeleted)).IsTrue()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b == nil).IsTrue()
+			})
+			g.It("should reject deleted repositories not signed by the organization hook from Hook", func() {
+				for _, c := range []*Gitea{{}, {OrgHookSecret: "secret"}} {
+					req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(fixtures.HookRepositoryDeleted))
+					req.Header.Set(hookEvent, hookRepository)
+					req.Header.Set(hookSignature256, "0123456789abcdef")
+					r, _, err := c.Hook(ctx, req)
+					g.Assert(errors.Is(err, remote.ErrInvalidSignature)).IsTrue()
+					g.Assert(r == nil).IsTrue()
+				}
+			})
+		})
+		g.Describe("given a package hook", func() {
+			packageHook := func(c *Gitea, payload string) (*model.Repo, *model.Build, error) {
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPackage)
+				return c.parseHook(ctx, req)
+			}
+			g.It("should build published packages", func() {
+				r, b, err := packageHook(c, fixtures.HookPackage)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Event).Equal(model.EventPackage)
+				g.Assert(b.Branch).Equal("master")
+				g.Assert(b.Ref).Equal("refs/heads/master")
+				g.Assert(b.Commit).Equal("")
+				g.Assert(b.Author).Equal("gordon")
+				g.Assert(b.Link).Equal("http://gitea.golang.org/gordon/-/packages/container/hello-world/1.0.0")
+				g.Assert(b.Message).Equal("Package hello-world 1.0.0 created")
+				g.Assert(*b.Package).Equal(model.Package{
+					Type:    "container",
+					Name:    "hello-world",
+					Version: "1.0.0",
+					Action:  "created",
+				})
+			})
+			g.It("should ignore deleted packages by default", func() {
+				payload := strings.Replace(fixtures.HookPackage, `"action": "created"`, `"action": "deleted"`, 1)
+				r, b, err := packageHook(c, payload)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+			g.It("should build deleted packages if enabled", func() {
+				payload := strings.Replace(fixtures.HookPackage, `"action": "created"`, `"action": "deleted"`, 1)
+				r, b, err := packageHook(&Gitea{PackageDeletions: true}, payload)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Package.Action).Equal("deleted")
+			})
+			g.It("should ignore packages without repository", func() {
+				r, b, err := packageHook(c, fixtures.HookPackageNoRepo)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
+		g.Describe("given a pull request review hook", func() {
+			g.It("should record the reviewer of an approval", func() {
+				buf := bytes.NewBufferString(fixtures.HookPullRequestApproved)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequestApproved)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Event).Equal(model.EventPull)
+				g.Assert(b.Action).Equal(actionApproved)
+				g.Assert(b.Approver).Equal("reviewer")
+				g.Assert(b.Approved).Equal(int64(1656922350))
+				g.Assert(b.ReviewState).Equal(model.ReviewApproved)
+				g.Assert(b.Reviewer).Equal("")
+				g.Assert(b.Reviewed).Equal(int64(0))
+			})
+			g.It("should record the state of reviews requesting changes", func() {
+				buf := bytes.NewBufferString(fixtures.HookPullRequestRejected)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequestRejected)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Event).Equal(model.EventPull)
+				g.Assert(b.Action).Equal(actionReviewed)
+				g.Assert(b.ReviewState).Equal(model.ReviewRejected)
+				g.Assert(b.Approver).Equal("")
+				g.Assert(b.Approved).Equal(int64(0))
+			})
+			g.It("should record the state of comment reviews", func() {
+				payload := strings.Replace(fixtures.HookPullRequestRejected, "pull_request_review_rejected", "pull_request_review_comment", 1)
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequestReviewComment)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(b.Action).Equal(actionReviewed)
+				g.Assert(b.ReviewState).Equal(model.ReviewComment)
+				g.Assert(b.Approver).Equal("")
+			})
+			g.It("should ignore approvals without read access", func() {
+				payload := strings.Replace(fixtures.HookPullRequestApproved, `"pull": true`, `"pull": false`, 1)
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPullRequestApproved)
+				_, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
+		g.Describe("given a comment hook", func() {
+			parse := func(c *Gitea, event, payload string) (*model.Repo, *model.Build) {
+				buf := bytes.NewBufferString(payload)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, event)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				return r, b
+			}
+			g.It("should return a rebuild of the pull request", func() {
+				for _, event := range []string{hookIssueComment, hookPullRequestComment} {
+					r, b := parse(c, event, fixtures.HookIssueCommentRebuild)
+					g.Assert(r.FullName).Equal("gordon/hello-world")
+					g.Assert(b.Event).Equal(model.EventPull)
+					g.Assert(b.Action).Equal(actionRebuild)
+					g.Assert(b.Ref).Equal("refs/pull/1/head")
+					g.Assert(b.Sender).Equal("gordon")
+				}
+			})
+			g.It("should ignore rebuild commands without push access", func() {
+				_, b := parse(c, hookPullRequestComment, fixtures.HookIssueCommentRebuildUnauthorized)
+				g.Assert(b).IsNil()
+			})
+			g.It("should ignore rebuild commands on issues", func() {
+				_, b := parse(c, hookIssueComment, fixtures.HookIssueComment)
+				g.Assert(b).IsNil()
+			})
+			g.It("should use the configured command", func() {
+				c := &Gitea{RebuildCommand: "/retest"}
+				_, b := parse(c, hookPullRequestComment, fixtures.HookIssueCommentRebuild)
+				g.Assert(b).IsNil()
+				g.Assert(c.isRebuildCommand("/retest")).IsTrue()
+				g.Assert(c.isRebuildCommand("please /retest")).IsFalse()
+			})
+		})
+		g.Describe("given a push hook for a deleted branch", func() {
+			g.It("should not return a build", func() {
+				buf := bytes.NewBufferString(fixtures.HookPushBranchDelete)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookPush)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
+		g.Describe("given a tag hook", func() {
+			g.It("should extract repository and build details", func() {
+				for _, payload := range []string{fixtures.HookPushTag, fixtures.HookPushTagAnnotated} {
+					buf := bytes.NewBufferString(payload)
+					req, _ := http.NewRequest("POST", "/hook", buf)
+					req.Header = http.Header{}
+					req.Header.Set(hookEvent, hookCreated)
+					r, b, err := c.parseHook(ctx, req)
+					g.Assert(err).IsNil()
+					g.Assert(r).IsNotNil()
+					g.Assert(b).IsNotNil()
+					g.Assert(b.Event).Equal(model.EventTag)
+					g.Assert(b.Ref).Equal("refs/tags/v1.0.0")
+					g.Assert(b.Commit).Equal("ef98532add3b2feb7a137426bba1248724367df5")
+				}
+			})
+			g.It("should not return a build for deleted tags", func() {
+				buf := bytes.NewBufferString(fixtures.HookPushTagDelete)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookCreated)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
+		g.Describe("given a create hook for a branch", func() {
+			g.It("should not return a build", func() {
+				buf := bytes.NewBufferString(fixtures.HookCreateBranch)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookCreated)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
+		g.Describe("given a delete hook", func() {
+			deleteHook := func(c *Gitea, payload string) (*model.Repo, *model.Build, error) {
+				req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookDelete)
+				return c.parseHook(ctx, req)
+			}
+			deleteTag := strings.Replace(strings.Replace(fixtures.HookDeleteBranch, `"feature/changes"`, `"v1.0.0"`, 1), `"ref_type": "branch"`, `"ref_type": "tag"`, 1)
+			g.It("should build deleted branches on the default branch", func() {
+				r, b, err := deleteHook(c, fixtures.HookDeleteBranch)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Event).Equal(model.EventDelete)
+				g.Assert(b.Branch).Equal("master")
+				g.Assert(b.Ref).Equal("refs/heads/master")
+				g.Assert(b.Commit).Equal("")
+				g.Assert(b.Author).Equal("gordon")
+				g.Assert(b.Message).Equal("Delete branch feature/changes")
+				g.Assert(*b.DeletedRef).Equal(model.DeletedRef{
+					Type: "branch",
+					Name: "feature/changes",
+				})
+			})
+			g.It("should ignore deleted tags by default", func() {
+				r, b, err := deleteHook(c, deleteTag)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+			g.It("should build deleted tags if enabled", func() {
+				r, b, err := deleteHook(&Gitea{TagDeletions: true}, deleteTag)
+				g.Assert(err).IsNil()
+				g.Assert(r.FullName).Equal("gordon/hello-world")
+				g.Assert(b.Message).Equal("Delete tag v1.0.0")
+				g.Assert(*b.DeletedRef).Equal(model.DeletedRef{
+					Type: "tag",
+					Name: "v1.0.0",
+				})
+			})
+		})
+		g.Describe("given a release hook", func() {
+			g.It("should extract repository and build details", func() {
+				buf := bytes.NewBufferString(fixtures.HookRelease)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookRelease)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNotNil()
+				g.Assert(b).IsNotNil()
+				g.Assert(b.Event).Equal(model.EventRelease)
+			})
+			g.It("should ignore draft releases", func() {
+				buf := bytes.NewBufferString(fixtures.HookReleaseDraft)
+				req, _ := http.NewRequest("POST", "/hook", buf)
+				req.Header = http.Header{}
+				req.Header.Set(hookEvent, hookRelease)
+				r, b, err := c.parseHook(ctx, req)
+				g.Assert(err).IsNil()
+				g.Assert(r).IsNil()
+				g.Assert(b).IsNil()
+			})
+		})
 	})
 }
+
+// repoStore is a store only returning the given repositories.
+type repoStore struct {
+	store.Store
+	repos map[string]*model.Repo
+}
+
+func (s *repoStore) GetRepoRemoteName(remote, name string) (*model.Repo, error) {
+	repo, ok := s.repos[name]
+	if !ok || repo.Remote != remote {
+		return nil, fmt.Errorf("repo %s not found", name)
+	}
+	return repo, nil
+}
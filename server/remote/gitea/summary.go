@@ -0,0 +1,197 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/rs/zerolog/log"
+
+	"github.com/woodpecker-ci/woodpecker/server"
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote/common"
+)
+
+// summaryMarker is the hidden first line of summary comments, which
+// identifies the comment to update on later builds of the pull request.
+const summaryMarker = "<!-- woodpecker-summary -->"
+
+// defaultSummaryTemplate is the template of summary comments, a table of the
+// states of the pipelines of the build.
+const defaultSummaryTemplate = `**{{ .context }}** build [#{{ .number }}]({{ .link }}) of {{ .commit }}: {{ .status }}
+
+| Pipeline | Status |
+| --- | --- |
+{{ range .pipelines }}| [{{ .name }}]({{ .link }}) | {{ .state }} |
+{{ end }}`
+
+// Summary creates or updates the summary comment of the pull request of the
+// build, if enabled. Only finished pull request builds are summarized.
+func (c *Gitea) Summary(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) error {
+	if c.summary == nil || b.Event != model.EventPull || r.IsMirror {
+		return nil
+	}
+	switch b.Status {
+	case model.StatusPending, model.StatusRunning, model.StatusBlocked:
+		return nil
+	}
+
+	index := b.PullRequest
+	if index == 0 {
+		parts := strings.Split(b.Ref, "/")
+		if len(parts) < 3 {
+			return fmt.Errorf("could not get the pull request of ref %s", b.Ref)
+		}
+		var err error
+		if index, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return fmt.Errorf("could not get the pull request of ref %s: %w", b.Ref, err)
+		}
+	}
+
+	body, err := c.renderSummary(r, b)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	comment, err := findSummary(client, r, index, u.Login)
+	if err != nil {
+		return err
+	}
+	if comment == nil {
+		_, resp, err := client.CreateIssueComment(r.Owner, r.Name, index, gitea.CreateIssueCommentOption{Body: body})
+		return scopeError(resp, err, scopeWriteIssue)
+	}
+	if comment.Body == body {
+		return nil
+	}
+	_, resp, err := client.EditIssueComment(r.Owner, r.Name, comment.ID, gitea.EditIssueCommentOption{Body: body})
+	return scopeError(resp, err, scopeWriteIssue)
+}
+
+// renderSummary returns the body of the summary comment of the build,
+// starting with the marker.
+func (c *Gitea) renderSummary(r *model.Repo, b *model.Build) (string, error) {
+	var pipelines []map[string]string
+	for _, proc := range b.Procs {
+		if !proc.IsParent() {
+			continue
+		}
+		pipelines = append(pipelines, map[string]string{
+			"name":  proc.Name,
+			"state": string(proc.State),
+			"link":  getStatusLink(r, b, proc),
+		})
+	}
+
+	commit := b.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+
+	var buf strings.Builder
+	buf.WriteString(summaryMarker + "\n")
+	err := c.summary.Execute(&buf, map[string]interface{}{
+		"context":   server.Config.Server.StatusContext,
+		"number":    b.Number,
+		"status":    string(b.Status),
+		"event":     string(b.Event),
+		"commit":    commit,
+		"link":      common.GetBuildStatusLink(r, b, nil),
+		"owner":     r.Owner,
+		"repo":      r.Name,
+		"pipelines": pipelines,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not render the summary of build %d: %w", b.Number, err)
+	}
+	return buf.String(), nil
+}
+
+// findSummary returns the summary comment posted by the user to the pull
+// request, or nil if there is none yet.
+func findSummary(client *gitea.Client, r *model.Repo, index int64, login string) (*gitea.Comment, error) {
+	var first int64
+	for page := 1; ; page++ {
+		comments, resp, err := client.ListIssueComments(r.Owner, r.Name, index, gitea.ListIssueCommentOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     page,
+				PageSize: perPage,
+			},
+		})
+		if err != nil {
+			return nil, scopeError(resp, err, scopeReadIssue)
+		}
+		// older Gitea versions return all comments regardless of the page
+		if len(comments) == 0 || comments[0].ID == first {
+			return nil, nil
+		}
+		first = comments[0].ID
+
+		for _, comment := range comments {
+			if isSummary(comment, login) {
+				return comment, nil
+			}
+		}
+		if len(comments) < perPage {
+			return nil, nil
+		}
+	}
+}
+
+// isSummary reports whether the comment is a summary posted by the user.
+func isSummary(comment *gitea.Comment, login string) bool {
+	if comment.Poster == nil || !strings.EqualFold(comment.Poster.UserName, login) {
+		return false
+	}
+	return strings.HasPrefix(comment.Body, summaryMarker)
+}
+
+// deleteSummaries deletes the summary comments of the open pull requests of
+// the repository, e.g. because Woodpecker is deactivated for it.
+func (c *Gitea) deleteSummaries(ctx context.Context, u *model.User, r *model.Repo) error {
+	pulls, err := c.PullRequests(ctx, u, r, 0)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.newClientUser(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	for _, pull := range pulls {
+		comment, err := findSummary(client, r, pull.Number, u.Login)
+		if err != nil {
+			return err
+		}
+		if comment == nil {
+			continue
+		}
+		if resp, err := client.DeleteIssueComment(r.Owner, r.Name, comment.ID); err != nil {
+			return scopeError(resp, err, scopeWriteIssue)
+		}
+		log.Debug().Msgf("deleted the build summary of pull request %d of %s", pull.Number, r.FullName)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+// newTransport returns the transport of Gitea API calls. Requests are sent via
+// the proxy if one is given, otherwise via the proxy configured by the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func newTransport(proxy *url.URL, tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
+
+// newTLSConfig returns the TLS config of Gitea API calls. The CA certificates
+// are either given as PEM bundle or as path to one and are trusted in
+// addition to the system certificates.
+func newTLSConfig(caCert string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: skipVerify}
+	if caCert == "" {
+		return config, nil
+	}
+
+	data, source := []byte(caCert), "the inline PEM bundle"
+	if !strings.HasPrefix(strings.TrimSpace(caCert), "-----BEGIN") {
+		var err error
+		if data, err = ioutil.ReadFile(caCert); err != nil {
+			return nil, fmt.Errorf("could not read CA certificates: %w", err)
+		}
+		source = caCert
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid CA certificates found in %s", source)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// maxRateLimitWait is the longest time a rate limited request waits before it
+// is retried, requests limited for longer fail.
+const maxRateLimitWait = time.Minute
+
+// retryTransport is a http.RoundTripper that retries requests failing with a
+// transport error or a server error using exponential backoff. Rate limited
+// requests are retried once the limit is reset.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		wait, limited := rateLimitWait(resp, time.Now())
+		if !limited {
+			wait = backoff
+		} else if wait > maxRateLimitWait {
+			return resp, err
+		}
+
+		// requests with a body can only be retried if it can be read again
+		var body io.ReadCloser
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			if body, err = req.GetBody(); err != nil {
+				return resp, err
+			}
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			if body != nil {
+				body.Close()
+			}
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// shouldRetry reports whether a request with the given result is worth
+// retrying.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusTooManyRequests
+}
+
+// rateLimitWait returns the time until a rate limited request can be retried,
+// taken from the Retry-After or X-RateLimit-Reset header. It reports false if
+// the response is not rate limited or does not tell when to retry.
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if after := resp.Header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.Atoi(after); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(after); err == nil {
+			return positive(date.Sub(now)), true
+		}
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return positive(time.Unix(reset, 0).Sub(now)), true
+	}
+	return 0, false
+}
+
+// rateLimitReserve is the number of API calls kept in reserve, once fewer
+// remain calls wait for the reset of the rate limit.
+const rateLimitReserve = 5
+
+// rateLimiter throttles the API calls to a Gitea instance by the rate limit
+// reported in the X-RateLimit-Remaining and X-RateLimit-Reset headers of its
+// responses. It is shared by all calls to the instance, as they all count
+// towards the same limit.
+type rateLimiter struct {
+	mu        sync.Mutex
+	reserve   int
+	remaining int // -1 if unknown
+	reset     time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{reserve: rateLimitReserve, remaining: -1}
+}
+
+// wait blocks until a call can be made without exceeding the rate limit or
+// the context is done. Calls that would have to wait longer than
+// maxRateLimitWait fail immediately.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.remaining >= 0 && !now.Before(l.reset) {
+		l.remaining = -1
+	}
+	if l.remaining < 0 || l.remaining > l.reserve {
+		// count the call until its response reports the actual number
+		if l.remaining > 0 {
+			l.remaining--
+		}
+		l.mu.Unlock()
+		return nil
+	}
+	reset := l.reset
+	l.mu.Unlock()
+
+	wait := reset.Sub(now)
+	if wait > maxRateLimitWait {
+		return fmt.Errorf("gitea rate limit exhausted until %s", reset.Format(time.RFC3339))
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// update records the rate limit reported by the response. Rate limited
+// responses without rate limit headers exhaust the limit until they can be
+// retried.
+func (l *rateLimiter) update(resp *http.Response, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if wait, limited := rateLimitWait(resp, now); limited {
+		l.remaining, l.reset = 0, now.Add(wait)
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	l.remaining, l.reset = remaining, time.Unix(reset, 0)
+}
+
+// rateLimitTransport is a http.RoundTripper that waits for the rate limiter
+// before sending requests and updates it from the responses.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *rateLimiter
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.limiter.update(resp, time.Now())
+	}
+	return resp, err
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// refreshTransport is a http.RoundTripper that refreshes the OAuth2 token of
+// the user once a request is rejected as unauthorized, and retries the request
+// with the new token. Users without refresh token are not refreshed.
+type refreshTransport struct {
+	next    http.RoundTripper
+	user    *model.User
+	refresh func(*model.User) error
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *refreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || t.user == nil || t.user.Secret == "" {
+		return resp, err
+	}
+
+	// requests with a body can only be retried if it can be read again
+	var body io.ReadCloser
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, err
+		}
+		if body, err = req.GetBody(); err != nil {
+			return resp, err
+		}
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if err := t.refresh(t.user); err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	if body != nil {
+		req.Body = body
+	}
+	req.Header.Set("Authorization", "token "+t.user.Token)
+	return t.next.RoundTrip(req)
+}
+
+// maxMessageSize is the maximum size of the body of a forbidden response
+// read to keep the message of Gitea.
+const maxMessageSize = 64 << 10
+
+// forbiddenMessageHeader is the response header messageTransport copies the
+// message of a forbidden response to. Gitea never sends it.
+const forbiddenMessageHeader = "X-Woodpecker-Forbidden-Message"
+
+// messageTransport is a http.RoundTripper keeping the message telling why
+// Gitea rejected a request as forbidden in a header of the response, as the
+// Gitea SDK drops the body of failed requests.
+type messageTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *messageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	message := new(struct {
+		Message string `json:"message"`
+	})
+	if json.Unmarshal(data, message) == nil && message.Message != "" {
+		resp.Header.Set(forbiddenMessageHeader, message.Message)
+	}
+	return resp, nil
+}
@@ -0,0 +1,342 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitea
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/franela/goblin"
+
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+func Test_retryTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("Gitea retry transport", func() {
+		var calls int
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			body, _ := ioutil.ReadAll(r.Body)
+			if calls < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write(body)
+		}))
+		g.After(func() {
+			s.Close()
+		})
+		g.BeforeEach(func() {
+			calls = 0
+		})
+
+		client := &http.Client{
+			Transport: &retryTransport{
+				next:    http.DefaultTransport,
+				retries: 3,
+				backoff: time.Millisecond,
+			},
+		}
+
+		g.It("Should retry server errors", func() {
+			resp, err := client.Post(s.URL, "text/plain", strings.NewReader("payload"))
+			g.Assert(err).IsNil()
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			g.Assert(resp.StatusCode).Equal(http.StatusOK)
+			g.Assert(string(body)).Equal("payload")
+			g.Assert(calls).Equal(3)
+		})
+
+		g.It("Should give up after the configured retries", func() {
+			client := &http.Client{
+				Transport: &retryTransport{
+					next:    http.DefaultTransport,
+					retries: 1,
+					backoff: time.Millisecond,
+				},
+			}
+			resp, err := client.Get(s.URL)
+			g.Assert(err).IsNil()
+			resp.Body.Close()
+			g.Assert(resp.StatusCode).Equal(http.StatusBadGateway)
+			g.Assert(calls).Equal(2)
+		})
+
+		g.It("Should stop retrying if the context is canceled", func() {
+			client := &http.Client{
+				Transport: &retryTransport{
+					next:    http.DefaultTransport,
+					retries: 3,
+					backoff: time.Hour,
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+			_, err := client.Do(req)
+			g.Assert(err).IsNotNil()
+			g.Assert(calls).Equal(1)
+		})
+	})
+}
+
+func Test_rateLimitWait(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("Gitea rate limits", func() {
+		now := time.Date(2022, 9, 14, 16, 0, 0, 0, time.UTC)
+		limited := func(header, value string) *http.Response {
+			resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+			if header != "" {
+				resp.Header.Set(header, value)
+			}
+			return resp
+		}
+
+		g.It("Should wait the seconds of the Retry-After header", func() {
+			wait, ok := rateLimitWait(limited("Retry-After", "30"), now)
+			g.Assert(ok).IsTrue()
+			g.Assert(wait).Equal(30 * time.Second)
+		})
+		g.It("Should wait until the date of the Retry-After header", func() {
+			wait, ok := rateLimitWait(limited("Retry-After", now.Add(time.Minute).Format(http.TimeFormat)), now)
+			g.Assert(ok).IsTrue()
+			g.Assert(wait).Equal(time.Minute)
+		})
+		g.It("Should wait until the reset of the X-RateLimit-Reset header", func() {
+			wait, ok := rateLimitWait(limited("X-RateLimit-Reset", strconv.FormatInt(now.Add(10*time.Second).Unix(), 10)), now)
+			g.Assert(ok).IsTrue()
+			g.Assert(wait).Equal(10 * time.Second)
+
+			wait, _ = rateLimitWait(limited("X-RateLimit-Reset", strconv.FormatInt(now.Add(-time.Second).Unix(), 10)), now)
+			g.Assert(wait).Equal(time.Duration(0))
+		})
+		g.It("Should use the backoff without headers", func() {
+			_, ok := rateLimitWait(limited("", ""), now)
+			g.Assert(ok).IsFalse()
+			_, ok = rateLimitWait(&http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{"Retry-After": []string{"1"}}}, now)
+			g.Assert(ok).IsFalse()
+		})
+		g.It("Should give up on rate limits lasting too long", func() {
+			var calls int
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer s.Close()
+			client := &http.Client{Transport: &retryTransport{next: http.DefaultTransport, retries: 3, backoff: time.Millisecond}}
+			resp, err := client.Get(s.URL)
+			g.Assert(err).IsNil()
+			resp.Body.Close()
+			g.Assert(resp.StatusCode).Equal(http.StatusTooManyRequests)
+			g.Assert(calls).Equal(1)
+		})
+	})
+}
+
+func Test_rateLimiter(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("Gitea rate limiter", func() {
+		ctx := context.Background()
+		rateLimited := func(remaining int, reset time.Time) *http.Response {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+			resp.Header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			return resp
+		}
+
+		g.It("Should not wait without a known rate limit", func() {
+			l := newRateLimiter()
+			l.update(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, time.Now())
+			g.Assert(l.wait(ctx)).IsNil()
+			g.Assert(l.remaining).Equal(-1)
+		})
+		g.It("Should count calls while enough remain", func() {
+			l := newRateLimiter()
+			l.update(rateLimited(100, time.Now().Add(time.Hour)), time.Now())
+			g.Assert(l.wait(ctx)).IsNil()
+			g.Assert(l.wait(ctx)).IsNil()
+			g.Assert(l.remaining).Equal(98)
+		})
+		g.It("Should wait for the reset once few calls remain", func() {
+			l := newRateLimiter()
+			l.remaining, l.reset = rateLimitReserve, time.Now().Add(50*time.Millisecond)
+			start := time.Now()
+			g.Assert(l.wait(ctx)).IsNil()
+			g.Assert(time.Since(start) >= 50*time.Millisecond).IsTrue()
+			// the limit is unknown again after the reset
+			g.Assert(l.wait(ctx)).IsNil()
+			g.Assert(l.remaining).Equal(-1)
+		})
+		g.It("Should stop waiting if the context is canceled", func() {
+			l := newRateLimiter()
+			l.update(rateLimited(0, time.Now().Add(30*time.Second)), time.Now())
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			g.Assert(l.wait(ctx)).Equal(context.DeadlineExceeded)
+		})
+		g.It("Should fail if the reset is too far away", func() {
+			l := newRateLimiter()
+			l.update(rateLimited(0, time.Now().Add(time.Hour)), time.Now())
+			g.Assert(l.wait(ctx) != nil).IsTrue()
+		})
+		g.It("Should exhaust the limit for rate limited responses", func() {
+			l := newRateLimiter()
+			now := time.Now()
+			l.update(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"30"}}}, now)
+			g.Assert(l.remaining).Equal(0)
+			g.Assert(l.reset).Equal(now.Add(30 * time.Second))
+		})
+		g.It("Should throttle the calls following a response with few remaining calls", func() {
+			var calls int
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("X-RateLimit-Remaining", "1")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10))
+			}))
+			defer s.Close()
+
+			c, _ := New(Opts{URL: s.URL})
+			client := c.(*Gitea).newHTTPClient()
+			resp, err := client.Get(s.URL)
+			g.Assert(err).IsNil()
+			resp.Body.Close()
+
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+			_, err = client.Do(req)
+			g.Assert(err).IsNotNil()
+			g.Assert(calls).Equal(1)
+
+			// other instances have their own limit
+			other, _ := New(Opts{URL: s.URL})
+			resp, err = other.(*Gitea).newHTTPClient().Get(s.URL)
+			g.Assert(err).IsNil()
+			resp.Body.Close()
+			g.Assert(calls).Equal(2)
+		})
+	})
+}
+
+func Test_newTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("Gitea transport", func() {
+		req, _ := http.NewRequest("GET", "https://gitea.example.com/api/v1/version", nil)
+
+		g.It("Should use the configured proxy", func() {
+			c, err := New(Opts{URL: "https://gitea.example.com", Proxy: "socks5://proxy.example.com:1080"})
+			g.Assert(err).IsNil()
+			proxy, err := c.(*Gitea).transport.Proxy(req)
+			g.Assert(err).IsNil()
+			g.Assert(proxy.String()).Equal("socks5://proxy.example.com:1080")
+		})
+		g.It("Should use the proxy environment variables by default", func() {
+			c, err := New(Opts{URL: "https://gitea.example.com"})
+			g.Assert(err).IsNil()
+			proxy := reflect.ValueOf(c.(*Gitea).transport.Proxy).Pointer()
+			g.Assert(proxy).Equal(reflect.ValueOf(http.ProxyFromEnvironment).Pointer())
+		})
+		g.It("Should keep the TLS settings with a proxy", func() {
+			c, err := New(Opts{URL: "https://gitea.example.com", Proxy: "http://proxy.example.com:3128", SkipVerify: true})
+			g.Assert(err).IsNil()
+			transport := c.(*Gitea).transport
+			g.Assert(transport.TLSClientConfig.InsecureSkipVerify).IsTrue()
+			proxy, _ := transport.Proxy(req)
+			g.Assert(proxy.Host).Equal("proxy.example.com:3128")
+		})
+		g.It("Should fail for unsupported proxy schemes", func() {
+			_, err := New(Opts{URL: "https://gitea.example.com", Proxy: "ftp://proxy.example.com"})
+			g.Assert(err).IsNotNil()
+		})
+		g.It("Should send API calls via the proxy", func() {
+			var hosts []string
+			mock := fixtures.Handler()
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hosts = append(hosts, r.URL.Host)
+				mock.ServeHTTP(w, r)
+			}))
+			defer proxy.Close()
+
+			c, err := New(Opts{URL: "http://gitea.invalid", Proxy: proxy.URL})
+			g.Assert(err).IsNil()
+			_, err = c.(remote.Pinger).Ping(context.Background(), nil)
+			g.Assert(err).IsNil()
+			g.Assert(len(hosts) > 0).IsTrue()
+			g.Assert(hosts[0]).Equal("gitea.invalid")
+		})
+	})
+}
+
+func Test_newTLSConfig(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("Gitea TLS config", func() {
+		s := httptest.NewTLSServer(fixtures.Handler())
+		g.After(func() {
+			s.Close()
+		})
+		caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		_ = ioutil.WriteFile(caFile, []byte(caCert), 0o600)
+
+		ping := func(c remote.Remote) error {
+			_, err := c.(remote.Pinger).Ping(context.Background(), nil)
+			return err
+		}
+
+		g.It("Should trust an inline CA bundle", func() {
+			c, err := New(Opts{URL: s.URL, CACert: caCert})
+			g.Assert(err).IsNil()
+			roots := c.(*Gitea).transport.TLSClientConfig.RootCAs
+			_, err = s.Certificate().Verify(x509.VerifyOptions{Roots: roots})
+			g.Assert(err).IsNil()
+			g.Assert(ping(c)).IsNil()
+		})
+		g.It("Should trust a CA bundle read from a file", func() {
+			c, err := New(Opts{URL: s.URL, CACert: caFile})
+			g.Assert(err).IsNil()
+			g.Assert(ping(c)).IsNil()
+		})
+		g.It("Should not trust the certificate without the CA", func() {
+			c, err := New(Opts{URL: s.URL})
+			g.Assert(err).IsNil()
+			g.Assert(ping(c) != nil).IsTrue()
+		})
+		g.It("Should skip the verification if configured", func() {
+			c, err := New(Opts{URL: s.URL, SkipVerify: true})
+			g.Assert(err).IsNil()
+			g.Assert(ping(c)).IsNil()
+		})
+		g.It("Should fail for invalid CA bundles", func() {
+			_, err := New(Opts{URL: s.URL, CACert: "-----BEGIN CERTIFICATE-----\ninvalid\n-----END CERTIFICATE-----"})
+			g.Assert(err).IsNotNil()
+			_, err = New(Opts{URL: s.URL, CACert: filepath.Join(t.TempDir(), "missing.pem")})
+			g.Assert(err).IsNotNil()
+		})
+	})
+}
@@ -14,6 +14,8 @@
 
 package gitea
 
+import "code.gitea.io/sdk/gitea"
+
 type pushHook struct {
 	Sha     string `json:"sha"`
 	Ref     string `json:"ref"`
@@ -21,6 +23,10 @@ type pushHook struct {
 	After   string `json:"after"`
 	Compare string `json:"compare_url"`
 	RefType string `json:"ref_type"`
+	Forced  bool   `json:"forced"`
+
+	// number of pushed commits, the commits list may be shorter
+	TotalCommits int `json:"total_commits"`
 
 	Pusher struct {
 		Name     string `json:"name"`
@@ -35,6 +41,7 @@ type pushHook struct {
 		FullName string `json:"full_name"`
 		URL      string `json:"html_url"`
 		Private  bool   `json:"private"`
+		Branch   string `json:"default_branch"`
 		Owner    struct {
 			Name     string `json:"name"`
 			Email    string `json:"email"`
@@ -42,10 +49,28 @@ type pushHook struct {
 		} `json:"owner"`
 	} `json:"repository"`
 
+	HeadCommit struct {
+		ID           string              `json:"id"`
+		Timestamp    string              `json:"timestamp"`
+		Verification *commitVerification `json:"verification"`
+	} `json:"head_commit"`
+
 	Commits []struct {
-		ID       string   `json:"id"`
-		Message  string   `json:"message"`
-		URL      string   `json:"url"`
+		ID           string              `json:"id"`
+		Message      string              `json:"message"`
+		URL          string              `json:"url"`
+		Timestamp    string              `json:"timestamp"`
+		Verification *commitVerification `json:"verification"`
+		Author       struct {
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Username string `json:"username"`
+		} `json:"author"`
+		Committer struct {
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Username string `json:"username"`
+		} `json:"committer"`
 		Added    []string `json:"added"`
 		Removed  []string `json:"removed"`
 		Modified []string `json:"modified"`
@@ -60,6 +85,18 @@ type pushHook struct {
 	} `json:"sender"`
 }
 
+// commitVerification is the result of Gitea verifying the signature of a
+// commit. Gitea omits it for unsigned commits in older versions.
+type commitVerification struct {
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason"`
+	Signer   *struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	} `json:"signer"`
+}
+
 type pullRequestHook struct {
 	Action      string `json:"action"`
 	Number      int64  `json:"number"`
@@ -75,11 +112,25 @@ type pullRequestHook struct {
 		Title     string `json:"title"`
 		Body      string `json:"body"`
 		State     string `json:"state"`
+		Draft     bool   `json:"draft"`
 		URL       string `json:"html_url"`
 		Mergeable bool   `json:"mergeable"`
 		Merged    bool   `json:"merged"`
 		MergeBase string `json:"merge_base"`
-		Base      struct {
+		MergeSha  string `json:"merge_commit_sha"`
+		Updated   string `json:"updated_at"`
+		Labels    []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Assignees []struct {
+			Login    string `json:"login"`
+			Username string `json:"username"`
+		} `json:"assignees"`
+		Milestone *struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+		} `json:"milestone"`
+		Base struct {
 			Label string `json:"label"`
 			Ref   string `json:"ref"`
 			Sha   string `json:"sha"`
@@ -88,6 +139,7 @@ type pullRequestHook struct {
 				Name     string `json:"name"`
 				FullName string `json:"full_name"`
 				URL      string `json:"html_url"`
+				CloneURL string `json:"clone_url"`
 				Private  bool   `json:"private"`
 				Owner    struct {
 					ID       int64  `json:"id"`
@@ -107,6 +159,7 @@ type pullRequestHook struct {
 				Name     string `json:"name"`
 				FullName string `json:"full_name"`
 				URL      string `json:"html_url"`
+				CloneURL string `json:"clone_url"`
 				Private  bool   `json:"private"`
 				Owner    struct {
 					ID       int64  `json:"id"`
@@ -118,12 +171,68 @@ type pullRequestHook struct {
 			} `json:"repo"`
 		} `json:"head"`
 	} `json:"pull_request"`
+	Changes struct {
+		Title struct {
+			From string `json:"from"`
+		} `json:"title"`
+	} `json:"changes"`
+	Review struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	} `json:"review"`
+	Repo struct {
+		ID          int64            `json:"id"`
+		Name        string           `json:"name"`
+		FullName    string           `json:"full_name"`
+		URL         string           `json:"html_url"`
+		Private     bool             `json:"private"`
+		Branch      string           `json:"default_branch"`
+		Permissions gitea.Permission `json:"permissions"`
+		Owner       struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+			Name     string `json:"full_name"`
+			Email    string `json:"email"`
+			Avatar   string `json:"avatar_url"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Sender struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+		Name     string `json:"full_name"`
+		Email    string `json:"email"`
+		Avatar   string `json:"avatar_url"`
+	} `json:"sender"`
+}
+
+type releaseHook struct {
+	Action  string `json:"action"`
+	Release struct {
+		ID         int64  `json:"id"`
+		TagName    string `json:"tag_name"`
+		Target     string `json:"target_commitish"`
+		Title      string `json:"name"`
+		Note       string `json:"body"`
+		URL        string `json:"html_url"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+		Author     struct {
+			ID       int64  `json:"id"`
+			Login    string `json:"login"`
+			Username string `json:"username"`
+			Name     string `json:"full_name"`
+			Email    string `json:"email"`
+			Avatar   string `json:"avatar_url"`
+		} `json:"author"`
+	} `json:"release"`
 	Repo struct {
 		ID       int64  `json:"id"`
 		Name     string `json:"name"`
 		FullName string `json:"full_name"`
 		URL      string `json:"html_url"`
 		Private  bool   `json:"private"`
+		Branch   string `json:"default_branch"`
 		Owner    struct {
 			ID       int64  `json:"id"`
 			Username string `json:"username"`
@@ -141,3 +250,135 @@ type pullRequestHook struct {
 		Avatar   string `json:"avatar_url"`
 	} `json:"sender"`
 }
+
+type issueCommentHook struct {
+	Action string `json:"action"`
+	IsPull bool   `json:"is_pull"`
+	Issue  struct {
+		ID          int64  `json:"id"`
+		Number      int64  `json:"number"`
+		Title       string `json:"title"`
+		State       string `json:"state"`
+		PullRequest *struct {
+			Merged bool `json:"merged"`
+		} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+		User struct {
+			ID       int64  `json:"id"`
+			Login    string `json:"login"`
+			Username string `json:"username"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repo struct {
+		ID          int64            `json:"id"`
+		Name        string           `json:"name"`
+		FullName    string           `json:"full_name"`
+		URL         string           `json:"html_url"`
+		Private     bool             `json:"private"`
+		Branch      string           `json:"default_branch"`
+		Permissions gitea.Permission `json:"permissions"`
+		Owner       struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+			Name     string `json:"full_name"`
+			Email    string `json:"email"`
+			Avatar   string `json:"avatar_url"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Sender struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+		Name     string `json:"full_name"`
+		Email    string `json:"email"`
+		Avatar   string `json:"avatar_url"`
+	} `json:"sender"`
+}
+
+type packageHook struct {
+	Action  string `json:"action"`
+	Package struct {
+		ID      int64  `json:"id"`
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		URL     string `json:"html_url"`
+		Creator struct {
+			ID       int64  `json:"id"`
+			Login    string `json:"login"`
+			Username string `json:"username"`
+			Email    string `json:"email"`
+			Avatar   string `json:"avatar_url"`
+		} `json:"creator"`
+	} `json:"package"`
+	// nil if the package is not linked to a repository
+	Repo *struct {
+		ID       int64  `json:"id"`
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		URL      string `json:"html_url"`
+		Private  bool   `json:"private"`
+		Branch   string `json:"default_branch"`
+		Owner    struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Sender struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"sender"`
+}
+
+type repositoryHook struct {
+	Action string `json:"action"`
+	Repo   struct {
+		ID       int64  `json:"id"`
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		URL      string `json:"html_url"`
+		Owner    struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Sender struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"sender"`
+}
+
+type wikiHook struct {
+	Action string `json:"action"`
+	Page   string `json:"page"`
+	Repo   struct {
+		ID       int64  `json:"id"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// compareResponse is the response of the compare API, which is not covered by
+// the Gitea SDK.
+type compareResponse struct {
+	TotalCommits int `json:"total_commits"`
+	Commits      []struct {
+		SHA   string `json:"sha"`
+		Files []struct {
+			Filename string `json:"filename"`
+		} `json:"files"`
+		Parents []struct {
+			SHA string `json:"sha"`
+		} `json:"parents"`
+	} `json:"commits"`
+}
+
+// collaboratorPermission is the response of the collaborator permission API,
+// which is not covered by the Gitea SDK.
+type collaboratorPermission struct {
+	Permission gitea.AccessMode `json:"permission"`
+}
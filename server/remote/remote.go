@@ -18,7 +18,9 @@ package remote
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/woodpecker-ci/woodpecker/server/model"
 )
@@ -96,3 +98,241 @@ func (a ByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 type Refresher interface {
 	Refresh(context.Context, *model.User) (bool, error)
 }
+
+// StepStatuser reports the status of a single step of a pipeline, while
+// Status only reports the status of whole pipelines.
+type StepStatuser interface {
+	StepStatus(ctx context.Context, u *model.User, r *model.Repo, b *model.Build, parent, step *model.Proc) error
+}
+
+// BuildSummarizer posts a summary of the results of a finished build to its
+// pull request. Repeated builds of the pull request update the same summary.
+type BuildSummarizer interface {
+	Summary(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) error
+}
+
+// Deployer registers read-only deploy keys with repositories, e.g. to clone
+// private submodules.
+type Deployer interface {
+	// Deploy registers the deploy key with the repository. An existing key
+	// with the same title is replaced.
+	Deploy(ctx context.Context, u *model.User, r *model.Repo, key *DeployKey) error
+
+	// Undeploy removes the deploy key with the title from the repository.
+	Undeploy(ctx context.Context, u *model.User, r *model.Repo, title string) error
+}
+
+// DeployKey represents a public ssh key registered with a repository.
+type DeployKey struct {
+	Title string `json:"title"`
+	Key   string `json:"key"`
+}
+
+// BranchHeadResolver resolves the latest commit of a branch, e.g. to create
+// builds not triggered by a hook.
+type BranchHeadResolver interface {
+	BranchHead(ctx context.Context, u *model.User, r *model.Repo, branch string) (string, error)
+}
+
+// CommitResolver resolves a ref, like a branch, a tag or a short commit sha,
+// to its commit, e.g. to build a commit given by a user. Unknown refs result
+// in remote.ErrNotFound.
+type CommitResolver interface {
+	CommitInfo(ctx context.Context, u *model.User, r *model.Repo, ref string) (*Commit, error)
+}
+
+// Commit represents a commit resolved from a ref.
+type Commit struct {
+	SHA       string `json:"sha"`
+	Message   string `json:"message"`
+	Author    string `json:"author"`
+	Email     string `json:"email"`
+	Avatar    string `json:"avatar"`
+	Link      string `json:"link"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Comparer lists the files changed between two commits, e.g. to get the files
+// changed since the last successful deployment. The list is truncated to the
+// maximum number of changed files of the remote, which is reported.
+type Comparer interface {
+	CompareFiles(ctx context.Context, u *model.User, r *model.Repo, base, head string) ([]string, bool, error)
+}
+
+// Differ streams the unified diff of the changes a build was triggered by,
+// e.g. to display it with the build.
+type Differ interface {
+	Diff(ctx context.Context, u *model.User, r *model.Repo, b *model.Build) (io.ReadCloser, error)
+}
+
+// RepoFileFetcher fetches a file at a pinned ref of another repository the
+// user can read, e.g. to include shared pipeline templates. The ref has to be
+// a commit sha or a tag.
+type RepoFileFetcher interface {
+	RepoFile(ctx context.Context, u *model.User, owner, name, ref, file string) ([]byte, error)
+}
+
+// StatusCheckLister lists the status contexts the branch protections of a
+// repository require, e.g. to validate the status context of the repository.
+type StatusCheckLister interface {
+	RequiredStatusChecks(ctx context.Context, u *model.User, r *model.Repo) ([]string, error)
+}
+
+// PullRequestLister lists the open pull requests of a repository, e.g. to run
+// a pipeline for one of them manually. All pages are returned for page 0.
+type PullRequestLister interface {
+	PullRequests(ctx context.Context, u *model.User, r *model.Repo, page int) ([]*PullRequest, error)
+}
+
+// PullRequest represents an open pull request.
+type PullRequest struct {
+	Number  int64  `json:"number"`
+	Title   string `json:"title"`
+	BaseRef string `json:"base_ref"`
+	HeadRef string `json:"head_ref"`
+	Author  string `json:"author"`
+}
+
+// OrgMemberLister lists the members of an organization with their role, e.g.
+// to sync team based permissions.
+type OrgMemberLister interface {
+	OrgMembers(ctx context.Context, u *model.User, org string) (*OrgMembers, error)
+}
+
+// Roles of organization members.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// OrgMembers represents the members of an organization visible to the user.
+// If the user can't see the private members, only the public members are
+// listed, without role, and Restricted is set.
+type OrgMembers struct {
+	Members    []*OrgMember `json:"members"`
+	Restricted bool         `json:"restricted"`
+}
+
+// OrgMember represents a member of an organization.
+type OrgMember struct {
+	Login string `json:"login"`
+	Role  string `json:"role,omitempty"`
+}
+
+// CollaboratorLister lists the collaborators of a repository with their
+// permission, e.g. for fine-grained authorization.
+type CollaboratorLister interface {
+	Collaborators(ctx context.Context, u *model.User, r *model.Repo) ([]*Collaborator, error)
+}
+
+// Collaborator represents a collaborator of a repository.
+type Collaborator struct {
+	Login string      `json:"login"`
+	Perm  *model.Perm `json:"permissions"`
+}
+
+// HookParser parses hooks like Hook, but without verifying their signature
+// or querying the remote, e.g. to debug why a hook did not create a build.
+type HookParser interface {
+	ParseHook(ctx context.Context, r *http.Request) (*ParsedHook, error)
+}
+
+// DeliveryIdentifier returns the id the remote sent a hook with, which stays
+// the same if the remote sends the hook again, e.g. after a timeout. An empty
+// id is returned if the hook has none.
+type DeliveryIdentifier interface {
+	DeliveryID(r *http.Request) string
+}
+
+// HookRepoNamer returns the name of the remote instance and the full name of
+// the repository a hook was sent for without parsing the hook, e.g. to process
+// the hooks of a repository in the order they arrived. The instance name is the
+// Remote of the repository. An empty full name is returned if the hook names
+// none.
+type HookRepoNamer interface {
+	HookRepo(r *http.Request) (remote, fullName string)
+}
+
+// ParsedHook represents the repo and build parsed from a hook. The build is
+// nil if the hook would be ignored.
+type ParsedHook struct {
+	Repo  *model.Repo  `json:"repo"`
+	Build *model.Build `json:"build"`
+}
+
+// CapabilitiesProvider reports which optional features the remote supports,
+// e.g. depending on its version.
+type CapabilitiesProvider interface {
+	Capabilities(context.Context) (*Capabilities, error)
+}
+
+// Capabilities represents the optional features supported by a remote.
+type Capabilities struct {
+	SupportsReviews       bool `json:"supports_reviews"`
+	SupportsReleases      bool `json:"supports_releases"`
+	SupportsStatusPerStep bool `json:"supports_status_per_step"`
+	SupportsDraftPulls    bool `json:"supports_draft_pulls"`
+}
+
+// Pinger checks that the remote is reachable and, if a user is given, that
+// the token of the user is valid. It returns remote.ErrUnreachable or
+// remote.ErrInvalidToken wrapped in the error if either check failed.
+type Pinger interface {
+	Ping(context.Context, *model.User) (*PingResult, error)
+}
+
+// PingResult represents the result of a remote health check.
+type PingResult struct {
+	Latency time.Duration `json:"latency"`
+	Login   string        `json:"login,omitempty"`
+}
+
+// AvatarFetcher fetches the current avatar of the user, so avatars changed in
+// the remote after the user logged in are picked up. It returns an empty
+// avatar if the user has none in the remote.
+type AvatarFetcher interface {
+	Avatar(ctx context.Context, u *model.User) (string, error)
+}
+
+// InstanceAuther is implemented by remotes integrating several instances. It
+// returns the login of the token's user and the name of the instance the token
+// belongs to, which is empty for the default instance.
+type InstanceAuther interface {
+	AuthInstance(ctx context.Context, token, secret string) (login, instance string, err error)
+}
+
+// HookSecretRotator replaces the link and secret of the hook registered for
+// the repository, e.g. to rotate the secret hooks are signed with.
+type HookSecretRotator interface {
+	RotateHookSecret(ctx context.Context, u *model.User, r *model.Repo, link string) error
+}
+
+// KeyLister lists the public keys a user registered with the remote, e.g. to
+// cross-check commit signatures against the keys of the committer.
+type KeyLister interface {
+	UserKeys(ctx context.Context, u *model.User, login string) (*UserKeys, error)
+}
+
+// UserKeys represents the public GPG and SSH keys of a user.
+type UserKeys struct {
+	GPG []*GPGKey `json:"gpg"`
+	SSH []*SSHKey `json:"ssh"`
+}
+
+// GPGKey represents a public GPG key with the ids of its subkeys and the
+// verified email addresses it was issued for.
+type GPGKey struct {
+	KeyID     string   `json:"key_id"`
+	SubKeyIDs []string `json:"sub_key_ids,omitempty"`
+	PublicKey string   `json:"public_key"`
+	Emails    []string `json:"emails,omitempty"`
+	CanSign   bool     `json:"can_sign"`
+}
+
+// SSHKey represents a public SSH key.
+type SSHKey struct {
+	Title       string `json:"title,omitempty"`
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
@@ -58,12 +58,16 @@ func apiRoutes(e *gin.Engine) {
 			repo.GET("", api.GetRepo)
 
 			repo.GET("/branches", api.GetRepoBranches)
+			repo.GET("/pull_requests", api.GetRepoPullRequests)
 
 			repo.GET("/builds", api.GetBuilds)
 			repo.GET("/builds/:number", api.GetBuild)
 			repo.GET("/builds/:number/config", api.GetBuildConfig)
+			repo.GET("/builds/:number/diff", api.GetBuildDiff)
 
 			// requires push permissions
+			repo.POST("/builds", session.MustPush, api.PostManualBuild)
+			repo.POST("/cron/:cron", session.MustPush, api.PostCronBuild)
 			repo.POST("/builds/:number", session.MustPush, api.PostBuild)
 			repo.DELETE("/builds/:number", session.MustPush, api.DeleteBuild)
 			repo.POST("/builds/:number/approve", session.MustPush, api.PostApproval)
@@ -98,6 +102,9 @@ func apiRoutes(e *gin.Engine) {
 			repo.DELETE("", session.MustRepoAdmin(), api.DeleteRepo)
 			repo.POST("/chown", session.MustRepoAdmin(), api.ChownRepo)
 			repo.POST("/repair", session.MustRepoAdmin(), api.RepairRepo)
+			repo.POST("/pause", session.MustRepoAdmin(), api.PauseRepo)
+			repo.POST("/resume", session.MustRepoAdmin(), api.ResumeRepo)
+			repo.POST("/rotate_secret", session.MustRepoAdmin(), api.RotateRepoSecret)
 			repo.POST("/move", session.MustRepoAdmin(), api.MoveRepo)
 		}
 	}
@@ -136,6 +143,7 @@ func apiRoutes(e *gin.Engine) {
 		debugger.GET("/pprof/symbol", debug.SymbolHandler())
 		debugger.POST("/pprof/symbol", debug.SymbolHandler())
 		debugger.GET("/pprof/trace", debug.TraceHandler())
+		debugger.POST("/hook", api.ParseHook)
 	}
 
 	logLevel := e.Group("/api/log-level")
@@ -47,7 +47,18 @@ func SetRepo() gin.HandlerFunc {
 			user   = User(c)
 		)
 
-		repo, err := _store.GetRepoName(owner + "/" + name)
+		// repos of the remote instance of the user take precedence over
+		// equally named repos of other instances
+		var (
+			repo *model.Repo
+			err  error
+		)
+		if user != nil {
+			repo, err = _store.GetRepoRemoteName(user.Remote, owner+"/"+name)
+		}
+		if user == nil || err != nil {
+			repo, err = _store.GetRepoName(owner + "/" + name)
+		}
 		if err == nil {
 			c.Set("repo", repo)
 			c.Next()
@@ -101,6 +112,7 @@ func SetPerm() gin.HandlerFunc {
 				if err == nil {
 					log.Debug().Msgf("Synced user permission for %s %s", user.Login, repo.FullName)
 					perm.Repo = repo.FullName
+					perm.RepoID = repo.ID
 					perm.UserID = user.ID
 					perm.Synced = time.Now().Unix()
 					if err := _store.PermUpsert(perm); err != nil {
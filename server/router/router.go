@@ -62,6 +62,7 @@ func Load(noRouteHandler http.HandlerFunc, middleware ...gin.HandlerFunc) http.H
 	e.GET("/metrics", metrics.PromHandler())
 	e.GET("/version", api.Version)
 	e.GET("/healthz", api.Health)
+	e.GET("/healthz/remote", session.MustUser(), api.RemoteHealth)
 
 	apiRoutes(e)
 
@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/plugins/configuration"
 
 	"github.com/woodpecker-ci/woodpecker/server/model"
@@ -24,6 +28,8 @@ type configFetcher struct {
 	repo          *model.Repo
 	build         *model.Build
 	configService configuration.ConfigService
+	includeRepos  []string
+	eventConfigs  bool
 }
 
 func NewConfigFetcher(remote remote.Remote, configurationService configuration.ConfigService, user *model.User, repo *model.Repo, build *model.Build) ConfigFetcher {
@@ -33,6 +39,8 @@ func NewConfigFetcher(remote remote.Remote, configurationService configuration.C
 		repo:          repo,
 		build:         build,
 		configService: configurationService,
+		includeRepos:  server.Config.Pipeline.IncludeRepos,
+		eventConfigs:  server.Config.Pipeline.EventConfigs,
 	}
 }
 
@@ -52,6 +60,11 @@ func (cf *configFetcher) Fetch(ctx context.Context) (files []*remote.FileMeta, e
 		if errors.Is(err, context.DeadlineExceeded) {
 			continue
 		}
+		if err == nil {
+			if files, err = cf.resolveIncludes(ctx, files); err != nil {
+				return nil, err
+			}
+		}
 
 		if cf.configService.IsConfigured() {
 			fetchCtx, cancel := context.WithTimeout(ctx, configFetchTimeout)
@@ -105,7 +118,20 @@ func (cf *configFetcher) fetch(c context.Context, timeout time.Duration, config
 	}
 
 	log.Trace().Msgf("ConfigFetch[%s]: user did not defined own config follow default procedure", cf.repo.FullName)
-	// no user defined config so try .woodpecker/*.yml -> .woodpecker.yml -> .drone.yml
+	// no user defined config so try .woodpecker/<event>.yml (if enabled) -> .woodpecker/*.yml -> .woodpecker.yml -> .drone.yml
+
+	// test the config file named after the event of the build
+	if name := eventConfigName(cf.build.Event); cf.eventConfigs && name != "" {
+		config = ".woodpecker/" + name + ".yml"
+		file, err := cf.remote.File(ctx, cf.user, cf.repo, cf.build, config)
+		if err == nil && len(file) != 0 {
+			log.Trace().Msgf("ConfigFetch[%s]: found event config '%s'", cf.repo.FullName, config)
+			return []*remote.FileMeta{{
+				Name: config,
+				Data: file,
+			}}, nil
+		}
+	}
 
 	// test .woodpecker/ folder
 	// if folder is not supported we will get a "Not implemented" error and continue
@@ -145,6 +171,124 @@ func (cf *configFetcher) fetch(c context.Context, timeout time.Duration, config
 	}
 }
 
+// maxIncludeDepth limits how many includes are followed for a config, so
+// configs including each other fail instead of looping forever.
+const maxIncludeDepth = 5
+
+// configInclude references a config of another repository at a pinned ref,
+// a commit sha or a tag. A config file consisting only of an include is
+// replaced by the referenced config, e.g.
+//
+//	include:
+//	  repo: org/templates
+//	  ref: v1.2.0
+//	  path: pipelines/go.yml
+type configInclude struct {
+	Repo string `yaml:"repo"`
+	Ref  string `yaml:"ref"`
+	Path string `yaml:"path"`
+}
+
+// resolveIncludes replaces the config files which include a config of another
+// repository with the included config.
+func (cf *configFetcher) resolveIncludes(c context.Context, files []*remote.FileMeta) ([]*remote.FileMeta, error) {
+	ctx, cancel := context.WithTimeout(c, configFetchTimeout)
+	defer cancel()
+
+	resolved := make([]*remote.FileMeta, 0, len(files))
+	for _, file := range files {
+		data, err := cf.resolveInclude(ctx, file.Name, file.Data, 0)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, &remote.FileMeta{Name: file.Name, Data: data})
+	}
+	return resolved, nil
+}
+
+func (cf *configFetcher) resolveInclude(ctx context.Context, name string, data []byte, depth int) ([]byte, error) {
+	include, err := parseInclude(data)
+	if err != nil {
+		return nil, fmt.Errorf("config '%s': %w", name, err)
+	}
+	if include == nil {
+		return data, nil
+	}
+	if depth >= maxIncludeDepth {
+		return nil, fmt.Errorf("config '%s': includes are nested deeper than %d", name, maxIncludeDepth)
+	}
+
+	parts := strings.SplitN(include.Repo, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || include.Ref == "" || include.Path == "" {
+		return nil, fmt.Errorf("config '%s': include needs a repo as owner/name, a ref and a path", name)
+	}
+	// the config of a fork is written by the contributor, who must not read
+	// files with the token of the repo owner
+	if cf.build.IsFork {
+		return nil, fmt.Errorf("config '%s': includes are not allowed for pull requests from forks", name)
+	}
+	if !cf.includeAllowed(parts[0], include.Repo) {
+		return nil, fmt.Errorf("config '%s': including configs of %s is not allowed", name, include.Repo)
+	}
+	fetcher, ok := cf.remote.(remote.RepoFileFetcher)
+	if !ok {
+		return nil, fmt.Errorf("config '%s': the remote does not support includes", name)
+	}
+
+	log.Trace().Msgf("ConfigFetch[%s]: include '%s' of %s@%s", cf.repo.FullName, include.Path, include.Repo, include.Ref)
+	data, err = fetcher.RepoFile(ctx, cf.user, parts[0], parts[1], include.Ref, include.Path)
+	if err != nil {
+		return nil, fmt.Errorf("config '%s': could not include '%s' of %s@%s: %w", name, include.Path, include.Repo, include.Ref, err)
+	}
+	return cf.resolveInclude(ctx, name, data, depth+1)
+}
+
+// includeAllowed reports whether configs of the repo may be included, which
+// is the case for repos of the same owner and repos matching one of the
+// configured patterns.
+func (cf *configFetcher) includeAllowed(owner, fullName string) bool {
+	if strings.EqualFold(owner, cf.repo.Owner) {
+		return true
+	}
+	for _, pattern := range cf.includeRepos {
+		if ok, _ := path.Match(pattern, fullName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseInclude returns the include of the config, or nil if the config does
+// not include another config. Configs which are no valid YAML are left to the
+// pipeline parser to report.
+func parseInclude(data []byte) (*configInclude, error) {
+	var config map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, nil
+	}
+	node, ok := config["include"]
+	if !ok {
+		return nil, nil
+	}
+	if len(config) > 1 {
+		return nil, errors.New("include has to be the only key of the config")
+	}
+	include := new(configInclude)
+	if err := node.Decode(include); err != nil {
+		return nil, fmt.Errorf("invalid include: %w", err)
+	}
+	return include, nil
+}
+
+// eventConfigName returns the name of the config file used for builds of
+// the event, e.g. "push" for .woodpecker/push.yml.
+func eventConfigName(event model.WebhookEvent) string {
+	if event == model.EventPull {
+		return "pr"
+	}
+	return string(event)
+}
+
 func filterPipelineFiles(files []*remote.FileMeta) []*remote.FileMeta {
 	var res []*remote.FileMeta
 
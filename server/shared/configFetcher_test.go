@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/woodpecker-ci/woodpecker/server"
 	"github.com/woodpecker-ci/woodpecker/server/model"
 	"github.com/woodpecker-ci/woodpecker/server/plugins/configuration"
 	"github.com/woodpecker-ci/woodpecker/server/remote"
@@ -35,6 +36,7 @@ func TestFetch(t *testing.T) {
 	testTable := []struct {
 		name              string
 		repoConfig        string
+		event             model.WebhookEvent
 		files             []file
 		expectedFileNames []string
 		expectedError     bool
@@ -215,6 +217,22 @@ func TestFetch(t *testing.T) {
 			expectedFileNames: []string{},
 			expectedError:     true,
 		},
+		{
+			name:  "Default config - event configs are disabled",
+			event: model.EventPush,
+			files: []file{{
+				name: ".woodpecker/push.yml",
+				data: dummyData,
+			}, {
+				name: ".woodpecker/pr.yml",
+				data: dummyData,
+			}},
+			expectedFileNames: []string{
+				".woodpecker/push.yml",
+				".woodpecker/pr.yml",
+			},
+			expectedError: false,
+		},
 	}
 
 	for _, tt := range testTable {
@@ -247,7 +265,7 @@ func TestFetch(t *testing.T) {
 				configuration.NewAPI("", ""),
 				&model.User{Token: "xxx"},
 				repo,
-				&model.Build{Commit: "89ab7b2d6bfb347144ac7c557e638ab402848fee"},
+				&model.Build{Commit: "89ab7b2d6bfb347144ac7c557e638ab402848fee", Event: tt.event},
 			)
 			files, err := configFetcher.Fetch(context.Background())
 			if tt.expectedError && err == nil {
@@ -458,3 +476,210 @@ func TestFetchFromConfigService(t *testing.T) {
 		})
 	}
 }
+
+// repoFileRemote is a remote serving the files of other repositories by
+// "owner/name@ref:path".
+type repoFileRemote struct {
+	*mocks.Remote
+	files map[string]string
+}
+
+func (r *repoFileRemote) RepoFile(_ context.Context, _ *model.User, owner, name, ref, file string) ([]byte, error) {
+	data, ok := r.files[owner+"/"+name+"@"+ref+":"+file]
+	if !ok {
+		return nil, fmt.Errorf("%w: repository %s/%s does not exist or is private", remote.ErrNotFound, owner, name)
+	}
+	return []byte(data), nil
+}
+
+func TestFetchIncludes(t *testing.T) {
+	t.Parallel()
+
+	include := func(repo, ref, path string) string {
+		return fmt.Sprintf("include:\n  repo: %s\n  ref: %s\n  path: %s\n", repo, ref, path)
+	}
+
+	testTable := []struct {
+		name          string
+		config        string
+		repoFiles     map[string]string
+		fork          bool
+		expectedData  string
+		expectedError string
+	}{
+		{
+			name:         "Include of another repository",
+			config:       include("org/templates", "v1.2.0", "pipelines/go.yml"),
+			repoFiles:    map[string]string{"org/templates@v1.2.0:pipelines/go.yml": "pipeline: go"},
+			expectedData: "pipeline: go",
+		},
+		{
+			name:   "Nested include",
+			config: include("org/templates", "v1.2.0", "pipelines/go.yml"),
+			repoFiles: map[string]string{
+				"org/templates@v1.2.0:pipelines/go.yml": include("org/base", "v1.0.0", "base.yml"),
+				"org/base@v1.0.0:base.yml":              "pipeline: base",
+			},
+			expectedData: "pipeline: base",
+		},
+		{
+			name:          "Missing include target",
+			config:        include("org/private", "v1.2.0", "pipelines/go.yml"),
+			expectedError: "could not include 'pipelines/go.yml' of org/private@v1.2.0: not found",
+		},
+		{
+			name:   "Recursive include",
+			config: include("org/templates", "v1.2.0", "loop.yml"),
+			repoFiles: map[string]string{
+				"org/templates@v1.2.0:loop.yml": include("org/templates", "v1.2.0", "loop.yml"),
+			},
+			expectedError: "config '.woodpecker.yml': includes are nested deeper than 5",
+		},
+		{
+			name:          "Include without ref",
+			config:        include("org/templates", "", "pipelines/go.yml"),
+			expectedError: "config '.woodpecker.yml': include needs a repo as owner/name, a ref and a path",
+		},
+		{
+			name:          "Include of another owner",
+			config:        include("other/templates", "v1.2.0", "pipelines/go.yml"),
+			repoFiles:     map[string]string{"other/templates@v1.2.0:pipelines/go.yml": "pipeline: go"},
+			expectedError: "config '.woodpecker.yml': including configs of other/templates is not allowed",
+		},
+		{
+			name:          "Include of a pull request from a fork",
+			config:        include("org/templates", "v1.2.0", "pipelines/go.yml"),
+			repoFiles:     map[string]string{"org/templates@v1.2.0:pipelines/go.yml": "pipeline: go"},
+			fork:          true,
+			expectedError: "config '.woodpecker.yml': includes are not allowed for pull requests from forks",
+		},
+		{
+			name:         "Config without include",
+			config:       "pipeline: local",
+			expectedData: "pipeline: local",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mocks.Remote)
+			m.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, ".woodpecker.yml").Return([]byte(tt.config), nil)
+			m.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("File not found"))
+			m.On("Dir", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("Directory not found"))
+
+			configFetcher := shared.NewConfigFetcher(
+				&repoFileRemote{Remote: m, files: tt.repoFiles},
+				configuration.NewAPI("", ""),
+				&model.User{Token: "xxx"},
+				&model.Repo{Owner: "org", Name: "app"},
+				&model.Build{Commit: "89ab7b2d6bfb347144ac7c557e638ab402848fee", IsFork: tt.fork},
+			)
+			files, err := configFetcher.Fetch(context.Background())
+			if tt.expectedError != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.expectedError)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			if assert.Len(t, files, 1) {
+				assert.Equal(t, ".woodpecker.yml", files[0].Name)
+				assert.Equal(t, tt.expectedData, string(files[0].Data))
+			}
+		})
+	}
+}
+
+func TestFetchIncludesOfAllowedRepos(t *testing.T) {
+	// not parallel, the allowed repos are read from the server config
+	server.Config.Pipeline.IncludeRepos = []string{"shared/*"}
+	defer func() { server.Config.Pipeline.IncludeRepos = nil }()
+
+	m := new(mocks.Remote)
+	m.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, ".woodpecker.yml").Return([]byte("include:\n  repo: shared/templates\n  ref: v1.2.0\n  path: go.yml\n"), nil)
+	m.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("File not found"))
+	m.On("Dir", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("Directory not found"))
+
+	configFetcher := shared.NewConfigFetcher(
+		&repoFileRemote{Remote: m, files: map[string]string{"shared/templates@v1.2.0:go.yml": "pipeline: go"}},
+		configuration.NewAPI("", ""),
+		&model.User{Token: "xxx"},
+		&model.Repo{Owner: "org", Name: "app"},
+		&model.Build{Commit: "89ab7b2d6bfb347144ac7c557e638ab402848fee"},
+	)
+	files, err := configFetcher.Fetch(context.Background())
+	if assert.NoError(t, err) && assert.Len(t, files, 1) {
+		assert.Equal(t, "pipeline: go", string(files[0].Data))
+	}
+}
+
+func TestFetchEventConfigs(t *testing.T) {
+	// not parallel, event configs are enabled in the server config
+	server.Config.Pipeline.EventConfigs = true
+	defer func() { server.Config.Pipeline.EventConfigs = false }()
+
+	testTable := []struct {
+		name              string
+		event             model.WebhookEvent
+		files             []string
+		expectedFileNames []string
+		expectedError     bool
+	}{
+		{
+			name:              "event config for push",
+			event:             model.EventPush,
+			files:             []string{".woodpecker/push.yml", ".woodpecker/pr.yml", ".woodpecker.yml"},
+			expectedFileNames: []string{".woodpecker/push.yml"},
+		},
+		{
+			name:              "event config for pull request",
+			event:             model.EventPull,
+			files:             []string{".woodpecker/push.yml", ".woodpecker/pr.yml"},
+			expectedFileNames: []string{".woodpecker/pr.yml"},
+		},
+		{
+			name:              "fallback without event config",
+			event:             model.EventTag,
+			files:             []string{".woodpecker.yml"},
+			expectedFileNames: []string{".woodpecker.yml"},
+		},
+		{
+			name:              "missing event config and fallback",
+			event:             model.EventPush,
+			files:             []string{},
+			expectedFileNames: []string{},
+			expectedError:     true,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(mocks.Remote)
+			for _, name := range tt.files {
+				r.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, name).Return([]byte("TEST"), nil)
+			}
+			r.On("File", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("File not found"))
+			r.On("Dir", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("Directory not found"))
+
+			configFetcher := shared.NewConfigFetcher(
+				r,
+				configuration.NewAPI("", ""),
+				&model.User{Token: "xxx"},
+				&model.Repo{Owner: "laszlocph", Name: "multipipeline"},
+				&model.Build{Commit: "89ab7b2d6bfb347144ac7c557e638ab402848fee", Event: tt.event},
+			)
+			files, err := configFetcher.Fetch(context.Background())
+			if tt.expectedError && err == nil {
+				t.Fatal("expected an error")
+			} else if !tt.expectedError && err != nil {
+				t.Fatal("error fetching config:", err)
+			}
+
+			matchingFiles := make([]string, len(files))
+			for i := range files {
+				matchingFiles[i] = files[i].Name
+			}
+			assert.ElementsMatch(t, tt.expectedFileNames, matchingFiles, "expected some other pipeline files")
+		})
+	}
+}
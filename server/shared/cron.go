@@ -0,0 +1,54 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// CreateCronBuild returns the build of the cron job with the name for the head
+// of the branch, which defaults to the default branch of the repo. The remote
+// has to support resolving branch heads.
+func CreateCronBuild(ctx context.Context, r remote.Remote, user *model.User, repo *model.Repo, name, branch string) (*model.Build, error) {
+	resolver, ok := r.(remote.BranchHeadResolver)
+	if !ok {
+		return nil, fmt.Errorf("remote does not support cron jobs")
+	}
+	if branch == "" {
+		branch = repo.Branch
+	}
+
+	commit, err := resolver.BranchHead(ctx, user, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Build{
+		Event:     model.EventCron,
+		Cron:      name,
+		Commit:    commit,
+		Ref:       "refs/heads/" + branch,
+		Branch:    branch,
+		Message:   fmt.Sprintf("cron job %s", name),
+		Link:      repo.Link,
+		Sender:    user.Login,
+		Timestamp: time.Now().UTC().Unix(),
+	}, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+func TestCreateCronBuild(t *testing.T) {
+	s := httptest.NewServer(fixtures.Handler())
+	defer s.Close()
+
+	r, err := gitea.New(gitea.Opts{URL: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Login: "someuser", Token: "cfcd2084"}
+	repo := &model.Repo{Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name"}
+	build, err := CreateCronBuild(context.Background(), r, user, repo, "nightly", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if build.Event != model.EventCron {
+		t.Errorf("expected event %s, got %s", model.EventCron, build.Event)
+	}
+	if build.Cron != "nightly" {
+		t.Errorf("expected cron nightly, got %s", build.Cron)
+	}
+	if build.Commit != "f05f642b892d59a0a9ef6a31f6c905a24b5db13a" {
+		t.Errorf("expected the head commit of master, got %s", build.Commit)
+	}
+	if build.Ref != "refs/heads/master" || build.Branch != "master" {
+		t.Errorf("expected ref refs/heads/master of branch master, got %s of %s", build.Ref, build.Branch)
+	}
+
+	repo.Branch = "master"
+	build, err = CreateCronBuild(context.Background(), r, user, repo, "nightly", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.Branch != "master" {
+		t.Errorf("expected the default branch master, got %s", build.Branch)
+	}
+
+	if _, err := CreateCronBuild(context.Background(), r, user, repo, "nightly", "missing"); err == nil {
+		t.Error("expected an error for a missing branch")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// LastSuccessStore is the part of the store needed to find the last
+// successful build.
+type LastSuccessStore interface {
+	GetBuildLastSuccess(*model.Repo, string, model.WebhookEvent) (*model.Build, error)
+}
+
+// LastSuccessfulCommit returns the commit of the last successful build of the
+// event for the branch, or an empty string if there is none yet.
+func LastSuccessfulCommit(store LastSuccessStore, repo *model.Repo, branch string, event model.WebhookEvent) (string, error) {
+	last, err := store.GetBuildLastSuccess(repo, branch, event)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return last.Commit, nil
+}
+
+// UpdateDeployChangedFiles sets the changed files of the deployment to the
+// files changed since the commit of the last successful deployment of the
+// branch, so path conditions match the changes the deployment ships. The first
+// deployment of a branch keeps its changed files. It reports whether the
+// changed files were updated.
+func UpdateDeployChangedFiles(ctx context.Context, store LastSuccessStore, comparer remote.Comparer, user *model.User, repo *model.Repo, build *model.Build) (bool, error) {
+	if build.Event != model.EventDeploy || build.Commit == "" {
+		return false, nil
+	}
+
+	base, err := LastSuccessfulCommit(store, repo, build.Branch, model.EventDeploy)
+	if err != nil || base == "" {
+		return false, err
+	}
+
+	files := []string{}
+	truncated := false
+	if base != build.Commit {
+		files, truncated, err = comparer.CompareFiles(ctx, user, repo, base, build.Commit)
+		if err != nil {
+			return false, err
+		}
+	}
+	build.ChangedFiles = files
+	build.Truncated = truncated
+	return true, nil
+}
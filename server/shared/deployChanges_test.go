@@ -0,0 +1,110 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+type lastSuccessStore struct {
+	last *model.Build
+}
+
+func (s *lastSuccessStore) GetBuildLastSuccess(*model.Repo, string, model.WebhookEvent) (*model.Build, error) {
+	if s.last == nil {
+		return nil, sql.ErrNoRows
+	}
+	return s.last, nil
+}
+
+type comparer struct {
+	base, head string
+	files      []string
+	err        error
+}
+
+func (c *comparer) CompareFiles(_ context.Context, _ *model.User, _ *model.Repo, base, head string) ([]string, bool, error) {
+	c.base, c.head = base, head
+	return c.files, false, c.err
+}
+
+func TestUpdateDeployChangedFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := &model.Repo{FullName: "gordon/hello-world"}
+
+	t.Run("changes since the last deployment", func(t *testing.T) {
+		s := &lastSuccessStore{last: &model.Build{Commit: "4b26262"}}
+		c := &comparer{files: []string{"README.md"}}
+		build := &model.Build{Event: model.EventDeploy, Branch: "master", Commit: "ef98532", ChangedFiles: []string{"main.go"}}
+		updated, err := UpdateDeployChangedFiles(ctx, s, c, nil, repo, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated || !reflect.DeepEqual(build.ChangedFiles, []string{"README.md"}) {
+			t.Errorf("expected the files changed since the last deployment, got %v", build.ChangedFiles)
+		}
+		if c.base != "4b26262" || c.head != "ef98532" {
+			t.Errorf("expected to compare 4b26262...ef98532, got %s...%s", c.base, c.head)
+		}
+	})
+
+	t.Run("first deployment", func(t *testing.T) {
+		c := &comparer{}
+		build := &model.Build{Event: model.EventDeploy, Branch: "master", Commit: "ef98532", ChangedFiles: []string{"main.go"}}
+		updated, err := UpdateDeployChangedFiles(ctx, &lastSuccessStore{}, c, nil, repo, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated || c.base != "" || !reflect.DeepEqual(build.ChangedFiles, []string{"main.go"}) {
+			t.Errorf("expected the first deployment to keep its changed files, got %v", build.ChangedFiles)
+		}
+	})
+
+	t.Run("redeployment of the same commit", func(t *testing.T) {
+		c := &comparer{}
+		build := &model.Build{Event: model.EventDeploy, Branch: "master", Commit: "ef98532", ChangedFiles: []string{"main.go"}}
+		updated, err := UpdateDeployChangedFiles(ctx, &lastSuccessStore{last: &model.Build{Commit: "ef98532"}}, c, nil, repo, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated || c.base != "" || len(build.ChangedFiles) != 0 {
+			t.Errorf("expected no changed files without comparing, got %v", build.ChangedFiles)
+		}
+	})
+
+	t.Run("failed compare", func(t *testing.T) {
+		c := &comparer{err: errors.New("not found")}
+		build := &model.Build{Event: model.EventDeploy, Branch: "master", Commit: "ef98532", ChangedFiles: []string{"main.go"}}
+		updated, err := UpdateDeployChangedFiles(ctx, &lastSuccessStore{last: &model.Build{Commit: "4b26262"}}, c, nil, repo, build)
+		if err == nil || updated || !reflect.DeepEqual(build.ChangedFiles, []string{"main.go"}) {
+			t.Errorf("expected a failed compare to keep the changed files")
+		}
+	})
+
+	t.Run("other events", func(t *testing.T) {
+		c := &comparer{}
+		build := &model.Build{Event: model.EventPush, Branch: "master", Commit: "ef98532"}
+		updated, err := UpdateDeployChangedFiles(ctx, &lastSuccessStore{last: &model.Build{Commit: "4b26262"}}, c, nil, repo, build)
+		if err != nil || updated || c.base != "" {
+			t.Errorf("expected only deployments to be compared")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"sync"
+)
+
+// HookLimiter limits the number of hooks processed at once. The hooks of a
+// repository are processed one after the other in the order they arrived, so
+// a burst of hooks of one repository occupies at most one slot and can't
+// starve the hooks of other repositories.
+type HookLimiter struct {
+	slots chan struct{}
+
+	mu    sync.Mutex
+	repos map[string][]chan struct{}
+}
+
+// NewHookLimiter returns a limiter processing at most max hooks at once, or
+// any number of hooks for a max of zero or less.
+func NewHookLimiter(max int) *HookLimiter {
+	l := &HookLimiter{repos: make(map[string][]chan struct{})}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Acquire blocks until the hook of the repository may be processed and
+// returns the function to call once it is processed. If the context is done
+// before, its error is returned.
+func (l *HookLimiter) Acquire(ctx context.Context, repo string) (func(), error) {
+	turn := make(chan struct{})
+	l.mu.Lock()
+	l.repos[repo] = append(l.repos[repo], turn)
+	if len(l.repos[repo]) == 1 {
+		close(turn)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-turn:
+	case <-ctx.Done():
+		l.leave(repo, turn)
+		return nil, ctx.Err()
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			l.leave(repo, turn)
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.slots != nil {
+				<-l.slots
+			}
+			l.leave(repo, turn)
+		})
+	}, nil
+}
+
+// leave removes the turn from the queue of the repository and passes the
+// turn on to the next hook if it was the current one.
+func (l *HookLimiter) leave(repo string, turn chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queue := l.repos[repo]
+	for i, t := range queue {
+		if t != turn {
+			continue
+		}
+		queue = append(queue[:i:i], queue[i+1:]...)
+		if i == 0 && len(queue) > 0 {
+			close(queue[0])
+		}
+		break
+	}
+	if len(queue) == 0 {
+		delete(l.repos, repo)
+		return
+	}
+	l.repos[repo] = queue
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until n hooks of the repository are queued.
+func waitQueued(t *testing.T, l *HookLimiter, repo string, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		l.mu.Lock()
+		queued := len(l.repos[repo])
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued hooks of %s", n, repo)
+}
+
+// process acquires the limiter for the hook in the background, records the
+// hook once it is processed and releases the limiter once done is closed.
+func process(l *HookLimiter, repo, hook string, order chan<- string, done <-chan struct{}, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		release, err := l.Acquire(context.Background(), repo)
+		if err != nil {
+			return
+		}
+		order <- hook
+		<-done
+		release()
+	}()
+}
+
+func TestHookLimiterOrder(t *testing.T) {
+	l := NewHookLimiter(10)
+	order := make(chan string, 5)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 1; i <= 5; i++ {
+		process(l, "gordon/hello-world", fmt.Sprint(i), order, done, &wg)
+		waitQueued(t, l, "gordon/hello-world", i)
+	}
+	if got := <-order; got != "1" {
+		t.Fatalf("expected the first hook to be processed first, got %s", got)
+	}
+	select {
+	case got := <-order:
+		t.Fatalf("expected hooks of a repository to be processed serially, got %s", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(done)
+	for i := 2; i <= 5; i++ {
+		if got := <-order; got != fmt.Sprint(i) {
+			t.Fatalf("expected hook %d, got %s", i, got)
+		}
+	}
+	wg.Wait()
+	if len(l.repos) != 0 {
+		t.Fatal("expected no queued repositories")
+	}
+}
+
+func TestHookLimiterFairness(t *testing.T) {
+	t.Run("burst does not block other repositories", func(t *testing.T) {
+		l := NewHookLimiter(2)
+		order := make(chan string, 11)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+
+		for i := 1; i <= 10; i++ {
+			process(l, "noisy/repo", fmt.Sprint("noisy", i), order, done, &wg)
+		}
+		waitQueued(t, l, "noisy/repo", 10)
+		<-order
+
+		// the noisy repository occupies one slot only
+		process(l, "quiet/repo", "quiet", order, done, &wg)
+		select {
+		case got := <-order:
+			if got != "quiet" {
+				t.Fatalf("expected the quiet repository to be processed, got %s", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the quiet repository not to wait for the noisy one")
+		}
+		close(done)
+		wg.Wait()
+	})
+
+	t.Run("repositories take turns for a single slot", func(t *testing.T) {
+		l := NewHookLimiter(1)
+		order := make(chan string, 4)
+		done := make(map[string]chan struct{})
+		var wg sync.WaitGroup
+
+		step := func(repo, hook string) {
+			done[hook] = make(chan struct{})
+			process(l, repo, hook, order, done[hook], &wg)
+		}
+		step("a/repo", "a1")
+		if got := <-order; got != "a1" {
+			t.Fatalf("expected a1, got %s", got)
+		}
+		step("a/repo", "a2")
+		waitQueued(t, l, "a/repo", 2)
+		step("a/repo", "a3")
+		waitQueued(t, l, "a/repo", 3)
+		step("b/repo", "b1")
+		waitQueued(t, l, "b/repo", 1)
+		// wait for b1 to block on the slot
+		time.Sleep(10 * time.Millisecond)
+
+		running := "a1"
+		for _, hook := range []string{"b1", "a2", "a3"} {
+			close(done[running])
+			if got := <-order; got != hook {
+				t.Fatalf("expected %s, got %s", hook, got)
+			}
+			running = hook
+		}
+		close(done[running])
+		wg.Wait()
+	})
+}
+
+func TestHookLimiterCanceled(t *testing.T) {
+	l := NewHookLimiter(1)
+	release, err := l.Acquire(context.Background(), "gordon/hello-world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "gordon/hello-world"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+	release()
+	release()
+
+	release, err = l.Acquire(context.Background(), "gordon/hello-world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if len(l.repos) != 0 {
+		t.Fatal("expected no queued repositories")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// CreateInitialBuild returns the build of the head of the default branch of a
+// newly activated repo. Nil is returned for empty repos, whose default branch
+// does not exist yet. The remote has to support resolving branch heads.
+func CreateInitialBuild(ctx context.Context, r remote.Remote, user *model.User, repo *model.Repo) (*model.Build, error) {
+	resolver, ok := r.(remote.BranchHeadResolver)
+	if !ok {
+		return nil, fmt.Errorf("remote does not support initial builds")
+	}
+	if repo.Branch == "" {
+		return nil, nil
+	}
+
+	commit, err := resolver.BranchHead(ctx, user, repo, repo.Branch)
+	if errors.Is(err, remote.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Build{
+		Event:     model.EventManual,
+		Commit:    commit,
+		Ref:       "refs/heads/" + repo.Branch,
+		Branch:    repo.Branch,
+		Message:   fmt.Sprintf("initial build of %s", repo.Branch),
+		Link:      repo.Link,
+		Author:    user.Login,
+		Avatar:    user.Avatar,
+		Sender:    user.Login,
+		Timestamp: time.Now().UTC().Unix(),
+	}, nil
+}
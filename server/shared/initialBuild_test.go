@@ -0,0 +1,64 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+func TestCreateInitialBuild(t *testing.T) {
+	s := httptest.NewServer(fixtures.Handler())
+	defer s.Close()
+
+	r, err := gitea.New(gitea.Opts{URL: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Login: "someuser", Token: "cfcd2084"}
+	repo := &model.Repo{Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name", Branch: "master"}
+	build, err := CreateInitialBuild(context.Background(), r, user, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if build.Event != model.EventManual {
+		t.Errorf("expected event %s, got %s", model.EventManual, build.Event)
+	}
+	if build.Commit != "f05f642b892d59a0a9ef6a31f6c905a24b5db13a" {
+		t.Errorf("expected the head commit of master, got %s", build.Commit)
+	}
+	if build.Ref != "refs/heads/master" || build.Branch != "master" {
+		t.Errorf("expected ref refs/heads/master of branch master, got %s of %s", build.Ref, build.Branch)
+	}
+	if build.Sender != "someuser" {
+		t.Errorf("expected the activating user as sender, got %s", build.Sender)
+	}
+
+	empty := &model.Repo{Owner: "test_name", Name: "empty_repo", FullName: "test_name/empty_repo", Branch: "master"}
+	build, err = CreateInitialBuild(context.Background(), r, user, empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build != nil {
+		t.Errorf("expected no build for an empty repo, got %v", build)
+	}
+}
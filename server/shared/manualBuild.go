@@ -0,0 +1,63 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// CreateManualBuild returns the build of the commit on the branch, or of the
+// head of the branch if no commit is given. The commit may be a short sha, it
+// is resolved to the full sha needed to report the status. The branch
+// defaults to the default branch of the repo. The remote has to support
+// resolving commits, unknown commits result in remote.ErrNotFound.
+func CreateManualBuild(ctx context.Context, r remote.Remote, user *model.User, repo *model.Repo, branch, commit string) (*model.Build, error) {
+	resolver, ok := r.(remote.CommitResolver)
+	if !ok {
+		return nil, fmt.Errorf("remote does not support manual builds")
+	}
+	if branch == "" {
+		branch = repo.Branch
+	}
+	ref := commit
+	if ref == "" {
+		ref = branch
+	}
+
+	info, err := resolver.CommitInfo(ctx, user, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Build{
+		Event:     model.EventManual,
+		Commit:    info.SHA,
+		Ref:       "refs/heads/" + branch,
+		Branch:    branch,
+		Title:     strings.TrimSpace(strings.SplitN(info.Message, "\n", 2)[0]),
+		Message:   info.Message,
+		Link:      info.Link,
+		Author:    info.Author,
+		Email:     info.Email,
+		Avatar:    info.Avatar,
+		Sender:    user.Login,
+		Timestamp: info.Timestamp,
+	}, nil
+}
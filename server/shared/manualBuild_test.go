@@ -0,0 +1,79 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+func TestCreateManualBuild(t *testing.T) {
+	s := httptest.NewServer(fixtures.Handler())
+	defer s.Close()
+
+	r, err := gitea.New(gitea.Opts{URL: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Login: "someuser", Token: "cfcd2084"}
+	repo := &model.Repo{Owner: "test_name", Name: "repo_name", FullName: "test_name/repo_name", Branch: "master"}
+
+	t.Run("short sha", func(t *testing.T) {
+		build, err := CreateManualBuild(context.Background(), r, user, repo, "", "f05f642")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if build.Event != model.EventManual {
+			t.Errorf("expected event %s, got %s", model.EventManual, build.Event)
+		}
+		if build.Commit != "f05f642b892d59a0a9ef6a31f6c905a24b5db13a" {
+			t.Errorf("expected the full sha, got %s", build.Commit)
+		}
+		if build.Ref != "refs/heads/master" || build.Branch != "master" {
+			t.Errorf("expected ref refs/heads/master of branch master, got %s of %s", build.Ref, build.Branch)
+		}
+		if build.Title != "Update the readme" || build.Author != "gordon" || build.Timestamp != 1646389230 {
+			t.Errorf("expected the metadata of the commit, got %q by %s at %d", build.Title, build.Author, build.Timestamp)
+		}
+		if build.Sender != "someuser" {
+			t.Errorf("expected the triggering user as sender, got %s", build.Sender)
+		}
+	})
+
+	t.Run("branch head", func(t *testing.T) {
+		build, err := CreateManualBuild(context.Background(), r, user, repo, "master", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if build.Commit != "f05f642b892d59a0a9ef6a31f6c905a24b5db13a" {
+			t.Errorf("expected the head commit of master, got %s", build.Commit)
+		}
+	})
+
+	t.Run("unknown commit", func(t *testing.T) {
+		_, err := CreateManualBuild(context.Background(), r, user, repo, "", "deadbeef")
+		if !errors.Is(err, remote.ErrNotFound) {
+			t.Errorf("expected a not found error, got %v", err)
+		}
+	})
+}
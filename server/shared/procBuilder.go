@@ -63,12 +63,18 @@ func (b *ProcBuilder) Build() ([]*BuildItem, error) {
 	sort.Sort(remote.ByName(b.Yamls))
 
 	pidSequence := 1
+	var errs []string
 
 	for _, y := range b.Yamls {
 		// matrix axes
 		axes, err := matrix.ParseString(string(y.Data))
 		if err != nil {
-			return nil, err
+			// a malformed file only fails its own pipeline
+			item := b.errorItem(pidSequence, y.Name, err)
+			errs = append(errs, item.Proc.Error)
+			items = append(items, item)
+			pidSequence++
+			continue
 		}
 		if len(axes) == 0 {
 			axes = append(axes, matrix.Axis{})
@@ -87,23 +93,14 @@ func (b *ProcBuilder) Build() ([]*BuildItem, error) {
 			metadata := metadataFromStruct(b.Repo, b.Curr, b.Last, proc, b.Link)
 			environ := b.environmentVariables(metadata, axis)
 
-			// substitute vars
-			substituted, err := b.envsubst(string(y.Data), environ)
+			parsed, err := b.parse(y, environ)
 			if err != nil {
-				return nil, err
-			}
-
-			// parse yaml pipeline
-			parsed, err := yaml.ParseString(substituted)
-			if err != nil {
-				return nil, err
-			}
-
-			// lint pipeline
-			if err := linter.New(
-				linter.WithTrusted(b.Repo.IsTrusted),
-			).Lint(parsed); err != nil {
-				return nil, err
+				item := b.errorItem(pidSequence, y.Name, err)
+				item.Proc.Environ = axis
+				errs = append(errs, item.Proc.Error)
+				items = append(items, item)
+				pidSequence++
+				continue
 			}
 
 			if !parsed.Branches.Match(b.Curr.Branch) && (b.Curr.Event != model.EventDeploy && b.Curr.Event != model.EventTag) {
@@ -136,16 +133,88 @@ func (b *ProcBuilder) Build() ([]*BuildItem, error) {
 	}
 
 	items = filterItemsWithMissingDependencies(items)
+	skipItemsWithErroredDependencies(items)
 
 	// check if at least one proc can start, if list is not empty
-	procListContainsItemsToRun(items)
 	if len(items) > 0 && !procListContainsItemsToRun(items) {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
 		return nil, fmt.Errorf("build has no startpoint")
 	}
 
 	return items, nil
 }
 
+// parse substitutes the environment into a pipeline file, parses and lints it.
+func (b *ProcBuilder) parse(y *remote.FileMeta, environ map[string]string) (*yaml.Config, error) {
+	// substitute vars
+	substituted, err := b.envsubst(string(y.Data), environ)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse yaml pipeline
+	parsed, err := yaml.ParseString(substituted)
+	if err != nil {
+		return nil, err
+	}
+
+	// lint pipeline
+	if err := linter.New(
+		linter.WithTrusted(b.Repo.IsTrusted),
+	).Lint(parsed); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// errorItem returns a build item for a pipeline file that could not be
+// compiled, its proc is set to error naming the file.
+func (b *ProcBuilder) errorItem(pid int, name string, err error) *BuildItem {
+	return &BuildItem{
+		Proc: &model.Proc{
+			BuildID: b.Curr.ID,
+			PID:     pid,
+			PGID:    pid,
+			State:   model.StatusError,
+			Error:   fmt.Sprintf("pipeline file %s: %s", name, err),
+			Name:    SanitizePath(name),
+		},
+		Config: new(backend.Config),
+		Labels: map[string]string{},
+	}
+}
+
+// skipItemsWithErroredDependencies skips items depending, directly or
+// transitively, on a pipeline that could not be compiled.
+func skipItemsWithErroredDependencies(items []*BuildItem) {
+	failed := make(map[string]bool)
+	for _, item := range items {
+		if item.Proc.State == model.StatusError {
+			failed[item.Proc.Name] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, item := range items {
+			if failed[item.Proc.Name] {
+				continue
+			}
+			for _, dep := range item.DependsOn {
+				if failed[dep] {
+					item.Proc.State = model.StatusSkipped
+					failed[item.Proc.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
 func procListContainsItemsToRun(items []*BuildItem) bool {
 	for i := range items {
 		if items[i].Proc.State == model.StatusPending {
@@ -243,6 +312,7 @@ func (b *ProcBuilder) toInternalRepresentation(parsed *yaml.Config, environ map[
 				b.Netrc.Login,
 				b.Netrc.Password,
 				b.Netrc.Machine,
+				b.Netrc.Hosts...,
 			),
 			b.Repo.IsSCMPrivate || server.Config.Pipeline.AuthenticatePublicRepos,
 		),
@@ -312,6 +382,7 @@ func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.
 			Remote:  repo.Clone,
 			Private: repo.IsSCMPrivate,
 			Branch:  repo.Branch,
+			Topics:  repo.Topics,
 		},
 		Curr: frontend.Build{
 			Number:   build.Number,
@@ -323,18 +394,40 @@ func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.
 			Event:    string(build.Event),
 			Link:     build.Link,
 			Target:   build.Deploy,
+			Cron:     build.Cron,
+			Package:  buildPackage(build),
+			Deleted:  deletedRef(build),
+
+			Prerelease: build.IsPrerelease,
+			FirstTag:   build.IsFirstTag,
 			Commit: frontend.Commit{
-				Sha:     build.Commit,
-				Ref:     build.Ref,
-				Refspec: build.Refspec,
-				Branch:  build.Branch,
-				Message: build.Message,
+				Sha:          build.Commit,
+				Ref:          build.Ref,
+				Refspec:      build.Refspec,
+				SourceBranch: build.SourceBranch,
+				PullRequest:  build.PullRequest,
+				Branch:       build.Branch,
+				Message:      build.Message,
 				Author: frontend.Author{
 					Name:   build.Author,
 					Email:  build.Email,
 					Avatar: build.Avatar,
 				},
-				ChangedFiles: build.ChangedFiles,
+				Committer:     buildCommitter(build),
+				ChangedFiles:  changedFiles(build),
+				Labels:        build.Labels,
+				Assignees:     build.Assignees,
+				Verified:      build.IsVerified,
+				Signer:        build.Signer,
+				BaseProtected: build.BaseProtected,
+				Milestone:     milestoneTitle(build),
+				Draft:         build.IsDraft,
+				BaseCommit:    build.BaseCommit,
+				MergeBase:     build.MergeBase,
+				MergeCommit:   build.MergeCommit,
+				Teams:         build.Teams,
+				Approver:      build.Approver,
+				ReviewState:   string(build.ReviewState),
 			},
 		},
 		Prev: frontend.Build{
@@ -347,17 +440,26 @@ func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.
 			Link:     last.Link,
 			Target:   last.Deploy,
 			Commit: frontend.Commit{
-				Sha:     last.Commit,
-				Ref:     last.Ref,
-				Refspec: last.Refspec,
-				Branch:  last.Branch,
-				Message: last.Message,
+				Sha:         last.Commit,
+				Ref:         last.Ref,
+				Refspec:     last.Refspec,
+				PullRequest: last.PullRequest,
+				Branch:      last.Branch,
+				Message:     last.Message,
 				Author: frontend.Author{
 					Name:   last.Author,
 					Email:  last.Email,
 					Avatar: last.Avatar,
 				},
-				ChangedFiles: last.ChangedFiles,
+				Committer:    buildCommitter(last),
+				ChangedFiles: changedFiles(last),
+				Labels:       last.Labels,
+				Assignees:    last.Assignees,
+				Verified:     last.IsVerified,
+				Signer:       last.Signer,
+				Teams:        last.Teams,
+				Approver:     last.Approver,
+				ReviewState:  string(last.ReviewState),
 			},
 		},
 		Job: frontend.Job{
@@ -379,3 +481,54 @@ func SanitizePath(path string) string {
 	path = strings.TrimPrefix(path, ".")
 	return path
 }
+
+// buildPackage returns the package of package builds.
+func buildPackage(build *model.Build) frontend.Package {
+	if build.Package == nil {
+		return frontend.Package{}
+	}
+	return frontend.Package{
+		Type:    build.Package.Type,
+		Name:    build.Package.Name,
+		Version: build.Package.Version,
+		Action:  build.Package.Action,
+	}
+}
+
+// deletedRef returns the deleted branch or tag of delete builds.
+func deletedRef(build *model.Build) frontend.Ref {
+	if build.DeletedRef == nil {
+		return frontend.Ref{}
+	}
+	return frontend.Ref{
+		Type: build.DeletedRef.Type,
+		Name: build.DeletedRef.Name,
+	}
+}
+
+// buildCommitter returns the committer of the build, which is the author if
+// the remote does not report committers.
+func buildCommitter(build *model.Build) frontend.Author {
+	if build.Committer == nil {
+		return frontend.Author{Name: build.Author, Email: build.Email}
+	}
+	return frontend.Author{Name: build.Committer.Name, Email: build.Committer.Email}
+}
+
+// changedFiles returns the files changed by the build. An incomplete list is
+// dropped, so path constraints fall back to always run.
+func changedFiles(build *model.Build) []string {
+	if build == nil || build.Truncated {
+		return nil
+	}
+	return build.ChangedFiles
+}
+
+// milestoneTitle returns the title of the milestone of the build, or an empty
+// title if it has none.
+func milestoneTitle(build *model.Build) string {
+	if build.Milestone == nil {
+		return ""
+	}
+	return build.Milestone.Title
+}
@@ -16,6 +16,7 @@ package shared
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/woodpecker-ci/woodpecker/server/model"
@@ -392,6 +393,77 @@ depends_on: [ shouldbefiltered ]
 	}
 }
 
+func TestMalformedMultiPipeline(t *testing.T) {
+	t.Parallel()
+
+	b := ProcBuilder{
+		Repo:  &model.Repo{},
+		Curr:  &model.Build{},
+		Last:  &model.Build{},
+		Netrc: &model.Netrc{},
+		Secs:  []*model.Secret{},
+		Regs:  []*model.Registry{},
+		Link:  "",
+		Yamls: []*remote.FileMeta{
+			{Name: ".woodpecker/lint.yml", Data: []byte(`
+pipeline:
+  lint:
+    image: scratch
+`)},
+			{Name: ".woodpecker/broken.yml", Data: []byte(`
+pipeline:
+  build: [
+`)},
+			{Name: ".woodpecker/build.yml", Data: []byte(`
+pipeline:
+  build:
+    image: scratch
+`)},
+			{Name: ".woodpecker/deploy.yml", Data: []byte(`
+pipeline:
+  deploy:
+    image: scratch
+depends_on: [ broken ]
+`)},
+		},
+	}
+
+	buildItems, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buildItems) != 4 {
+		t.Fatalf("expected 4 build items, got %d", len(buildItems))
+	}
+	states := map[string]model.StatusValue{}
+	for _, item := range buildItems {
+		states[item.Proc.Name] = item.Proc.State
+	}
+	if states["build"] != model.StatusPending || states["lint"] != model.StatusPending {
+		t.Fatal("valid pipelines should be pending")
+	}
+	if states["broken"] != model.StatusError {
+		t.Fatal("malformed pipeline should be errored")
+	}
+	if states["deploy"] != model.StatusSkipped {
+		t.Fatal("pipeline depending on a malformed pipeline should be skipped")
+	}
+	if buildItems[0].Proc.Name != "broken" || !strings.Contains(buildItems[0].Proc.Error, ".woodpecker/broken.yml") {
+		t.Fatalf("expected error naming the malformed file, got '%s'", buildItems[0].Proc.Error)
+	}
+
+	// the build fails if no pipeline can start
+	for _, y := range b.Yamls {
+		if y.Name == ".woodpecker/broken.yml" {
+			b.Yamls = []*remote.FileMeta{y}
+			break
+		}
+	}
+	if _, err := b.Build(); err == nil || !strings.Contains(err.Error(), ".woodpecker/broken.yml") {
+		t.Fatalf("expected build to fail naming the malformed file, got '%v'", err)
+	}
+}
+
 func TestTree(t *testing.T) {
 	t.Parallel()
 
@@ -457,3 +529,168 @@ func TestSanitizePath(t *testing.T) {
 		}
 	}
 }
+
+func TestPullRequestNumber(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", PullRequest: 7}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_COMMIT_PULL_REQUEST"] != "7" {
+		t.Errorf("expected pull request 7, got %q", env["CI_COMMIT_PULL_REQUEST"])
+	}
+
+	// remotes without number fall back to the one in the ref
+	build = &model.Build{Event: model.EventPull, Ref: "refs/pull/8/head"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_COMMIT_PULL_REQUEST"] != "8" {
+		t.Errorf("expected pull request 8, got %q", env["CI_COMMIT_PULL_REQUEST"])
+	}
+
+	build = &model.Build{Event: model.EventPush, Ref: "refs/heads/master"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_COMMIT_PULL_REQUEST"] != "" {
+		t.Errorf("expected no pull request for push builds, got %q", env["CI_COMMIT_PULL_REQUEST"])
+	}
+}
+
+func TestPullRequestAssignees(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", Assignees: []string{"security", "octocat"}}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_COMMIT_ASSIGNEES"] != "security,octocat" {
+		t.Errorf("expected the assignees security,octocat, got %q", env["CI_COMMIT_ASSIGNEES"])
+	}
+
+	build = &model.Build{Event: model.EventPush, Ref: "refs/heads/master", Assignees: []string{"security"}}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_COMMIT_ASSIGNEES"] != "" {
+		t.Errorf("expected no assignees for push builds, got %q", env["CI_COMMIT_ASSIGNEES"])
+	}
+}
+
+func TestCommitCommitter(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Author: "gopher", Email: "gopher@golang.org", Committer: &model.Committer{Name: "gordon", Email: "gordon@golang.org"}}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_COMMIT_COMMITTER"] != "gordon" || env["CI_COMMIT_COMMITTER_EMAIL"] != "gordon@golang.org" {
+		t.Errorf("expected the committer gordon, got %q <%s>", env["CI_COMMIT_COMMITTER"], env["CI_COMMIT_COMMITTER_EMAIL"])
+	}
+	if env["CI_COMMIT_AUTHOR"] != "gopher" {
+		t.Errorf("expected the author gopher, got %q", env["CI_COMMIT_AUTHOR"])
+	}
+
+	// builds without committer were committed by their author
+	build = &model.Build{Author: "gopher", Email: "gopher@golang.org"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_COMMIT_COMMITTER"] != "gopher" || env["CI_COMMIT_COMMITTER_EMAIL"] != "gopher@golang.org" {
+		t.Errorf("expected the author as committer, got %q <%s>", env["CI_COMMIT_COMMITTER"], env["CI_COMMIT_COMMITTER_EMAIL"])
+	}
+}
+
+func TestDeletedRef(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventDelete, Branch: "master", DeletedRef: &model.DeletedRef{Type: "branch", Name: "feature/changes"}}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_DELETED_REF_TYPE"] != "branch" || env["CI_DELETED_REF_NAME"] != "feature/changes" {
+		t.Errorf("expected the deleted branch feature/changes, got %s %q", env["CI_DELETED_REF_TYPE"], env["CI_DELETED_REF_NAME"])
+	}
+
+	build = &model.Build{Event: model.EventPush, Branch: "master"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_DELETED_REF_TYPE"] != "" || env["CI_DELETED_REF_NAME"] != "" {
+		t.Errorf("expected no deleted ref for push builds, got %s %q", env["CI_DELETED_REF_TYPE"], env["CI_DELETED_REF_NAME"])
+	}
+}
+
+func TestReleasePrerelease(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventRelease, Ref: "refs/tags/v1.0.0-rc.1", IsPrerelease: true}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	if !metadata.Curr.Prerelease {
+		t.Errorf("expected the release to be a prerelease")
+	}
+	env := metadata.Environ()
+	if env["CI_RELEASE_IS_PRERELEASE"] != "true" {
+		t.Errorf("expected CI_RELEASE_IS_PRERELEASE true, got %q", env["CI_RELEASE_IS_PRERELEASE"])
+	}
+
+	build = &model.Build{Event: model.EventRelease, Ref: "refs/tags/v1.0.0"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_RELEASE_IS_PRERELEASE"] != "false" {
+		t.Errorf("expected CI_RELEASE_IS_PRERELEASE false, got %q", env["CI_RELEASE_IS_PRERELEASE"])
+	}
+
+	build = &model.Build{Event: model.EventTag, Ref: "refs/tags/v1.0.0"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_RELEASE_IS_PRERELEASE"] != "" {
+		t.Errorf("expected no CI_RELEASE_IS_PRERELEASE for tag builds, got %q", env["CI_RELEASE_IS_PRERELEASE"])
+	}
+}
+
+func TestSourceBranch(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", Refspec: "refs/pull/7/head:master", SourceBranch: "feature/changes"}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_COMMIT_SOURCE_BRANCH"] != "feature/changes" || env["CI_COMMIT_TARGET_BRANCH"] != "master" {
+		t.Errorf("expected feature/changes into master, got %q into %q", env["CI_COMMIT_SOURCE_BRANCH"], env["CI_COMMIT_TARGET_BRANCH"])
+	}
+
+	// the source of the refspec is used if the branch is unknown
+	build = &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", Refspec: "refs/pull/7/head:master"}
+	metadata = metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env = metadata.Environ()
+	if env["CI_COMMIT_SOURCE_BRANCH"] != "refs/pull/7/head" {
+		t.Errorf("expected the pull request ref as source branch, got %q", env["CI_COMMIT_SOURCE_BRANCH"])
+	}
+}
+
+func TestDraftPullRequest(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", IsDraft: true}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	if !metadata.Curr.Commit.Draft {
+		t.Error("expected the build of the pull request to be a draft")
+	}
+	if env := metadata.Environ(); env["CI_COMMIT_PULL_REQUEST_DRAFT"] != "true" {
+		t.Errorf("expected the draft to be exposed, got %q", env["CI_COMMIT_PULL_REQUEST_DRAFT"])
+	}
+}
+
+func TestPullRequestCommits(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventPull, Ref: "refs/pull/7/head", BaseCommit: "4b26262", MergeBase: "9353195", MergeCommit: "f05f642"}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	env := metadata.Environ()
+	if env["CI_COMMIT_BASE_SHA"] != "4b26262" || env["CI_COMMIT_MERGE_BASE_SHA"] != "9353195" || env["CI_COMMIT_MERGE_SHA"] != "f05f642" {
+		t.Errorf("expected the commits of the pull request, got %q, %q and %q", env["CI_COMMIT_BASE_SHA"], env["CI_COMMIT_MERGE_BASE_SHA"], env["CI_COMMIT_MERGE_SHA"])
+	}
+}
+
+func TestFirstTag(t *testing.T) {
+	t.Parallel()
+
+	build := &model.Build{Event: model.EventTag, Ref: "refs/tags/v1.0.0", IsFirstTag: true}
+	metadata := metadataFromStruct(&model.Repo{}, build, &model.Build{}, &model.Proc{}, "")
+	if env := metadata.Environ(); env["CI_COMMIT_TAG_IS_FIRST"] != "true" {
+		t.Errorf("expected the first tag to be exposed, got %q", env["CI_COMMIT_TAG_IS_FIRST"])
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"errors"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+// ErrRepoInactive is returned if a repository which is not active is paused
+// or resumed.
+var ErrRepoInactive = errors.New("repository is not active")
+
+// PauseRepoStore is the part of the store needed to pause repositories.
+type PauseRepoStore interface {
+	UpdateRepo(*model.Repo) error
+}
+
+// PauseRepo pauses the active repo. Unlike deactivating it, the hook
+// registered with the remote is kept, only the hooks it delivers are dropped
+// until the repo is resumed.
+func PauseRepo(store PauseRepoStore, repo *model.Repo) error {
+	return setRepoPaused(store, repo, true)
+}
+
+// ResumeRepo resumes the paused repo, the hooks delivered by the remote are
+// built again.
+func ResumeRepo(store PauseRepoStore, repo *model.Repo) error {
+	return setRepoPaused(store, repo, false)
+}
+
+func setRepoPaused(store PauseRepoStore, repo *model.Repo, paused bool) error {
+	if !repo.IsActive {
+		return ErrRepoInactive
+	}
+	if repo.IsPaused == paused {
+		return nil
+	}
+	repo.IsPaused = paused
+	return store.UpdateRepo(repo)
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+func TestPauseRepo(t *testing.T) {
+	repo := storedRepo()
+	repo.IsActive = true
+	repo.UserID = 1
+	store := &renameRepoStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+	if repo.IsPaused {
+		t.Fatal("expected the hooks of an active repo to be built")
+	}
+	if err := PauseRepo(store, repo); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.updated) != 1 || !store.updated[0].IsPaused {
+		t.Fatal("expected the paused repo to be stored")
+	}
+	// deactivating removes the hook and the owner, pausing keeps both
+	if !repo.IsActive || repo.UserID != 1 {
+		t.Fatal("expected the paused repo to stay active with its owner")
+	}
+	if !store.repos[repo.FullName].IsPaused {
+		t.Fatal("expected the hooks of the paused repo to be dropped")
+	}
+
+	if err := PauseRepo(store, repo); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.updated) != 1 {
+		t.Fatal("expected pausing a paused repo not to store it again")
+	}
+}
+
+func TestResumeRepo(t *testing.T) {
+	repo := storedRepo()
+	repo.IsActive = true
+	repo.IsPaused = true
+	store := &renameRepoStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+	if err := ResumeRepo(store, repo); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.updated) != 1 || store.updated[0].IsPaused {
+		t.Fatal("expected the resumed repo to be stored")
+	}
+	if store.repos[repo.FullName].IsPaused {
+		t.Fatal("expected the hooks of the resumed repo to be built")
+	}
+}
+
+func TestPauseInactiveRepo(t *testing.T) {
+	repo := storedRepo()
+	store := &renameRepoStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+	if err := PauseRepo(store, repo); !errors.Is(err, ErrRepoInactive) {
+		t.Fatalf("expected an inactive repo not to be paused, got %v", err)
+	}
+	if err := ResumeRepo(store, repo); !errors.Is(err, ErrRepoInactive) {
+		t.Fatalf("expected an inactive repo not to be resumed, got %v", err)
+	}
+	if len(store.updated) != 0 || repo.IsPaused {
+		t.Fatal("expected no repo to be paused")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+// ErrRepoNameConflict is returned if a repository was renamed or transferred
+// to a name that is already used by another stored repository.
+var ErrRepoNameConflict = errors.New("repository name is already taken")
+
+// RenameRepoStore is the part of the store needed to rename repositories.
+type RenameRepoStore interface {
+	GetRepoRemoteName(remote, fullName string) (*model.Repo, error)
+	UpdateRepo(*model.Repo) error
+}
+
+// UpdateRenamedRepo updates the name, owner and links of the stored repo to
+// the ones of the repo received from the remote. It reports whether the repo
+// was renamed or transferred and therefore had to be updated.
+func UpdateRenamedRepo(store RenameRepoStore, repo, from *model.Repo) (bool, error) {
+	fullName := from.FullName
+	if fullName == "" {
+		fullName = from.Owner + "/" + from.Name
+	}
+	if repo.FullName == fullName {
+		return false, nil
+	}
+
+	if existing, err := store.GetRepoRemoteName(repo.Remote, fullName); err == nil && existing.ID != repo.ID {
+		return false, fmt.Errorf("rename repo %s to %s: %w", repo.FullName, fullName, ErrRepoNameConflict)
+	}
+
+	repo.Owner = from.Owner
+	repo.Name = from.Name
+	repo.FullName = fullName
+	if from.Link != "" {
+		repo.Link = from.Link
+	}
+	if from.Clone != "" {
+		repo.Clone = from.Clone
+	}
+	if err := store.UpdateRepo(repo); err != nil {
+		return false, err
+	}
+	return true, nil
+}
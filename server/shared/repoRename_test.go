@@ -0,0 +1,160 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea"
+	"github.com/woodpecker-ci/woodpecker/server/remote/gitea/fixtures"
+)
+
+type renameRepoStore struct {
+	repos   map[string]*model.Repo
+	updated []*model.Repo
+}
+
+func (s *renameRepoStore) GetRepoName(name string) (*model.Repo, error) {
+	repo, ok := s.repos[name]
+	if !ok {
+		return nil, fmt.Errorf("repo %s not found", name)
+	}
+	return repo, nil
+}
+
+func (s *renameRepoStore) GetRepoRemoteName(remote, name string) (*model.Repo, error) {
+	repo, err := s.GetRepoName(name)
+	if err != nil || repo.Remote != remote {
+		return nil, fmt.Errorf("repo %s of remote %q not found", name, remote)
+	}
+	return repo, nil
+}
+
+func (s *renameRepoStore) UpdateRepo(repo *model.Repo) error {
+	s.updated = append(s.updated, repo)
+	return nil
+}
+
+func hookRepo(t *testing.T, payload string) *model.Repo {
+	r, err := gitea.New(gitea.Opts{URL: "http://gitea.golang.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(payload))
+	req.Header.Set("X-Gitea-Event", "push")
+	// the signature is not verified, as the repo is not stored yet
+	parsed, err := r.(remote.HookParser).ParseHook(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Repo
+}
+
+func storedRepo() *model.Repo {
+	return &model.Repo{
+		ID:       1,
+		Owner:    "gordon",
+		Name:     "hello-world",
+		FullName: "gordon/hello-world",
+		Link:     "http://gitea.golang.org/gordon/hello-world",
+		Clone:    "http://gitea.golang.org/gordon/hello-world.git",
+	}
+}
+
+func TestUpdateRenamedRepo(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		owner    string
+		fullName string
+	}{
+		{name: "renamed", payload: fixtures.HookPushRenamed, owner: "gordon", fullName: "gordon/hello-gopher"},
+		{name: "transferred", payload: fixtures.HookPushTransferred, owner: "gophers", fullName: "gophers/hello-world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storedRepo()
+			s := &renameRepoStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+			renamed, err := UpdateRenamedRepo(s, repo, hookRepo(t, tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !renamed || len(s.updated) != 1 {
+				t.Fatalf("expected the repo to be updated once, got %d updates", len(s.updated))
+			}
+			if repo.Owner != tt.owner || repo.FullName != tt.fullName {
+				t.Errorf("expected repo %s of %s, got %s of %s", tt.fullName, tt.owner, repo.FullName, repo.Owner)
+			}
+			if repo.Link != "http://gitea.golang.org/"+tt.fullName {
+				t.Errorf("expected the link of %s, got %s", tt.fullName, repo.Link)
+			}
+		})
+	}
+}
+
+func TestUpdateRenamedRepoUnchanged(t *testing.T) {
+	repo := storedRepo()
+	s := &renameRepoStore{repos: map[string]*model.Repo{repo.FullName: repo}}
+
+	renamed, err := UpdateRenamedRepo(s, repo, hookRepo(t, fixtures.HookPush))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed || len(s.updated) != 0 {
+		t.Error("expected an unchanged repo not to be updated")
+	}
+}
+
+func TestUpdateRenamedRepoConflict(t *testing.T) {
+	repo := storedRepo()
+	s := &renameRepoStore{repos: map[string]*model.Repo{
+		repo.FullName:         repo,
+		"gordon/hello-gopher": {ID: 2, FullName: "gordon/hello-gopher"},
+	}}
+
+	renamed, err := UpdateRenamedRepo(s, repo, hookRepo(t, fixtures.HookPushRenamed))
+	if !errors.Is(err, ErrRepoNameConflict) {
+		t.Fatalf("expected a name conflict, got %v", err)
+	}
+	if renamed || len(s.updated) != 0 || repo.FullName != "gordon/hello-world" {
+		t.Error("expected the repo to keep its name on a conflict")
+	}
+}
+
+func TestUpdateRenamedRepoOtherRemote(t *testing.T) {
+	repo := storedRepo()
+	s := &renameRepoStore{repos: map[string]*model.Repo{
+		repo.FullName:         repo,
+		"gordon/hello-gopher": {ID: 2, FullName: "gordon/hello-gopher", Remote: "codeberg"},
+	}}
+
+	renamed, err := UpdateRenamedRepo(s, repo, hookRepo(t, fixtures.HookPushRenamed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renamed || repo.FullName != "gordon/hello-gopher" {
+		t.Error("expected a repo of another remote not to conflict")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NewSkipPattern returns a pattern matching any of the tokens wrapped in
+// square brackets, ignoring case and the number of spaces between words. It
+// is nil if there are no tokens, so nothing is skipped.
+func NewSkipPattern(tokens []string) *regexp.Regexp {
+	alternatives := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words := strings.Fields(token)
+		if len(words) == 0 {
+			continue
+		}
+		for i := range words {
+			words[i] = regexp.QuoteMeta(words[i])
+		}
+		alternatives = append(alternatives, strings.Join(words, " *"))
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`\[ *(?i:` + strings.Join(alternatives, "|") + `) *\]`)
+}
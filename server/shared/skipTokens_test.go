@@ -0,0 +1,48 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import "testing"
+
+func TestNewSkipPattern(t *testing.T) {
+	t.Parallel()
+
+	pattern := NewSkipPattern([]string{"ci skip", "skip ci"})
+	for _, message := range []string{
+		"bump [ci skip]",
+		"bump\n\n[skip ci]",
+		"bump [CI  SKIP]",
+		"bump [ Skip CI ]",
+		"Squashed (#2)\n\n* bump\n\n* docs [skip ci]",
+	} {
+		if !pattern.MatchString(message) {
+			t.Errorf("expected %q to be skipped", message)
+		}
+	}
+	for _, message := range []string{"bump", "bump ci skip", "bump [ci] skip"} {
+		if pattern.MatchString(message) {
+			t.Errorf("expected %q not to be skipped", message)
+		}
+	}
+
+	custom := NewSkipPattern([]string{"no build"})
+	if !custom.MatchString("bump [no build]") || custom.MatchString("bump [ci skip]") {
+		t.Errorf("expected only the configured tokens to be matched")
+	}
+
+	if NewSkipPattern(nil) != nil || NewSkipPattern([]string{""}) != nil {
+		t.Errorf("expected no pattern without tokens")
+	}
+}
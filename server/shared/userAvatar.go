@@ -0,0 +1,46 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
+)
+
+// UpdateUserStore is the part of the store needed to update users.
+type UpdateUserStore interface {
+	UpdateUser(*model.User) error
+}
+
+// UpdateUserAvatar fetches the current avatar of the user from the remote and
+// stores it if it changed. It reports whether the user was updated, an empty
+// avatar keeps the stored one.
+func UpdateUserAvatar(ctx context.Context, store UpdateUserStore, fetcher remote.AvatarFetcher, user *model.User) (bool, error) {
+	avatar, err := fetcher.Avatar(ctx, user)
+	if err != nil {
+		return false, err
+	}
+	if avatar == "" || avatar == user.Avatar {
+		return false, nil
+	}
+
+	user.Avatar = avatar
+	if err := store.UpdateUser(user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
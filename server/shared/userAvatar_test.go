@@ -0,0 +1,92 @@
+// Copyright 2022 Woodpecker Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/woodpecker-ci/woodpecker/server/model"
+)
+
+type avatarFetcher struct {
+	avatar string
+	err    error
+}
+
+func (f *avatarFetcher) Avatar(context.Context, *model.User) (string, error) {
+	return f.avatar, f.err
+}
+
+type updateUserStore struct {
+	updated []*model.User
+}
+
+func (s *updateUserStore) UpdateUser(user *model.User) error {
+	s.updated = append(s.updated, user)
+	return nil
+}
+
+func TestUpdateUserAvatar(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unchanged avatar", func(t *testing.T) {
+		s := &updateUserStore{}
+		user := &model.User{Login: "gordon", Avatar: "http://gitea.golang.org/avatars/1"}
+		updated, err := UpdateUserAvatar(ctx, s, &avatarFetcher{avatar: "http://gitea.golang.org/avatars/1"}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated || len(s.updated) != 0 {
+			t.Errorf("expected an unchanged avatar not to be stored")
+		}
+	})
+
+	t.Run("changed avatar", func(t *testing.T) {
+		s := &updateUserStore{}
+		user := &model.User{Login: "gordon", Avatar: "http://gitea.golang.org/avatars/1"}
+		updated, err := UpdateUserAvatar(ctx, s, &avatarFetcher{avatar: "http://gitea.golang.org/avatars/2"}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated || len(s.updated) != 1 {
+			t.Fatalf("expected a changed avatar to be stored")
+		}
+		if s.updated[0].Avatar != "http://gitea.golang.org/avatars/2" {
+			t.Errorf("expected the new avatar to be stored, got %s", s.updated[0].Avatar)
+		}
+	})
+
+	t.Run("empty avatar", func(t *testing.T) {
+		s := &updateUserStore{}
+		user := &model.User{Login: "gordon", Avatar: "http://gitea.golang.org/avatars/1"}
+		updated, err := UpdateUserAvatar(ctx, s, &avatarFetcher{}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated || user.Avatar != "http://gitea.golang.org/avatars/1" {
+			t.Errorf("expected an empty avatar to keep the stored one")
+		}
+	})
+
+	t.Run("failed fetch", func(t *testing.T) {
+		s := &updateUserStore{}
+		_, err := UpdateUserAvatar(ctx, s, &avatarFetcher{err: errors.New("unreachable")}, &model.User{})
+		if err == nil || len(s.updated) != 0 {
+			t.Errorf("expected the error of the remote without update")
+		}
+	})
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/rs/zerolog/log"
+
 	"github.com/woodpecker-ci/woodpecker/server/model"
 	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/store"
@@ -61,9 +63,18 @@ func (s *Syncer) SetFilter(fn FilterFunc) {
 
 func (s *Syncer) Sync(ctx context.Context, user *model.User, flatPermissions bool) error {
 	unix := time.Now().Unix() - (3601) // force immediate expiration. note 1 hour expiration is hard coded at the moment
-	repos, err := s.Remote.Repos(ctx, user)
-	if err != nil {
-		return err
+
+	// avatars changed in the remote are not part of the session
+	if fetcher, ok := s.Remote.(remote.AvatarFetcher); ok {
+		if _, err := UpdateUserAvatar(ctx, s.Store, fetcher, user); err != nil {
+			log.Warn().Err(err).Msgf("could not update the avatar of %s", user.Login)
+		}
+	}
+	// remotes may return the repos listed before the sync was interrupted,
+	// those are stored but the permissions of the others are kept
+	repos, syncErr := s.Remote.Repos(ctx, user)
+	if syncErr != nil && len(repos) == 0 {
+		return syncErr
 	}
 
 	remoteRepos := make([]*model.Repo, 0, len(repos))
@@ -97,10 +108,13 @@ func (s *Syncer) Sync(ctx context.Context, user *model.User, flatPermissions boo
 		}
 	}
 
-	err = s.Store.RepoBatch(remoteRepos)
+	err := s.Store.RepoBatch(remoteRepos)
 	if err != nil {
 		return err
 	}
+	if syncErr != nil {
+		return syncErr
+	}
 
 	// this is here as a precaution. I want to make sure that if an api
 	// call to the version control system fails and (for some reason) returns
@@ -61,6 +61,16 @@ func (s storage) GetBuildLast(repo *model.Repo, branch string) (*model.Build, er
 	return build, wrapGet(s.engine.Desc("build_number").Get(build))
 }
 
+func (s storage) GetBuildLastSuccess(repo *model.Repo, branch string, event model.WebhookEvent) (*model.Build, error) {
+	build := &model.Build{
+		RepoID: repo.ID,
+		Branch: branch,
+		Event:  event,
+		Status: model.StatusSuccess,
+	}
+	return build, wrapGet(s.engine.Desc("build_number").Get(build))
+}
+
 func (s storage) GetBuildLastBefore(repo *model.Repo, branch string, num int64) (*model.Build, error) {
 	build := &model.Build{
 		RepoID: repo.ID,
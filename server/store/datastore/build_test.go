@@ -94,6 +94,21 @@ func TestBuilds(t *testing.T) {
 			g.Assert(build.Number).Equal(getbuild.Number)
 		})
 
+		g.It("Should store the commit verification of a Build", func() {
+			build := model.Build{
+				RepoID:     repo.ID,
+				Status:     model.StatusPending,
+				IsVerified: true,
+				Signer:     "gordon",
+			}
+			err := store.CreateBuild(&build)
+			g.Assert(err).IsNil()
+			getbuild, err := store.GetBuild(build.ID)
+			g.Assert(err).IsNil()
+			g.Assert(getbuild.IsVerified).IsTrue()
+			g.Assert(getbuild.Signer).Equal("gordon")
+		})
+
 		g.It("Should Get a Build", func() {
 			build := model.Build{
 				RepoID: repo.ID,
@@ -229,6 +244,24 @@ func TestBuilds(t *testing.T) {
 			g.Assert(build2.Commit).Equal(getbuild.Commit)
 		})
 
+		g.It("Should Get the last successful Build of the event", func() {
+			builds := []*model.Build{
+				{RepoID: repo.ID, Status: model.StatusSuccess, Branch: "master", Commit: "85f8c029b902ed9400bc600bac301a0aadb144ac", Event: model.EventDeploy},
+				{RepoID: repo.ID, Status: model.StatusSuccess, Branch: "master", Commit: "85f8c029b902ed9400bc600bac301a0aadb144aa", Event: model.EventPush},
+				{RepoID: repo.ID, Status: model.StatusFailure, Branch: "master", Commit: "85f8c029b902ed9400bc600bac301a0aadb144ab", Event: model.EventDeploy},
+			}
+			for _, build := range builds {
+				g.Assert(store.CreateBuild(build, []*model.Proc{}...)).IsNil()
+			}
+			getbuild, err := store.GetBuildLastSuccess(&model.Repo{ID: 1}, "master", model.EventDeploy)
+			g.Assert(err).IsNil()
+			g.Assert(getbuild.ID).Equal(builds[0].ID)
+			g.Assert(getbuild.Commit).Equal(builds[0].Commit)
+
+			_, err = store.GetBuildLastSuccess(&model.Repo{ID: 1}, "develop", model.EventDeploy)
+			g.Assert(err).Equal(RecordNotExist)
+		})
+
 		g.It("Should Get the last Build Before Build N", func() {
 			build1 := &model.Build{
 				RepoID: repo.ID,
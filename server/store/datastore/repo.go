@@ -38,6 +38,11 @@ func (s storage) getRepoName(e *xorm.Session, fullName string) (*model.Repo, err
 	return repo, wrapGet(e.Where("repo_full_name = ?", fullName).Get(repo))
 }
 
+func (s storage) GetRepoRemoteName(remote, fullName string) (*model.Repo, error) {
+	repo := new(model.Repo)
+	return repo, wrapGet(s.engine.Where("repo_remote = ? AND repo_full_name = ?", remote, fullName).Get(repo))
+}
+
 func (s storage) GetRepoCount() (int64, error) {
 	return s.engine.Where(builder.Eq{"repo_active": true}).Count(new(model.Repo))
 }
@@ -132,7 +137,7 @@ func (s storage) RepoBatch(repos []*model.Repo) error {
 		}
 
 		exist, err := sess.
-			Where("repo_owner = ? AND repo_name = ?", repos[i].Owner, repos[i].Name).
+			Where("repo_remote = ? AND repo_owner = ? AND repo_name = ?", repos[i].Remote, repos[i].Owner, repos[i].Name).
 			Exist(new(model.Repo))
 		if err != nil {
 			return err
@@ -140,14 +145,14 @@ func (s storage) RepoBatch(repos []*model.Repo) error {
 
 		if exist {
 			if _, err := sess.
-				Where("repo_owner = ? AND repo_name = ?", repos[i].Owner, repos[i].Name).
-				Cols("repo_scm", "repo_avatar", "repo_link", "repo_private", "repo_clone", "repo_branch").
+				Where("repo_remote = ? AND repo_owner = ? AND repo_name = ?", repos[i].Remote, repos[i].Owner, repos[i].Name).
+				Cols("repo_scm", "repo_avatar", "repo_link", "repo_private", "repo_mirror", "repo_fork", "repo_clone", "repo_branch").
 				Update(repos[i]); err != nil {
 				return err
 			}
 
 			_, err := sess.
-				Where("repo_owner = ? AND repo_name = ?", repos[i].Owner, repos[i].Name).
+				Where("repo_remote = ? AND repo_owner = ? AND repo_name = ?", repos[i].Remote, repos[i].Owner, repos[i].Name).
 				Get(repos[i])
 			if err != nil {
 				return err
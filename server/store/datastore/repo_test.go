@@ -120,6 +120,33 @@ func TestRepos(t *testing.T) {
 			g.Assert(err1).IsNil()
 			g.Assert(err2 == nil).IsFalse()
 		})
+
+		g.It("Should Get a Repo by Remote and Name", func() {
+			repo1 := model.Repo{
+				UserID:   1,
+				FullName: "bradrydzewski/test",
+				Owner:    "bradrydzewski",
+				Name:     "test",
+			}
+			repo2 := model.Repo{
+				UserID:   2,
+				FullName: "bradrydzewski/test",
+				Owner:    "bradrydzewski",
+				Name:     "test",
+				Remote:   "codeberg",
+			}
+			g.Assert(store.CreateRepo(&repo1)).IsNil()
+			g.Assert(store.CreateRepo(&repo2)).IsNil()
+
+			getrepo, err := store.GetRepoRemoteName("", repo1.FullName)
+			g.Assert(err).IsNil()
+			g.Assert(getrepo.ID).Equal(repo1.ID)
+			getrepo, err = store.GetRepoRemoteName("codeberg", repo2.FullName)
+			g.Assert(err).IsNil()
+			g.Assert(getrepo.ID).Equal(repo2.ID)
+			_, err = store.GetRepoRemoteName("other", repo1.FullName)
+			g.Assert(err).IsNotNil()
+		})
 	})
 }
 
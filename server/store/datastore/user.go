@@ -28,6 +28,11 @@ func (s storage) GetUserLogin(login string) (*model.User, error) {
 	return user, wrapGet(s.engine.Where("user_login=?", login).Get(user))
 }
 
+func (s storage) GetUserRemoteLogin(remote, login string) (*model.User, error) {
+	user := new(model.User)
+	return user, wrapGet(s.engine.Where("user_remote=? AND user_login=?", remote, login).Get(user))
+}
+
 func (s storage) GetUserList() ([]*model.User, error) {
 	users := make([]*model.User, 0, 10)
 	return users, s.engine.Find(&users)
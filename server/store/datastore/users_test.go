@@ -102,6 +102,21 @@ func TestUsers(t *testing.T) {
 			g.Assert(user.Login).Equal(getuser.Login)
 		})
 
+		g.It("Should Get a User By Remote and Login", func() {
+			user := &model.User{
+				Login:  "joe",
+				Email:  "foo@bar.com",
+				Token:  "e42080dddf012c718e476da161d21ad5",
+				Remote: "codeberg",
+			}
+			g.Assert(store.CreateUser(user)).IsNil()
+			getuser, err := store.GetUserRemoteLogin("codeberg", user.Login)
+			g.Assert(err).IsNil()
+			g.Assert(user.ID).Equal(getuser.ID)
+			_, err = store.GetUserRemoteLogin("", user.Login)
+			g.Assert(err).IsNotNil()
+		})
+
 		g.It("Should Enforce Unique User Login", func() {
 			user1 := model.User{
 				Login: "joe",
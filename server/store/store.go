@@ -28,6 +28,8 @@ type Store interface {
 	GetUser(int64) (*model.User, error)
 	// GetUserLogin gets a user by unique Login name.
 	GetUserLogin(string) (*model.User, error)
+	// GetUserRemoteLogin gets a user by the remote instance and Login name.
+	GetUserRemoteLogin(remote, login string) (*model.User, error)
 	// GetUserList gets a list of all users in the system.
 	// TODO: paginate
 	GetUserList() ([]*model.User, error)
@@ -45,6 +47,9 @@ type Store interface {
 	GetRepo(int64) (*model.Repo, error)
 	// GetRepoName gets a repo by its full name.
 	GetRepoName(string) (*model.Repo, error)
+	// GetRepoRemoteName gets a repo by the remote instance hosting it and its
+	// full name.
+	GetRepoRemoteName(remote, fullName string) (*model.Repo, error)
 	// GetRepoCount gets a count of all repositories in the system.
 	GetRepoCount() (int64, error)
 	// CreateRepo creates a new repository.
@@ -65,6 +70,8 @@ type Store interface {
 	GetBuildCommit(*model.Repo, string, string) (*model.Build, error)
 	// GetBuildLast gets the last build for the branch.
 	GetBuildLast(*model.Repo, string) (*model.Build, error)
+	// GetBuildLastSuccess gets the last successful build of the event for the branch.
+	GetBuildLastSuccess(*model.Repo, string, model.WebhookEvent) (*model.Build, error)
 	// GetBuildLastBefore gets the last build before build number N.
 	GetBuildLastBefore(*model.Repo, string, int64) (*model.Build, error)
 	// GetBuildList gets a list of builds for the repository
@@ -24,6 +24,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/woodpecker-ci/woodpecker/server"
+	"github.com/woodpecker-ci/woodpecker/server/remote"
 	"github.com/woodpecker-ci/woodpecker/server/router/middleware/session"
 	"github.com/woodpecker-ci/woodpecker/shared/token"
 	"github.com/woodpecker-ci/woodpecker/version"
@@ -45,12 +46,22 @@ func Config(c *gin.Context) {
 		syncing = time.Unix(user.Synced, 0).Add(time.Hour * 72).Before(time.Now())
 	}
 
+	// remotes without capabilities are assumed to support all features
+	var capabilities *remote.Capabilities
+	if provider, ok := server.Config.Services.Remote.(remote.CapabilitiesProvider); ok {
+		var err error
+		if capabilities, err = provider.Capabilities(c); err != nil {
+			log.Warn().Err(err).Msg("could not get capabilities of the remote")
+		}
+	}
+
 	configData := map[string]interface{}{
-		"user":    user,
-		"csrf":    csrf,
-		"syncing": syncing,
-		"docs":    server.Config.Server.Docs,
-		"version": version.String(),
+		"user":         user,
+		"csrf":         csrf,
+		"syncing":      syncing,
+		"docs":         server.Config.Server.Docs,
+		"version":      version.String(),
+		"capabilities": capabilities,
 	}
 
 	// default func map with json parser.
@@ -76,4 +87,5 @@ window.WOODPECKER_SYNC = {{ .syncing }};
 window.WOODPECKER_CSRF = "{{ .csrf }}";
 window.WOODPECKER_VERSION = "{{ .version }}";
 window.WOODPECKER_DOCS = "{{ .docs }}";
+window.WOODPECKER_REMOTE_CAPABILITIES = {{ json .capabilities }};
 `
@@ -51,6 +51,9 @@ export type Build = {
   // The mapping from the local repository to a branch in the remote.
   refspec: string;
 
+  // The branch of the contributor of a pull request, empty if it was deleted.
+  source_branch?: string;
+
   // The remote repository.
   remote: string;
 
@@ -79,6 +82,12 @@ export type Build = {
 
   reviewed_at: number;
 
+  approved_by?: string;
+
+  approved_at?: number;
+
+  review_state?: 'approved' | 'rejected' | 'comment';
+
   // The jobs associated with this build.
   // A build will have multiple jobs if a matrix build was used or if a rebuild was requested.
   procs?: BuildProc[];
@@ -20,6 +20,8 @@ const (
 	pathRepoMove       = "%s/api/repos/%s/%s/move?to=%s"
 	pathChown          = "%s/api/repos/%s/%s/chown"
 	pathRepair         = "%s/api/repos/%s/%s/repair"
+	pathPause          = "%s/api/repos/%s/%s/pause"
+	pathResume         = "%s/api/repos/%s/%s/resume"
 	pathBuilds         = "%s/api/repos/%s/%s/builds"
 	pathBuild          = "%s/api/repos/%s/%s/builds/%v"
 	pathApprove        = "%s/api/repos/%s/%s/builds/%d/approve"
@@ -161,6 +163,22 @@ func (c *client) RepoRepair(owner, name string) error {
 	return c.post(uri, nil, nil)
 }
 
+// RepoPause pauses the repository, its hooks are dropped but kept.
+func (c *client) RepoPause(owner, name string) (*Repo, error) {
+	out := new(Repo)
+	uri := fmt.Sprintf(pathPause, c.addr, owner, name)
+	err := c.post(uri, nil, out)
+	return out, err
+}
+
+// RepoResume resumes the paused repository.
+func (c *client) RepoResume(owner, name string) (*Repo, error) {
+	out := new(Repo)
+	uri := fmt.Sprintf(pathResume, c.addr, owner, name)
+	err := c.post(uri, nil, out)
+	return out, err
+}
+
 // RepoPatch updates a repository.
 func (c *client) RepoPatch(owner, name string, in *RepoPatch) (*Repo, error) {
 	out := new(Repo)
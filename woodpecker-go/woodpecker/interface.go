@@ -56,6 +56,12 @@ type Client interface {
 	// RepoRepair repairs the repository hooks.
 	RepoRepair(string, string) error
 
+	// RepoPause pauses the repository, its hooks are dropped but kept.
+	RepoPause(string, string) (*Repo, error)
+
+	// RepoResume resumes the paused repository.
+	RepoResume(string, string) (*Repo, error)
+
 	// RepoDel deletes a repository.
 	RepoDel(string, string) error
 
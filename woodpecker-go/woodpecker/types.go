@@ -28,6 +28,7 @@ type (
 		IsTrusted  bool   `json:"trusted"`
 		IsStarred  bool   `json:"starred,omitempty"`
 		IsGated    bool   `json:"gated"`
+		IsPaused   bool   `json:"paused,omitempty"`
 		AllowPull  bool   `json:"allow_pr"`
 		Config     string `json:"config_file"`
 	}